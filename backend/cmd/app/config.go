@@ -0,0 +1,33 @@
+package main
+
+import (
+	"backend/internal/config"
+	"flag"
+	"fmt"
+	"os"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// runConfig prints the validated configuration the app runs with, including the secret files and defaults.
+func runConfig(cfg *config.Config, args []string) error {
+	sub, args, err := subcommand("config", args, "print")
+	if err != nil {
+		return err
+	}
+
+	flags := flag.NewFlagSet("config "+sub, flag.ExitOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	switch sub {
+	case "print":
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		defer encoder.Close()
+		return encoder.Encode(cfg.Masked())
+	default:
+		return fmt.Errorf("unknown subcommand %q, usage: linkshelf config print", sub)
+	}
+}
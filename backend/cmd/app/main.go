@@ -2,58 +2,94 @@ package main
 
 import (
 	"backend/internal/config"
-	"backend/internal/domain"
-	"backend/internal/infrastructure/api/controller"
-	"backend/internal/infrastructure/repository"
+	"backend/internal/infrastructure/logging"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
-
-	"github.com/spf13/viper"
 )
 
+const usage = `Usage: linkshelf <command> [arguments]
+
+Commands:
+  serve                          Start the HTTP server (default)
+  migrate up|down|status|force   Manage the database migrations
+  user create|list|delete|reset-password|set-role
+                                 Manage users
+  shelf export|import            Export a shelf to or import a shelf from JSON
+  config print                   Print the effective configuration with masked secrets
+
+Run 'linkshelf <command> -h' for more information about a command.
+`
+
 func main() {
-	err := config.LoadConfig()
+	cfg, err := config.LoadConfig()
 	if err != nil {
 		slog.Error(err.Error())
 		os.Exit(1)
 	}
 
-	loadLogger()
+	command := "serve"
+	args := os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
 
-	repo, err := repository.NewRepository()
-	if err != nil {
+	// Only the server logs to stdout, all other commands write their result to stdout, so the logs
+	// must not be mixed into it.
+	logOutput := io.Writer(os.Stderr)
+	if command == "serve" {
+		logOutput = os.Stdout
+	}
+	if err = loadLogger(logOutput, cfg); err != nil {
 		slog.Error(err.Error())
 		os.Exit(1)
 	}
 
-	svc := domain.NewService(repo)
-
-	router, err := controller.Router(svc)
-	if err != nil {
-		slog.Error(err.Error())
-		os.Exit(1)
+	switch command {
+	case "serve":
+		err = runServe(cfg, args)
+	case "migrate":
+		err = runMigrate(cfg, args)
+	case "user":
+		err = runUser(cfg, args)
+	case "shelf":
+		err = runShelf(cfg, args)
+	case "config":
+		err = runConfig(cfg, args)
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
 	}
 
-	err = router.Run(fmt.Sprintf(":%s", viper.GetString("server.port")))
 	if err != nil {
 		slog.Error(err.Error())
 		os.Exit(1)
 	}
 }
 
-func loadLogger() {
-
-	fmt.Println(viper.GetString("database.name"))
-	fmt.Println(viper.GetString("logging.level"))
-	fmt.Println("---")
-	level := config.ParseLevel(viper.GetString("logging.level"))
+func loadLogger(w io.Writer, cfg *config.Config) error {
+	level := config.ParseLevel(cfg.Logging.Level)
 
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: level,
-	}))
+	logger, err := logging.New(w, cfg.Logging.Format, level)
+	if err != nil {
+		return err
+	}
 
 	slog.SetDefault(logger)
 
 	slog.Info("Logger initialized", slog.String("level", level.String()))
+	return nil
+}
+
+// subcommand splits the arguments of a command into its subcommand and the remaining arguments.
+func subcommand(command string, args []string, subcommands string) (string, []string, error) {
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("missing subcommand, usage: linkshelf %s %s", command, subcommands)
+	}
+
+	return args[0], args[1:], nil
 }
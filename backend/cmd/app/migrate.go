@@ -0,0 +1,111 @@
+package main
+
+import (
+	"backend/internal/config"
+	"backend/internal/infrastructure/repository"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+func runMigrate(cfg *config.Config, args []string) error {
+	sub, args, err := subcommand("migrate", args, "up|down|status|force")
+	if err != nil {
+		return err
+	}
+
+	flags := flag.NewFlagSet("migrate "+sub, flag.ExitOnError)
+	all := false
+	if sub == "down" {
+		flags.BoolVar(&all, "all", false, "Roll back all migrations")
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	m, err := repository.NewMigrate(cfg)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	switch sub {
+	case "up":
+		steps, err := optionalSteps(flags.Args())
+		if err != nil {
+			return err
+		}
+		if steps == 0 {
+			err = m.Up()
+		} else {
+			err = m.Steps(steps)
+		}
+		return migrationResult(err)
+	case "down":
+		// Rolling back is destructive, so the amount of steps has to be given explicitly.
+		steps, err := optionalSteps(flags.Args())
+		if err != nil {
+			return err
+		}
+		switch {
+		case all:
+			err = m.Down()
+		case steps > 0:
+			err = m.Steps(-steps)
+		default:
+			return errors.New("usage: linkshelf migrate down <steps> | --all")
+		}
+		return migrationResult(err)
+	case "status":
+		version, dirty, err := m.Version()
+		if errors.Is(err, migrate.ErrNilVersion) {
+			fmt.Println("no migrations applied")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("version: %d\ndirty: %t\n", version, dirty)
+		return nil
+	case "force":
+		if flags.NArg() != 1 {
+			return errors.New("usage: linkshelf migrate force <version>")
+		}
+		version, err := strconv.Atoi(flags.Arg(0))
+		if err != nil {
+			return fmt.Errorf("invalid version %q: %w", flags.Arg(0), err)
+		}
+		return m.Force(version)
+	default:
+		return fmt.Errorf("unknown subcommand %q, usage: linkshelf migrate up|down|status|force", sub)
+	}
+}
+
+func optionalSteps(args []string) (int, error) {
+	if len(args) == 0 {
+		return 0, nil
+	}
+
+	steps, err := strconv.Atoi(args[0])
+	if err != nil || steps <= 0 {
+		return 0, fmt.Errorf("invalid amount of steps %q", args[0])
+	}
+	return steps, nil
+}
+
+func migrationResult(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		slog.Info("No migrations to apply")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
+	slog.Info("Migrations applied successfully")
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"backend/internal/config"
+	"backend/internal/domain"
+	"backend/internal/infrastructure/api/controller"
+	"backend/internal/infrastructure/certificate"
+	"backend/internal/infrastructure/health"
+	"backend/internal/infrastructure/mail"
+	"backend/internal/infrastructure/ratelimit"
+	"backend/internal/infrastructure/repository"
+	"backend/internal/infrastructure/tracing"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"syscall"
+)
+
+func runServe(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			slog.Error("Failed to flush traces", slog.String("error", err.Error()))
+		}
+	}()
+
+	repo, err := repository.NewRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := repo.Close(); err != nil {
+			slog.Error("Failed to close database", slog.String("error", err.Error()))
+		}
+	}()
+
+	if cfg.Metrics.Enabled {
+		if err := repo.RegisterMetrics(); err != nil {
+			return err
+		}
+	}
+
+	mailer, err := mail.NewMailer(cfg)
+	if err != nil {
+		return err
+	}
+	svc := domain.NewService(cfg, repo, mailer)
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	waitForWorkers := svc.StartWorkers(workerCtx)
+	defer func() {
+		stopWorkers()
+		waitForWorkers()
+	}()
+
+	checks := health.NewRegistry(cfg.Health.Timeout)
+	checks.Register(health.Readiness, "database", repo.Ping)
+	checks.Register(health.Readiness, "migrations", repo.CheckMigrations)
+	checks.Register(health.Liveness, "workers", svc.CheckWorkers)
+
+	limiter, err := ratelimit.NewLimiterFromConfig(cfg)
+	if err != nil {
+		return err
+	}
+	if limiter != nil {
+		defer func() {
+			if err := limiter.Close(); err != nil {
+				slog.Error("Failed to close rate limit store", slog.String("error", err.Error()))
+			}
+		}()
+	}
+
+	router, err := controller.Router(cfg, svc, checks, limiter)
+	if err != nil {
+		return err
+	}
+
+	tlsConfig, certManager, err := certificate.NewTLSConfig(cfg, svc, repo)
+	if err != nil {
+		return err
+	}
+
+	servers := []*http.Server{newHTTPServer(cfg, fmt.Sprintf(":%s", cfg.Server.Port), router)}
+	servers[0].TLSConfig = tlsConfig
+	if certManager != nil {
+		// The HTTP-01 challenges have to be answered on port 80, everything else is redirected to HTTPS.
+		challengeAddr := fmt.Sprintf(":%s", cfg.Server.TLS.ACME.HTTPPort)
+		servers = append(servers, newHTTPServer(cfg, challengeAddr, certManager.HTTPHandler(nil)))
+	}
+
+	serverErr := make(chan error, len(servers))
+	for _, server := range servers {
+		go func() {
+			slog.Info("Server started", slog.String("address", server.Addr), slog.Bool("tls", server.TLSConfig != nil))
+			if server.TLSConfig != nil {
+				serverErr <- server.ListenAndServeTLS("", "")
+			} else {
+				serverErr <- server.ListenAndServe()
+			}
+		}()
+	}
+
+	select {
+	case err = <-serverErr:
+	case <-ctx.Done():
+		stop()
+	}
+
+	slog.Info("Shutting down server, draining in-flight requests")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	for _, server := range servers {
+		if shutdownErr := server.Shutdown(shutdownCtx); shutdownErr != nil {
+			err = errors.Join(err, fmt.Errorf("graceful shutdown of %s failed: %w", server.Addr, shutdownErr))
+		}
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	slog.Info("Server stopped")
+	return nil
+}
+
+func newHTTPServer(cfg *config.Config, addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    cfg.Server.MaxHeaderBytes,
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"backend/internal/config"
+	"backend/internal/domain"
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/mail"
+	"backend/internal/infrastructure/repository"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+func runShelf(cfg *config.Config, args []string) error {
+	sub, args, err := subcommand("shelf", args, "export|import")
+	if err != nil {
+		return err
+	}
+
+	flags := flag.NewFlagSet("shelf "+sub, flag.ExitOnError)
+	var file, userId string
+	switch sub {
+	case "export":
+		flags.StringVar(&file, "output", "-", "File to write the export to, '-' for stdout")
+	case "import":
+		flags.StringVar(&file, "input", "-", "File to read the export from, '-' for stdin")
+		flags.StringVar(&userId, "user", "", "ID of the user who owns the imported shelf (required)")
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	repo, err := repository.NewRepository(cfg)
+	if err != nil {
+		return err
+	}
+	mailer, err := mail.NewMailer(cfg)
+	if err != nil {
+		return err
+	}
+	svc := domain.NewService(cfg, repo, mailer)
+	defer svc.WaitForBackgroundTasks()
+	ctx := context.Background()
+
+	switch sub {
+	case "export":
+		if flags.NArg() != 1 {
+			return errors.New("usage: linkshelf shelf export [--output <file>] <shelfId>")
+		}
+		export, err := svc.ShelfService.ExportShelf(ctx, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		return writeJSON(file, export)
+	case "import":
+		if userId == "" {
+			return errors.New("usage: linkshelf shelf import --user <userId> [--input <file>]")
+		}
+		var export model.ShelfExport
+		if err := readJSON(file, &export); err != nil {
+			return err
+		}
+		shelf, err := svc.ShelfService.ImportShelf(ctx, userId, &export)
+		if err != nil {
+			return err
+		}
+		fmt.Println(shelf.Id)
+		return nil
+	default:
+		return fmt.Errorf("unknown subcommand %q, usage: linkshelf shelf export|import", sub)
+	}
+}
+
+func writeJSON(file string, v any) error {
+	var w io.Writer = os.Stdout
+	if file != "-" {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func readJSON(file string, v any) error {
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid shelf export: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"backend/internal/config"
+	"backend/internal/domain"
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/mail"
+	"backend/internal/infrastructure/repository"
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+func runUser(cfg *config.Config, args []string) error {
+	sub, args, err := subcommand("user", args, "create|list|delete|reset-password|set-role")
+	if err != nil {
+		return err
+	}
+
+	flags := flag.NewFlagSet("user "+sub, flag.ExitOnError)
+	var email, firstName, lastName, password, role string
+	var passwordStdin bool
+	switch sub {
+	case "create":
+		flags.StringVar(&email, "email", "", "Email address of the user (required)")
+		flags.StringVar(&firstName, "first-name", "", "First name of the user")
+		flags.StringVar(&lastName, "last-name", "", "Last name of the user")
+		fallthrough
+	case "reset-password":
+		flags.StringVar(&password, "password", "", "Password of the user, prefer --password-stdin")
+		flags.BoolVar(&passwordStdin, "password-stdin", false, "Read the password from stdin")
+	case "set-role":
+		flags.StringVar(&role, "role", "", "Role of the user, user or admin (required)")
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if passwordStdin {
+		password, err = readPasswordFromStdin()
+		if err != nil {
+			return err
+		}
+	}
+
+	repo, err := repository.NewRepository(cfg)
+	if err != nil {
+		return err
+	}
+	mailer, err := mail.NewMailer(cfg)
+	if err != nil {
+		return err
+	}
+	svc := domain.NewService(cfg, repo, mailer)
+	defer svc.WaitForBackgroundTasks()
+	ctx := context.Background()
+
+	switch sub {
+	case "create":
+		if email == "" || password == "" {
+			return errors.New("usage: linkshelf user create --email <email> --password-stdin [--first-name <name>] [--last-name <name>]")
+		}
+		user, err := svc.UserService.CreateUser(ctx, &model.User{
+			UserBase: model.UserBase{
+				Email:     email,
+				FirstName: firstName,
+				LastName:  lastName,
+			},
+			Password: password,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Println(user.Id)
+		return nil
+	case "list":
+		users, err := svc.UserService.ListUsers(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tEMAIL\tFIRST NAME\tLAST NAME\tROLE")
+		for _, user := range users {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", user.Id, user.Email, user.FirstName, user.LastName, user.Role)
+		}
+		return w.Flush()
+	case "delete":
+		if flags.NArg() != 1 {
+			return errors.New("usage: linkshelf user delete <userId>")
+		}
+		user, err := svc.UserService.GetUserById(ctx, flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return fmt.Errorf("user %s not found", flags.Arg(0))
+		}
+		return svc.UserService.DeleteUser(ctx, user)
+	case "reset-password":
+		if flags.NArg() != 1 || password == "" {
+			return errors.New("usage: linkshelf user reset-password --password-stdin <userId>")
+		}
+		return svc.UserService.ResetPassword(ctx, flags.Arg(0), password)
+	case "set-role":
+		if flags.NArg() != 1 || role == "" {
+			return errors.New("usage: linkshelf user set-role --role user|admin <userId>")
+		}
+		_, err := svc.AdminService.SetRole(ctx, flags.Arg(0), role)
+		return err
+	default:
+		return fmt.Errorf("unknown subcommand %q, usage: linkshelf user create|list|delete|reset-password|set-role", sub)
+	}
+}
+
+// readPasswordFromStdin reads the first line of stdin, so passwords don't end up in the shell history
+// or the process list.
+func readPasswordFromStdin() (string, error) {
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read password from stdin: %w", err)
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
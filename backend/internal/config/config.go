@@ -2,7 +2,11 @@ package config
 
 import (
 	"flag"
+	"fmt"
+	"os"
+	"reflect"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -13,44 +17,190 @@ type Config struct {
 		Description string `yaml:"description" json:"description" mapstructure:"description"`
 		Environment string `yaml:"environment" json:"environment" mapstructure:"environment"`
 		Logo        string `yaml:"logo" json:"logo" mapstructure:"logo"`
+		Version     string `yaml:"version" json:"version" mapstructure:"version"`
 	} `yaml:"app" json:"app" mapstructure:"app"`
 
 	Server struct {
-		Scheme string `yaml:"scheme" json:"scheme" mapstructure:"scheme"`
-		Host   string `yaml:"host" json:"host" mapstructure:"host"`
-		Port   string `yaml:"port" json:"port" mapstructure:"port"`
-	}
+		Scheme            string        `yaml:"scheme" json:"scheme" mapstructure:"scheme"`
+		Host              string        `yaml:"host" json:"host" mapstructure:"host"`
+		Port              string        `yaml:"port" json:"port" mapstructure:"port"`
+		TrustedProxies    []string      `yaml:"trustedProxies" json:"trustedProxies" mapstructure:"trustedProxies"`
+		ReadTimeout       time.Duration `yaml:"readTimeout" json:"readTimeout" mapstructure:"readTimeout"`
+		ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout" json:"readHeaderTimeout" mapstructure:"readHeaderTimeout"`
+		WriteTimeout      time.Duration `yaml:"writeTimeout" json:"writeTimeout" mapstructure:"writeTimeout"`
+		IdleTimeout       time.Duration `yaml:"idleTimeout" json:"idleTimeout" mapstructure:"idleTimeout"`
+		ShutdownTimeout   time.Duration `yaml:"shutdownTimeout" json:"shutdownTimeout" mapstructure:"shutdownTimeout"`
+		MaxHeaderBytes    int           `yaml:"maxHeaderBytes" json:"maxHeaderBytes" mapstructure:"maxHeaderBytes"`
+
+		TLS struct {
+			Mode     string `yaml:"mode" json:"mode" mapstructure:"mode"`
+			CertFile string `yaml:"certFile" json:"certFile" mapstructure:"certFile"`
+			KeyFile  string `yaml:"keyFile" json:"keyFile" mapstructure:"keyFile"`
+
+			ACME struct {
+				DirectoryURL string `yaml:"directoryUrl" json:"directoryUrl" mapstructure:"directoryUrl"`
+				Email        string `yaml:"email" json:"email" mapstructure:"email"`
+				Cache        string `yaml:"cache" json:"cache" mapstructure:"cache"`
+				CacheDir     string `yaml:"cacheDir" json:"cacheDir" mapstructure:"cacheDir"`
+				HTTPPort     string `yaml:"httpPort" json:"httpPort" mapstructure:"httpPort"`
+			} `yaml:"acme" json:"acme" mapstructure:"acme"`
+		} `yaml:"tls" json:"tls" mapstructure:"tls"`
+	} `yaml:"server" json:"server" mapstructure:"server"`
+
+	CORS struct {
+		AllowedOrigins   []string      `yaml:"allowedOrigins" json:"allowedOrigins" mapstructure:"allowedOrigins"`
+		AllowedMethods   []string      `yaml:"allowedMethods" json:"allowedMethods" mapstructure:"allowedMethods"`
+		AllowedHeaders   []string      `yaml:"allowedHeaders" json:"allowedHeaders" mapstructure:"allowedHeaders"`
+		AllowCredentials bool          `yaml:"allowCredentials" json:"allowCredentials" mapstructure:"allowCredentials"`
+		MaxAge           time.Duration `yaml:"maxAge" json:"maxAge" mapstructure:"maxAge"`
+	} `yaml:"cors" json:"cors" mapstructure:"cors"`
+
+	Security struct {
+		ContentSecurityPolicy string `yaml:"contentSecurityPolicy" json:"contentSecurityPolicy" mapstructure:"contentSecurityPolicy"`
+		FrameOptions          string `yaml:"frameOptions" json:"frameOptions" mapstructure:"frameOptions"`
+		ReferrerPolicy        string `yaml:"referrerPolicy" json:"referrerPolicy" mapstructure:"referrerPolicy"`
+
+		HSTS struct {
+			MaxAge            time.Duration `yaml:"maxAge" json:"maxAge" mapstructure:"maxAge"`
+			IncludeSubDomains bool          `yaml:"includeSubDomains" json:"includeSubDomains" mapstructure:"includeSubDomains"`
+		} `yaml:"hsts" json:"hsts" mapstructure:"hsts"`
+	} `yaml:"security" json:"security" mapstructure:"security"`
 
 	Database struct {
 		Engine   string `yaml:"engine" json:"engine" mapstructure:"engine"`
 		Host     string `yaml:"host" json:"host" mapstructure:"host"`
 		Port     string `yaml:"port" json:"port" mapstructure:"port"`
 		Username string `yaml:"username" json:"username" mapstructure:"username"`
-		Password string `yaml:"password" json:"password" mapstructure:"password"`
+		Password string `yaml:"password" json:"password" mapstructure:"password" secret:"true"`
 		Name     string `yaml:"name" json:"name" mapstructure:"name"`
 		Params   string `yaml:"params" json:"params" mapstructure:"params"`
+
+		MaxOpenConns    int           `yaml:"maxOpenConns" json:"maxOpenConns" mapstructure:"maxOpenConns"`
+		MaxIdleConns    int           `yaml:"maxIdleConns" json:"maxIdleConns" mapstructure:"maxIdleConns"`
+		ConnMaxLifetime time.Duration `yaml:"connMaxLifetime" json:"connMaxLifetime" mapstructure:"connMaxLifetime"`
+		ConnMaxIdleTime time.Duration `yaml:"connMaxIdleTime" json:"connMaxIdleTime" mapstructure:"connMaxIdleTime"`
 	} `yaml:"database" json:"database" mapstructure:"database"`
 
+	Health struct {
+		Timeout time.Duration `yaml:"timeout" json:"timeout" mapstructure:"timeout"`
+	} `yaml:"health" json:"health" mapstructure:"health"`
+
+	Metrics struct {
+		Enabled bool   `yaml:"enabled" json:"enabled" mapstructure:"enabled"`
+		Path    string `yaml:"path" json:"path" mapstructure:"path"`
+		Token   string `yaml:"token" json:"token" mapstructure:"token" secret:"true"`
+	} `yaml:"metrics" json:"metrics" mapstructure:"metrics"`
+
+	Tracing struct {
+		Enabled     bool    `yaml:"enabled" json:"enabled" mapstructure:"enabled"`
+		Protocol    string  `yaml:"protocol" json:"protocol" mapstructure:"protocol"`
+		Endpoint    string  `yaml:"endpoint" json:"endpoint" mapstructure:"endpoint"`
+		Insecure    bool    `yaml:"insecure" json:"insecure" mapstructure:"insecure"`
+		ServiceName string  `yaml:"serviceName" json:"serviceName" mapstructure:"serviceName"`
+		SampleRatio float64 `yaml:"sampleRatio" json:"sampleRatio" mapstructure:"sampleRatio"`
+	} `yaml:"tracing" json:"tracing" mapstructure:"tracing"`
+
+	RateLimit struct {
+		Enabled bool   `yaml:"enabled" json:"enabled" mapstructure:"enabled"`
+		Store   string `yaml:"store" json:"store" mapstructure:"store"`
+
+		Redis struct {
+			Address  string `yaml:"address" json:"address" mapstructure:"address"`
+			Password string `yaml:"password" json:"password" mapstructure:"password" secret:"true"`
+			DB       int    `yaml:"db" json:"db" mapstructure:"db"`
+		} `yaml:"redis" json:"redis" mapstructure:"redis"`
+
+		Default    RateLimitPolicy            `yaml:"default" json:"default" mapstructure:"default"`
+		Operations map[string]RateLimitPolicy `yaml:"operations" json:"operations" mapstructure:"operations"`
+	} `yaml:"rateLimit" json:"rateLimit" mapstructure:"rateLimit"`
+
 	Logging struct {
-		Level string `yaml:"level" json:"level" mapstructure:"level"`
+		Level  string `yaml:"level" json:"level" mapstructure:"level"`
+		Format string `yaml:"format" json:"format" mapstructure:"format"`
 	} `yaml:"logging" json:"logging" mapstructure:"logging"`
 
+	Mail struct {
+		Driver  string `yaml:"driver" json:"driver" mapstructure:"driver"`
+		From    string `yaml:"from" json:"from" mapstructure:"from"`
+		BaseURL string `yaml:"baseUrl" json:"baseUrl" mapstructure:"baseUrl"`
+
+		SMTP struct {
+			Host     string `yaml:"host" json:"host" mapstructure:"host"`
+			Port     string `yaml:"port" json:"port" mapstructure:"port"`
+			Username string `yaml:"username" json:"username" mapstructure:"username"`
+			Password string `yaml:"password" json:"password" mapstructure:"password" secret:"true"`
+		} `yaml:"smtp" json:"smtp" mapstructure:"smtp"`
+	} `yaml:"mail" json:"mail" mapstructure:"mail"`
+
+	Authentication struct {
+		OIDC struct {
+			Issuer string `yaml:"issuer" json:"issuer" mapstructure:"issuer"`
+		} `yaml:"oidc" json:"oidc" mapstructure:"oidc"`
+	} `yaml:"authentication" json:"authentication" mapstructure:"authentication"`
+
 	Domain struct {
 		OpenAPI struct {
-			UserPort string `yaml:"userPort" json:"userPort" mapstructure:"userPort"`
-		}
+			UsePort bool `yaml:"usePort" json:"usePort" mapstructure:"usePort"`
+		} `yaml:"openapi" json:"openapi" mapstructure:"openapi"`
 		Authentication struct {
 			SkipAuthentication bool `yaml:"skipAuthentication" json:"skipAuthentication" mapstructure:"skipAuthentication"`
 		} `yaml:"authentication" json:"authentication" mapstructure:"authentication"`
-	}
+		PasswordPolicy PasswordPolicy `yaml:"passwordPolicy" json:"passwordPolicy" mapstructure:"passwordPolicy"`
+		Tokens         struct {
+			EmailVerificationTTL time.Duration `yaml:"emailVerificationTtl" json:"emailVerificationTtl" mapstructure:"emailVerificationTtl"`
+			PasswordResetTTL     time.Duration `yaml:"passwordResetTtl" json:"passwordResetTtl" mapstructure:"passwordResetTtl"`
+			ShelfInvitationTTL   time.Duration `yaml:"shelfInvitationTtl" json:"shelfInvitationTtl" mapstructure:"shelfInvitationTtl"`
+		} `yaml:"tokens" json:"tokens" mapstructure:"tokens"`
+		Sessions struct {
+			TTL              time.Duration `yaml:"ttl" json:"ttl" mapstructure:"ttl"`
+			ImpersonationTTL time.Duration `yaml:"impersonationTtl" json:"impersonationTtl" mapstructure:"impersonationTtl"`
+		} `yaml:"sessions" json:"sessions" mapstructure:"sessions"`
+		TwoFactor struct {
+			EncryptionKey string `yaml:"encryptionKey" json:"encryptionKey" mapstructure:"encryptionKey" secret:"true"`
+		} `yaml:"twoFactor" json:"twoFactor" mapstructure:"twoFactor"`
+		AccountDeletion struct {
+			GracePeriod time.Duration `yaml:"gracePeriod" json:"gracePeriod" mapstructure:"gracePeriod"`
+		} `yaml:"accountDeletion" json:"accountDeletion" mapstructure:"accountDeletion"`
+		Revisions struct {
+			SessionWindow time.Duration `yaml:"sessionWindow" json:"sessionWindow" mapstructure:"sessionWindow"`
+			Limit         int           `yaml:"limit" json:"limit" mapstructure:"limit"`
+		} `yaml:"revisions" json:"revisions" mapstructure:"revisions"`
+		Trash struct {
+			Retention time.Duration `yaml:"retention" json:"retention" mapstructure:"retention"`
+		} `yaml:"trash" json:"trash" mapstructure:"trash"`
+	} `yaml:"domain" json:"domain" mapstructure:"domain"`
+}
+
+// PasswordPolicy is the strength every new password has to satisfy.
+type PasswordPolicy struct {
+	MinLength        int  `yaml:"minLength" json:"minLength" mapstructure:"minLength"`
+	RequireUppercase bool `yaml:"requireUppercase" json:"requireUppercase" mapstructure:"requireUppercase"`
+	RequireLowercase bool `yaml:"requireLowercase" json:"requireLowercase" mapstructure:"requireLowercase"`
+	RequireDigit     bool `yaml:"requireDigit" json:"requireDigit" mapstructure:"requireDigit"`
+	RequireSymbol    bool `yaml:"requireSymbol" json:"requireSymbol" mapstructure:"requireSymbol"`
+}
+
+// RateLimitPolicy limits an operation per client IP and per user.
+type RateLimitPolicy struct {
+	IP   RateLimit `yaml:"ip" json:"ip" mapstructure:"ip"`
+	User RateLimit `yaml:"user" json:"user" mapstructure:"user"`
 }
 
-func LoadConfig() error {
+// RateLimit allows Requests per Period with bursts of up to Requests.
+type RateLimit struct {
+	Requests int           `yaml:"requests" json:"requests" mapstructure:"requests"`
+	Period   time.Duration `yaml:"period" json:"period" mapstructure:"period"`
+}
+
+// LoadConfig reads the configuration file, the environment and the secret files into a validated Config.
+// Unknown keys are rejected, so a typo doesn't silently fall back to the default.
+func LoadConfig() (*Config, error) {
 	viper.SetEnvPrefix("APP")
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
 
 	viper.SetConfigType("yaml")
+	setDefaults()
 
 	if isRunningTests() {
 		viper.SetConfigName("config.test")
@@ -65,15 +215,194 @@ func LoadConfig() error {
 	viper.AddConfigPath("../../../..")
 
 	if err := viper.ReadInConfig(); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Optional local override (lowest priority)
 	viper.SetConfigName("config")
 	_ = viper.MergeInConfig()
 
+	if err := readSecretFiles(); err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if err := viper.UnmarshalExact(&config); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &config, nil
+}
+
+// Masked returns a copy of the configuration whose fields tagged secret:"true" are replaced by *** unless
+// they're empty, so it can be printed.
+func (c Config) Masked() Config {
+	maskSecrets(reflect.ValueOf(&c).Elem())
+	return c
+}
+
+func maskSecrets(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		switch {
+		case v.Type().Field(i).Tag.Get("secret") == "true":
+			if field.String() != "" {
+				field.SetString("***")
+			}
+		case field.Kind() == reflect.Struct:
+			maskSecrets(field)
+		}
+	}
+}
+
+// readSecretFiles reads the value of every key whose environment variable has a _FILE variant from that
+// file, e.g. APP_DATABASE_PASSWORD_FILE=/run/secrets/db-password. This keeps secrets out of the
+// environment, where they leak into process listings and crash reports.
+func readSecretFiles() error {
+	for _, key := range viper.AllKeys() {
+		variable := "APP_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_")) + "_FILE"
+		path, ok := os.LookupEnv(variable)
+		if !ok {
+			continue
+		}
+
+		value, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", variable, err)
+		}
+		viper.Set(key, strings.TrimRight(string(value), "\r\n"))
+	}
+
 	return nil
 }
+
+// setDefaults sets the values of optional keys, so existing configuration files keep working when new
+// keys are introduced.
+func setDefaults() {
+	viper.SetDefault("app.version", "dev")
+
+	viper.SetDefault("server.readTimeout", 15*time.Second)
+	viper.SetDefault("server.readHeaderTimeout", 5*time.Second)
+	viper.SetDefault("server.writeTimeout", 30*time.Second)
+	viper.SetDefault("server.idleTimeout", 120*time.Second)
+	viper.SetDefault("server.shutdownTimeout", 30*time.Second)
+	viper.SetDefault("server.maxHeaderBytes", 1<<20)
+	viper.SetDefault("server.tls.mode", "none")
+	viper.SetDefault("server.tls.acme.directoryUrl", "https://acme-v02.api.letsencrypt.org/directory")
+	viper.SetDefault("server.tls.acme.cache", "database")
+	viper.SetDefault("server.tls.acme.httpPort", "80")
+
+	viper.SetDefault("cors.allowedMethods", []string{"GET", "POST", "PUT", "PATCH", "DELETE"})
+	viper.SetDefault("cors.allowedHeaders", []string{"Authorization", "Content-Type", "X-Request-ID", "X-OTP-Code"})
+	viper.SetDefault("cors.maxAge", 10*time.Minute)
+
+	// The public shelf pages show icons of arbitrary sites, everything else has to come from the instance.
+	viper.SetDefault("security.contentSecurityPolicy", "default-src 'self'; img-src 'self' data: https:; "+
+		"style-src 'self' 'unsafe-inline'; object-src 'none'; base-uri 'self'; form-action 'self'; frame-ancestors 'none'")
+	viper.SetDefault("security.frameOptions", "DENY")
+	viper.SetDefault("security.referrerPolicy", "strict-origin-when-cross-origin")
+	viper.SetDefault("security.hsts.maxAge", 365*24*time.Hour)
+	viper.SetDefault("security.hsts.includeSubDomains", false)
+
+	viper.SetDefault("health.timeout", 2*time.Second)
+
+	viper.SetDefault("metrics.enabled", false)
+	viper.SetDefault("metrics.path", "/metrics")
+
+	viper.SetDefault("tracing.enabled", false)
+	viper.SetDefault("tracing.protocol", "grpc")
+	viper.SetDefault("tracing.endpoint", "localhost:4317")
+	viper.SetDefault("tracing.serviceName", "linkshelf")
+	viper.SetDefault("tracing.sampleRatio", 1.0)
+
+	viper.SetDefault("rateLimit.enabled", true)
+	viper.SetDefault("rateLimit.store", "memory")
+	viper.SetDefault("rateLimit.redis.address", "localhost:6379")
+	viper.SetDefault("rateLimit.default.ip.requests", 300)
+	viper.SetDefault("rateLimit.default.ip.period", time.Minute)
+	viper.SetDefault("rateLimit.default.user.requests", 600)
+	viper.SetDefault("rateLimit.default.user.period", time.Minute)
+	// Configured operations replace these defaults as a whole, so the sensitive ones have to be listed again.
+	viper.SetDefault("rateLimit.operations", map[string]any{
+		"post-create-user": map[string]any{
+			"ip": map[string]any{"requests": 10, "period": time.Hour},
+		},
+		"patch-user-password": map[string]any{
+			"ip":   map[string]any{"requests": 5, "period": 15 * time.Minute},
+			"user": map[string]any{"requests": 5, "period": 15 * time.Minute},
+		},
+		"post-forgot-password": map[string]any{
+			"ip": map[string]any{"requests": 5, "period": 15 * time.Minute},
+		},
+		"post-reset-password": map[string]any{
+			"ip": map[string]any{"requests": 5, "period": 15 * time.Minute},
+		},
+		"post-verify-email": map[string]any{
+			"ip": map[string]any{"requests": 10, "period": 15 * time.Minute},
+		},
+		"post-resend-email-verification": map[string]any{
+			"user": map[string]any{"requests": 3, "period": time.Hour},
+		},
+		"get-user-data-export": map[string]any{
+			"user": map[string]any{"requests": 5, "period": time.Hour},
+		},
+		"post-login": map[string]any{
+			"ip":   map[string]any{"requests": 10, "period": 15 * time.Minute},
+			"user": map[string]any{"requests": 10, "period": 15 * time.Minute},
+		},
+		"post-confirm-two-factor": map[string]any{
+			"user": map[string]any{"requests": 5, "period": 15 * time.Minute},
+		},
+		"delete-two-factor": map[string]any{
+			"user": map[string]any{"requests": 5, "period": 15 * time.Minute},
+		},
+		"post-regenerate-recovery-codes": map[string]any{
+			"user": map[string]any{"requests": 5, "period": 15 * time.Minute},
+		},
+		"delete-user": map[string]any{
+			"user": map[string]any{"requests": 5, "period": 15 * time.Minute},
+		},
+		"post-create-personal-access-token": map[string]any{
+			"user": map[string]any{"requests": 10, "period": time.Hour},
+		},
+		"post-create-shelf-invitation": map[string]any{
+			"user": map[string]any{"requests": 20, "period": time.Hour},
+		},
+		"post-decline-shelf-invitation": map[string]any{
+			"ip": map[string]any{"requests": 10, "period": 15 * time.Minute},
+		},
+	})
+
+	viper.SetDefault("logging.level", "info")
+	viper.SetDefault("logging.format", "json")
+
+	viper.SetDefault("mail.driver", "log")
+	viper.SetDefault("mail.from", "LinkShelf <no-reply@localhost>")
+	viper.SetDefault("mail.baseUrl", "http://localhost:3000")
+	viper.SetDefault("mail.smtp.port", "587")
+
+	viper.SetDefault("domain.authentication.skipAuthentication", false)
+	viper.SetDefault("domain.passwordPolicy.minLength", 12)
+
+	viper.SetDefault("domain.tokens.emailVerificationTtl", 48*time.Hour)
+	viper.SetDefault("domain.tokens.passwordResetTtl", time.Hour)
+	viper.SetDefault("domain.tokens.shelfInvitationTtl", 7*24*time.Hour)
+	viper.SetDefault("domain.sessions.ttl", 24*time.Hour)
+	viper.SetDefault("domain.sessions.impersonationTtl", time.Hour)
+	viper.SetDefault("domain.accountDeletion.gracePeriod", 30*24*time.Hour)
+	viper.SetDefault("domain.revisions.sessionWindow", 10*time.Minute)
+	viper.SetDefault("domain.revisions.limit", 100)
+	viper.SetDefault("domain.trash.retention", 30*24*time.Hour)
+
+	viper.SetDefault("database.maxOpenConns", 25)
+	viper.SetDefault("database.maxIdleConns", 25)
+	viper.SetDefault("database.connMaxLifetime", 5*time.Minute)
+	viper.SetDefault("database.connMaxIdleTime", 5*time.Minute)
+}
+
 func isRunningTests() bool {
 	return flag.Lookup("test.v") != nil
 }
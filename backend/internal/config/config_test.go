@@ -0,0 +1,106 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func loadConfig(t *testing.T) (*Config, error) {
+	t.Helper()
+
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	return LoadConfig()
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := loadConfig(t)
+	require.NoError(t, err)
+
+	require.Equal(t, "LinkShelfTest", cfg.App.Name)
+	require.True(t, cfg.Domain.OpenAPI.UsePort)
+	require.Equal(t, []string{"127.0.0.1"}, cfg.Server.TrustedProxies)
+	require.Equal(t, 5, cfg.RateLimit.Operations["patch-user-password"].User.Requests)
+}
+
+func TestDefaultRateLimitsMatchTheDefaultConfig(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	setDefaults()
+	var defaults Config
+	require.NoError(t, viper.Unmarshal(&defaults))
+
+	viper.Reset()
+	viper.SetConfigFile(filepath.Join("..", "..", "config.default.yaml"))
+	require.NoError(t, viper.ReadInConfig())
+	var file Config
+	require.NoError(t, viper.Unmarshal(&file))
+
+	require.Equal(t, file.RateLimit.Operations, defaults.RateLimit.Operations)
+}
+
+func TestLoadConfigRejectsUnknownKeys(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set("domain.openapi.userPort", true)
+
+	_, err := LoadConfig()
+	require.ErrorContains(t, err, "userport")
+}
+
+func TestLoadConfigReadsSecretFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db-password")
+	require.NoError(t, os.WriteFile(path, []byte("from-file\n"), 0o600))
+	t.Setenv("APP_DATABASE_PASSWORD_FILE", path)
+
+	cfg, err := loadConfig(t)
+	require.NoError(t, err)
+	require.Equal(t, "from-file", cfg.Database.Password)
+}
+
+func TestValidate(t *testing.T) {
+	cfg, err := loadConfig(t)
+	require.NoError(t, err)
+
+	cfg.Server.Port = "http"
+	cfg.Database.Engine = "sqlite"
+	cfg.Tracing.SampleRatio = 2
+	cfg.CORS.AllowedOrigins = []string{"*"}
+	cfg.CORS.AllowCredentials = true
+	cfg.Metrics.Enabled = true
+	cfg.Metrics.Token = ""
+	cfg.App.Environment = "prod"
+	cfg.Mail.Driver = "memory"
+	cfg.Domain.TwoFactor.EncryptionKey = "dG9vIHNob3J0"
+
+	err = cfg.Validate()
+	require.ErrorContains(t, err, "server.port")
+	require.ErrorContains(t, err, "database.engine")
+	require.ErrorContains(t, err, "tracing.sampleRatio")
+	require.ErrorContains(t, err, "cors.allowedOrigins")
+	require.ErrorContains(t, err, "metrics.token")
+	require.ErrorContains(t, err, "mail.driver memory")
+	require.ErrorContains(t, err, "domain.twoFactor.encryptionKey")
+}
+
+func TestMaskedHidesTheSecretFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db-password")
+	require.NoError(t, os.WriteFile(path, []byte("from-file\n"), 0o600))
+	t.Setenv("APP_DATABASE_PASSWORD_FILE", path)
+
+	cfg, err := loadConfig(t)
+	require.NoError(t, err)
+	cfg.Mail.SMTP.Password = ""
+
+	masked := cfg.Masked()
+	require.Equal(t, "***", masked.Database.Password)
+	require.Equal(t, "***", masked.Metrics.Token)
+	require.Equal(t, "***", masked.Domain.TwoFactor.EncryptionKey)
+	require.Empty(t, masked.Mail.SMTP.Password, "empty secrets show that they aren't set")
+	require.Equal(t, cfg.Database.Host, masked.Database.Host)
+	require.Equal(t, "from-file", cfg.Database.Password, "the configuration itself isn't changed")
+}
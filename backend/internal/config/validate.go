@@ -0,0 +1,117 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Validate checks the values which can't be expressed by the types of Config, so misconfigurations fail
+// on startup instead of on the first request which needs them.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		check(slices.Contains(allowed, strings.ToLower(value)), "%s must be one of %s, got %q", key, strings.Join(allowed, ", "), value)
+	}
+
+	check(c.App.Name != "", "app.name is required")
+
+	oneOf("server.scheme", c.Server.Scheme, "http", "https")
+	check(c.Server.Host != "", "server.host is required")
+	check(validPort(c.Server.Port), "server.port must be a port number, got %q", c.Server.Port)
+	check(c.Server.ShutdownTimeout > 0, "server.shutdownTimeout must be positive")
+	oneOf("server.tls.mode", c.Server.TLS.Mode, "none", "static", "acme")
+	switch strings.ToLower(c.Server.TLS.Mode) {
+	case "static":
+		check(c.Server.TLS.CertFile != "" && c.Server.TLS.KeyFile != "", "server.tls.certFile and server.tls.keyFile are required in the static TLS mode")
+	case "acme":
+		oneOf("server.tls.acme.cache", c.Server.TLS.ACME.Cache, "database", "directory")
+		check(!strings.EqualFold(c.Server.TLS.ACME.Cache, "directory") || c.Server.TLS.ACME.CacheDir != "", "server.tls.acme.cacheDir is required for the directory cache")
+		check(validPort(c.Server.TLS.ACME.HTTPPort), "server.tls.acme.httpPort must be a port number, got %q", c.Server.TLS.ACME.HTTPPort)
+	}
+
+	check(!(slices.Contains(c.CORS.AllowedOrigins, "*") && c.CORS.AllowCredentials), "cors.allowedOrigins can't contain * if cors.allowCredentials is enabled")
+
+	oneOf("database.engine", c.Database.Engine, "postgres", "mysql")
+	check(c.Database.Host != "", "database.host is required")
+	check(validPort(c.Database.Port), "database.port must be a port number, got %q", c.Database.Port)
+	check(c.Database.Name != "", "database.name is required")
+	check(c.Database.Username != "", "database.username is required")
+
+	check(c.Health.Timeout > 0, "health.timeout must be positive")
+	check(strings.HasPrefix(c.Metrics.Path, "/"), "metrics.path must start with /, got %q", c.Metrics.Path)
+	// The server listens on every interface, so the metrics would be public without a token.
+	check(!c.Metrics.Enabled || c.Metrics.Token != "", "metrics.token is required if metrics are enabled")
+
+	oneOf("tracing.protocol", c.Tracing.Protocol, "grpc", "http")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sampleRatio must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+
+	if c.RateLimit.Enabled {
+		oneOf("rateLimit.store", c.RateLimit.Store, "memory", "redis")
+		check(!strings.EqualFold(c.RateLimit.Store, "redis") || c.RateLimit.Redis.Address != "", "rateLimit.redis.address is required for the redis store")
+		errs = append(errs, c.RateLimit.Default.validate("rateLimit.default")...)
+		for operation, policy := range c.RateLimit.Operations {
+			errs = append(errs, policy.validate("rateLimit.operations."+operation)...)
+		}
+	}
+
+	oneOf("mail.driver", c.Mail.Driver, "smtp", "log", "memory")
+	// The memory driver keeps the mails instead of delivering them, outside of tests they would be lost.
+	check(!strings.EqualFold(c.Mail.Driver, "memory") || strings.EqualFold(c.App.Environment, "test"), "mail.driver memory is only allowed in the test environment, use smtp or log")
+	_, err := mail.ParseAddress(c.Mail.From)
+	check(err == nil, "mail.from must be a mail address, got %q", c.Mail.From)
+	baseURL, err := url.Parse(c.Mail.BaseURL)
+	check(err == nil && baseURL.IsAbs(), "mail.baseUrl must be an absolute URL, got %q", c.Mail.BaseURL)
+	if strings.EqualFold(c.Mail.Driver, "smtp") {
+		check(c.Mail.SMTP.Host != "", "mail.smtp.host is required for the smtp driver")
+		check(validPort(c.Mail.SMTP.Port), "mail.smtp.port must be a port number, got %q", c.Mail.SMTP.Port)
+	}
+
+	// bcrypt only considers the first 72 bytes, longer minimums would reject every password.
+	check(c.Domain.PasswordPolicy.MinLength >= 1 && c.Domain.PasswordPolicy.MinLength <= 72, "domain.passwordPolicy.minLength must be between 1 and 72, got %d", c.Domain.PasswordPolicy.MinLength)
+	check(c.Domain.Tokens.EmailVerificationTTL > 0, "domain.tokens.emailVerificationTtl must be positive")
+	check(c.Domain.Tokens.PasswordResetTTL > 0, "domain.tokens.passwordResetTtl must be positive")
+	check(c.Domain.Tokens.ShelfInvitationTTL > 0, "domain.tokens.shelfInvitationTtl must be positive")
+	check(c.Domain.Sessions.TTL > 0, "domain.sessions.ttl must be positive")
+	check(c.Domain.Sessions.ImpersonationTTL > 0, "domain.sessions.impersonationTtl must be positive")
+	// The TOTP secrets are encrypted with AES-256, which takes a key of 32 bytes.
+	twoFactorKey, err := base64.StdEncoding.DecodeString(c.Domain.TwoFactor.EncryptionKey)
+	check(err == nil && len(twoFactorKey) == 32, "domain.twoFactor.encryptionKey must be 32 base64 encoded bytes, e.g. from openssl rand -base64 32")
+	check(c.Domain.AccountDeletion.GracePeriod >= 0, "domain.accountDeletion.gracePeriod must not be negative")
+	check(c.Domain.Revisions.SessionWindow >= 0, "domain.revisions.sessionWindow must not be negative")
+	check(c.Domain.Revisions.Limit >= 1, "domain.revisions.limit must be at least 1, got %d", c.Domain.Revisions.Limit)
+	check(c.Domain.Trash.Retention >= 0, "domain.trash.retention must not be negative")
+
+	oneOf("logging.level", c.Logging.Level, "debug", "info", "warn", "warning", "error")
+	oneOf("logging.format", c.Logging.Format, "json", "text")
+
+	return errors.Join(errs...)
+}
+
+func (p RateLimitPolicy) validate(key string) []error {
+	var errs []error
+	for name, limit := range map[string]RateLimit{"ip": p.IP, "user": p.User} {
+		if limit.Requests < 0 || limit.Period < 0 {
+			errs = append(errs, fmt.Errorf("%s.%s must not be negative", key, name))
+		}
+		if limit.Requests > 0 && limit.Period == 0 {
+			errs = append(errs, fmt.Errorf("%s.%s.period is required", key, name))
+		}
+	}
+	return errs
+}
+
+func validPort(port string) bool {
+	number, err := strconv.Atoi(port)
+	return err == nil && number > 0 && number <= 65535
+}
@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/repository"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrForbidden is returned if the caller lacks the role on a shelf or team which an action needs.
+var ErrForbidden = errors.New("you lack the permission for this action")
+
+// roleRanks orders the roles of team members and shares, every role includes the permissions of the lower
+// ones. Unknown roles, like no role at all, rank lowest.
+var roleRanks = map[string]int{
+	model.ShelfRoleViewer: 1,
+	model.ShelfRoleEditor: 2,
+	model.ShelfRoleOwner:  3,
+}
+
+func hasRole(role, required string) bool {
+	return roleRanks[role] >= roleRanks[required]
+}
+
+// authorizeShelf loads the shelf and checks that the caller has at least the required role on it. Calls
+// without a principal, i.e. by the CLI or with skipped authentication, may do everything.
+func authorizeShelf(ctx context.Context, repo *repository.Repository, shelfId, required string) (*model.Shelf, error) {
+	shelf, err := repo.ShelfRepository.Get(ctx, shelfId)
+	if err != nil {
+		return nil, err
+	}
+	if shelf == nil {
+		return nil, fmt.Errorf("shelf %s not found", shelfId)
+	}
+
+	principal := PrincipalFromContext(ctx)
+	if principal == nil {
+		return shelf, nil
+	}
+
+	role, err := shelfRole(ctx, repo, shelf, principal.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if !hasRole(role, required) {
+		return nil, ErrForbidden
+	}
+
+	return shelf, nil
+}
+
+// shelfRole returns the role of the user on the shelf. The user of a personal shelf owns it, everybody else
+// gets the better role of the team membership and the share.
+func shelfRole(ctx context.Context, repo *repository.Repository, shelf *model.Shelf, userId string) (string, error) {
+	if shelf.UserId != "" && shelf.UserId == userId {
+		return model.ShelfRoleOwner, nil
+	}
+
+	var role string
+	if shelf.TeamId != "" {
+		member, err := repo.TeamRepository.GetMember(ctx, shelf.TeamId, userId)
+		if err != nil {
+			return "", err
+		}
+		if member != nil {
+			role = member.Role
+		}
+	}
+	if role == model.ShelfRoleOwner {
+		return role, nil
+	}
+
+	share, err := repo.ShelfShareRepository.Get(ctx, shelf.Id, userId)
+	if err != nil {
+		return "", err
+	}
+	if share != nil && roleRanks[share.Role] > roleRanks[role] {
+		role = share.Role
+	}
+
+	return role, nil
+}
+
+// authorizeSection checks the role on the shelf of the section.
+func authorizeSection(ctx context.Context, repo *repository.Repository, sectionId, required string) (*model.Section, error) {
+	section, err := repo.SectionRepository.Get(ctx, sectionId)
+	if err != nil {
+		return nil, err
+	}
+	if section == nil {
+		return nil, fmt.Errorf("section %s not found", sectionId)
+	}
+
+	_, err = authorizeShelf(ctx, repo, section.ShelfId, required)
+	if err != nil {
+		return nil, err
+	}
+
+	return section, nil
+}
+
+// authorizeLink checks the role on the shelf of the section of the link. The section is returned as well,
+// it tells the shelf of the link.
+func authorizeLink(ctx context.Context, repo *repository.Repository, linkId, required string) (*model.Link, *model.Section, error) {
+	link, err := repo.LinkRepository.Get(ctx, linkId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if link == nil {
+		return nil, nil, fmt.Errorf("link %s not found", linkId)
+	}
+
+	section, err := authorizeSection(ctx, repo, link.SectionId, required)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return link, section, nil
+}
+
+// authorizeTeam loads the team and checks that the caller is a member with at least the required role.
+// Like authorizeShelf, calls without a principal may do everything.
+func authorizeTeam(ctx context.Context, repo *repository.Repository, teamId, required string) (*model.Team, error) {
+	team, err := repo.TeamRepository.Get(ctx, teamId)
+	if err != nil {
+		return nil, err
+	}
+	if team == nil {
+		return nil, fmt.Errorf("team %s not found", teamId)
+	}
+
+	principal := PrincipalFromContext(ctx)
+	if principal == nil {
+		return team, nil
+	}
+
+	member, err := repo.TeamRepository.GetMember(ctx, teamId, principal.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil || !hasRole(member.Role, required) {
+		return nil, ErrForbidden
+	}
+
+	return team, nil
+}
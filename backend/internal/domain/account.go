@@ -0,0 +1,206 @@
+package domain
+
+import (
+	"archive/zip"
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/logging"
+	"backend/internal/infrastructure/repository"
+	"backend/internal/infrastructure/tracing"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"time"
+)
+
+// ErrAccountDisabled is returned for changes to a disabled account, e.g. during the grace period of its
+// deletion.
+var ErrAccountDisabled = errors.New("the account is disabled")
+
+// exportReadme explains the content of a data export archive.
+const exportReadme = `This archive contains all data LinkShelf stores about your account.
+
+profile.json      your profile, the password is only stored as a hash and therefore not included
+shelves/*.json    one file per shelf with all its sections and links, each can be imported again
+
+LinkShelf doesn't record analytics like visits or clicks of your shelves, so there are none to export.
+`
+
+var unsafeFileNameCharacters = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// ExportUserData creates a ZIP archive with the profile and all shelves of the user.
+func (s *userServiceImpl) ExportUserData(ctx context.Context, userId string) ([]byte, error) {
+	ctx, span := tracing.Start(ctx, "UserService.ExportUserData")
+	defer span.End()
+
+	user, err := s.Repository.UserRepository.Get(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %s not found", userId)
+	}
+
+	shelves, err := s.Repository.ShelfRepository.ListByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	err = writeArchiveFile(archive, "README.txt", []byte(exportReadme))
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeArchiveJSON(archive, "profile.json", model.UserOutput{
+		Id:               user.Id,
+		Email:            user.Email,
+		FirstName:        user.FirstName,
+		LastName:         user.LastName,
+		EmailVerifiedAt:  user.EmailVerifiedAt,
+		DisabledAt:       user.DisabledAt,
+		PurgeAt:          user.PurgeAt,
+		TwoFactorEnabled: user.TwoFactorEnabled,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, shelf := range shelves {
+		export, err := s.Domain.ShelfService.ExportShelf(ctx, shelf.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		// The index keeps the names unique, the path only makes them recognizable.
+		name := fmt.Sprintf("shelves/%03d-%s.json", i+1, unsafeFileNameCharacters.ReplaceAllString(shelf.Path, "_"))
+		err = writeArchiveJSON(archive, name, export)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("User data exported", slog.String("userId", userId), slog.Int("shelves", len(shelves)))
+	return buf.Bytes(), nil
+}
+
+// ScheduleUserDeletion disables the account right away and deletes it with all its data once the grace
+// period ended. Until then, logging in restores the account, see AuthService.Login.
+func (s *userServiceImpl) ScheduleUserDeletion(ctx context.Context, userId string, secondFactor string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.ScheduleUserDeletion")
+	defer span.End()
+
+	if err := rejectImpersonation(ctx); err != nil {
+		return nil, err
+	}
+
+	user, err := s.Repository.UserRepository.Get(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %s not found", userId)
+	}
+	if user.PurgeAt != nil {
+		return user, nil
+	}
+
+	err = verifySecondFactor(ctx, s.Repository, s.Domain.totpKey, userId, secondFactor)
+	if err != nil {
+		return nil, err
+	}
+
+	disabledAt := time.Now().UTC()
+	purgeAt := disabledAt.Add(s.Domain.config.Domain.AccountDeletion.GracePeriod)
+	err = s.Repository.UserRepository.ScheduleDeletion(ctx, userId, disabledAt, purgeAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Disabled users can't use their sessions anyway, ending them keeps the table small.
+	err = s.Repository.SessionRepository.DeleteByUser(ctx, userId, "")
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("User deletion scheduled", slog.String("userId", userId), slog.Time("purgeAt", purgeAt))
+	scheduled, err := s.Repository.UserRepository.Get(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.Repository, model.AuditActionUpdate, model.AuditEntityUser, userId, "", auditUser(user), auditUser(scheduled))
+	return scheduled, nil
+}
+
+// requireEnabledUser loads the user and fails if it doesn't exist or is disabled.
+func requireEnabledUser(ctx context.Context, repo *repository.Repository, userId string) (*model.User, error) {
+	user, err := repo.UserRepository.Get(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %s not found", userId)
+	}
+	if user.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+	return user, nil
+}
+
+func writeArchiveJSON(archive *zip.Writer, name string, v any) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeArchiveFile(archive, name, content)
+}
+
+func writeArchiveFile(archive *zip.Writer, name string, content []byte) error {
+	writer, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(content)
+	return err
+}
+
+func purgeDeletedUsers(repo *repository.Repository) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		now := time.Now().UTC()
+		ids, err := repo.UserRepository.ListPurgeable(ctx, now)
+		if err != nil {
+			return err
+		}
+		// The events of the users stay in the audit log, only without their personal data. The redaction
+		// comes first, a failed one is repeated by the next run as long as the user isn't purged.
+		for _, id := range ids {
+			err := repo.AuditRepository.RedactUser(ctx, id, auditRedacted)
+			if err != nil {
+				return err
+			}
+		}
+
+		purged, err := repo.UserRepository.PurgeDeleted(ctx, now)
+		if err != nil {
+			return err
+		}
+		if purged > 0 {
+			logging.FromContext(ctx).Info("Deleted users purged", slog.Int64("count", purged))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,294 @@
+package domain
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/logging"
+	"backend/internal/infrastructure/repository"
+	"backend/internal/infrastructure/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// ErrSelfAdministration is returned if admins try to disable, demote or impersonate themselves, which would
+// lock them out or serve no purpose.
+var ErrSelfAdministration = errors.New("admins can't apply this action to their own account")
+
+// ErrImpersonated is returned if an impersonated session tries to change the credentials of the account.
+// They would outlive the short session, so the admin could take the account over for good.
+var ErrImpersonated = fmt.Errorf("%w: impersonated sessions can't change the credentials of the account", ErrForbidden)
+
+type AdminService interface {
+	SearchUsers(ctx context.Context, search model.UserSearch) ([]model.User, int64, error)
+	SetRole(ctx context.Context, userId, role string) (*model.User, error)
+	DisableUser(ctx context.Context, userId string) (*model.User, error)
+	EnableUser(ctx context.Context, userId string) (*model.User, error)
+	Impersonate(ctx context.Context, userId string) (string, *model.UserSession, error)
+	Statistics(ctx context.Context) (*model.Statistics, error)
+	UnpublishShelf(ctx context.Context, shelfId string) (*model.Shelf, error)
+	PublishShelf(ctx context.Context, shelfId string) (*model.Shelf, error)
+	DeleteShelf(ctx context.Context, shelfId string) error
+}
+
+type adminServiceImpl struct {
+	Repository *repository.Repository
+	Domain     *Service
+}
+
+func NewAdminService(repository *repository.Repository, domain *Service) AdminService {
+	return &adminServiceImpl{
+		Repository: repository,
+		Domain:     domain,
+	}
+}
+
+func (s *adminServiceImpl) SearchUsers(ctx context.Context, search model.UserSearch) ([]model.User, int64, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.SearchUsers")
+	defer span.End()
+
+	return s.Repository.UserRepository.Search(ctx, search)
+}
+
+// SetRole changes the role of a user. It's also used by the CLI to appoint the first admin.
+func (s *adminServiceImpl) SetRole(ctx context.Context, userId, role string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.SetRole")
+	defer span.End()
+
+	if !slices.Contains([]string{model.RoleUser, model.RoleAdmin}, role) {
+		return nil, fmt.Errorf("unknown role %q", role)
+	}
+	if isCurrentAdmin(ctx, userId) {
+		return nil, ErrSelfAdministration
+	}
+
+	user, err := getUser(ctx, s.Repository, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Repository.UserRepository.SetRole(ctx, userId, role)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log(ctx, "User role changed", userId, slog.String("role", role), slog.String("previousRole", user.Role))
+	return s.auditedUser(ctx, user)
+}
+
+// DisableUser disables the account and ends all its sessions. It stays disabled until an admin enables it
+// again, a login doesn't restore it.
+func (s *adminServiceImpl) DisableUser(ctx context.Context, userId string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.DisableUser")
+	defer span.End()
+
+	if isCurrentAdmin(ctx, userId) {
+		return nil, ErrSelfAdministration
+	}
+
+	user, err := getUser(ctx, s.Repository, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Repository.UserRepository.Disable(ctx, userId, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Repository.SessionRepository.DeleteByUser(ctx, userId, "")
+	if err != nil {
+		return nil, err
+	}
+
+	s.log(ctx, "User disabled", userId)
+	return s.auditedUser(ctx, user)
+}
+
+// EnableUser enables a disabled account, which also cancels a scheduled deletion.
+func (s *adminServiceImpl) EnableUser(ctx context.Context, userId string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.EnableUser")
+	defer span.End()
+
+	user, err := getUser(ctx, s.Repository, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.DisabledAt == nil {
+		return nil, fmt.Errorf("user %s is not disabled", userId)
+	}
+
+	err = s.Repository.UserRepository.Enable(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log(ctx, "User enabled", userId)
+	return s.auditedUser(ctx, user)
+}
+
+// Impersonate starts a short session as the user for support. The session records the admin, so the
+// actions taken with it can be told apart from the ones of the user. Other admins can't be impersonated,
+// since that would allow taking over their accounts, and the session can't change credentials, see
+// rejectImpersonation.
+func (s *adminServiceImpl) Impersonate(ctx context.Context, userId string) (string, *model.UserSession, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.Impersonate")
+	defer span.End()
+
+	principal := PrincipalFromContext(ctx)
+	if principal == nil {
+		return "", nil, errors.New("impersonation requires an authenticated admin")
+	}
+	if principal.UserId == userId {
+		return "", nil, ErrSelfAdministration
+	}
+	if err := rejectImpersonation(ctx); err != nil {
+		return "", nil, err
+	}
+
+	user, err := requireEnabledUser(ctx, s.Repository, userId)
+	if err != nil {
+		return "", nil, err
+	}
+	if user.Role == model.RoleAdmin {
+		return "", nil, errors.New("admins can't be impersonated")
+	}
+
+	token, session, err := startSession(ctx, s.Repository, userId, principal.UserId, s.Domain.config.Domain.Sessions.ImpersonationTTL)
+	if err != nil {
+		return "", nil, err
+	}
+
+	s.log(ctx, "User impersonated", userId, slog.String("sessionId", session.Id))
+	recordAudit(ctx, s.Repository, model.AuditActionUpdate, model.AuditEntityUser, userId, "", nil,
+		map[string]any{"impersonation_session_id": session.Id, "impersonation_expires_at": session.ExpiresAt})
+	return token, session, nil
+}
+
+// rejectImpersonation fails for impersonated sessions, every operation which changes how the account
+// signs in calls it first.
+func rejectImpersonation(ctx context.Context) error {
+	if principal := PrincipalFromContext(ctx); principal != nil && principal.ImpersonatorId != "" {
+		return ErrImpersonated
+	}
+	return nil
+}
+
+func (s *adminServiceImpl) Statistics(ctx context.Context) (*model.Statistics, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.Statistics")
+	defer span.End()
+
+	return s.Repository.StatisticsRepository.Get(ctx, time.Now().UTC())
+}
+
+// UnpublishShelf hides the shelf from its domain until it's published again. The owner can still edit it.
+func (s *adminServiceImpl) UnpublishShelf(ctx context.Context, shelfId string) (*model.Shelf, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.UnpublishShelf")
+	defer span.End()
+
+	unpublishedAt := time.Now().UTC()
+	return s.setShelfUnpublishedAt(ctx, shelfId, &unpublishedAt)
+}
+
+func (s *adminServiceImpl) PublishShelf(ctx context.Context, shelfId string) (*model.Shelf, error) {
+	ctx, span := tracing.Start(ctx, "AdminService.PublishShelf")
+	defer span.End()
+
+	return s.setShelfUnpublishedAt(ctx, shelfId, nil)
+}
+
+// DeleteShelf removes the shelf for good, moderation bypasses the trash so owners can't restore it.
+func (s *adminServiceImpl) DeleteShelf(ctx context.Context, shelfId string) error {
+	ctx, span := tracing.Start(ctx, "AdminService.DeleteShelf")
+	defer span.End()
+
+	shelf, err := s.Repository.ShelfRepository.Get(ctx, shelfId)
+	if err != nil {
+		return err
+	}
+	if shelf == nil {
+		return fmt.Errorf("shelf %s not found", shelfId)
+	}
+
+	err = s.Repository.ShelfRepository.Delete(ctx, shelf)
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Info("Shelf deleted by admin", slog.String("shelfId", shelfId), slog.String("ownerId", shelf.UserId), adminAttr(ctx))
+	recordAudit(ctx, s.Repository, model.AuditActionDelete, model.AuditEntityShelf, shelfId, shelfId, shelf, nil)
+	return nil
+}
+
+func (s *adminServiceImpl) setShelfUnpublishedAt(ctx context.Context, shelfId string, unpublishedAt *time.Time) (*model.Shelf, error) {
+	shelf, err := s.Repository.ShelfRepository.Get(ctx, shelfId)
+	if err != nil {
+		return nil, err
+	}
+	if shelf == nil {
+		return nil, fmt.Errorf("shelf %s not found", shelfId)
+	}
+
+	err = s.Repository.ShelfRepository.SetUnpublishedAt(ctx, shelfId, unpublishedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Shelf moderated",
+		slog.String("shelfId", shelfId),
+		slog.Bool("unpublished", unpublishedAt != nil),
+		adminAttr(ctx),
+	)
+	moderated, err := s.Repository.ShelfRepository.Get(ctx, shelfId)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.Repository, model.AuditActionUpdate, model.AuditEntityShelf, shelfId, shelfId, shelf, moderated)
+	return moderated, nil
+}
+
+// auditedUser loads the user after an admin action and audits the change.
+func (s *adminServiceImpl) auditedUser(ctx context.Context, before *model.User) (*model.User, error) {
+	user, err := getUser(ctx, s.Repository, before.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.Repository, model.AuditActionUpdate, model.AuditEntityUser, user.Id, "", auditUser(before), auditUser(user))
+	return user, nil
+}
+
+// log records an admin action on a user together with the acting admin.
+func (s *adminServiceImpl) log(ctx context.Context, message, userId string, attrs ...any) {
+	attrs = append([]any{slog.String("userId", userId), adminAttr(ctx)}, attrs...)
+	logging.FromContext(ctx).Info(message, attrs...)
+}
+
+// adminAttr is the acting admin, it's empty for actions from the CLI.
+func adminAttr(ctx context.Context) slog.Attr {
+	var adminId string
+	if principal := PrincipalFromContext(ctx); principal != nil {
+		adminId = principal.UserId
+	}
+	return slog.String("adminId", adminId)
+}
+
+// isCurrentAdmin reports whether the user is the authenticated caller.
+func isCurrentAdmin(ctx context.Context, userId string) bool {
+	principal := PrincipalFromContext(ctx)
+	return principal != nil && principal.UserId == userId
+}
+
+func getUser(ctx context.Context, repo *repository.Repository, userId string) (*model.User, error) {
+	user, err := repo.UserRepository.Get(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %s not found", userId)
+	}
+	return user, nil
+}
@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"backend/internal/infrastructure/api/mapper"
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/logging"
+	"backend/internal/infrastructure/repository"
+	"backend/internal/infrastructure/tracing"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"time"
+)
+
+// auditRedacted replaces the values of secrets in the audit log, it only tells that they changed. The purge of
+// a user also replaces the personal data of the user with it.
+const auditRedacted = "[redacted]"
+
+type AuditService interface {
+	ListByShelf(ctx context.Context, shelfId string, search model.AuditSearch) ([]model.AuditEvent, int64, error)
+	Search(ctx context.Context, shelfId string, search model.AuditSearch) ([]model.AuditEvent, int64, error)
+}
+
+type auditServiceImpl struct {
+	Repository *repository.Repository
+	Domain     *Service
+}
+
+func NewAuditService(repository *repository.Repository, domain *Service) AuditService {
+	return &auditServiceImpl{
+		Repository: repository,
+		Domain:     domain,
+	}
+}
+
+// ListByShelf returns the events of the shelf, its sections, links, shares and invitations. They reveal
+// who worked on the shelf from where, so only owners may see them.
+func (s *auditServiceImpl) ListByShelf(ctx context.Context, shelfId string, search model.AuditSearch) ([]model.AuditEvent, int64, error) {
+	ctx, span := tracing.Start(ctx, "AuditService.ListByShelf")
+	defer span.End()
+
+	_, err := authorizeShelf(ctx, s.Repository, shelfId, model.ShelfRoleOwner)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return s.Repository.AuditRepository.Search(ctx, shelfId, search)
+}
+
+// Search returns the events of the whole instance, optionally of a single shelf. It's meant for admins.
+func (s *auditServiceImpl) Search(ctx context.Context, shelfId string, search model.AuditSearch) ([]model.AuditEvent, int64, error) {
+	ctx, span := tracing.Start(ctx, "AuditService.Search")
+	defer span.End()
+
+	return s.Repository.AuditRepository.Search(ctx, shelfId, search)
+}
+
+// recordAudit appends an event for a mutation which already happened, with the attributes which differ
+// between before and after. Creations pass nil as before, deletions nil as after. The event can't be part
+// of the transaction of the mutation, so a failure is only logged instead of failing the finished change.
+func recordAudit(ctx context.Context, repo *repository.Repository, action, entityType, entityId, shelfId string, before, after any) {
+	event := &model.AuditEvent{
+		Action:     action,
+		EntityType: entityType,
+		EntityId:   entityId,
+		ShelfId:    shelfId,
+		RequestId:  logging.RequestID(ctx),
+		IP:         logging.ClientIP(ctx),
+		CreatedAt:  time.Now().UTC(),
+	}
+	if principal := PrincipalFromContext(ctx); principal != nil {
+		event.ActorId = principal.UserId
+		event.ImpersonatorId = principal.ImpersonatorId
+	}
+
+	changes, err := auditChanges(before, after)
+	if err == nil {
+		event.Changes = changes
+		err = repo.AuditRepository.Create(ctx, event)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Audit event not recorded", slog.String("entityType", entityType),
+			slog.String("entityId", entityId), slog.String("action", action), slog.String("error", err.Error()))
+	}
+}
+
+// auditUser is the audited view of a user, the one clients see, without credentials.
+func auditUser(user *model.User) any {
+	if user == nil {
+		return nil
+	}
+	return mapper.MapUserToUserOutput(*user)
+}
+
+// recordPasswordChange audits a new password without its hash.
+func recordPasswordChange(ctx context.Context, repo *repository.Repository, userId string) {
+	recordAudit(ctx, repo, model.AuditActionUpdate, model.AuditEntityUser, userId, "",
+		map[string]any{"password": nil}, map[string]any{"password": auditRedacted})
+}
+
+// auditChanges compares the JSON representations, so only attributes which clients can see end up in the
+// audit log and credentials tagged with json:"-" never do.
+func auditChanges(before, after any) (map[string]model.AuditChange, error) {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]model.AuditChange)
+	for name, value := range afterFields {
+		if previous, ok := beforeFields[name]; !ok || !reflect.DeepEqual(previous, value) {
+			changes[name] = model.AuditChange{Before: beforeFields[name], After: value}
+		}
+	}
+	for name, value := range beforeFields {
+		if _, ok := afterFields[name]; !ok {
+			changes[name] = model.AuditChange{Before: value}
+		}
+	}
+
+	return changes, nil
+}
+
+func auditFields(v any) (map[string]any, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	content, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+	err = json.Unmarshal(content, &fields)
+	return fields, err
+}
@@ -0,0 +1,199 @@
+package domain
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/logging"
+	"backend/internal/infrastructure/repository"
+	"backend/internal/infrastructure/tracing"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCredentials is returned for unknown email addresses and wrong passwords alike.
+var ErrInvalidCredentials = errors.New("the email address or the password is wrong")
+
+// ErrUnauthenticated is returned for unknown and expired bearer tokens.
+var ErrUnauthenticated = errors.New("the bearer token is invalid or expired")
+
+// dummyPasswordHash is compared for unknown email addresses, so the login takes as long as for existing
+// accounts and doesn't reveal which addresses are registered.
+var dummyPasswordHash, _ = hashPassword("linkshelf-dummy-password")
+
+type AuthService interface {
+	Login(ctx context.Context, email, password, secondFactor string) (string, *model.UserSession, error)
+	Logout(ctx context.Context, token string) error
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+}
+
+type authServiceImpl struct {
+	Repository *repository.Repository
+	Domain     *Service
+}
+
+func NewAuthService(repository *repository.Repository, domain *Service) AuthService {
+	return &authServiceImpl{
+		Repository: repository,
+		Domain:     domain,
+	}
+}
+
+// Login checks the credentials and the second factor of users with two-factor authentication and starts a
+// session. The plaintext bearer token is returned, only its hash is stored. A login cancels a scheduled
+// deletion of the account.
+func (s *authServiceImpl) Login(ctx context.Context, email, password, secondFactor string) (string, *model.UserSession, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Login")
+	defer span.End()
+
+	user, err := s.Repository.UserRepository.GetByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		return "", nil, err
+	}
+	if user == nil {
+		_ = checkPassword(dummyPasswordHash, password)
+		return "", nil, ErrInvalidCredentials
+	}
+
+	passwordHash, err := s.Repository.UserRepository.GetPassword(ctx, user.Id)
+	if err != nil {
+		return "", nil, err
+	}
+	if checkPassword(passwordHash, password) != nil {
+		return "", nil, ErrInvalidCredentials
+	}
+	if user.DisabledAt != nil && user.PurgeAt == nil {
+		return "", nil, ErrAccountDisabled
+	}
+
+	err = verifySecondFactor(ctx, s.Repository, s.Domain.totpKey, user.Id, secondFactor)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Logging in during the grace period of a deletion restores the account.
+	if user.PurgeAt != nil {
+		err = s.Repository.UserRepository.CancelDeletion(ctx, user.Id)
+		if err != nil {
+			return "", nil, err
+		}
+		logging.FromContext(ctx).Info("User restored by login", slog.String("userId", user.Id))
+
+		restored, err := s.Repository.UserRepository.Get(ctx, user.Id)
+		if err != nil {
+			return "", nil, err
+		}
+		recordAudit(ctx, s.Repository, model.AuditActionUpdate, model.AuditEntityUser, user.Id, "", auditUser(user), auditUser(restored))
+	}
+
+	token, session, err := startSession(ctx, s.Repository, user.Id, "", s.Domain.config.Domain.Sessions.TTL)
+	if err != nil {
+		return "", nil, err
+	}
+
+	logging.FromContext(ctx).Info("User logged in", slog.String("userId", user.Id), slog.String("sessionId", session.Id))
+	return token, session, nil
+}
+
+func (s *authServiceImpl) Logout(ctx context.Context, token string) error {
+	ctx, span := tracing.Start(ctx, "AuthService.Logout")
+	defer span.End()
+
+	session, err := s.Repository.SessionRepository.GetByHash(ctx, hashToken(token))
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		return ErrUnauthenticated
+	}
+
+	return s.Repository.SessionRepository.Delete(ctx, session.Id)
+}
+
+// Authenticate resolves a bearer token to the user of its session or personal access token. Tokens of
+// disabled users aren't accepted anymore.
+func (s *authServiceImpl) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Authenticate")
+	defer span.End()
+
+	if strings.HasPrefix(token, personalAccessTokenPrefix) {
+		return authenticatePersonalAccessToken(ctx, s.Repository, token)
+	}
+
+	session, err := s.Repository.SessionRepository.GetByHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if session == nil || !now.Before(session.ExpiresAt) {
+		return nil, ErrUnauthenticated
+	}
+
+	user, err := requireEnabledUser(ctx, s.Repository, session.UserId)
+	if err != nil {
+		return nil, ErrUnauthenticated
+	}
+
+	err = s.Repository.SessionRepository.Touch(ctx, session.Id, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Principal{
+		UserId:         session.UserId,
+		Role:           user.Role,
+		SessionId:      session.Id,
+		ImpersonatorId: session.ImpersonatorId,
+	}, nil
+}
+
+// startSession creates a session for the user. The plaintext bearer token is returned, only its hash is
+// stored.
+func startSession(ctx context.Context, repo *repository.Repository, userId, impersonatorId string, ttl time.Duration) (string, *model.UserSession, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	now := time.Now().UTC()
+	session := &model.UserSession{
+		UserId:         userId,
+		ImpersonatorId: impersonatorId,
+		TokenHash:      hashToken(token),
+		ExpiresAt:      now.Add(ttl),
+		CreatedAt:      now,
+	}
+	_, err := repo.SessionRepository.Create(ctx, session)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, session, nil
+}
+
+// endOtherSessions ends all sessions of the user except the one of the current request, e.g. after the
+// password changed.
+func endOtherSessions(ctx context.Context, repo *repository.Repository, userId string) error {
+	var exceptId string
+	if principal := PrincipalFromContext(ctx); principal != nil && principal.UserId == userId {
+		exceptId = principal.SessionId
+	}
+	return repo.SessionRepository.DeleteByUser(ctx, userId, exceptId)
+}
+
+func purgeExpiredSessions(repo *repository.Repository) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		deleted, err := repo.SessionRepository.DeleteExpired(ctx, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if deleted > 0 {
+			logging.FromContext(ctx).Info("Expired sessions purged", slog.Int64("count", deleted))
+		}
+		return nil
+	}
+}
@@ -1,20 +1,112 @@
 package domain
 
-import "backend/internal/infrastructure/repository"
+import (
+	"backend/internal/config"
+	"backend/internal/infrastructure/logging"
+	"backend/internal/infrastructure/mail"
+	"backend/internal/infrastructure/repository"
+	"context"
+	"encoding/base64"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// backgroundTimeout bounds tasks which outlive the request that started them, like sending mails.
+const backgroundTimeout = time.Minute
 
 type Service struct {
-	UserService    UserService
-	ShelfService   ShelfService
-	SectionService SectionService
-	LinkService    LinkService
+	UserService                UserService
+	ShelfService               ShelfService
+	SectionService             SectionService
+	LinkService                LinkService
+	AuthService                AuthService
+	TwoFactorService           TwoFactorService
+	PersonalAccessTokenService PersonalAccessTokenService
+	AdminService               AdminService
+	TeamService                TeamService
+	ShelfInvitationService     ShelfInvitationService
+	AuditService               AuditService
+	ShelfRevisionService       ShelfRevisionService
+	TrashService               TrashService
+	ShelfTemplateService       ShelfTemplateService
+	SearchService              SearchService
+
+	config     *config.Config
+	mailer     mail.Mailer
+	totpKey    []byte
+	workers    []Worker
+	heartbeats heartbeats
+	background sync.WaitGroup
 }
 
-func NewService(repository *repository.Repository) *Service {
-	service := Service{}
+func NewService(cfg *config.Config, repository *repository.Repository, mailer mail.Mailer) *Service {
+	// The key is checked by config.Validate, a missing one fails every use of a TOTP secret.
+	totpKey, _ := base64.StdEncoding.DecodeString(cfg.Domain.TwoFactor.EncryptionKey)
+	service := Service{
+		config:  cfg,
+		mailer:  mailer,
+		totpKey: totpKey,
+	}
 	service.UserService = NewUserService(repository, &service)
 	service.ShelfService = NewShelfService(repository, &service)
 	service.SectionService = NewSectionService(repository, &service)
 	service.LinkService = NewLinkService(repository, &service)
+	service.AuthService = NewAuthService(repository, &service)
+	service.TwoFactorService = NewTwoFactorService(repository, &service)
+	service.PersonalAccessTokenService = NewPersonalAccessTokenService(repository, &service)
+	service.AdminService = NewAdminService(repository, &service)
+	service.TeamService = NewTeamService(repository, &service)
+	service.ShelfInvitationService = NewShelfInvitationService(repository, &service)
+	service.AuditService = NewAuditService(repository, &service)
+	service.ShelfRevisionService = NewShelfRevisionService(repository, &service)
+	service.TrashService = NewTrashService(repository, &service)
+	service.ShelfTemplateService = NewShelfTemplateService(repository, &service)
+	service.SearchService = NewSearchService(repository, &service)
+
+	service.workers = append(service.workers, Worker{
+		Name:     "purge-expired-user-tokens",
+		Interval: time.Hour,
+		Run:      purgeExpiredUserTokens(repository),
+	}, Worker{
+		Name:     "purge-expired-sessions",
+		Interval: time.Hour,
+		Run:      purgeExpiredSessions(repository),
+	}, Worker{
+		Name:     "purge-deleted-users",
+		Interval: time.Hour,
+		Run:      purgeDeletedUsers(repository),
+	}, Worker{
+		Name:     "purge-expired-shelf-invitations",
+		Interval: time.Hour,
+		Run:      purgeExpiredShelfInvitations(repository),
+	}, Worker{
+		Name:     "purge-trash",
+		Interval: time.Hour,
+		Run:      purgeTrash(repository, cfg.Domain.Trash.Retention),
+	})
 
 	return &service
 }
+
+// runInBackground runs a task detached from the cancellation of the request, so the response doesn't have
+// to wait for it. Errors can only be logged. The wait function of StartWorkers also waits for these tasks.
+func (s *Service) runInBackground(ctx context.Context, name string, task func(ctx context.Context) error) {
+	logger := logging.FromContext(ctx)
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), backgroundTimeout)
+
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		defer cancel()
+
+		if err := task(ctx); err != nil {
+			logger.Error("Background task failed", slog.String("task", name), slog.String("error", err.Error()))
+		}
+	}()
+}
+
+// WaitForBackgroundTasks blocks until all tasks started by runInBackground are done.
+func (s *Service) WaitForBackgroundTasks() {
+	s.background.Wait()
+}
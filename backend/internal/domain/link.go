@@ -2,14 +2,17 @@ package domain
 
 import (
 	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/metrics"
 	"backend/internal/infrastructure/repository"
+	"backend/internal/infrastructure/tracing"
+	"context"
 )
 
 type LinkService interface {
-	List(shelfId string) ([]model.Link, error)
-	Create(u *model.Link) (*model.Link, error)
-	Update(linkId string, linkRequest *model.Link) (*model.Link, error)
-	Delete(linkId string) error
+	List(ctx context.Context, shelfId string) ([]model.Link, error)
+	Create(ctx context.Context, u *model.Link) (*model.Link, error)
+	Update(ctx context.Context, linkId string, linkRequest *model.Link) (*model.Link, error)
+	Delete(ctx context.Context, linkId string) error
 }
 
 type linkServiceImpl struct {
@@ -24,39 +27,84 @@ func NewLinkService(repository *repository.Repository, domain *Service) LinkServ
 	}
 }
 
-func (s *linkServiceImpl) List(shelfId string) ([]model.Link, error) {
-	return s.Repository.LinkRepository.ListByShelfId(shelfId)
+func (s *linkServiceImpl) List(ctx context.Context, shelfId string) ([]model.Link, error) {
+	ctx, span := tracing.Start(ctx, "LinkService.List")
+	defer span.End()
+
+	_, err := authorizeShelf(ctx, s.Repository, shelfId, model.ShelfRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repository.LinkRepository.ListByShelfId(ctx, shelfId)
 }
 
-func (s *linkServiceImpl) Create(u *model.Link) (*model.Link, error) {
-	linkId, err := s.Repository.LinkRepository.Create(u)
+func (s *linkServiceImpl) Create(ctx context.Context, u *model.Link) (*model.Link, error) {
+	ctx, span := tracing.Start(ctx, "LinkService.Create")
+	defer span.End()
+
+	section, err := authorizeSection(ctx, s.Repository, u.SectionId, model.ShelfRoleEditor)
 	if err != nil {
 		return nil, err
 	}
 
-	link, err := s.Repository.LinkRepository.Get(linkId)
+	linkId, err := s.Repository.LinkRepository.Create(ctx, u)
 	if err != nil {
 		return nil, err
 	}
+	metrics.LinkCreated()
 
+	link, err := s.Repository.LinkRepository.Get(ctx, linkId)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.Repository, model.AuditActionCreate, model.AuditEntityLink, linkId, section.ShelfId, nil, link)
+	s.Domain.recordRevision(ctx, s.Repository, section.ShelfId)
 	return link, nil
 }
 
-func (s *linkServiceImpl) Update(linkId string, linkRequest *model.Link) (*model.Link, error) {
+func (s *linkServiceImpl) Update(ctx context.Context, linkId string, linkRequest *model.Link) (*model.Link, error) {
+	ctx, span := tracing.Start(ctx, "LinkService.Update")
+	defer span.End()
+
+	existing, section, err := authorizeLink(ctx, s.Repository, linkId, model.ShelfRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
 	linkRequest.Id = linkId
-	err := s.Repository.LinkRepository.Update(linkRequest)
+	err = s.Repository.LinkRepository.Update(ctx, linkRequest)
 	if err != nil {
 		return nil, err
 	}
 
-	links, err := s.Repository.LinkRepository.Get(linkId)
+	links, err := s.Repository.LinkRepository.Get(ctx, linkId)
 	if err != nil {
 		return nil, err
 	}
 
+	recordAudit(ctx, s.Repository, model.AuditActionUpdate, model.AuditEntityLink, linkId, section.ShelfId, existing, links)
+	s.Domain.recordRevision(ctx, s.Repository, section.ShelfId)
 	return links, nil
 }
 
-func (s *linkServiceImpl) Delete(linkId string) error {
-	return s.Repository.LinkRepository.Delete(&model.Link{Id: linkId})
+func (s *linkServiceImpl) Delete(ctx context.Context, linkId string) error {
+	ctx, span := tracing.Start(ctx, "LinkService.Delete")
+	defer span.End()
+
+	existing, section, err := authorizeLink(ctx, s.Repository, linkId, model.ShelfRoleEditor)
+	if err != nil {
+		return err
+	}
+
+	deletedBy, deletedAt := trashDeletion(ctx)
+	err = s.Repository.LinkRepository.SoftDelete(ctx, linkId, deletedBy, deletedAt)
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, s.Repository, model.AuditActionDelete, model.AuditEntityLink, linkId, section.ShelfId, existing, nil)
+	s.Domain.recordRevision(ctx, s.Repository, section.ShelfId)
+	return nil
 }
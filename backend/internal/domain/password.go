@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"backend/internal/config"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxPasswordBytes is the limit of bcrypt, which refuses to hash longer passwords.
+const maxPasswordBytes = 72
+
+// PasswordPolicyError lists every requirement a password misses, so clients can show them all at once.
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "the password is too weak: " + strings.Join(e.Violations, ", ")
+}
+
+// validatePassword checks a new password against the policy. Existing passwords aren't checked, so
+// tightening the policy doesn't lock anybody out.
+func validatePassword(policy config.PasswordPolicy, password string) error {
+	var violations []string
+	if length := utf8.RuneCountInString(password); length < policy.MinLength {
+		violations = append(violations, fmt.Sprintf("it must be at least %d characters long", policy.MinLength))
+	}
+	if len(password) > maxPasswordBytes {
+		violations = append(violations, fmt.Sprintf("it must not be longer than %d bytes", maxPasswordBytes))
+	}
+	if policy.RequireUppercase && !strings.ContainsFunc(password, unicode.IsUpper) {
+		violations = append(violations, "it must contain an uppercase letter")
+	}
+	if policy.RequireLowercase && !strings.ContainsFunc(password, unicode.IsLower) {
+		violations = append(violations, "it must contain a lowercase letter")
+	}
+	if policy.RequireDigit && !strings.ContainsFunc(password, unicode.IsDigit) {
+		violations = append(violations, "it must contain a digit")
+	}
+	if policy.RequireSymbol && !strings.ContainsFunc(password, isSymbol) {
+		violations = append(violations, "it must contain a symbol")
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func isSymbol(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r)
+}
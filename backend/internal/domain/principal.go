@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"context"
+	"slices"
+)
+
+type principalKey struct{}
+
+// Principal is the authenticated caller of a request. Callers authenticated by a personal access token
+// carry its ID and scopes, sessions have neither. ImpersonatorId is set if an admin acts as the user.
+type Principal struct {
+	UserId         string
+	Role           string
+	SessionId      string
+	ImpersonatorId string
+	TokenId        string
+	Scopes         []string
+}
+
+// CanUse reports whether the principal may call an operation which declares the given scopes. Sessions
+// may call every operation, personal access tokens only those which declare scopes and only if all of
+// them were granted. So operations without scopes, like the management of the tokens, need a login.
+func (p *Principal) CanUse(scopes []string) bool {
+	if p.TokenId == "" {
+		return true
+	}
+	if len(scopes) == 0 {
+		return false
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(p.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// WithPrincipal stores the authenticated caller in the context.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated caller or nil for anonymous requests and if the
+// authentication is skipped.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/repository"
+	"backend/internal/infrastructure/tracing"
+	"context"
+	"errors"
+	"html"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// maxSearchTerms bounds the words of a search query, each of them adds to the cost of the query.
+const maxSearchTerms = 10
+
+type SearchService interface {
+	Search(ctx context.Context, query string, page model.PaginationQuery) ([]model.SearchResult, int64, error)
+}
+
+type searchServiceImpl struct {
+	Repository *repository.Repository
+	Domain     *Service
+}
+
+func NewSearchService(repository *repository.Repository, domain *Service) SearchService {
+	return &searchServiceImpl{
+		Repository: repository,
+		Domain:     domain,
+	}
+}
+
+// Search finds the shelves and links matching all words of the query in the shelves the caller can access,
+// the best matches first. The matched words are highlighted in the attributes of the results.
+func (s *searchServiceImpl) Search(ctx context.Context, query string, page model.PaginationQuery) ([]model.SearchResult, int64, error) {
+	ctx, span := tracing.Start(ctx, "SearchService.Search")
+	defer span.End()
+
+	principal := PrincipalFromContext(ctx)
+	if principal == nil {
+		return nil, 0, errors.New("the search needs a signed in user")
+	}
+
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, 0, errors.New("the query contains no words to search for")
+	}
+
+	results, total, err := s.Repository.SearchRepository.Search(ctx, principal.UserId, terms, page)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i := range results {
+		results[i].Highlights = make(map[string]string)
+		for field, value := range map[string]string{
+			"title":       results[i].Title,
+			"link":        results[i].Link,
+			"description": results[i].Description,
+		} {
+			if highlighted, ok := highlight(value, terms); ok {
+				results[i].Highlights[field] = highlighted
+			}
+		}
+	}
+
+	return results, total, nil
+}
+
+// searchTerms splits the query into distinct lower case words of letters and digits, everything else
+// separates them like in the full-text indexes.
+func searchTerms(query string) []string {
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(query), isNotWordRune) {
+		if !slices.Contains(terms, word) {
+			terms = append(terms, word)
+		}
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// highlight escapes the text for HTML and wraps the words starting with one of the terms in <mark>
+// elements. It reports whether any word matched.
+func highlight(text string, terms []string) (string, bool) {
+	var b strings.Builder
+	matched := false
+	start := -1
+	flush := func(end int) {
+		word := text[start:end]
+		lower := strings.ToLower(word)
+		if slices.ContainsFunc(terms, func(term string) bool { return strings.HasPrefix(lower, term) }) {
+			b.WriteString("<mark>" + html.EscapeString(word) + "</mark>")
+			matched = true
+		} else {
+			b.WriteString(html.EscapeString(word))
+		}
+		start = -1
+	}
+
+	for i, r := range text {
+		if isNotWordRune(r) {
+			if start >= 0 {
+				flush(i)
+			}
+			b.WriteString(html.EscapeString(string(r)))
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		flush(len(text))
+	}
+
+	return b.String(), matched
+}
+
+func isNotWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
@@ -2,15 +2,23 @@ package domain
 
 import (
 	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/logging"
+	"backend/internal/infrastructure/metrics"
 	"backend/internal/infrastructure/repository"
+	"backend/internal/infrastructure/tracing"
+	"context"
+	"fmt"
+	"log/slog"
 )
 
 type SectionService interface {
-	List(shelfId string) ([]model.Section, error)
-	Get(sectionId string) (*model.Section, error)
-	Create(u *model.Section) (*model.Section, error)
-	Update(sectionId string, u *model.Section) (*model.Section, error)
-	Delete(sectionId string) error
+	List(ctx context.Context, shelfId string) ([]model.Section, error)
+	Get(ctx context.Context, sectionId string) (*model.Section, error)
+	Create(ctx context.Context, u *model.Section) (*model.Section, error)
+	Update(ctx context.Context, sectionId string, u *model.Section) (*model.Section, error)
+	Delete(ctx context.Context, sectionId string) error
+	Clone(ctx context.Context, sectionId string, clone *model.SectionClone) (*model.Section, error)
+	Move(ctx context.Context, sectionId, shelfId string) (*model.Section, error)
 }
 
 type sectionServiceImpl struct {
@@ -25,37 +33,173 @@ func NewSectionService(repository *repository.Repository, domain *Service) Secti
 	}
 }
 
-func (s *sectionServiceImpl) List(shelfId string) ([]model.Section, error) {
-	return s.Repository.SectionRepository.ListByShelfId(shelfId)
+func (s *sectionServiceImpl) List(ctx context.Context, shelfId string) ([]model.Section, error) {
+	ctx, span := tracing.Start(ctx, "SectionService.List")
+	defer span.End()
+
+	_, err := authorizeShelf(ctx, s.Repository, shelfId, model.ShelfRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repository.SectionRepository.ListByShelfId(ctx, shelfId)
 }
 
-func (s *sectionServiceImpl) Get(sectionId string) (*model.Section, error) {
-	return s.Repository.SectionRepository.Get(sectionId)
+func (s *sectionServiceImpl) Get(ctx context.Context, sectionId string) (*model.Section, error) {
+	ctx, span := tracing.Start(ctx, "SectionService.Get")
+	defer span.End()
+
+	return authorizeSection(ctx, s.Repository, sectionId, model.ShelfRoleViewer)
 }
 
-func (s *sectionServiceImpl) Create(sectionRequest *model.Section) (*model.Section, error) {
-	sectionId, err := s.Repository.SectionRepository.Create(sectionRequest)
+func (s *sectionServiceImpl) Create(ctx context.Context, sectionRequest *model.Section) (*model.Section, error) {
+	ctx, span := tracing.Start(ctx, "SectionService.Create")
+	defer span.End()
+
+	_, err := authorizeShelf(ctx, s.Repository, sectionRequest.ShelfId, model.ShelfRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	sectionId, err := s.Repository.SectionRepository.Create(ctx, sectionRequest)
+	if err != nil {
+		return nil, err
+	}
+	section, err := s.Repository.SectionRepository.Get(ctx, sectionId)
 	if err != nil {
 		return nil, err
 	}
-	section, err := s.Repository.SectionRepository.Get(sectionId)
+
+	recordAudit(ctx, s.Repository, model.AuditActionCreate, model.AuditEntitySection, sectionId, section.ShelfId, nil, section)
+	s.Domain.recordRevision(ctx, s.Repository, section.ShelfId)
 	return section, nil
 }
 
-func (s *sectionServiceImpl) Update(sectionId string, u *model.Section) (*model.Section, error) {
+func (s *sectionServiceImpl) Update(ctx context.Context, sectionId string, u *model.Section) (*model.Section, error) {
+	ctx, span := tracing.Start(ctx, "SectionService.Update")
+	defer span.End()
+
+	existing, err := authorizeSection(ctx, s.Repository, sectionId, model.ShelfRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
 	u.Id = sectionId
-	err := s.Repository.SectionRepository.Update(u)
+	err = s.Repository.SectionRepository.Update(ctx, u)
 	if err != nil {
 		return nil, err
 	}
 
-	section, err := s.Repository.SectionRepository.Get(sectionId)
+	section, err := s.Repository.SectionRepository.Get(ctx, sectionId)
 	if err != nil {
 		return nil, err
 	}
+
+	recordAudit(ctx, s.Repository, model.AuditActionUpdate, model.AuditEntitySection, sectionId, section.ShelfId, existing, section)
+	s.Domain.recordRevision(ctx, s.Repository, section.ShelfId)
 	return section, nil
 }
 
-func (s *sectionServiceImpl) Delete(sectionId string) error {
-	return s.Repository.SectionRepository.Delete(&model.Section{Id: sectionId})
+func (s *sectionServiceImpl) Delete(ctx context.Context, sectionId string) error {
+	ctx, span := tracing.Start(ctx, "SectionService.Delete")
+	defer span.End()
+
+	existing, err := authorizeSection(ctx, s.Repository, sectionId, model.ShelfRoleEditor)
+	if err != nil {
+		return err
+	}
+
+	deletedBy, deletedAt := trashDeletion(ctx)
+	err = s.Repository.SectionRepository.SoftDelete(ctx, sectionId, deletedBy, deletedAt)
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, s.Repository, model.AuditActionDelete, model.AuditEntitySection, sectionId, existing.ShelfId, existing, nil)
+	s.Domain.recordRevision(ctx, s.Repository, existing.ShelfId)
+	return nil
+}
+
+// Clone copies the section with its links into the shelf of the clone, or next to the section if the clone
+// names no shelf. Viewers of the section may clone it into a shelf they edit.
+func (s *sectionServiceImpl) Clone(ctx context.Context, sectionId string, clone *model.SectionClone) (*model.Section, error) {
+	ctx, span := tracing.Start(ctx, "SectionService.Clone")
+	defer span.End()
+
+	source, err := authorizeSection(ctx, s.Repository, sectionId, model.ShelfRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	section := &model.Section{SectionBase: model.SectionBase{Title: clone.Title, ShelfId: clone.ShelfId}}
+	if section.Title == "" {
+		section.Title = source.Title
+	}
+	if section.ShelfId == "" {
+		section.ShelfId = source.ShelfId
+	}
+	_, err = authorizeShelf(ctx, s.Repository, section.ShelfId, model.ShelfRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	cloneId, err := s.Repository.SectionRepository.Clone(ctx, sectionId, section)
+	if err != nil {
+		return nil, err
+	}
+	cloned, err := s.Repository.SectionRepository.Get(ctx, cloneId)
+	if err != nil {
+		return nil, err
+	}
+	links, err := s.Repository.LinkRepository.ListByShelfId(ctx, cloned.ShelfId)
+	if err != nil {
+		return nil, err
+	}
+	for _, link := range links {
+		if link.SectionId == cloneId {
+			metrics.LinkCreated()
+		}
+	}
+
+	logging.FromContext(ctx).Info("Section cloned", slog.String("sectionId", sectionId), slog.String("cloneId", cloneId))
+	recordAudit(ctx, s.Repository, model.AuditActionCreate, model.AuditEntitySection, cloneId, cloned.ShelfId, nil, cloned)
+	s.Domain.recordRevision(ctx, s.Repository, cloned.ShelfId)
+	return cloned, nil
+}
+
+// Move moves the section with its links to another shelf. It needs the editor role on both shelves. The
+// moved section and its links get new IDs, see SectionRepository.Move.
+func (s *sectionServiceImpl) Move(ctx context.Context, sectionId, shelfId string) (*model.Section, error) {
+	ctx, span := tracing.Start(ctx, "SectionService.Move")
+	defer span.End()
+
+	existing, err := authorizeSection(ctx, s.Repository, sectionId, model.ShelfRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	if existing.ShelfId == shelfId {
+		return nil, fmt.Errorf("section %s is already on shelf %s", sectionId, shelfId)
+	}
+	_, err = authorizeShelf(ctx, s.Repository, shelfId, model.ShelfRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	section := &model.Section{SectionBase: model.SectionBase{Title: existing.Title, ShelfId: shelfId}}
+	movedId, err := s.Repository.SectionRepository.Move(ctx, sectionId, section)
+	if err != nil {
+		return nil, err
+	}
+	moved, err := s.Repository.SectionRepository.Get(ctx, movedId)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Section moved", slog.String("sectionId", sectionId), slog.String("shelfId", shelfId),
+		slog.String("movedId", movedId))
+	recordAudit(ctx, s.Repository, model.AuditActionDelete, model.AuditEntitySection, sectionId, existing.ShelfId, existing, nil)
+	recordAudit(ctx, s.Repository, model.AuditActionCreate, model.AuditEntitySection, movedId, shelfId, nil, moved)
+	s.Domain.recordRevision(ctx, s.Repository, existing.ShelfId)
+	s.Domain.recordRevision(ctx, s.Repository, shelfId)
+	return moved, nil
 }
@@ -2,14 +2,41 @@ package domain
 
 import (
 	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/logging"
+	"backend/internal/infrastructure/metrics"
 	"backend/internal/infrastructure/repository"
+	"backend/internal/infrastructure/tracing"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"time"
 )
 
+// domainVerificationPrefix is the label of the TXT record which has to contain the verification token of
+// a shelf to prove the ownership of its custom domain.
+const domainVerificationPrefix = "_linkshelf"
+
 type ShelfService interface {
-	GetShelfById(id string) (*model.Shelf, error)
-	CreateShelf(u *model.Shelf) (string, error)
-	UpdateShelf(shelfId string, shelfRequest *model.Shelf) (*model.Shelf, error)
-	DeleteShelf(u *model.Shelf) error
+	GetShelfById(ctx context.Context, id string) (*model.Shelf, error)
+	ListShelves(ctx context.Context, userId string) ([]model.Shelf, error)
+	CreateShelf(ctx context.Context, u *model.Shelf) (string, error)
+	UpdateShelf(ctx context.Context, shelfId string, shelfRequest *model.Shelf) (*model.Shelf, error)
+	DeleteShelf(ctx context.Context, u *model.Shelf) error
+	MoveShelf(ctx context.Context, shelfId string, owner *model.ShelfOwner) (*model.Shelf, error)
+	CloneShelf(ctx context.Context, shelfId string, clone *model.ShelfClone) (*model.Shelf, error)
+	ExportShelf(ctx context.Context, shelfId string) (*model.ShelfExport, error)
+	ImportShelf(ctx context.Context, userId string, export *model.ShelfExport) (*model.Shelf, error)
+	VerifyDomain(ctx context.Context, shelfId string) (*model.Shelf, error)
+	IsVerifiedDomain(ctx context.Context, domain string) (bool, error)
+	ListShares(ctx context.Context, shelfId string) ([]model.ShelfShare, error)
+	Share(ctx context.Context, shelfId, userId, role string) (*model.ShelfShare, error)
+	RevokeShare(ctx context.Context, shelfId, userId string) error
 }
 
 type shelfServiceImpl struct {
@@ -24,28 +51,483 @@ func NewShelfService(repository *repository.Repository, domain *Service) ShelfSe
 	}
 }
 
-func (s *shelfServiceImpl) GetShelfById(id string) (*model.Shelf, error) {
-	return s.Repository.ShelfRepository.Get(id)
+func (s *shelfServiceImpl) GetShelfById(ctx context.Context, id string) (*model.Shelf, error) {
+	ctx, span := tracing.Start(ctx, "ShelfService.GetShelfById")
+	defer span.End()
+
+	return authorizeShelf(ctx, s.Repository, id, model.ShelfRoleViewer)
 }
 
-func (s *shelfServiceImpl) CreateShelf(shelfRequest *model.Shelf) (string, error) {
-	return s.Repository.ShelfRepository.Create(shelfRequest)
+// ListShelves returns the shelves of the user, of the teams of the user and those shared with the user.
+func (s *shelfServiceImpl) ListShelves(ctx context.Context, userId string) ([]model.Shelf, error) {
+	ctx, span := tracing.Start(ctx, "ShelfService.ListShelves")
+	defer span.End()
+
+	return s.Repository.ShelfRepository.ListAccessible(ctx, userId)
+}
+
+func (s *shelfServiceImpl) CreateShelf(ctx context.Context, shelfRequest *model.Shelf) (string, error) {
+	ctx, span := tracing.Start(ctx, "ShelfService.CreateShelf")
+	defer span.End()
+
+	err := s.authorizeOwner(ctx, shelfRequest)
+	if err != nil {
+		return "", err
+	}
+
+	err = prepareDomainVerification(shelfRequest, nil)
+	if err != nil {
+		return "", err
+	}
+
+	shelfId, err := s.Repository.ShelfRepository.Create(ctx, shelfRequest)
+	if err != nil {
+		return "", err
+	}
+
+	metrics.ShelfCreated()
+	shelfRequest.Id = shelfId
+	recordAudit(ctx, s.Repository, model.AuditActionCreate, model.AuditEntityShelf, shelfId, shelfId, nil, shelfRequest)
+	s.Domain.recordRevision(ctx, s.Repository, shelfId)
+	return shelfId, nil
 }
 
-func (s *shelfServiceImpl) UpdateShelf(shelfId string, shelfRequest *model.Shelf) (*model.Shelf, error) {
+func (s *shelfServiceImpl) UpdateShelf(ctx context.Context, shelfId string, shelfRequest *model.Shelf) (*model.Shelf, error) {
+	ctx, span := tracing.Start(ctx, "ShelfService.UpdateShelf")
+	defer span.End()
+
+	existing, err := authorizeShelf(ctx, s.Repository, shelfId, model.ShelfRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
 	shelfRequest.Id = shelfId
-	err := s.Repository.ShelfRepository.Update(shelfRequest)
+	err = prepareDomainVerification(shelfRequest, existing)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Repository.ShelfRepository.Update(ctx, shelfRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	shelf, err := s.Repository.ShelfRepository.Get(ctx, shelfId)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.Repository, model.AuditActionUpdate, model.AuditEntityShelf, shelfId, shelfId, existing, shelf)
+	s.Domain.recordRevision(ctx, s.Repository, shelfId)
+	return shelf, nil
+}
+
+func (s *shelfServiceImpl) DeleteShelf(ctx context.Context, shelfRequest *model.Shelf) error {
+	ctx, span := tracing.Start(ctx, "ShelfService.DeleteShelf")
+	defer span.End()
+
+	existing, err := authorizeShelf(ctx, s.Repository, shelfRequest.Id, model.ShelfRoleOwner)
+	if err != nil {
+		return err
+	}
+
+	deletedBy, deletedAt := trashDeletion(ctx)
+	err = s.Repository.ShelfRepository.SoftDelete(ctx, existing.Id, deletedBy, deletedAt)
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, s.Repository, model.AuditActionDelete, model.AuditEntityShelf, existing.Id, existing.Id, existing, nil)
+	return nil
+}
+
+// MoveShelf hands the shelf to a team or back to a user. Only owners of the shelf may move it, and only to
+// a team they edit or to themselves.
+func (s *shelfServiceImpl) MoveShelf(ctx context.Context, shelfId string, owner *model.ShelfOwner) (*model.Shelf, error) {
+	ctx, span := tracing.Start(ctx, "ShelfService.MoveShelf")
+	defer span.End()
+
+	existing, err := authorizeShelf(ctx, s.Repository, shelfId, model.ShelfRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	shelf := &model.Shelf{ShelfBase: model.ShelfBase{UserId: owner.UserId, TeamId: owner.TeamId}}
+	err = s.authorizeOwner(ctx, shelf)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Repository.ShelfRepository.SetOwner(ctx, shelfId, shelf.UserId, shelf.TeamId)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Shelf moved", slog.String("shelfId", shelfId),
+		slog.String("userId", shelf.UserId), slog.String("teamId", shelf.TeamId))
+	moved, err := s.Repository.ShelfRepository.Get(ctx, shelfId)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.Repository, model.AuditActionUpdate, model.AuditEntityShelf, shelfId, shelfId, existing, moved)
+	return moved, nil
+}
+
+// CloneShelf copies the shelf with its sections and links into a new shelf with the title and path of the
+// clone. Viewers may clone a shelf, the copy belongs to the owner of the clone like a created shelf. The
+// custom domain isn't copied, it can only be served by one shelf.
+func (s *shelfServiceImpl) CloneShelf(ctx context.Context, shelfId string, clone *model.ShelfClone) (*model.Shelf, error) {
+	ctx, span := tracing.Start(ctx, "ShelfService.CloneShelf")
+	defer span.End()
+
+	source, err := authorizeShelf(ctx, s.Repository, shelfId, model.ShelfRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	shelf := &model.Shelf{
+		ShelfBase: model.ShelfBase{
+			Title:       clone.Title,
+			Path:        clone.Path,
+			Description: source.Description,
+			Theme:       source.Theme,
+			Icon:        source.Icon,
+			UserId:      clone.UserId,
+			TeamId:      clone.TeamId,
+		},
+	}
+	err = s.authorizeOwner(ctx, shelf)
+	if err != nil {
+		return nil, err
+	}
+
+	cloneId, err := s.Repository.ShelfRepository.Clone(ctx, shelfId, shelf)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.ShelfCreated()
+	links, err := s.Repository.LinkRepository.ListByShelfId(ctx, cloneId)
+	if err != nil {
+		return nil, err
+	}
+	for range links {
+		metrics.LinkCreated()
+	}
+	logging.FromContext(ctx).Info("Shelf cloned", slog.String("shelfId", shelfId), slog.String("cloneId", cloneId))
+	cloned, err := s.Repository.ShelfRepository.Get(ctx, cloneId)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.Repository, model.AuditActionCreate, model.AuditEntityShelf, cloneId, cloneId, nil, cloned)
+	s.Domain.recordRevision(ctx, s.Repository, cloneId)
+	return cloned, nil
+}
+
+func (s *shelfServiceImpl) ExportShelf(ctx context.Context, shelfId string) (*model.ShelfExport, error) {
+	ctx, span := tracing.Start(ctx, "ShelfService.ExportShelf")
+	defer span.End()
+
+	shelf, err := authorizeShelf(ctx, s.Repository, shelfId, model.ShelfRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	sections, err := s.Repository.SectionRepository.ListByShelfId(ctx, shelfId)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := s.Repository.LinkRepository.ListByShelfId(ctx, shelfId)
+	if err != nil {
+		return nil, err
+	}
+
+	linksBySection := make(map[string][]model.LinkExport)
+	for _, link := range links {
+		linksBySection[link.SectionId] = append(linksBySection[link.SectionId], model.LinkExport{
+			Title: link.Title,
+			Link:  link.Link,
+			Icon:  link.Icon,
+			Color: link.Color,
+		})
+	}
+
+	export := &model.ShelfExport{
+		Title:       shelf.Title,
+		Path:        shelf.Path,
+		Domain:      shelf.Domain,
+		Description: shelf.Description,
+		Theme:       shelf.Theme,
+		Icon:        shelf.Icon,
+		Sections:    make([]model.SectionExport, 0, len(sections)),
+	}
+	for _, section := range sections {
+		export.Sections = append(export.Sections, model.SectionExport{
+			Title: section.Title,
+			Links: linksBySection[section.Id],
+		})
+	}
+
+	return export, nil
+}
+
+// ImportShelf creates a new shelf with all sections and links of the export for the given user, in a single
+// transaction. Like a clone, it's audited as the creation of the shelf.
+func (s *shelfServiceImpl) ImportShelf(ctx context.Context, userId string, export *model.ShelfExport) (*model.Shelf, error) {
+	ctx, span := tracing.Start(ctx, "ShelfService.ImportShelf")
+	defer span.End()
+
+	_, err := requireEnabledUser(ctx, s.Repository, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	shelf := &model.Shelf{
+		ShelfBase: model.ShelfBase{
+			Title:       export.Title,
+			Path:        export.Path,
+			Domain:      export.Domain,
+			Description: export.Description,
+			Theme:       export.Theme,
+			Icon:        export.Icon,
+			UserId:      userId,
+		},
+	}
+
+	err = prepareDomainVerification(shelf, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	shelfId, err := s.Repository.ShelfRepository.Import(ctx, shelf, export.Sections)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.ShelfCreated()
+	for _, section := range export.Sections {
+		for range section.Links {
+			metrics.LinkCreated()
+		}
+	}
+	imported, err := s.Repository.ShelfRepository.Get(ctx, shelfId)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.Repository, model.AuditActionCreate, model.AuditEntityShelf, shelfId, shelfId, nil, imported)
+	s.Domain.recordRevision(ctx, s.Repository, shelfId)
+	return imported, nil
+}
+
+// VerifyDomain checks whether the TXT record _linkshelf.<domain> contains the verification token of the
+// shelf. Only verified domains are served and get certificates.
+func (s *shelfServiceImpl) VerifyDomain(ctx context.Context, shelfId string) (*model.Shelf, error) {
+	ctx, span := tracing.Start(ctx, "ShelfService.VerifyDomain")
+	defer span.End()
+
+	shelf, err := authorizeShelf(ctx, s.Repository, shelfId, model.ShelfRoleEditor)
 	if err != nil {
 		return nil, err
 	}
+	if shelf.Domain == "" {
+		return nil, fmt.Errorf("shelf %s has no custom domain", shelfId)
+	}
+	if shelf.DomainVerifiedAt != nil {
+		return shelf, nil
+	}
 
-	shelf, err := s.Repository.ShelfRepository.Get(shelfId)
+	records, err := net.DefaultResolver.LookupTXT(ctx, domainVerificationPrefix+"."+shelf.Domain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the verification record of %s: %w", shelf.Domain, err)
+	}
+	if !slices.Contains(records, shelf.DomainVerificationToken) {
+		return nil, fmt.Errorf("the TXT record %s.%s doesn't contain the verification token", domainVerificationPrefix, shelf.Domain)
+	}
+
+	existing := *shelf
+	verifiedAt := time.Now().UTC()
+	shelf.DomainVerifiedAt = &verifiedAt
+	err = s.Repository.ShelfRepository.UpdateDomainVerification(ctx, shelf)
 	if err != nil {
 		return nil, err
 	}
+
+	recordAudit(ctx, s.Repository, model.AuditActionUpdate, model.AuditEntityShelf, shelfId, shelfId, existing, shelf)
+	logging.FromContext(ctx).Info("Shelf domain verified", slog.String("shelfId", shelfId), slog.String("domain", shelf.Domain))
 	return shelf, nil
 }
 
-func (s *shelfServiceImpl) DeleteShelf(shelfRequest *model.Shelf) error {
-	return s.Repository.ShelfRepository.Delete(shelfRequest)
+func (s *shelfServiceImpl) IsVerifiedDomain(ctx context.Context, domain string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "ShelfService.IsVerifiedDomain")
+	defer span.End()
+
+	shelf, err := s.Repository.ShelfRepository.GetVerifiedByDomain(ctx, normalizeDomain(domain))
+	if err != nil {
+		return false, err
+	}
+
+	return shelf != nil, nil
+}
+
+func (s *shelfServiceImpl) ListShares(ctx context.Context, shelfId string) ([]model.ShelfShare, error) {
+	ctx, span := tracing.Start(ctx, "ShelfService.ListShares")
+	defer span.End()
+
+	_, err := authorizeShelf(ctx, s.Repository, shelfId, model.ShelfRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repository.ShelfShareRepository.ListByShelfId(ctx, shelfId)
+}
+
+// Share grants a user the role on the shelf, or changes the role if the shelf is already shared with the
+// user. Only owners may share a shelf.
+func (s *shelfServiceImpl) Share(ctx context.Context, shelfId, userId, role string) (*model.ShelfShare, error) {
+	ctx, span := tracing.Start(ctx, "ShelfService.Share")
+	defer span.End()
+
+	if !slices.Contains([]string{model.ShelfRoleEditor, model.ShelfRoleViewer}, role) {
+		return nil, fmt.Errorf("unknown share role %q", role)
+	}
+
+	shelf, err := authorizeShelf(ctx, s.Repository, shelfId, model.ShelfRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	if shelf.UserId == userId {
+		return nil, fmt.Errorf("shelf %s already belongs to user %s", shelfId, userId)
+	}
+
+	_, err = requireEnabledUser(ctx, s.Repository, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	share, err := saveShare(ctx, s.Repository, shelfId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Shelf shared", slog.String("shelfId", shelfId), slog.String("userId", userId), slog.String("role", role))
+	return share, nil
+}
+
+// saveShare creates the share or changes the role of an existing one.
+func saveShare(ctx context.Context, repo *repository.Repository, shelfId, userId, role string) (*model.ShelfShare, error) {
+	share, err := repo.ShelfShareRepository.Get(ctx, shelfId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	entityId := shelfId + "/" + userId
+	if share == nil {
+		share = &model.ShelfShare{ShelfId: shelfId, UserId: userId, Role: role, CreatedAt: time.Now().UTC()}
+		err = repo.ShelfShareRepository.Create(ctx, share)
+		if err != nil {
+			return nil, err
+		}
+		recordAudit(ctx, repo, model.AuditActionCreate, model.AuditEntityShelfShare, entityId, shelfId, nil, share)
+	} else {
+		existing := *share
+		share.Role = role
+		err = repo.ShelfShareRepository.SetRole(ctx, shelfId, userId, role)
+		if err != nil {
+			return nil, err
+		}
+		recordAudit(ctx, repo, model.AuditActionUpdate, model.AuditEntityShelfShare, entityId, shelfId, existing, share)
+	}
+
+	return share, nil
+}
+
+// RevokeShare removes the share. Owners may revoke every share, users may also give up their own.
+func (s *shelfServiceImpl) RevokeShare(ctx context.Context, shelfId, userId string) error {
+	ctx, span := tracing.Start(ctx, "ShelfService.RevokeShare")
+	defer span.End()
+
+	required := model.ShelfRoleOwner
+	if principal := PrincipalFromContext(ctx); principal != nil && principal.UserId == userId {
+		required = model.ShelfRoleViewer
+	}
+	_, err := authorizeShelf(ctx, s.Repository, shelfId, required)
+	if err != nil {
+		return err
+	}
+
+	share, err := s.Repository.ShelfShareRepository.Get(ctx, shelfId, userId)
+	if err != nil {
+		return err
+	}
+	revoked, err := s.Repository.ShelfShareRepository.Delete(ctx, shelfId, userId)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return fmt.Errorf("shelf %s isn't shared with user %s", shelfId, userId)
+	}
+
+	recordAudit(ctx, s.Repository, model.AuditActionDelete, model.AuditEntityShelfShare, shelfId+"/"+userId, shelfId, share, nil)
+	return nil
+}
+
+// authorizeOwner checks that the caller may create shelves for the team or user of the shelf. Team shelves
+// need an editor of the team and belong to the team alone, personal shelves default to the caller and
+// can't be created for other users.
+func (s *shelfServiceImpl) authorizeOwner(ctx context.Context, shelf *model.Shelf) error {
+	if shelf.TeamId != "" {
+		shelf.UserId = ""
+		_, err := authorizeTeam(ctx, s.Repository, shelf.TeamId, model.ShelfRoleEditor)
+		return err
+	}
+
+	principal := PrincipalFromContext(ctx)
+	if principal != nil {
+		if shelf.UserId == "" {
+			shelf.UserId = principal.UserId
+		}
+		if shelf.UserId != principal.UserId {
+			return ErrForbidden
+		}
+	}
+	if shelf.UserId == "" {
+		return errors.New("a shelf needs either a user or a team")
+	}
+
+	_, err := requireEnabledUser(ctx, s.Repository, shelf.UserId)
+	return err
+}
+
+// prepareDomainVerification keeps the verification of an unchanged domain and requires a new verification
+// with a fresh token whenever the domain of a shelf changes.
+func prepareDomainVerification(shelf *model.Shelf, existing *model.Shelf) error {
+	shelf.Domain = normalizeDomain(shelf.Domain)
+
+	if existing != nil && existing.Domain == shelf.Domain {
+		shelf.DomainVerificationToken = existing.DomainVerificationToken
+		shelf.DomainVerifiedAt = existing.DomainVerifiedAt
+		return nil
+	}
+
+	shelf.DomainVerificationToken = ""
+	shelf.DomainVerifiedAt = nil
+	if shelf.Domain == "" {
+		return nil
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+	shelf.DomainVerificationToken = hex.EncodeToString(token)
+
+	return nil
+}
+
+func normalizeDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
 }
@@ -0,0 +1,279 @@
+package domain
+
+import (
+	"backend/internal/infrastructure/api/mapper"
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/logging"
+	"backend/internal/infrastructure/mail"
+	"backend/internal/infrastructure/repository"
+	"backend/internal/infrastructure/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrInvitationForAnotherEmail is returned if a user accepts an invitation which was mailed to another
+// address.
+var ErrInvitationForAnotherEmail = errors.New("the invitation was sent to another email address")
+
+// ErrInvitationEmailNotVerified is returned if a user accepts an invitation which was mailed to their
+// address before verifying it, anyone could sign up with the address otherwise.
+var ErrInvitationEmailNotVerified = errors.New("verify your email address to accept the invitation")
+
+// invitationMail is the data of the shelf invitation mail.
+type invitationMail struct {
+	AppName     string
+	InviterName string
+	ShelfTitle  string
+	Role        string
+	Link        string
+	ExpiresAt   time.Time
+}
+
+type ShelfInvitationService interface {
+	Invite(ctx context.Context, shelfId string, create *model.ShelfInvitationCreate) (string, *model.ShelfInvitation, error)
+	List(ctx context.Context, shelfId string) ([]model.ShelfInvitation, error)
+	Revoke(ctx context.Context, shelfId, invitationId string) error
+	Accept(ctx context.Context, userId, token string) (*model.ShelfShare, error)
+	Decline(ctx context.Context, token string) error
+}
+
+type shelfInvitationServiceImpl struct {
+	Repository *repository.Repository
+	Domain     *Service
+}
+
+func NewShelfInvitationService(repository *repository.Repository, domain *Service) ShelfInvitationService {
+	return &shelfInvitationServiceImpl{
+		Repository: repository,
+		Domain:     domain,
+	}
+}
+
+// Invite creates an invitation to the shelf, only owners of the shelf may invite. Invitations with an email
+// are mailed in the background, for the others the link is returned, it can't be shown again.
+func (s *shelfInvitationServiceImpl) Invite(ctx context.Context, shelfId string, create *model.ShelfInvitationCreate) (string, *model.ShelfInvitation, error) {
+	ctx, span := tracing.Start(ctx, "ShelfInvitationService.Invite")
+	defer span.End()
+
+	if !slices.Contains([]string{model.ShelfRoleEditor, model.ShelfRoleViewer}, create.Role) {
+		return "", nil, fmt.Errorf("unknown share role %q", create.Role)
+	}
+
+	shelf, err := authorizeShelf(ctx, s.Repository, shelfId, model.ShelfRoleOwner)
+	if err != nil {
+		return "", nil, err
+	}
+
+	inviterName := s.Domain.config.App.Name
+	var invitedBy string
+	if principal := PrincipalFromContext(ctx); principal != nil {
+		inviter, err := requireEnabledUser(ctx, s.Repository, principal.UserId)
+		if err != nil {
+			return "", nil, err
+		}
+		inviterName = strings.TrimSpace(inviter.FirstName + " " + inviter.LastName)
+		invitedBy = inviter.Id
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now().UTC()
+	invitation := &model.ShelfInvitation{
+		ShelfId:   shelfId,
+		Role:      create.Role,
+		Email:     strings.ToLower(strings.TrimSpace(create.Email)),
+		TokenHash: hashToken(token),
+		InvitedBy: invitedBy,
+		ExpiresAt: now.Add(s.Domain.config.Domain.Tokens.ShelfInvitationTTL),
+		CreatedAt: now,
+	}
+	_, err = s.Repository.ShelfInvitationRepository.Create(ctx, invitation)
+	if err != nil {
+		return "", nil, err
+	}
+
+	logging.FromContext(ctx).Info("Shelf invitation created", slog.String("shelfId", shelfId), slog.String("invitationId", invitation.Id), slog.String("role", invitation.Role))
+	recordAudit(ctx, s.Repository, model.AuditActionCreate, model.AuditEntityShelfInvitation, invitation.Id, shelfId,
+		nil, mapper.MapShelfInvitationToOutput(*invitation))
+
+	link := s.Domain.frontendLink("/invitation", token)
+	if invitation.Email == "" {
+		return link, invitation, nil
+	}
+
+	s.Domain.runInBackground(ctx, "shelf-invitation-mail", func(ctx context.Context) error {
+		message, err := mail.Render(mail.TemplateShelfInvitation, invitationMail{
+			AppName:     s.Domain.config.App.Name,
+			InviterName: inviterName,
+			ShelfTitle:  shelf.Title,
+			Role:        invitation.Role,
+			Link:        link,
+			ExpiresAt:   invitation.ExpiresAt,
+		})
+		if err != nil {
+			return err
+		}
+		message.To = invitation.Email
+
+		return s.Domain.mailer.Send(ctx, message)
+	})
+
+	return "", invitation, nil
+}
+
+// List returns the invitations of the shelf which are neither answered nor expired.
+func (s *shelfInvitationServiceImpl) List(ctx context.Context, shelfId string) ([]model.ShelfInvitation, error) {
+	ctx, span := tracing.Start(ctx, "ShelfInvitationService.List")
+	defer span.End()
+
+	_, err := authorizeShelf(ctx, s.Repository, shelfId, model.ShelfRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repository.ShelfInvitationRepository.ListPendingByShelfId(ctx, shelfId, time.Now().UTC())
+}
+
+func (s *shelfInvitationServiceImpl) Revoke(ctx context.Context, shelfId, invitationId string) error {
+	ctx, span := tracing.Start(ctx, "ShelfInvitationService.Revoke")
+	defer span.End()
+
+	_, err := authorizeShelf(ctx, s.Repository, shelfId, model.ShelfRoleOwner)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := s.Repository.ShelfInvitationRepository.Delete(ctx, shelfId, invitationId)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return fmt.Errorf("shelf %s has no invitation %s", shelfId, invitationId)
+	}
+
+	logging.FromContext(ctx).Info("Shelf invitation revoked", slog.String("shelfId", shelfId), slog.String("invitationId", invitationId))
+	recordAudit(ctx, s.Repository, model.AuditActionDelete, model.AuditEntityShelfInvitation, invitationId, shelfId,
+		map[string]any{"id": invitationId, "shelf_id": shelfId}, nil)
+	return nil
+}
+
+// Accept shares the shelf with the user in the role of the invitation. A better role the user already has is
+// kept. Invitations with an email can only be accepted by the user with that address, once it's verified.
+func (s *shelfInvitationServiceImpl) Accept(ctx context.Context, userId, token string) (*model.ShelfShare, error) {
+	ctx, span := tracing.Start(ctx, "ShelfInvitationService.Accept")
+	defer span.End()
+
+	user, err := requireEnabledUser(ctx, s.Repository, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := s.pendingInvitation(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if invitation.Email != "" && !strings.EqualFold(invitation.Email, user.Email) {
+		return nil, ErrInvitationForAnotherEmail
+	}
+	if invitation.Email != "" && user.EmailVerifiedAt == nil {
+		return nil, ErrInvitationEmailNotVerified
+	}
+
+	shelf, err := s.Repository.ShelfRepository.Get(ctx, invitation.ShelfId)
+	if err != nil {
+		return nil, err
+	}
+	if shelf == nil {
+		return nil, ErrInvalidToken
+	}
+	role, err := shelfRole(ctx, s.Repository, shelf, userId)
+	if err != nil {
+		return nil, err
+	}
+	if role == model.ShelfRoleOwner {
+		return nil, fmt.Errorf("user %s already owns shelf %s", userId, shelf.Id)
+	}
+
+	acceptedAt := time.Now().UTC()
+	accepted, err := s.Repository.ShelfInvitationRepository.Accept(ctx, invitation.Id, userId, acceptedAt)
+	if err != nil {
+		return nil, err
+	}
+	if !accepted {
+		return nil, ErrInvalidToken
+	}
+	recordAudit(ctx, s.Repository, model.AuditActionUpdate, model.AuditEntityShelfInvitation, invitation.Id, shelf.Id,
+		map[string]any{"accepted_at": nil, "accepted_by": nil}, map[string]any{"accepted_at": acceptedAt, "accepted_by": userId})
+
+	share, err := s.Repository.ShelfShareRepository.Get(ctx, shelf.Id, userId)
+	if err != nil {
+		return nil, err
+	}
+	if share == nil || !hasRole(share.Role, invitation.Role) {
+		share, err = saveShare(ctx, s.Repository, shelf.Id, userId, invitation.Role)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	logging.FromContext(ctx).Info("Shelf invitation accepted", slog.String("shelfId", shelf.Id), slog.String("invitationId", invitation.Id), slog.String("userId", userId))
+	return share, nil
+}
+
+// Decline answers the invitation without an account, so invitees don't have to sign up to refuse.
+func (s *shelfInvitationServiceImpl) Decline(ctx context.Context, token string) error {
+	ctx, span := tracing.Start(ctx, "ShelfInvitationService.Decline")
+	defer span.End()
+
+	invitation, err := s.pendingInvitation(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	declinedAt := time.Now().UTC()
+	declined, err := s.Repository.ShelfInvitationRepository.Decline(ctx, invitation.Id, declinedAt)
+	if err != nil {
+		return err
+	}
+	if !declined {
+		return ErrInvalidToken
+	}
+	recordAudit(ctx, s.Repository, model.AuditActionUpdate, model.AuditEntityShelfInvitation, invitation.Id, invitation.ShelfId,
+		map[string]any{"declined_at": nil}, map[string]any{"declined_at": declinedAt})
+
+	logging.FromContext(ctx).Info("Shelf invitation declined", slog.String("shelfId", invitation.ShelfId), slog.String("invitationId", invitation.Id))
+	return nil
+}
+
+// pendingInvitation returns the invitation of the token unless it was answered or expired.
+func (s *shelfInvitationServiceImpl) pendingInvitation(ctx context.Context, token string) (*model.ShelfInvitation, error) {
+	invitation, err := s.Repository.ShelfInvitationRepository.GetByHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if invitation == nil || invitation.AcceptedAt != nil || invitation.DeclinedAt != nil || !time.Now().UTC().Before(invitation.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	return invitation, nil
+}
+
+func purgeExpiredShelfInvitations(repo *repository.Repository) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		deleted, err := repo.ShelfInvitationRepository.DeleteExpired(ctx, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if deleted > 0 {
+			logging.FromContext(ctx).Info("Expired shelf invitations purged", slog.Int64("count", deleted))
+		}
+		return nil
+	}
+}
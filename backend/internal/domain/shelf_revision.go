@@ -0,0 +1,375 @@
+package domain
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/logging"
+	"backend/internal/infrastructure/repository"
+	"backend/internal/infrastructure/tracing"
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+type ShelfRevisionService interface {
+	List(ctx context.Context, shelfId string, page model.PaginationQuery) ([]model.ShelfRevision, int64, error)
+	Get(ctx context.Context, shelfId, revisionId string) (*model.ShelfRevision, error)
+	Diff(ctx context.Context, shelfId, from, to string) (*model.ShelfRevisionDiff, error)
+	Restore(ctx context.Context, shelfId, revisionId string) (*model.Shelf, error)
+}
+
+type shelfRevisionServiceImpl struct {
+	Repository *repository.Repository
+	Domain     *Service
+}
+
+func NewShelfRevisionService(repository *repository.Repository, domain *Service) ShelfRevisionService {
+	return &shelfRevisionServiceImpl{
+		Repository: repository,
+		Domain:     domain,
+	}
+}
+
+func (s *shelfRevisionServiceImpl) List(ctx context.Context, shelfId string, page model.PaginationQuery) ([]model.ShelfRevision, int64, error) {
+	ctx, span := tracing.Start(ctx, "ShelfRevisionService.List")
+	defer span.End()
+
+	_, err := authorizeShelf(ctx, s.Repository, shelfId, model.ShelfRoleViewer)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return s.Repository.ShelfRevisionRepository.List(ctx, shelfId, page)
+}
+
+func (s *shelfRevisionServiceImpl) Get(ctx context.Context, shelfId, revisionId string) (*model.ShelfRevision, error) {
+	ctx, span := tracing.Start(ctx, "ShelfRevisionService.Get")
+	defer span.End()
+
+	_, err := authorizeShelf(ctx, s.Repository, shelfId, model.ShelfRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.revision(ctx, shelfId, revisionId)
+}
+
+// Diff compares two revisions of the shelf. Without a newer revision the older one is compared with the
+// current state of the shelf.
+func (s *shelfRevisionServiceImpl) Diff(ctx context.Context, shelfId, from, to string) (*model.ShelfRevisionDiff, error) {
+	ctx, span := tracing.Start(ctx, "ShelfRevisionService.Diff")
+	defer span.End()
+
+	_, err := authorizeShelf(ctx, s.Repository, shelfId, model.ShelfRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	older, err := s.revision(ctx, shelfId, from)
+	if err != nil {
+		return nil, err
+	}
+
+	var newer *model.ShelfSnapshot
+	if to == "" {
+		newer, err = loadShelfSnapshot(ctx, s.Repository, shelfId)
+	} else {
+		var revision *model.ShelfRevision
+		revision, err = s.revision(ctx, shelfId, to)
+		if revision != nil {
+			newer = revision.Snapshot
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return diffShelfSnapshots(from, to, older.Snapshot, newer)
+}
+
+// Restore brings the attributes, sections and links of the shelf back to the state of the revision. The
+// restore is a revision of its own, so it can be undone again.
+func (s *shelfRevisionServiceImpl) Restore(ctx context.Context, shelfId, revisionId string) (*model.Shelf, error) {
+	ctx, span := tracing.Start(ctx, "ShelfRevisionService.Restore")
+	defer span.End()
+
+	existing, err := authorizeShelf(ctx, s.Repository, shelfId, model.ShelfRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := s.revision(ctx, shelfId, revisionId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	restore := &model.ShelfRevision{
+		ShelfId:      shelfId,
+		RestoredFrom: revisionId,
+		Snapshot:     revision.Snapshot,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if principal := PrincipalFromContext(ctx); principal != nil {
+		restore.AuthorId = principal.UserId
+	}
+	err = s.Repository.ShelfRevisionRepository.Restore(ctx, restore)
+	if err != nil {
+		return nil, err
+	}
+
+	shelf, err := s.Repository.ShelfRepository.Get(ctx, shelfId)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Shelf restored", slog.String("shelfId", shelfId), slog.String("revisionId", revisionId))
+	recordAudit(ctx, s.Repository, model.AuditActionUpdate, model.AuditEntityShelf, shelfId, shelfId,
+		existing, struct {
+			*model.Shelf
+			RestoredFrom string `json:"restoredFrom"`
+		}{shelf, revisionId})
+	s.Domain.pruneRevisions(ctx, s.Repository, shelfId)
+	return shelf, nil
+}
+
+func (s *shelfRevisionServiceImpl) revision(ctx context.Context, shelfId, revisionId string) (*model.ShelfRevision, error) {
+	revision, err := s.Repository.ShelfRevisionRepository.Get(ctx, shelfId, revisionId)
+	if err != nil {
+		return nil, err
+	}
+	if revision == nil {
+		return nil, fmt.Errorf("shelf %s has no revision %s", shelfId, revisionId)
+	}
+	return revision, nil
+}
+
+// recordRevision snapshots the shelf after a change of its attributes, sections or links. Changes of the
+// same author within the session window update the latest revision, so an editing session doesn't bury
+// older revisions, but restores are never extended. Like the audit log, failures are only logged.
+func (s *Service) recordRevision(ctx context.Context, repo *repository.Repository, shelfId string) {
+	err := s.saveRevision(ctx, repo, shelfId)
+	if err != nil {
+		logging.FromContext(ctx).Error("Shelf revision not recorded", slog.String("shelfId", shelfId), slog.String("error", err.Error()))
+	}
+}
+
+func (s *Service) saveRevision(ctx context.Context, repo *repository.Repository, shelfId string) error {
+	snapshot, err := loadShelfSnapshot(ctx, repo, shelfId)
+	if err != nil {
+		return err
+	}
+
+	latest, err := repo.ShelfRevisionRepository.Latest(ctx, shelfId)
+	if err != nil {
+		return err
+	}
+	if latest != nil && reflect.DeepEqual(latest.Snapshot, snapshot) {
+		return nil
+	}
+
+	var authorId string
+	if principal := PrincipalFromContext(ctx); principal != nil {
+		authorId = principal.UserId
+	}
+
+	now := time.Now().UTC()
+	if latest != nil && authorId != "" && latest.AuthorId == authorId && latest.RestoredFrom == "" &&
+		now.Sub(latest.UpdatedAt) < s.config.Domain.Revisions.SessionWindow {
+		return repo.ShelfRevisionRepository.UpdateSnapshot(ctx, latest.Id, snapshot, now)
+	}
+
+	_, err = repo.ShelfRevisionRepository.Create(ctx, &model.ShelfRevision{
+		ShelfId:   shelfId,
+		AuthorId:  authorId,
+		Snapshot:  snapshot,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.pruneRevisions(ctx, repo, shelfId)
+	return nil
+}
+
+// pruneRevisions removes the revisions of the shelf beyond the configured limit.
+func (s *Service) pruneRevisions(ctx context.Context, repo *repository.Repository, shelfId string) {
+	pruned, err := repo.ShelfRevisionRepository.Prune(ctx, shelfId, s.config.Domain.Revisions.Limit)
+	if err != nil {
+		logging.FromContext(ctx).Error("Shelf revisions not pruned", slog.String("shelfId", shelfId), slog.String("error", err.Error()))
+		return
+	}
+	if pruned > 0 {
+		logging.FromContext(ctx).Debug("Shelf revisions pruned", slog.String("shelfId", shelfId), slog.Int64("count", pruned))
+	}
+}
+
+// loadShelfSnapshot reads the current tree of the shelf. Sections and links have no order of their own, so
+// they're sorted by ID to compare snapshots, and slices are never nil to compare equal to the stored JSON.
+func loadShelfSnapshot(ctx context.Context, repo *repository.Repository, shelfId string) (*model.ShelfSnapshot, error) {
+	shelf, err := repo.ShelfRepository.Get(ctx, shelfId)
+	if err != nil {
+		return nil, err
+	}
+	if shelf == nil {
+		return nil, fmt.Errorf("shelf %s not found", shelfId)
+	}
+
+	sections, err := repo.SectionRepository.ListByShelfId(ctx, shelfId)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := repo.LinkRepository.ListByShelfId(ctx, shelfId)
+	if err != nil {
+		return nil, err
+	}
+
+	linksBySection := make(map[string][]model.LinkSnapshot)
+	for _, link := range links {
+		linksBySection[link.SectionId] = append(linksBySection[link.SectionId], model.LinkSnapshot{
+			Id:    link.Id,
+			Title: link.Title,
+			Link:  link.Link,
+			Icon:  link.Icon,
+			Color: link.Color,
+		})
+	}
+
+	snapshot := &model.ShelfSnapshot{
+		Title:       shelf.Title,
+		Description: shelf.Description,
+		Theme:       shelf.Theme,
+		Icon:        shelf.Icon,
+		Sections:    make([]model.SectionSnapshot, 0, len(sections)),
+	}
+	slices.SortFunc(sections, func(a, b model.Section) int { return strings.Compare(a.Id, b.Id) })
+	for _, section := range sections {
+		sectionLinks := linksBySection[section.Id]
+		if sectionLinks == nil {
+			sectionLinks = []model.LinkSnapshot{}
+		}
+		slices.SortFunc(sectionLinks, func(a, b model.LinkSnapshot) int { return strings.Compare(a.Id, b.Id) })
+		snapshot.Sections = append(snapshot.Sections, model.SectionSnapshot{
+			Id:    section.Id,
+			Title: section.Title,
+			Links: sectionLinks,
+		})
+	}
+
+	return snapshot, nil
+}
+
+// revisionSection is a section of a snapshot without its links, they're compared on their own.
+type revisionSection struct {
+	Id    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// revisionLink is a link of a snapshot together with its section, so moves between sections show up in
+// the diff.
+type revisionLink struct {
+	model.LinkSnapshot
+	SectionId string `json:"sectionId"`
+}
+
+// revisionEntity is a section or link to match by its ID.
+type revisionEntity struct {
+	id    string
+	value any
+}
+
+func diffShelfSnapshots(from, to string, older, newer *model.ShelfSnapshot) (*model.ShelfRevisionDiff, error) {
+	// Without sections, only the attributes of the shelf itself are compared.
+	shelfChanges, err := auditChanges(
+		model.ShelfSnapshot{Title: older.Title, Description: older.Description, Theme: older.Theme, Icon: older.Icon},
+		model.ShelfSnapshot{Title: newer.Title, Description: newer.Description, Theme: newer.Theme, Icon: newer.Icon},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &model.ShelfRevisionDiff{
+		From:     from,
+		To:       to,
+		Shelf:    shelfChanges,
+		Sections: []model.RevisionEntityDiff{},
+		Links:    []model.RevisionEntityDiff{},
+	}
+
+	var olderSections, newerSections []revisionEntity
+	var olderLinks, newerLinks []revisionEntity
+	for _, section := range older.Sections {
+		olderSections = append(olderSections, revisionEntity{section.Id, revisionSection{section.Id, section.Title}})
+		for _, link := range section.Links {
+			olderLinks = append(olderLinks, revisionEntity{link.Id, revisionLink{link, section.Id}})
+		}
+	}
+	for _, section := range newer.Sections {
+		newerSections = append(newerSections, revisionEntity{section.Id, revisionSection{section.Id, section.Title}})
+		for _, link := range section.Links {
+			newerLinks = append(newerLinks, revisionEntity{link.Id, revisionLink{link, section.Id}})
+		}
+	}
+
+	diff.Sections, err = diffEntities(olderSections, newerSections)
+	if err != nil {
+		return nil, err
+	}
+	diff.Links, err = diffEntities(olderLinks, newerLinks)
+	if err != nil {
+		return nil, err
+	}
+
+	return diff, nil
+}
+
+// diffEntities matches the entities by their IDs and keeps the order of the older and then the newer
+// revision.
+func diffEntities(older, newer []revisionEntity) ([]model.RevisionEntityDiff, error) {
+	newerById := make(map[string]any, len(newer))
+	for _, entity := range newer {
+		newerById[entity.id] = entity.value
+	}
+	olderIds := make(map[string]bool, len(older))
+
+	diffs := []model.RevisionEntityDiff{}
+	for _, entity := range older {
+		olderIds[entity.id] = true
+		value, ok := newerById[entity.id]
+		if !ok {
+			changes, err := auditChanges(entity.value, nil)
+			if err != nil {
+				return nil, err
+			}
+			diffs = append(diffs, model.RevisionEntityDiff{Id: entity.id, Change: model.RevisionChangeRemoved, Changes: changes})
+			continue
+		}
+
+		changes, err := auditChanges(entity.value, value)
+		if err != nil {
+			return nil, err
+		}
+		if len(changes) > 0 {
+			diffs = append(diffs, model.RevisionEntityDiff{Id: entity.id, Change: model.RevisionChangeChanged, Changes: changes})
+		}
+	}
+	for _, entity := range newer {
+		if olderIds[entity.id] {
+			continue
+		}
+		changes, err := auditChanges(nil, entity.value)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, model.RevisionEntityDiff{Id: entity.id, Change: model.RevisionChangeAdded, Changes: changes})
+	}
+
+	return diffs, nil
+}
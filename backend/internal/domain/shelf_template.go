@@ -0,0 +1,220 @@
+package domain
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/logging"
+	"backend/internal/infrastructure/repository"
+	"backend/internal/infrastructure/tracing"
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"strings"
+	"sync"
+)
+
+// The built-in templates are stored as templates/<id>.json, in the JSON form of model.ShelfTemplate without
+// ID.
+//
+//go:embed templates/*.json
+var builtInTemplateFiles embed.FS
+
+// builtInTemplates parses the built-in templates once, ordered by their IDs.
+var builtInTemplates = sync.OnceValues(func() ([]model.ShelfTemplate, error) {
+	files, err := fs.Glob(builtInTemplateFiles, "templates/*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make([]model.ShelfTemplate, 0, len(files))
+	for _, file := range files {
+		content, err := builtInTemplateFiles.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var template model.ShelfTemplate
+		err = json.Unmarshal(content, &template)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the built-in template %s: %w", file, err)
+		}
+		template.Id = strings.TrimSuffix(path.Base(file), ".json")
+		template.BuiltIn = true
+		templates = append(templates, template)
+	}
+
+	return templates, nil
+})
+
+type ShelfTemplateService interface {
+	List(ctx context.Context) ([]model.ShelfTemplate, error)
+	Get(ctx context.Context, templateId string) (*model.ShelfTemplate, error)
+	Publish(ctx context.Context, shelfId string, publish *model.ShelfTemplatePublish) (*model.ShelfTemplate, error)
+	Delete(ctx context.Context, templateId string) error
+	CreateShelf(ctx context.Context, userId string, from *model.ShelfFromTemplate) (*model.Shelf, error)
+}
+
+type shelfTemplateServiceImpl struct {
+	Repository *repository.Repository
+	Domain     *Service
+}
+
+func NewShelfTemplateService(repository *repository.Repository, domain *Service) ShelfTemplateService {
+	return &shelfTemplateServiceImpl{
+		Repository: repository,
+		Domain:     domain,
+	}
+}
+
+// List returns the built-in templates followed by those published by users, newest first.
+func (s *shelfTemplateServiceImpl) List(ctx context.Context) ([]model.ShelfTemplate, error) {
+	ctx, span := tracing.Start(ctx, "ShelfTemplateService.List")
+	defer span.End()
+
+	builtIn, err := builtInTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	published, err := s.Repository.ShelfTemplateRepository.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append([]model.ShelfTemplate{}, builtIn...), published...), nil
+}
+
+func (s *shelfTemplateServiceImpl) Get(ctx context.Context, templateId string) (*model.ShelfTemplate, error) {
+	ctx, span := tracing.Start(ctx, "ShelfTemplateService.Get")
+	defer span.End()
+
+	builtIn, err := builtInTemplates()
+	if err != nil {
+		return nil, err
+	}
+	for _, template := range builtIn {
+		if template.Id == templateId {
+			return &template, nil
+		}
+	}
+
+	template, err := s.Repository.ShelfTemplateRepository.Get(ctx, templateId)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return nil, fmt.Errorf("template %s not found", templateId)
+	}
+
+	return template, nil
+}
+
+// Publish makes the sections and links of the shelf a template every user can start a shelf from. Only
+// owners may publish a shelf, the template keeps its contents even if the shelf changes later on.
+func (s *shelfTemplateServiceImpl) Publish(ctx context.Context, shelfId string, publish *model.ShelfTemplatePublish) (*model.ShelfTemplate, error) {
+	ctx, span := tracing.Start(ctx, "ShelfTemplateService.Publish")
+	defer span.End()
+
+	shelf, err := authorizeShelf(ctx, s.Repository, shelfId, model.ShelfRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	authorId := shelf.UserId
+	if principal := PrincipalFromContext(ctx); principal != nil {
+		authorId = principal.UserId
+	}
+	if authorId == "" {
+		return nil, errors.New("a template needs an author, team shelves can only be published by a member")
+	}
+
+	export, err := s.Domain.ShelfService.ExportShelf(ctx, shelfId)
+	if err != nil {
+		return nil, err
+	}
+	for i := range export.Sections {
+		if export.Sections[i].Links == nil {
+			export.Sections[i].Links = []model.LinkExport{}
+		}
+	}
+
+	template := &model.ShelfTemplate{
+		Name:        publish.Name,
+		Description: publish.Description,
+		Theme:       export.Theme,
+		Icon:        export.Icon,
+		AuthorId:    authorId,
+		Sections:    export.Sections,
+	}
+	templateId, err := s.Repository.ShelfTemplateRepository.Create(ctx, template)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Shelf published as template", slog.String("shelfId", shelfId), slog.String("templateId", templateId))
+	published, err := s.Repository.ShelfTemplateRepository.Get(ctx, templateId)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.Repository, model.AuditActionCreate, model.AuditEntityShelfTemplate, templateId, "", nil, published)
+	return published, nil
+}
+
+// Delete removes a published template. Only its author and admins may delete it, built-in templates can't
+// be deleted at all. Shelves created from the template are kept.
+func (s *shelfTemplateServiceImpl) Delete(ctx context.Context, templateId string) error {
+	ctx, span := tracing.Start(ctx, "ShelfTemplateService.Delete")
+	defer span.End()
+
+	template, err := s.Get(ctx, templateId)
+	if err != nil {
+		return err
+	}
+	if template.BuiltIn {
+		return fmt.Errorf("the built-in template %s can't be deleted", templateId)
+	}
+
+	principal := PrincipalFromContext(ctx)
+	if principal != nil && principal.UserId != template.AuthorId && principal.Role != model.RoleAdmin {
+		return ErrForbidden
+	}
+
+	err = s.Repository.ShelfTemplateRepository.Delete(ctx, templateId)
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, s.Repository, model.AuditActionDelete, model.AuditEntityShelfTemplate, templateId, "", template, nil)
+	return nil
+}
+
+// CreateShelf creates a new personal shelf of the user with the title and path of the request and the
+// theme, icon, sections and links of the template.
+func (s *shelfTemplateServiceImpl) CreateShelf(ctx context.Context, userId string, from *model.ShelfFromTemplate) (*model.Shelf, error) {
+	ctx, span := tracing.Start(ctx, "ShelfTemplateService.CreateShelf")
+	defer span.End()
+
+	template, err := s.Get(ctx, from.TemplateId)
+	if err != nil {
+		return nil, err
+	}
+
+	shelf, err := s.Domain.ShelfService.ImportShelf(ctx, userId, &model.ShelfExport{
+		Title:    from.Title,
+		Path:     from.Path,
+		Theme:    template.Theme,
+		Icon:     template.Icon,
+		Sections: template.Sections,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Shelf created from template", slog.String("shelfId", shelf.Id), slog.String("templateId", template.Id))
+	return shelf, nil
+}
@@ -0,0 +1,247 @@
+package domain
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/logging"
+	"backend/internal/infrastructure/repository"
+	"backend/internal/infrastructure/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// ErrLastTeamOwner is returned if the last owner of a team would be removed or demoted, nobody could manage
+// the team afterwards.
+var ErrLastTeamOwner = errors.New("a team needs at least one owner")
+
+// ErrTeamOwnsShelves is returned if a team with shelves would be deleted, the shelves have to be deleted
+// or moved first, so they pass through the trash and the audit log.
+var ErrTeamOwnsShelves = errors.New("the team still owns shelves, delete or move them first")
+
+type TeamService interface {
+	Create(ctx context.Context, userId, name string) (*model.Team, error)
+	List(ctx context.Context, userId string) ([]model.Team, error)
+	Get(ctx context.Context, teamId string) (*model.Team, error)
+	Rename(ctx context.Context, teamId, name string) (*model.Team, error)
+	Delete(ctx context.Context, teamId string) error
+	ListMembers(ctx context.Context, teamId string) ([]model.TeamMember, error)
+	SetMember(ctx context.Context, teamId, userId, role string) (*model.TeamMember, error)
+	RemoveMember(ctx context.Context, teamId, userId string) error
+}
+
+type teamServiceImpl struct {
+	Repository *repository.Repository
+	Domain     *Service
+}
+
+func NewTeamService(repository *repository.Repository, domain *Service) TeamService {
+	return &teamServiceImpl{
+		Repository: repository,
+		Domain:     domain,
+	}
+}
+
+// Create creates a team with the user as its first owner.
+func (s *teamServiceImpl) Create(ctx context.Context, userId, name string) (*model.Team, error) {
+	ctx, span := tracing.Start(ctx, "TeamService.Create")
+	defer span.End()
+
+	_, err := requireEnabledUser(ctx, s.Repository, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	team := &model.Team{Name: name, CreatedAt: time.Now().UTC()}
+	teamId, err := s.Repository.TeamRepository.Create(ctx, team, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Team created", slog.String("teamId", teamId), slog.String("userId", userId))
+	recordAudit(ctx, s.Repository, model.AuditActionCreate, model.AuditEntityTeam, teamId, "", nil, team)
+	return s.Repository.TeamRepository.Get(ctx, teamId)
+}
+
+// List returns the teams the user is a member of.
+func (s *teamServiceImpl) List(ctx context.Context, userId string) ([]model.Team, error) {
+	ctx, span := tracing.Start(ctx, "TeamService.List")
+	defer span.End()
+
+	return s.Repository.TeamRepository.ListByUserId(ctx, userId)
+}
+
+func (s *teamServiceImpl) Get(ctx context.Context, teamId string) (*model.Team, error) {
+	ctx, span := tracing.Start(ctx, "TeamService.Get")
+	defer span.End()
+
+	return authorizeTeam(ctx, s.Repository, teamId, model.ShelfRoleViewer)
+}
+
+func (s *teamServiceImpl) Rename(ctx context.Context, teamId, name string) (*model.Team, error) {
+	ctx, span := tracing.Start(ctx, "TeamService.Rename")
+	defer span.End()
+
+	team, err := authorizeTeam(ctx, s.Repository, teamId, model.ShelfRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := *team
+	team.Name = name
+	err = s.Repository.TeamRepository.Update(ctx, team)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.Repository, model.AuditActionUpdate, model.AuditEntityTeam, teamId, "", existing, team)
+	return team, nil
+}
+
+// Delete removes the team together with all its shelves.
+func (s *teamServiceImpl) Delete(ctx context.Context, teamId string) error {
+	ctx, span := tracing.Start(ctx, "TeamService.Delete")
+	defer span.End()
+
+	team, err := authorizeTeam(ctx, s.Repository, teamId, model.ShelfRoleOwner)
+	if err != nil {
+		return err
+	}
+
+	// Deleting the team cascades to its shelves, including the ones in the trash.
+	shelves, err := s.Repository.TeamRepository.CountShelves(ctx, teamId)
+	if err != nil {
+		return err
+	}
+	if shelves > 0 {
+		return ErrTeamOwnsShelves
+	}
+
+	err = s.Repository.TeamRepository.Delete(ctx, teamId)
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Info("Team deleted", slog.String("teamId", teamId))
+	recordAudit(ctx, s.Repository, model.AuditActionDelete, model.AuditEntityTeam, teamId, "", team, nil)
+	return nil
+}
+
+func (s *teamServiceImpl) ListMembers(ctx context.Context, teamId string) ([]model.TeamMember, error) {
+	ctx, span := tracing.Start(ctx, "TeamService.ListMembers")
+	defer span.End()
+
+	_, err := authorizeTeam(ctx, s.Repository, teamId, model.ShelfRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Repository.TeamRepository.ListMembers(ctx, teamId)
+}
+
+// SetMember adds the user to the team or changes the role of a member. Only owners manage the members.
+func (s *teamServiceImpl) SetMember(ctx context.Context, teamId, userId, role string) (*model.TeamMember, error) {
+	ctx, span := tracing.Start(ctx, "TeamService.SetMember")
+	defer span.End()
+
+	if !slices.Contains([]string{model.ShelfRoleOwner, model.ShelfRoleEditor, model.ShelfRoleViewer}, role) {
+		return nil, fmt.Errorf("unknown team role %q", role)
+	}
+
+	_, err := authorizeTeam(ctx, s.Repository, teamId, model.ShelfRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := s.Repository.TeamRepository.GetMember(ctx, teamId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	if member == nil {
+		_, err = requireEnabledUser(ctx, s.Repository, userId)
+		if err != nil {
+			return nil, err
+		}
+
+		member = &model.TeamMember{TeamId: teamId, UserId: userId, Role: role, CreatedAt: time.Now().UTC()}
+		err = s.Repository.TeamRepository.AddMember(ctx, member)
+		if err != nil {
+			return nil, err
+		}
+		recordAudit(ctx, s.Repository, model.AuditActionCreate, model.AuditEntityTeamMember, teamId+"/"+userId, "", nil, member)
+	} else {
+		if member.Role == model.ShelfRoleOwner && role != model.ShelfRoleOwner {
+			err = s.requireAnotherOwner(ctx, teamId, userId)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		existing := *member
+		member.Role = role
+		err = s.Repository.TeamRepository.SetMemberRole(ctx, teamId, userId, role)
+		if err != nil {
+			return nil, err
+		}
+		recordAudit(ctx, s.Repository, model.AuditActionUpdate, model.AuditEntityTeamMember, teamId+"/"+userId, "", existing, member)
+	}
+
+	logging.FromContext(ctx).Info("Team member set", slog.String("teamId", teamId), slog.String("userId", userId), slog.String("role", role))
+	return member, nil
+}
+
+// RemoveMember removes the user from the team. Owners may remove every member, members may also leave on
+// their own.
+func (s *teamServiceImpl) RemoveMember(ctx context.Context, teamId, userId string) error {
+	ctx, span := tracing.Start(ctx, "TeamService.RemoveMember")
+	defer span.End()
+
+	required := model.ShelfRoleOwner
+	if principal := PrincipalFromContext(ctx); principal != nil && principal.UserId == userId {
+		required = model.ShelfRoleViewer
+	}
+	_, err := authorizeTeam(ctx, s.Repository, teamId, required)
+	if err != nil {
+		return err
+	}
+
+	member, err := s.Repository.TeamRepository.GetMember(ctx, teamId, userId)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return fmt.Errorf("user %s isn't a member of team %s", userId, teamId)
+	}
+	if member.Role == model.ShelfRoleOwner {
+		err = s.requireAnotherOwner(ctx, teamId, userId)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = s.Repository.TeamRepository.RemoveMember(ctx, teamId, userId)
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Info("Team member removed", slog.String("teamId", teamId), slog.String("userId", userId))
+	recordAudit(ctx, s.Repository, model.AuditActionDelete, model.AuditEntityTeamMember, teamId+"/"+userId, "", member, nil)
+	return nil
+}
+
+func (s *teamServiceImpl) requireAnotherOwner(ctx context.Context, teamId, userId string) error {
+	members, err := s.Repository.TeamRepository.ListMembers(ctx, teamId)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		if member.Role == model.ShelfRoleOwner && member.UserId != userId {
+			return nil
+		}
+	}
+	return ErrLastTeamOwner
+}
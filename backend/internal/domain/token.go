@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/logging"
+	"backend/internal/infrastructure/mail"
+	"backend/internal/infrastructure/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken is returned for unknown, expired and already used tokens and those mailed to a former
+// email address alike, so callers can't tell which tokens exist.
+var ErrInvalidToken = errors.New("the token is invalid or expired")
+
+// tokenMail is the data of the mail templates which carry a token.
+type tokenMail struct {
+	AppName   string
+	Name      string
+	Link      string
+	ExpiresAt time.Time
+}
+
+// issueUserToken creates a token for the purpose and replaces older tokens of the same purpose, so only the
+// latest mail of a user works. The plaintext token is returned, only its hash is stored.
+func issueUserToken(ctx context.Context, repo *repository.Repository, user *model.User, purpose string, ttl time.Duration) (string, time.Time, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	err = repo.UserTokenRepository.DeleteByUser(ctx, user.Id, purpose)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(ttl)
+	_, err = repo.UserTokenRepository.Create(ctx, &model.UserToken{
+		UserId:    user.Id,
+		Purpose:   purpose,
+		Email:     user.Email,
+		TokenHash: hashToken(token),
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+// consumeUserToken marks a valid token as used and returns it. A token can only be consumed once, even by
+// concurrent requests.
+func consumeUserToken(ctx context.Context, repo *repository.Repository, purpose, token string) (*model.UserToken, error) {
+	userToken, err := repo.UserTokenRepository.GetByHash(ctx, purpose, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if userToken == nil || userToken.UsedAt != nil || !now.Before(userToken.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	used, err := repo.UserTokenRepository.Use(ctx, userToken.Id, now)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, ErrInvalidToken
+	}
+
+	return userToken, nil
+}
+
+// tokenMailedTo reports whether the token was mailed to the current email address of the user, only then
+// redeeming it proves the ownership of the address.
+func tokenMailedTo(token *model.UserToken, user *model.User) bool {
+	return token.Email != "" && strings.EqualFold(token.Email, user.Email)
+}
+
+// sendTokenMail renders the template with a link to the frontend page which submits the token.
+func (s *Service) sendTokenMail(ctx context.Context, user *model.User, template, page, token string, expiresAt time.Time) error {
+	message, err := mail.Render(template, tokenMail{
+		AppName:   s.config.App.Name,
+		Name:      user.FirstName,
+		Link:      s.frontendLink(page, token),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+	message.To = user.Email
+
+	return s.mailer.Send(ctx, message)
+}
+
+// randomToken returns 32 random bytes, encoded to be used in links.
+func randomToken() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// frontendLink builds a link to the frontend page which submits the token.
+func (s *Service) frontendLink(page, token string) string {
+	return strings.TrimSuffix(s.config.Mail.BaseURL, "/") + page + "?" + url.Values{"token": {token}}.Encode()
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func purgeExpiredUserTokens(repo *repository.Repository) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		deleted, err := repo.UserTokenRepository.DeleteExpired(ctx, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if deleted > 0 {
+			logging.FromContext(ctx).Info("Expired user tokens purged", slog.Int64("count", deleted))
+		}
+		return nil
+	}
+}
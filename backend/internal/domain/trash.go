@@ -0,0 +1,172 @@
+package domain
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/logging"
+	"backend/internal/infrastructure/repository"
+	"backend/internal/infrastructure/tracing"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ErrParentInTrash is returned when a section or link is restored while its shelf or section is still in
+// the trash.
+var ErrParentInTrash = errors.New("the parent of the item is in the trash, restore it first")
+
+type TrashService interface {
+	List(ctx context.Context, userId string) ([]model.TrashItem, error)
+	Restore(ctx context.Context, itemType, itemId string) error
+}
+
+type trashServiceImpl struct {
+	Repository *repository.Repository
+	Domain     *Service
+}
+
+func NewTrashService(repository *repository.Repository, domain *Service) TrashService {
+	return &trashServiceImpl{
+		Repository: repository,
+		Domain:     domain,
+	}
+}
+
+// List returns the items the user deleted and those deleted from the personal shelves of the user.
+func (s *trashServiceImpl) List(ctx context.Context, userId string) ([]model.TrashItem, error) {
+	ctx, span := tracing.Start(ctx, "TrashService.List")
+	defer span.End()
+
+	items, err := s.Repository.TrashRepository.ListByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		items[i].PurgeAt = items[i].DeletedAt.Add(s.Domain.config.Domain.Trash.Retention)
+	}
+	return items, nil
+}
+
+// Restore takes an item out of the trash together with the children which were deleted with it. Shelves
+// need the owner role, sections and links the editor role like deleting them.
+func (s *trashServiceImpl) Restore(ctx context.Context, itemType, itemId string) error {
+	ctx, span := tracing.Start(ctx, "TrashService.Restore")
+	defer span.End()
+
+	var shelfId string
+	var restored bool
+	var err error
+	switch itemType {
+	case model.TrashItemShelf:
+		shelfId = itemId
+		restored, err = s.restoreShelf(ctx, itemId)
+	case model.TrashItemSection:
+		shelfId, restored, err = s.restoreSection(ctx, itemId)
+	case model.TrashItemLink:
+		shelfId, restored, err = s.restoreLink(ctx, itemId)
+	default:
+		return fmt.Errorf("unknown trash item type %q", itemType)
+	}
+	if err != nil {
+		return err
+	}
+	if !restored {
+		return fmt.Errorf("%s %s is not in the trash", itemType, itemId)
+	}
+
+	logging.FromContext(ctx).Info("Item restored from trash", slog.String("type", itemType), slog.String("id", itemId))
+	recordAudit(ctx, s.Repository, model.AuditActionUpdate, itemType, itemId, shelfId,
+		map[string]any{"deleted": true}, map[string]any{"deleted": false})
+	s.Domain.recordRevision(ctx, s.Repository, shelfId)
+	return nil
+}
+
+func (s *trashServiceImpl) restoreShelf(ctx context.Context, shelfId string) (bool, error) {
+	shelf, err := s.Repository.ShelfRepository.GetDeleted(ctx, shelfId)
+	if err != nil || shelf == nil {
+		return false, err
+	}
+
+	if principal := PrincipalFromContext(ctx); principal != nil {
+		role, err := shelfRole(ctx, s.Repository, shelf, principal.UserId)
+		if err != nil {
+			return false, err
+		}
+		if !hasRole(role, model.ShelfRoleOwner) {
+			return false, ErrForbidden
+		}
+	}
+
+	return s.Repository.ShelfRepository.Restore(ctx, shelfId)
+}
+
+func (s *trashServiceImpl) restoreSection(ctx context.Context, sectionId string) (string, bool, error) {
+	section, err := s.Repository.SectionRepository.GetDeleted(ctx, sectionId)
+	if err != nil || section == nil {
+		return "", false, err
+	}
+
+	shelf, err := s.Repository.ShelfRepository.GetDeleted(ctx, section.ShelfId)
+	if err != nil {
+		return "", false, err
+	}
+	if shelf != nil {
+		return "", false, ErrParentInTrash
+	}
+
+	_, err = authorizeShelf(ctx, s.Repository, section.ShelfId, model.ShelfRoleEditor)
+	if err != nil {
+		return "", false, err
+	}
+
+	restored, err := s.Repository.SectionRepository.Restore(ctx, sectionId)
+	return section.ShelfId, restored, err
+}
+
+func (s *trashServiceImpl) restoreLink(ctx context.Context, linkId string) (string, bool, error) {
+	link, err := s.Repository.LinkRepository.GetDeleted(ctx, linkId)
+	if err != nil || link == nil {
+		return "", false, err
+	}
+
+	section, err := s.Repository.SectionRepository.GetDeleted(ctx, link.SectionId)
+	if err != nil {
+		return "", false, err
+	}
+	if section != nil {
+		return "", false, ErrParentInTrash
+	}
+
+	section, err = authorizeSection(ctx, s.Repository, link.SectionId, model.ShelfRoleEditor)
+	if err != nil {
+		return "", false, err
+	}
+
+	restored, err := s.Repository.LinkRepository.Restore(ctx, linkId)
+	return section.ShelfId, restored, err
+}
+
+// trashDeletion returns who deletes an item and when. The time is cut to seconds, which every database
+// stores exactly, so the children deleted together keep the very same deleted_at.
+func trashDeletion(ctx context.Context) (string, time.Time) {
+	var deletedBy string
+	if principal := PrincipalFromContext(ctx); principal != nil {
+		deletedBy = principal.UserId
+	}
+	return deletedBy, time.Now().UTC().Truncate(time.Second)
+}
+
+func purgeTrash(repo *repository.Repository, retention time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		purged, err := repo.TrashRepository.Purge(ctx, time.Now().UTC().Add(-retention))
+		if err != nil {
+			return err
+		}
+		if purged > 0 {
+			logging.FromContext(ctx).Info("Trash purged", slog.Int64("count", purged))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,386 @@
+package domain
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/logging"
+	"backend/internal/infrastructure/repository"
+	"backend/internal/infrastructure/tracing"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/png"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	totpPeriod = 30
+	// totpSkew accepts the codes of the previous and the next period to tolerate clock drift.
+	totpSkew          = 1
+	recoveryCodeCount = 10
+	// sealedSecretPrefix marks the encrypted TOTP secrets. Secrets stored before they were encrypted lack it
+	// and are encrypted the next time they're read.
+	sealedSecretPrefix = "v1:"
+)
+
+var (
+	// ErrSecondFactorRequired is returned if two-factor authentication is enabled and no code was given.
+	ErrSecondFactorRequired = errors.New("a TOTP or recovery code is required")
+	// ErrInvalidSecondFactor is returned for wrong, expired and already used codes alike.
+	ErrInvalidSecondFactor = errors.New("the TOTP or recovery code is invalid")
+)
+
+var totpOptions = totp.ValidateOpts{
+	Period:    totpPeriod,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+type TwoFactorService interface {
+	Enroll(ctx context.Context, userId string, password string) (*model.TwoFactorEnrollment, error)
+	Confirm(ctx context.Context, userId string, code string) ([]string, error)
+	Disable(ctx context.Context, userId string, secondFactor string) error
+	RegenerateRecoveryCodes(ctx context.Context, userId string, secondFactor string) ([]string, error)
+}
+
+type twoFactorServiceImpl struct {
+	Repository *repository.Repository
+	Domain     *Service
+}
+
+func NewTwoFactorService(repository *repository.Repository, domain *Service) TwoFactorService {
+	return &twoFactorServiceImpl{
+		Repository: repository,
+		Domain:     domain,
+	}
+}
+
+// Enroll creates a new TOTP secret once the user confirmed the current password, a stolen session alone
+// can't bind the account to another authenticator. It's only enforced after Confirm, so an abandoned
+// enrollment doesn't lock the user out.
+func (s *twoFactorServiceImpl) Enroll(ctx context.Context, userId string, password string) (*model.TwoFactorEnrollment, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.Enroll")
+	defer span.End()
+
+	if err := rejectImpersonation(ctx); err != nil {
+		return nil, err
+	}
+
+	user, err := requireEnabledUser(ctx, s.Repository, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled {
+		return nil, errors.New("two-factor authentication is already enabled, disable it first")
+	}
+
+	passwordHash, err := s.Repository.UserRepository.GetPassword(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if checkPassword(passwordHash, password) != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.Domain.config.App.Name,
+		AccountName: user.Email,
+		Period:      totpPeriod,
+		Digits:      totpOptions.Digits,
+		Algorithm:   totpOptions.Algorithm,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := sealTOTPSecret(s.Domain.totpKey, userId, key.Secret())
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Repository.TwoFactorRepository.SaveTOTP(ctx, &model.UserTOTP{
+		UserId:    userId,
+		Secret:    sealed,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	image, err := key.Image(256, 256)
+	if err != nil {
+		return nil, err
+	}
+	var qrCode bytes.Buffer
+	if err := png.Encode(&qrCode, image); err != nil {
+		return nil, err
+	}
+
+	return &model.TwoFactorEnrollment{
+		Secret:     key.Secret(),
+		OTPAuthURI: key.URL(),
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(qrCode.Bytes()),
+	}, nil
+}
+
+// Confirm enables two-factor authentication once the user proved to have set up the secret, and returns
+// the recovery codes.
+func (s *twoFactorServiceImpl) Confirm(ctx context.Context, userId string, code string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.Confirm")
+	defer span.End()
+
+	if err := rejectImpersonation(ctx); err != nil {
+		return nil, err
+	}
+
+	userTOTP, err := s.Repository.TwoFactorRepository.GetTOTP(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if userTOTP == nil || userTOTP.ConfirmedAt != nil {
+		return nil, errors.New("no pending two-factor enrollment found")
+	}
+
+	secret, err := openTOTPSecret(ctx, s.Repository, s.Domain.totpKey, userTOTP)
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := matchTOTP(secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidSecondFactor
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Repository.TwoFactorRepository.ConfirmTOTP(ctx, userId, time.Now().UTC(), step, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Two-factor authentication enabled", slog.String("userId", userId))
+	recordAudit(ctx, s.Repository, model.AuditActionUpdate, model.AuditEntityUser, userId, "",
+		map[string]any{"two_factor_enabled": false}, map[string]any{"two_factor_enabled": true})
+	return codes, nil
+}
+
+func (s *twoFactorServiceImpl) Disable(ctx context.Context, userId string, secondFactor string) error {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.Disable")
+	defer span.End()
+
+	err := rejectImpersonation(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = verifySecondFactor(ctx, s.Repository, s.Domain.totpKey, userId, secondFactor)
+	if err != nil {
+		return err
+	}
+
+	err = s.Repository.TwoFactorRepository.DeleteTOTP(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Info("Two-factor authentication disabled", slog.String("userId", userId))
+	recordAudit(ctx, s.Repository, model.AuditActionUpdate, model.AuditEntityUser, userId, "",
+		map[string]any{"two_factor_enabled": true}, map[string]any{"two_factor_enabled": false})
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes, used or not.
+func (s *twoFactorServiceImpl) RegenerateRecoveryCodes(ctx context.Context, userId string, secondFactor string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "TwoFactorService.RegenerateRecoveryCodes")
+	defer span.End()
+
+	if err := rejectImpersonation(ctx); err != nil {
+		return nil, err
+	}
+
+	userTOTP, err := s.Repository.TwoFactorRepository.GetTOTP(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if userTOTP == nil || userTOTP.ConfirmedAt == nil {
+		return nil, errors.New("two-factor authentication isn't enabled")
+	}
+
+	err = verifySecondFactor(ctx, s.Repository, s.Domain.totpKey, userId, secondFactor)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Repository.TwoFactorRepository.ReplaceRecoveryCodes(ctx, userId, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Recovery codes regenerated", slog.String("userId", userId))
+	recordAudit(ctx, s.Repository, model.AuditActionUpdate, model.AuditEntityUser, userId, "",
+		map[string]any{"recovery_codes": nil}, map[string]any{"recovery_codes": auditRedacted})
+	return codes, nil
+}
+
+// verifySecondFactor checks the TOTP or recovery code of a user with enabled two-factor authentication, for
+// everybody else it accepts any code. Every code is only accepted once.
+func verifySecondFactor(ctx context.Context, repo *repository.Repository, key []byte, userId string, code string) error {
+	userTOTP, err := repo.TwoFactorRepository.GetTOTP(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if userTOTP == nil || userTOTP.ConfirmedAt == nil {
+		return nil
+	}
+
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return ErrSecondFactorRequired
+	}
+
+	secret, err := openTOTPSecret(ctx, repo, key, userTOTP)
+	if err != nil {
+		return err
+	}
+
+	if step, ok := matchTOTP(secret, code, time.Now()); ok {
+		used, err := repo.TwoFactorRepository.UseTOTPStep(ctx, userId, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidSecondFactor
+		}
+		return nil
+	}
+
+	used, err := repo.TwoFactorRepository.UseRecoveryCode(ctx, userId, hashToken(normalizeRecoveryCode(code)), time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidSecondFactor
+	}
+
+	logging.FromContext(ctx).Warn("Recovery code used", slog.String("userId", userId))
+	return nil
+}
+
+// sealTOTPSecret encrypts the secret with AES-256-GCM. The user ID is authenticated along, so a secret
+// copied to another user doesn't decrypt.
+func sealTOTPSecret(key []byte, userId, secret string) (string, error) {
+	aead, err := newTOTPCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(secret), []byte(userId))
+	return sealedSecretPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// openTOTPSecret decrypts the secret of the user. Plaintext secrets stored before the encryption are
+// encrypted on the way.
+func openTOTPSecret(ctx context.Context, repo *repository.Repository, key []byte, userTOTP *model.UserTOTP) (string, error) {
+	aead, err := newTOTPCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	encoded, ok := strings.CutPrefix(userTOTP.Secret, sealedSecretPrefix)
+	if !ok {
+		sealed, err := sealTOTPSecret(key, userTOTP.UserId, userTOTP.Secret)
+		if err != nil {
+			return "", err
+		}
+		err = repo.TwoFactorRepository.UpdateTOTPSecret(ctx, userTOTP.UserId, userTOTP.Secret, sealed)
+		if err != nil {
+			return "", err
+		}
+		return userTOTP.Secret, nil
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("the TOTP secret is malformed")
+	}
+	secret, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(userTOTP.UserId))
+	if err != nil {
+		return "", errors.New("the TOTP secret can't be decrypted, was domain.twoFactor.encryptionKey changed?")
+	}
+
+	return string(secret), nil
+}
+
+func newTOTPCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TOTP encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// matchTOTP returns the time step of the code if it's valid at the given time.
+func matchTOTP(secret, code string, at time.Time) (int64, bool) {
+	if len(code) != int(totpOptions.Digits) {
+		return 0, false
+	}
+
+	step := at.Unix() / totpPeriod
+	for offset := int64(-totpSkew); offset <= totpSkew; offset++ {
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix((step+offset)*totpPeriod, 0), totpOptions)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step + offset, true
+		}
+	}
+
+	return 0, false
+}
+
+// newRecoveryCodes returns the codes to show the user and the hashes to store.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		secret := make([]byte, 10)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret))
+		codes = append(codes, fmt.Sprintf("%s-%s", code[:8], code[8:]))
+		hashes = append(hashes, hashToken(code))
+	}
+
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
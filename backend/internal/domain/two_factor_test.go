@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/repository"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTwoFactorRepository struct {
+	repository.TwoFactorRepository
+	secret string
+}
+
+func (r *fakeTwoFactorRepository) UpdateTOTPSecret(_ context.Context, _, previous, secret string) error {
+	if r.secret == previous {
+		r.secret = secret
+	}
+	return nil
+}
+
+func TestTOTPSecretsAreEncryptedForTheirUser(t *testing.T) {
+	key := make([]byte, 32)
+	repo := &repository.Repository{TwoFactorRepository: &fakeTwoFactorRepository{}}
+
+	sealed, err := sealTOTPSecret(key, "user-1", "JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+	require.NotContains(t, sealed, "JBSWY3DPEHPK3PXP")
+
+	secret, err := openTOTPSecret(context.Background(), repo, key, &model.UserTOTP{UserId: "user-1", Secret: sealed})
+	require.NoError(t, err)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", secret)
+
+	_, err = openTOTPSecret(context.Background(), repo, key, &model.UserTOTP{UserId: "user-2", Secret: sealed})
+	require.Error(t, err, "a secret copied to another user must not decrypt")
+	_, err = openTOTPSecret(context.Background(), repo, make([]byte, 16), &model.UserTOTP{UserId: "user-1", Secret: sealed})
+	require.Error(t, err)
+}
+
+func TestPlaintextTOTPSecretsAreEncryptedWhenRead(t *testing.T) {
+	key := make([]byte, 32)
+	twoFactor := &fakeTwoFactorRepository{secret: "JBSWY3DPEHPK3PXP"}
+	repo := &repository.Repository{TwoFactorRepository: twoFactor}
+
+	secret, err := openTOTPSecret(context.Background(), repo, key, &model.UserTOTP{UserId: "user-1", Secret: twoFactor.secret})
+	require.NoError(t, err)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", secret)
+	require.NotEqual(t, "JBSWY3DPEHPK3PXP", twoFactor.secret)
+
+	secret, err = openTOTPSecret(context.Background(), repo, key, &model.UserTOTP{UserId: "user-1", Secret: twoFactor.secret})
+	require.NoError(t, err)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", secret)
+}
@@ -2,17 +2,33 @@ package domain
 
 import (
 	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/logging"
+	"backend/internal/infrastructure/mail"
 	"backend/internal/infrastructure/repository"
+	"backend/internal/infrastructure/tracing"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserService interface {
-	GetUserById(id string) (*model.User, error)
-	CreateUser(u *model.User) (*model.User, error)
-	UpdateUser(userId string, userRequest *model.User) (*model.User, error)
-	PatchPassword(userId string, u *model.UserRequestBodyOnlyPassword) error
-	DeleteUser(u *model.User) error
+	ListUsers(ctx context.Context) ([]model.User, error)
+	GetUserById(ctx context.Context, id string) (*model.User, error)
+	CreateUser(ctx context.Context, u *model.User) (*model.User, error)
+	UpdateUser(ctx context.Context, userId string, userRequest *model.User) (*model.User, error)
+	PatchPassword(ctx context.Context, userId string, u *model.UserRequestBodyOnlyPassword, secondFactor string) error
+	ResetPassword(ctx context.Context, userId string, newPassword string) error
+	DeleteUser(ctx context.Context, u *model.User) error
+	RequestEmailVerification(ctx context.Context, userId string) error
+	VerifyEmail(ctx context.Context, token string) error
+	RequestPasswordReset(ctx context.Context, email string)
+	ResetPasswordWithToken(ctx context.Context, token string, newPassword string) error
+	ExportUserData(ctx context.Context, userId string) ([]byte, error)
+	ScheduleUserDeletion(ctx context.Context, userId string, secondFactor string) (*model.User, error)
 }
 
 type userServiceImpl struct {
@@ -27,47 +43,114 @@ func NewUserService(repository *repository.Repository, domain *Service) UserServ
 	}
 }
 
-func (s *userServiceImpl) GetUserById(id string) (*model.User, error) {
-	return s.Repository.UserRepository.Get(id)
+func (s *userServiceImpl) ListUsers(ctx context.Context) ([]model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.ListUsers")
+	defer span.End()
+
+	return s.Repository.UserRepository.List(ctx)
 }
 
-func (s *userServiceImpl) CreateUser(u *model.User) (*model.User, error) {
+func (s *userServiceImpl) GetUserById(ctx context.Context, id string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserById")
+	defer span.End()
+
+	return s.Repository.UserRepository.Get(ctx, id)
+}
+
+func (s *userServiceImpl) CreateUser(ctx context.Context, u *model.User) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.CreateUser")
+	defer span.End()
+
+	err := validatePassword(s.Domain.config.Domain.PasswordPolicy, u.Password)
+	if err != nil {
+		return nil, err
+	}
 
-	var err error
 	u.Password, err = hashPassword(u.Password)
 	if err != nil {
 		return nil, err
 	}
 
-	userId, err := s.Repository.UserRepository.Create(u)
+	userId, err := s.Repository.UserRepository.Create(ctx, u)
 	if err != nil {
 		return nil, err
 	}
 
-	user, err := s.GetUserById(userId)
+	user, err := s.GetUserById(ctx, userId)
 	if err != nil {
 		return nil, err
 	}
+
+	recordAudit(ctx, s.Repository, model.AuditActionCreate, model.AuditEntityUser, userId, "", nil, auditUser(user))
+	s.sendEmailVerification(ctx, user)
 	return user, nil
 }
 
-func (s *userServiceImpl) UpdateUser(userId string, userRequest *model.User) (*model.User, error) {
+func (s *userServiceImpl) UpdateUser(ctx context.Context, userId string, userRequest *model.User) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateUser")
+	defer span.End()
+
+	existing, err := requireEnabledUser(ctx, s.Repository, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	// The email address receives the password resets, so it's a credential.
+	emailChanged := !strings.EqualFold(existing.Email, userRequest.Email)
+	if emailChanged {
+		err = rejectImpersonation(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	userRequest.Id = userId
-	err := s.Repository.UserRepository.Update(userRequest)
+	err = s.Repository.UserRepository.Update(ctx, userRequest)
 	if err != nil {
 		return nil, err
 	}
 
-	user, err := s.GetUserById(userId)
+	// A new email address has to be verified again, the pending tokens were mailed to the former one.
+	if emailChanged {
+		err = s.Repository.UserRepository.SetEmailVerifiedAt(ctx, userId, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, purpose := range []string{model.UserTokenPurposeVerifyEmail, model.UserTokenPurposeResetPassword} {
+			err = s.Repository.UserTokenRepository.DeleteByUser(ctx, userId, purpose)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	user, err := s.GetUserById(ctx, userId)
 	if err != nil {
 		return nil, err
 	}
+
+	recordAudit(ctx, s.Repository, model.AuditActionUpdate, model.AuditEntityUser, userId, "", auditUser(existing), auditUser(user))
+	if emailChanged {
+		s.sendEmailVerification(ctx, user)
+	}
 	return user, nil
 }
 
-func (s *userServiceImpl) PatchPassword(userId string, u *model.UserRequestBodyOnlyPassword) error {
+func (s *userServiceImpl) PatchPassword(ctx context.Context, userId string, u *model.UserRequestBodyOnlyPassword, secondFactor string) error {
+	ctx, span := tracing.Start(ctx, "UserService.PatchPassword")
+	defer span.End()
 
-	safedPasswordHash, err := s.Repository.UserRepository.GetPassword(userId)
+	err := rejectImpersonation(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = requireEnabledUser(ctx, s.Repository, userId)
+	if err != nil {
+		return err
+	}
+
+	safedPasswordHash, err := s.Repository.UserRepository.GetPassword(ctx, userId)
 	if err != nil {
 		return err
 	}
@@ -77,21 +160,228 @@ func (s *userServiceImpl) PatchPassword(userId string, u *model.UserRequestBodyO
 		return err
 	}
 
+	err = validatePassword(s.Domain.config.Domain.PasswordPolicy, u.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	err = verifySecondFactor(ctx, s.Repository, s.Domain.totpKey, userId, secondFactor)
+	if err != nil {
+		return err
+	}
+
 	newHashedPassword, err := hashPassword(u.NewPassword)
 	if err != nil {
 		return err
 	}
 
-	return s.Repository.UserRepository.PatchPassword(&model.User{
-		Id: userId,
-		UserBase: model.UserBase{
-			Password: newHashedPassword,
-		},
+	err = s.Repository.UserRepository.PatchPassword(ctx, &model.User{
+		Id:       userId,
+		Password: newHashedPassword,
+	})
+	if err != nil {
+		return err
+	}
+
+	recordPasswordChange(ctx, s.Repository, userId)
+	return endOtherSessions(ctx, s.Repository, userId)
+}
+
+// ResetPassword sets a new password without checking the old one. It's meant for operators, so it must
+// not be exposed over the public API.
+func (s *userServiceImpl) ResetPassword(ctx context.Context, userId string, newPassword string) error {
+	ctx, span := tracing.Start(ctx, "UserService.ResetPassword")
+	defer span.End()
+
+	user, err := s.Repository.UserRepository.Get(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("user %s not found", userId)
+	}
+
+	err = validatePassword(s.Domain.config.Domain.PasswordPolicy, newPassword)
+	if err != nil {
+		return err
+	}
+
+	newHashedPassword, err := hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	err = s.Repository.UserRepository.PatchPassword(ctx, &model.User{
+		Id:       userId,
+		Password: newHashedPassword,
+	})
+	if err != nil {
+		return err
+	}
+
+	recordPasswordChange(ctx, s.Repository, userId)
+	return endOtherSessions(ctx, s.Repository, userId)
+}
+
+// DeleteUser deletes the user with all data right away. The API schedules the deletion instead, this is
+// meant for operators.
+func (s *userServiceImpl) DeleteUser(ctx context.Context, u *model.User) error {
+	ctx, span := tracing.Start(ctx, "UserService.DeleteUser")
+	defer span.End()
+
+	user, err := s.Repository.UserRepository.Get(ctx, u.Id)
+	if err != nil {
+		return err
+	}
+
+	err = s.Repository.UserRepository.Delete(ctx, u)
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, s.Repository, model.AuditActionDelete, model.AuditEntityUser, u.Id, "", auditUser(user), nil)
+	return nil
+}
+
+// RequestEmailVerification sends a new verification mail, which invalidates the previous one.
+func (s *userServiceImpl) RequestEmailVerification(ctx context.Context, userId string) error {
+	ctx, span := tracing.Start(ctx, "UserService.RequestEmailVerification")
+	defer span.End()
+
+	user, err := requireEnabledUser(ctx, s.Repository, userId)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return fmt.Errorf("the email address of user %s is already verified", userId)
+	}
+
+	s.sendEmailVerification(ctx, user)
+	return nil
+}
+
+func (s *userServiceImpl) VerifyEmail(ctx context.Context, token string) error {
+	ctx, span := tracing.Start(ctx, "UserService.VerifyEmail")
+	defer span.End()
+
+	userToken, err := consumeUserToken(ctx, s.Repository, model.UserTokenPurposeVerifyEmail, token)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.Repository.UserRepository.Get(ctx, userToken.UserId)
+	if err != nil {
+		return err
+	}
+	if user == nil || !tokenMailedTo(userToken, user) {
+		return ErrInvalidToken
+	}
+
+	verifiedAt := time.Now().UTC()
+	err = s.Repository.UserRepository.SetEmailVerifiedAt(ctx, userToken.UserId, &verifiedAt)
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, s.Repository, model.AuditActionUpdate, model.AuditEntityUser, userToken.UserId, "",
+		map[string]any{"email_verified_at": nil}, map[string]any{"email_verified_at": verifiedAt})
+	logging.FromContext(ctx).Info("Email address verified", slog.String("userId", userToken.UserId))
+	return nil
+}
+
+// RequestPasswordReset sends a password reset mail if an account with the email address exists. It never
+// fails and does all work in the background, so neither the response nor its timing reveal whether the
+// address is registered.
+func (s *userServiceImpl) RequestPasswordReset(ctx context.Context, email string) {
+	ctx, span := tracing.Start(ctx, "UserService.RequestPasswordReset")
+	defer span.End()
+
+	s.Domain.runInBackground(ctx, "password-reset-mail", func(ctx context.Context) error {
+		user, err := s.Repository.UserRepository.GetByEmail(ctx, strings.TrimSpace(email))
+		if err != nil {
+			return err
+		}
+		if user == nil || user.DisabledAt != nil {
+			logging.FromContext(ctx).Debug("Password reset requested for an unknown or disabled account")
+			return nil
+		}
+
+		token, expiresAt, err := issueUserToken(ctx, s.Repository, user, model.UserTokenPurposeResetPassword, s.Domain.config.Domain.Tokens.PasswordResetTTL)
+		if err != nil {
+			return err
+		}
+
+		return s.Domain.sendTokenMail(ctx, user, mail.TemplateResetPassword, "/reset-password", token, expiresAt)
 	})
 }
 
-func (s *userServiceImpl) DeleteUser(u *model.User) error {
-	return s.Repository.UserRepository.Delete(u)
+// ResetPasswordWithToken sets a new password with the token of a password reset mail. Receiving the mail
+// also proves the ownership of the email address, so it's marked as verified if it's still the one the
+// mail was sent to.
+func (s *userServiceImpl) ResetPasswordWithToken(ctx context.Context, token string, newPassword string) error {
+	ctx, span := tracing.Start(ctx, "UserService.ResetPasswordWithToken")
+	defer span.End()
+
+	// A weak password must not use up the token, so it's checked first.
+	err := validatePassword(s.Domain.config.Domain.PasswordPolicy, newPassword)
+	if err != nil {
+		return err
+	}
+
+	userToken, err := consumeUserToken(ctx, s.Repository, model.UserTokenPurposeResetPassword, token)
+	if err != nil {
+		return err
+	}
+
+	user, err := requireEnabledUser(ctx, s.Repository, userToken.UserId)
+	if err != nil {
+		return err
+	}
+
+	newHashedPassword, err := hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	err = s.Repository.UserRepository.PatchPassword(ctx, &model.User{
+		Id:       userToken.UserId,
+		Password: newHashedPassword,
+	})
+	if err != nil {
+		return err
+	}
+	recordPasswordChange(ctx, s.Repository, userToken.UserId)
+
+	err = endOtherSessions(ctx, s.Repository, userToken.UserId)
+	if err != nil {
+		return err
+	}
+
+	if user.EmailVerifiedAt == nil && tokenMailedTo(userToken, user) {
+		verifiedAt := time.Now().UTC()
+		err = s.Repository.UserRepository.SetEmailVerifiedAt(ctx, user.Id, &verifiedAt)
+		if err != nil {
+			return err
+		}
+		recordAudit(ctx, s.Repository, model.AuditActionUpdate, model.AuditEntityUser, user.Id, "",
+			map[string]any{"email_verified_at": nil}, map[string]any{"email_verified_at": verifiedAt})
+	}
+
+	logging.FromContext(ctx).Info("Password reset with token", slog.String("userId", userToken.UserId))
+	return nil
+}
+
+// sendEmailVerification issues a verification token and mails it in the background. A failed delivery
+// doesn't fail the request, the user can ask for a new mail.
+func (s *userServiceImpl) sendEmailVerification(ctx context.Context, user *model.User) {
+	s.Domain.runInBackground(ctx, "email-verification-mail", func(ctx context.Context) error {
+		token, expiresAt, err := issueUserToken(ctx, s.Repository, user, model.UserTokenPurposeVerifyEmail, s.Domain.config.Domain.Tokens.EmailVerificationTTL)
+		if err != nil {
+			return err
+		}
+
+		return s.Domain.sendTokenMail(ctx, user, mail.TemplateVerifyEmail, "/verify-email", token, expiresAt)
+	})
 }
 
 // hashPassword hashes a plaintext password using bcrypt.
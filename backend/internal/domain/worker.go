@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"backend/internal/infrastructure/logging"
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Worker is a periodic background job of the domain. Run is executed once on start and then every Interval
+// until the context passed to StartWorkers is canceled.
+type Worker struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// workerHeartbeat is the last sign of life of a worker, it's updated after every run.
+type workerHeartbeat struct {
+	name     string
+	interval time.Duration
+	lastBeat time.Time
+}
+
+type heartbeats struct {
+	mu    sync.Mutex
+	beats map[string]workerHeartbeat
+}
+
+func (h *heartbeats) beat(worker Worker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.beats == nil {
+		h.beats = make(map[string]workerHeartbeat)
+	}
+	h.beats[worker.Name] = workerHeartbeat{
+		name:     worker.Name,
+		interval: worker.Interval,
+		lastBeat: time.Now(),
+	}
+}
+
+// StartWorkers starts all registered workers and returns a function which blocks until every worker has
+// returned, which happens after the given context is canceled, and all background tasks are done.
+func (s *Service) StartWorkers(ctx context.Context) (wait func()) {
+	var wg sync.WaitGroup
+	for _, worker := range s.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.runWorker(ctx, worker)
+		}()
+	}
+
+	return func() {
+		wg.Wait()
+		s.WaitForBackgroundTasks()
+	}
+}
+
+func (s *Service) runWorker(ctx context.Context, worker Worker) {
+	logger := logging.FromContext(ctx).With(slog.String("worker", worker.Name))
+	ctx = logging.WithLogger(ctx, logger)
+
+	logger.Info("Worker started")
+	defer logger.Info("Worker stopped")
+
+	ticker := time.NewTicker(worker.Interval)
+	defer ticker.Stop()
+
+	for {
+		err := worker.Run(ctx)
+		if err != nil && ctx.Err() == nil {
+			logger.Error("Worker failed", slog.String("error", err.Error()))
+		}
+		s.heartbeats.beat(worker)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckWorkers fails if a started worker hasn't finished a run within twice its interval, which means
+// it's stuck.
+func (s *Service) CheckWorkers(_ context.Context) error {
+	s.heartbeats.mu.Lock()
+	defer s.heartbeats.mu.Unlock()
+
+	for _, heartbeat := range s.heartbeats.beats {
+		if since := time.Since(heartbeat.lastBeat); since > 2*heartbeat.interval {
+			return fmt.Errorf("worker %s hasn't reported for %s", heartbeat.name, since.Round(time.Second))
+		}
+	}
+
+	return nil
+}
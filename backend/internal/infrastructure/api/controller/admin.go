@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"backend/internal/domain"
+	"backend/internal/infrastructure/api/mapper"
+	"backend/internal/infrastructure/api/model"
+	"context"
+	"errors"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// adminError answers actions admins can't apply to themselves with 403 and everything else with 400.
+func adminError(message string, err error) error {
+	if errors.Is(err, domain.ErrSelfAdministration) {
+		return huma.Error403Forbidden(message, err)
+	}
+	return huma.Error400BadRequest(message, err)
+}
+
+func SearchUsers(svc *domain.Service) func(c context.Context, input *model.UserSearch) (*model.UserPageResponse, error) {
+	return func(c context.Context, input *model.UserSearch) (*model.UserPageResponse, error) {
+		users, total, err := svc.AdminService.SearchUsers(c, *input)
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to search users", err)
+		}
+
+		items := make([]model.UserOutput, 0, len(users))
+		for _, user := range users {
+			items = append(items, mapper.MapUserToUserOutput(user))
+		}
+
+		return &model.UserPageResponse{
+			Body: model.UserPage{
+				Items:      items,
+				Pagination: model.NewPagination(input.PaginationQuery, total),
+			},
+		}, nil
+	}
+}
+
+func SetUserRole(svc *domain.Service) func(c context.Context, input *model.UserRoleFilterAndBody) (*model.UserResponse, error) {
+	return func(c context.Context, input *model.UserRoleFilterAndBody) (*model.UserResponse, error) {
+		user, err := svc.AdminService.SetRole(c, input.UserId, input.Body.Role)
+		if err != nil {
+			return nil, adminError("failed to set user role", err)
+		}
+
+		return mapper.MapUserToUserResponse(*user), nil
+	}
+}
+
+func DisableUser(svc *domain.Service) func(c context.Context, input *model.UserRequestFilter) (*model.UserResponse, error) {
+	return func(c context.Context, input *model.UserRequestFilter) (*model.UserResponse, error) {
+		user, err := svc.AdminService.DisableUser(c, input.UserId)
+		if err != nil {
+			return nil, adminError("failed to disable user", err)
+		}
+
+		return mapper.MapUserToUserResponse(*user), nil
+	}
+}
+
+func EnableUser(svc *domain.Service) func(c context.Context, input *model.UserRequestFilter) (*model.UserResponse, error) {
+	return func(c context.Context, input *model.UserRequestFilter) (*model.UserResponse, error) {
+		user, err := svc.AdminService.EnableUser(c, input.UserId)
+		if err != nil {
+			return nil, adminError("failed to enable user", err)
+		}
+
+		return mapper.MapUserToUserResponse(*user), nil
+	}
+}
+
+func ImpersonateUser(svc *domain.Service) func(c context.Context, input *model.UserRequestFilter) (*model.LoginResponse, error) {
+	return func(c context.Context, input *model.UserRequestFilter) (*model.LoginResponse, error) {
+		token, session, err := svc.AdminService.Impersonate(c, input.UserId)
+		if err != nil {
+			return nil, adminError("failed to impersonate user", err)
+		}
+
+		user, err := svc.UserService.GetUserById(c, session.UserId)
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to get user", err)
+		}
+
+		return &model.LoginResponse{
+			Body: model.LoginOutput{
+				Token:     token,
+				ExpiresAt: session.ExpiresAt,
+				User:      mapper.MapUserToUserOutput(*user),
+			},
+		}, nil
+	}
+}
+
+func GetStatistics(svc *domain.Service) func(c context.Context, input *struct{}) (*model.StatisticsResponse, error) {
+	return func(c context.Context, input *struct{}) (*model.StatisticsResponse, error) {
+		statistics, err := svc.AdminService.Statistics(c)
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to get statistics", err)
+		}
+
+		return &model.StatisticsResponse{Body: *statistics}, nil
+	}
+}
+
+func UnpublishShelf(svc *domain.Service) func(c context.Context, input *model.ShelfRequestFilter) (*model.ShelfResponse, error) {
+	return func(c context.Context, input *model.ShelfRequestFilter) (*model.ShelfResponse, error) {
+		shelf, err := svc.AdminService.UnpublishShelf(c, input.ShelfId)
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to unpublish shelf", err)
+		}
+
+		return mapper.MapShelfToShelfResponse(*shelf), nil
+	}
+}
+
+func PublishShelf(svc *domain.Service) func(c context.Context, input *model.ShelfRequestFilter) (*model.ShelfResponse, error) {
+	return func(c context.Context, input *model.ShelfRequestFilter) (*model.ShelfResponse, error) {
+		shelf, err := svc.AdminService.PublishShelf(c, input.ShelfId)
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to publish shelf", err)
+		}
+
+		return mapper.MapShelfToShelfResponse(*shelf), nil
+	}
+}
+
+func ModerateDeleteShelf(svc *domain.Service) func(c context.Context, input *model.ShelfRequestFilter) (*struct{}, error) {
+	return func(c context.Context, input *model.ShelfRequestFilter) (*struct{}, error) {
+		err := svc.AdminService.DeleteShelf(c, input.ShelfId)
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to delete shelf", err)
+		}
+
+		return nil, nil
+	}
+}
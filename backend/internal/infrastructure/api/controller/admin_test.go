@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/repository"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/stretchr/testify/require"
+)
+
+func (r *fakeUserRepository) Search(_ context.Context, search model.UserSearch) ([]model.User, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var users []model.User
+	for _, user := range r.users {
+		if strings.Contains(user.Email, search.Query) && (search.Role == "" || user.Role == search.Role) {
+			users = append(users, user)
+		}
+	}
+	slices.SortFunc(users, func(a, b model.User) int { return strings.Compare(a.Email, b.Email) })
+
+	total := int64(len(users))
+	users = users[min(search.Offset(), len(users)):min(search.Offset()+search.PageSize, len(users))]
+	return users, total, nil
+}
+
+func (r *fakeUserRepository) SetRole(_ context.Context, id, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user := r.users[id]
+	user.Role = role
+	r.users[id] = user
+	return nil
+}
+
+func (r *fakeUserRepository) Disable(_ context.Context, id string, disabledAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user := r.users[id]
+	user.DisabledAt, user.PurgeAt = &disabledAt, nil
+	r.users[id] = user
+	return nil
+}
+
+func (r *fakeUserRepository) Enable(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user := r.users[id]
+	user.DisabledAt, user.PurgeAt = nil, nil
+	r.users[id] = user
+	return nil
+}
+
+func (r *fakeShelfRepository) SetUnpublishedAt(_ context.Context, id string, unpublishedAt *time.Time) error {
+	r.shelves[id].UnpublishedAt = unpublishedAt
+	return nil
+}
+
+type fakeStatisticsRepository struct {
+	repository.StatisticsRepository
+}
+
+func (r *fakeStatisticsRepository) Get(_ context.Context, _ time.Time) (*model.Statistics, error) {
+	return &model.Statistics{Users: 2, Admins: 1, Shelves: 1}, nil
+}
+
+// newAdminTestAPI makes jane (user-1) an admin and returns the sessions of both users.
+func newAdminTestAPI(t *testing.T) (api humatest.TestAPI, admin, user string) {
+	api, svc, _, admin, user := newTwoUserTestAPI(t)
+
+	// The first admin is appointed by the CLI, which has no principal.
+	_, err := svc.AdminService.SetRole(context.Background(), "user-1", model.RoleAdmin)
+	require.NoError(t, err)
+
+	return api, admin, user
+}
+
+func TestAdminAPIRequiresTheAdminRole(t *testing.T) {
+	api, admin, user := newAdminTestAPI(t)
+
+	resp := api.Get("/v1/admin/users", user)
+	require.Equal(t, http.StatusForbidden, resp.Code)
+	resp = api.Get("/v1/admin/statistics", user)
+	require.Equal(t, http.StatusForbidden, resp.Code)
+
+	resp = api.Get("/v1/admin/users?q=john&pageSize=1", admin)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var page model.UserPage
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &page))
+	require.Equal(t, int64(1), page.Total)
+	require.Equal(t, "user-2", page.Items[0].Id)
+	requireNoCredentials(t, resp.Body.String())
+
+	resp = api.Get("/v1/admin/users?page=2&pageSize=1", admin)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &page))
+	require.Equal(t, int64(2), page.Total)
+	require.Equal(t, "user-2", page.Items[0].Id)
+
+	resp = api.Get("/v1/admin/statistics", admin)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.Contains(t, resp.Body.String(), `"admins":1`)
+
+	resp = api.Post("/v1/admin/shelves/shelf-1/unpublish", admin)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.Contains(t, resp.Body.String(), `"unpublishedAt"`)
+}
+
+func TestAdminCanDisableAndEnableUsers(t *testing.T) {
+	api, admin, user := newAdminTestAPI(t)
+
+	resp := api.Post("/v1/admin/users/user-1/disable", admin)
+	require.Equal(t, http.StatusForbidden, resp.Code, "admins must not lock themselves out")
+
+	resp = api.Post("/v1/admin/users/user-2/disable", admin)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.Contains(t, resp.Body.String(), `"disabled_at"`)
+
+	resp = api.Get("/v1/user/user-2", user)
+	require.Equal(t, http.StatusUnauthorized, resp.Code, "disabling ends the sessions")
+	code, _ := loginAs(api, "john@example.com", "correct horse 2")
+	require.Equal(t, http.StatusBadRequest, code)
+
+	resp = api.Post("/v1/admin/users/user-2/enable", admin)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	code, _ = loginAs(api, "john@example.com", "correct horse 2")
+	require.Equal(t, http.StatusOK, code)
+}
+
+func TestAdminCanImpersonateUsers(t *testing.T) {
+	api, admin, user := newAdminTestAPI(t)
+
+	resp := api.Post("/v1/admin/users/user-1/impersonate", admin)
+	require.Equal(t, http.StatusForbidden, resp.Code)
+	resp = api.Post("/v1/admin/users/user-1/impersonate", user)
+	require.Equal(t, http.StatusForbidden, resp.Code)
+
+	resp = api.Post("/v1/admin/users/user-2/impersonate", admin)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var output model.LoginOutput
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &output))
+	require.Equal(t, "user-2", output.User.Id)
+	impersonated := "Authorization: Bearer " + output.Token
+
+	resp = api.Get("/v1/user/user-2", impersonated)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	resp = api.Get("/v1/admin/users", impersonated)
+	require.Equal(t, http.StatusForbidden, resp.Code, "the impersonated session has the role of the user")
+}
+
+func TestImpersonatedSessionsCantChangeCredentials(t *testing.T) {
+	api, admin, _ := newAdminTestAPI(t)
+
+	resp := api.Post("/v1/admin/users/user-2/impersonate", admin)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var output model.LoginOutput
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &output))
+	impersonated := "Authorization: Bearer " + output.Token
+
+	tests := []struct {
+		name    string
+		request func() *httptest.ResponseRecorder
+	}{
+		{name: "create personal access token", request: func() *httptest.ResponseRecorder {
+			return api.Post("/v1/user/user-2/tokens", impersonated, map[string]any{"name": "backdoor", "scopes": []string{model.ScopeShelfRead}})
+		}},
+		{name: "change email", request: func() *httptest.ResponseRecorder {
+			return api.Put("/v1/user/user-2", impersonated, map[string]any{"email": "admin@example.com", "first_name": "John", "last_name": "Doe"})
+		}},
+		{name: "change password", request: func() *httptest.ResponseRecorder {
+			return api.Patch("/v1/user/user-2/password", impersonated, map[string]any{"old_password": "correct horse 2", "new_password": "correct horse 3"})
+		}},
+		{name: "enroll two-factor authentication", request: func() *httptest.ResponseRecorder {
+			return api.Post("/v1/user/user-2/2fa", impersonated, map[string]any{"password": "correct horse 2"})
+		}},
+		{name: "confirm two-factor authentication", request: func() *httptest.ResponseRecorder {
+			return api.Post("/v1/user/user-2/2fa/confirm", impersonated, map[string]any{"code": "123456"})
+		}},
+		{name: "disable two-factor authentication", request: func() *httptest.ResponseRecorder {
+			return api.Delete("/v1/user/user-2/2fa", impersonated)
+		}},
+		{name: "regenerate recovery codes", request: func() *httptest.ResponseRecorder {
+			return api.Post("/v1/user/user-2/2fa/recovery-codes", impersonated)
+		}},
+		{name: "delete account", request: func() *httptest.ResponseRecorder {
+			return api.Delete("/v1/user/user-2", impersonated)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := tt.request()
+			require.Equal(t, http.StatusForbidden, resp.Code, resp.Body.String())
+			require.Contains(t, resp.Body.String(), "impersonated sessions")
+		})
+	}
+
+	resp = api.Put("/v1/user/user-2", impersonated, map[string]any{"email": "john@example.com", "first_name": "John", "last_name": "Roe"})
+	require.Equal(t, http.StatusOK, resp.Code, "support can still fix the profile: %s", resp.Body.String())
+
+	resp = api.Get("/v1/user/user-2", impersonated)
+	require.Equal(t, http.StatusOK, resp.Code, "the account is unchanged and the session still works")
+	require.Contains(t, resp.Body.String(), "john@example.com")
+}
@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"backend/internal/domain"
+	"backend/internal/infrastructure/api/model"
+	"context"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func GetShelfAuditEvents(svc *domain.Service) func(c context.Context, input *model.ShelfAuditSearch) (*model.AuditEventPageResponse, error) {
+	return func(c context.Context, input *model.ShelfAuditSearch) (*model.AuditEventPageResponse, error) {
+		events, total, err := svc.AuditService.ListByShelf(c, input.ShelfId, input.AuditSearch)
+		if err != nil {
+			return nil, accessError("failed to get shelf audit events", err)
+		}
+
+		return auditEventPage(events, input.PaginationQuery, total), nil
+	}
+}
+
+func SearchAuditEvents(svc *domain.Service) func(c context.Context, input *model.AdminAuditSearch) (*model.AuditEventPageResponse, error) {
+	return func(c context.Context, input *model.AdminAuditSearch) (*model.AuditEventPageResponse, error) {
+		events, total, err := svc.AuditService.Search(c, input.ShelfId, input.AuditSearch)
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to search audit events", err)
+		}
+
+		return auditEventPage(events, input.PaginationQuery, total), nil
+	}
+}
+
+func auditEventPage(events []model.AuditEvent, query model.PaginationQuery, total int64) *model.AuditEventPageResponse {
+	if events == nil {
+		events = []model.AuditEvent{}
+	}
+
+	return &model.AuditEventPageResponse{
+		Body: model.AuditEventPage{
+			Items:      events,
+			Pagination: model.NewPagination(query, total),
+		},
+	}
+}
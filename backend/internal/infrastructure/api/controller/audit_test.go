@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/repository"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAuditRepository struct {
+	repository.AuditRepository
+	mu     sync.Mutex
+	events []model.AuditEvent
+}
+
+func (r *fakeAuditRepository) Create(_ context.Context, e *model.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e.Id = fmt.Sprintf("event-%d", len(r.events)+1)
+	r.events = append(r.events, *e)
+	return nil
+}
+
+func (r *fakeAuditRepository) Search(_ context.Context, shelfId string, search model.AuditSearch) ([]model.AuditEvent, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []model.AuditEvent
+	for _, event := range slices.Backward(r.events) {
+		if (shelfId == "" || event.ShelfId == shelfId) &&
+			(search.EntityType == "" || event.EntityType == search.EntityType) &&
+			(search.EntityId == "" || event.EntityId == search.EntityId) &&
+			(search.Action == "" || event.Action == search.Action) &&
+			(search.ActorId == "" || event.ActorId == search.ActorId) {
+			events = append(events, event)
+		}
+	}
+
+	total := int64(len(events))
+	offset := min(search.Offset(), len(events))
+	end := min(offset+search.PageSize, len(events))
+	return events[offset:end], total, nil
+}
+
+func TestAuditLogRecordsTheChangesOfAShelf(t *testing.T) {
+	api, admin, user := newAdminTestAPI(t)
+
+	resp := api.Put("/v1/shelf/shelf-1/shares/user-2", admin, map[string]any{"role": "viewer"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	resp = api.Put("/v1/shelf/shelf-1/shares/user-2", admin, map[string]any{"role": "editor"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	resp = api.Get("/v1/shelf/shelf-1/audit", user)
+	require.Equal(t, http.StatusForbidden, resp.Code, "editors don't see who worked on the shelf")
+	resp = api.Get("/v1/admin/audit", user)
+	require.Equal(t, http.StatusForbidden, resp.Code)
+
+	resp = api.Get("/v1/shelf/shelf-1/audit?entityType=shelf_share", admin)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var page model.AuditEventPage
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &page))
+	require.Equal(t, int64(2), page.Total)
+	update := page.Items[0]
+	require.Equal(t, model.AuditActionUpdate, update.Action)
+	require.Equal(t, "shelf-1/user-2", update.EntityId)
+	require.Equal(t, "user-1", update.ActorId)
+	require.Equal(t, model.AuditChange{Before: "viewer", After: "editor"}, update.Changes["role"])
+	require.Len(t, update.Changes, 1, "unchanged attributes aren't recorded")
+	require.Equal(t, model.AuditActionCreate, page.Items[1].Action)
+
+	resp = api.Get("/v1/admin/audit?entityType=user&actorId=user-2", admin)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &page))
+	require.Empty(t, page.Items, "users are created anonymously")
+	resp = api.Get("/v1/admin/audit?entityType=user&entityId=user-1&action=update", admin)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &page))
+	require.Equal(t, int64(1), page.Total, "the role granted by the CLI")
+	requireNoCredentials(t, resp.Body.String())
+}
+
+func TestAuditLogRecordsImpersonationsAndRecoveryCodes(t *testing.T) {
+	api, admin, _ := newAdminTestAPI(t)
+
+	resp := api.Post("/v1/admin/users/user-2/impersonate", admin)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	resp = api.Get("/v1/admin/audit?entityType=user&entityId=user-2&action=update", admin)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var page model.AuditEventPage
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &page))
+	require.Equal(t, int64(1), page.Total)
+	require.Equal(t, "user-1", page.Items[0].ActorId)
+	require.Contains(t, page.Items[0].Changes, "impersonation_session_id")
+	require.Contains(t, page.Items[0].Changes, "impersonation_expires_at")
+
+	resp = api.Post("/v1/user/user-1/2fa", admin, map[string]any{"password": "correct horse 1"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var enrollment model.TwoFactorEnrollment
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &enrollment))
+	code, err := totp.GenerateCode(enrollment.Secret, time.Now())
+	require.NoError(t, err)
+	resp = api.Post("/v1/user/user-1/2fa/confirm", admin, map[string]any{"code": code})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var recovery model.RecoveryCodes
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &recovery))
+
+	resp = api.Post("/v1/user/user-1/2fa/recovery-codes", admin, "X-OTP-Code: "+recovery.RecoveryCodes[0])
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &recovery))
+
+	resp = api.Get("/v1/admin/audit?entityType=user&entityId=user-1&action=update&pageSize=1", admin)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &page))
+	require.Equal(t, model.AuditChange{After: "[redacted]"}, page.Items[0].Changes["recovery_codes"])
+	for _, recoveryCode := range recovery.RecoveryCodes {
+		require.NotContains(t, resp.Body.String(), recoveryCode)
+	}
+}
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"backend/internal/domain"
+	"backend/internal/infrastructure/api/mapper"
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/ratelimit"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// authError answers failed authentications with 401, so clients know to ask for credentials or a second
+// factor, missing permissions with 403 and everything else with 400.
+func authError(message string, err error) error {
+	if errors.Is(err, domain.ErrForbidden) {
+		return huma.Error403Forbidden(message, err)
+	}
+	if errors.Is(err, domain.ErrInvalidCredentials) ||
+		errors.Is(err, domain.ErrSecondFactorRequired) ||
+		errors.Is(err, domain.ErrInvalidSecondFactor) ||
+		errors.Is(err, domain.ErrUnauthenticated) {
+		return huma.Error401Unauthorized(message, err)
+	}
+	return huma.Error400BadRequest(message, err)
+}
+
+// Login limits the attempts per account besides the IP limit of the middleware, this covers wrong second
+// factors too, since they're sent with the login.
+func Login(svc *domain.Service, limiter *ratelimit.Limiter) func(c context.Context, input *model.LoginRequestBody) (*model.LoginResponse, error) {
+	return func(c context.Context, input *model.LoginRequestBody) (*model.LoginResponse, error) {
+		if err := limitAccount(c, limiter, "post-login", input.Body.Email); err != nil {
+			return nil, err
+		}
+
+		token, session, err := svc.AuthService.Login(c, input.Body.Email, input.Body.Password, input.SecondFactor)
+		if err != nil {
+			return nil, authError("failed to log in", err)
+		}
+
+		user, err := svc.UserService.GetUserById(c, session.UserId)
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to get user", err)
+		}
+
+		return &model.LoginResponse{
+			Body: model.LoginOutput{
+				Token:     token,
+				ExpiresAt: session.ExpiresAt,
+				User:      mapper.MapUserToUserOutput(*user),
+			},
+		}, nil
+	}
+}
+
+func Logout(svc *domain.Service) func(c context.Context, input *model.LogoutRequest) (*struct{}, error) {
+	return func(c context.Context, input *model.LogoutRequest) (*struct{}, error) {
+		token, ok := bearerToken(input.Authorization)
+		if !ok {
+			return nil, huma.Error401Unauthorized("a bearer token is required")
+		}
+
+		err := svc.AuthService.Logout(c, token)
+		if err != nil {
+			return nil, authError("failed to log out", err)
+		}
+
+		return nil, nil
+	}
+}
+
+func EnrollTwoFactor(svc *domain.Service) func(c context.Context, input *model.TwoFactorEnrollFilterAndBody) (*model.TwoFactorEnrollmentResponse, error) {
+	return func(c context.Context, input *model.TwoFactorEnrollFilterAndBody) (*model.TwoFactorEnrollmentResponse, error) {
+		enrollment, err := svc.TwoFactorService.Enroll(c, input.UserId, input.Body.Password)
+		if err != nil {
+			return nil, authError("failed to enroll two-factor authentication", err)
+		}
+
+		return &model.TwoFactorEnrollmentResponse{Body: *enrollment}, nil
+	}
+}
+
+func ConfirmTwoFactor(svc *domain.Service) func(c context.Context, input *model.TwoFactorConfirmFilterAndBody) (*model.RecoveryCodesResponse, error) {
+	return func(c context.Context, input *model.TwoFactorConfirmFilterAndBody) (*model.RecoveryCodesResponse, error) {
+		codes, err := svc.TwoFactorService.Confirm(c, input.UserId, input.Body.Code)
+		if err != nil {
+			return nil, accessError("failed to confirm two-factor authentication", err)
+		}
+
+		return &model.RecoveryCodesResponse{Body: model.RecoveryCodes{RecoveryCodes: codes}}, nil
+	}
+}
+
+func DisableTwoFactor(svc *domain.Service) func(c context.Context, input *model.UserSecondFactorFilter) (*struct{}, error) {
+	return func(c context.Context, input *model.UserSecondFactorFilter) (*struct{}, error) {
+		err := svc.TwoFactorService.Disable(c, input.UserId, input.SecondFactor)
+		if err != nil {
+			return nil, authError("failed to disable two-factor authentication", err)
+		}
+
+		return nil, nil
+	}
+}
+
+func RegenerateRecoveryCodes(svc *domain.Service) func(c context.Context, input *model.UserSecondFactorFilter) (*model.RecoveryCodesResponse, error) {
+	return func(c context.Context, input *model.UserSecondFactorFilter) (*model.RecoveryCodesResponse, error) {
+		codes, err := svc.TwoFactorService.RegenerateRecoveryCodes(c, input.UserId, input.SecondFactor)
+		if err != nil {
+			return nil, authError("failed to regenerate recovery codes", err)
+		}
+
+		return &model.RecoveryCodesResponse{Body: model.RecoveryCodes{RecoveryCodes: codes}}, nil
+	}
+}
+
+// bearerToken extracts the token of an Authorization header with the Bearer scheme.
+func bearerToken(authorization string) (string, bool) {
+	scheme, token, found := strings.Cut(strings.TrimSpace(authorization), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,248 @@
+package controller
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/repository"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSessionRepository struct {
+	repository.SessionRepository
+	mu       sync.Mutex
+	sessions map[string]model.UserSession
+}
+
+func (r *fakeSessionRepository) Create(_ context.Context, s *model.UserSession) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s.Id = s.TokenHash[:8]
+	r.sessions[s.Id] = *s
+	return s.Id, nil
+}
+
+func (r *fakeSessionRepository) GetByHash(_ context.Context, tokenHash string) (*model.UserSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, session := range r.sessions {
+		if session.TokenHash == tokenHash {
+			return &session, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeSessionRepository) Touch(_ context.Context, _ string, _ time.Time) error {
+	return nil
+}
+
+func (r *fakeSessionRepository) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.sessions, id)
+	return nil
+}
+
+func (r *fakeSessionRepository) DeleteByUser(_ context.Context, userId, exceptId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, session := range r.sessions {
+		if session.UserId == userId && id != exceptId {
+			delete(r.sessions, id)
+		}
+	}
+	return nil
+}
+
+// fakeTwoFactorRepository holds the TOTP secret and the recovery codes of a single user.
+type fakeTwoFactorRepository struct {
+	repository.TwoFactorRepository
+	mu            sync.Mutex
+	totp          *model.UserTOTP
+	recoveryCodes map[string]bool
+}
+
+func (r *fakeTwoFactorRepository) GetTOTP(_ context.Context, _ string) (*model.UserTOTP, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.totp == nil {
+		return nil, nil
+	}
+	userTOTP := *r.totp
+	return &userTOTP, nil
+}
+
+func (r *fakeTwoFactorRepository) SaveTOTP(_ context.Context, t *model.UserTOTP) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userTOTP := *t
+	r.totp = &userTOTP
+	return nil
+}
+
+func (r *fakeTwoFactorRepository) UpdateTOTPSecret(_ context.Context, _, previous, secret string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.totp != nil && r.totp.Secret == previous {
+		r.totp.Secret = secret
+	}
+	return nil
+}
+
+func (r *fakeTwoFactorRepository) ConfirmTOTP(_ context.Context, _ string, confirmedAt time.Time, step int64, recoveryCodeHashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.totp.ConfirmedAt, r.totp.LastUsedStep = &confirmedAt, &step
+	clear(r.recoveryCodes)
+	for _, hash := range recoveryCodeHashes {
+		r.recoveryCodes[hash] = false
+	}
+	return nil
+}
+
+func (r *fakeTwoFactorRepository) UseTOTPStep(_ context.Context, _ string, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.totp.LastUsedStep != nil && *r.totp.LastUsedStep >= step {
+		return false, nil
+	}
+	r.totp.LastUsedStep = &step
+	return true, nil
+}
+
+func (r *fakeTwoFactorRepository) ReplaceRecoveryCodes(_ context.Context, _ string, recoveryCodeHashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	clear(r.recoveryCodes)
+	for _, hash := range recoveryCodeHashes {
+		r.recoveryCodes[hash] = false
+	}
+	return nil
+}
+
+func (r *fakeTwoFactorRepository) UseRecoveryCode(_ context.Context, _, codeHash string, _ time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	used, ok := r.recoveryCodes[codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	r.recoveryCodes[codeHash] = true
+	return true, nil
+}
+
+func login(api humatest.TestAPI, password string, headers ...any) (int, model.LoginOutput) {
+	return loginAs(api, "jane@example.com", password, headers...)
+}
+
+func loginAs(api humatest.TestAPI, email, password string, headers ...any) (int, model.LoginOutput) {
+	args := append(headers, map[string]any{"email": email, "password": password})
+	resp := api.Post("/v1/auth/login", args...)
+
+	var output model.LoginOutput
+	_ = json.Unmarshal(resp.Body.Bytes(), &output)
+	return resp.Code, output
+}
+
+func TestLoginRequiresTheSecondFactorOnceConfirmed(t *testing.T) {
+	api, _, _ := newUserTestAPI(t)
+	createTestUser(t, api)
+
+	resp := api.Post("/v1/user/user-1/2fa", map[string]any{"password": "correct horse 1"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var enrollment model.TwoFactorEnrollment
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &enrollment))
+	require.Contains(t, enrollment.OTPAuthURI, "otpauth://totp/")
+	require.Contains(t, enrollment.QRCode, "data:image/png;base64,")
+
+	// An unconfirmed enrollment isn't enforced yet.
+	code, _ := login(api, "correct horse 1")
+	require.Equal(t, http.StatusOK, code)
+
+	now := time.Now()
+	totpCode, err := totp.GenerateCode(enrollment.Secret, now)
+	require.NoError(t, err)
+	resp = api.Post("/v1/user/user-1/2fa/confirm", map[string]any{"code": totpCode})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var recovery model.RecoveryCodes
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &recovery))
+	require.Len(t, recovery.RecoveryCodes, 10)
+
+	code, _ = login(api, "correct horse 1")
+	require.Equal(t, http.StatusUnauthorized, code)
+
+	// The code of the confirmation was used up, so the next period is used.
+	totpCode, err = totp.GenerateCode(enrollment.Secret, now.Add(30*time.Second))
+	require.NoError(t, err)
+	code, output := login(api, "correct horse 1", "X-OTP-Code: "+totpCode)
+	require.Equal(t, http.StatusOK, code)
+	require.NotEmpty(t, output.Token)
+	require.Equal(t, "user-1", output.User.Id)
+
+	code, _ = login(api, "correct horse 1", "X-OTP-Code: "+totpCode)
+	require.Equal(t, http.StatusUnauthorized, code, "a TOTP code must not be accepted twice")
+
+	code, _ = login(api, "correct horse 1", "X-OTP-Code: "+recovery.RecoveryCodes[0])
+	require.Equal(t, http.StatusOK, code)
+	code, _ = login(api, "correct horse 1", "X-OTP-Code: "+recovery.RecoveryCodes[0])
+	require.Equal(t, http.StatusUnauthorized, code, "a recovery code must not be accepted twice")
+
+	code, _ = login(api, "correct horse 1", "X-OTP-Code: 000000")
+	require.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestEnrollingTwoFactorRequiresThePassword(t *testing.T) {
+	api, _, _ := newUserTestAPI(t)
+	createTestUser(t, api)
+
+	resp := api.Post("/v1/user/user-1/2fa", map[string]any{"password": "wrong horse"})
+	require.Equal(t, http.StatusUnauthorized, resp.Code, resp.Body.String())
+	resp = api.Post("/v1/user/user-1/2fa", map[string]any{"password": "correct horse 1"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+}
+
+func TestAuthorizationMiddlewareRequiresASession(t *testing.T) {
+	api, _, _ := newTestAPI(t, false)
+	createTestUser(t, api)
+
+	resp := api.Get("/v1/user/user-1")
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	code, _ := login(api, "wrong password 1")
+	require.Equal(t, http.StatusUnauthorized, code)
+
+	code, output := login(api, "correct horse 1")
+	require.Equal(t, http.StatusOK, code)
+	authorization := "Authorization: Bearer " + output.Token
+
+	resp = api.Get("/v1/user/user-1", authorization)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	resp = api.Get("/v1/user/user-2", authorization)
+	require.Equal(t, http.StatusForbidden, resp.Code)
+
+	resp = api.Post("/v1/auth/logout", authorization)
+	require.Equal(t, http.StatusNoContent, resp.Code, resp.Body.String())
+
+	resp = api.Get("/v1/user/user-1", authorization)
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+}
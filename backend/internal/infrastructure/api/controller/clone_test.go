@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/metrics"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func linksCreated(t *testing.T) float64 {
+	t.Helper()
+
+	families, err := metrics.Registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() == "linkshelf_domain_links_created_total" {
+			return family.GetMetric()[0].GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
+// Clone copies only the shelf, the fakes keep the sections and links of shelf-1 regardless of their shelf.
+func (r *fakeShelfRepository) Clone(ctx context.Context, _ string, s *model.Shelf) (string, error) {
+	return r.Create(ctx, s)
+}
+
+func TestViewersCanCloneSharedShelvesForThemselves(t *testing.T) {
+	api, _, _, jane, john := newTwoUserTestAPI(t)
+	clone := map[string]any{"title": "John", "path": "john/links"}
+
+	resp := api.Post("/v1/shelf/shelf-1/clone", john, clone)
+	require.Equal(t, http.StatusForbidden, resp.Code, "the shelf isn't shared yet")
+
+	resp = api.Put("/v1/shelf/shelf-1/shares/user-2", jane, map[string]any{"role": "viewer"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	clone["userId"] = "user-1"
+	resp = api.Post("/v1/shelf/shelf-1/clone", john, clone)
+	require.Equal(t, http.StatusForbidden, resp.Code, "john can't create shelves for jane")
+
+	delete(clone, "userId")
+	resp = api.Post("/v1/shelf/shelf-1/clone", john, clone)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var shelf model.Shelf
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &shelf))
+	require.NotEqual(t, "shelf-1", shelf.Id)
+	require.Equal(t, "John", shelf.Title)
+	require.Equal(t, "john/links", shelf.Path)
+	require.Equal(t, "user-2", shelf.UserId)
+
+	resp = api.Get("/v1/shelf/"+shelf.Id, john)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+}
+
+func TestClonedLinksAreCountedAsCreated(t *testing.T) {
+	api, _, _, jane, _ := newTwoUserTestAPI(t)
+	before := linksCreated(t)
+
+	resp := api.Post("/v1/shelf/shelf-1/clone", jane, map[string]any{"title": "Copy", "path": "jane/copy"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.Equal(t, before+1, linksCreated(t), "the link of shelf-1 was copied")
+}
@@ -0,0 +1,20 @@
+package controller
+
+import (
+	"backend/internal/infrastructure/health"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func Health(checks *health.Registry, kind health.Kind, healthyStatus string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := checks.Run(c.Request.Context(), kind)
+		if !report.Healthy {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "checks": report.Checks})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": healthyStatus, "checks": report.Checks})
+	}
+}
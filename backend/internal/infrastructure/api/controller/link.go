@@ -5,15 +5,13 @@ import (
 	"backend/internal/infrastructure/api/mapper"
 	"backend/internal/infrastructure/api/model"
 	"context"
-
-	"github.com/danielgtaylor/huma/v2"
 )
 
 func CreateLink(svc *domain.Service) func(c context.Context, input *model.LinkRequestBody) (*model.LinkResponse, error) {
 	return func(c context.Context, input *model.LinkRequestBody) (*model.LinkResponse, error) {
-		link, err := svc.LinkService.Create(mapper.MapLinkBaseToLinkPointer(input.Body))
+		link, err := svc.LinkService.Create(c, mapper.MapLinkBaseToLinkPointer(input.Body))
 		if err != nil {
-			return nil, huma.Error400BadRequest("failed to create link", err)
+			return nil, accessError("failed to create link", err)
 		}
 
 		return mapper.MapLinkToLinkResponse(*link), nil
@@ -22,9 +20,9 @@ func CreateLink(svc *domain.Service) func(c context.Context, input *model.LinkRe
 
 func GetLinks(svc *domain.Service) func(c context.Context, input *model.LinkRequestFilter) (*model.LinkResponseList, error) {
 	return func(c context.Context, input *model.LinkRequestFilter) (*model.LinkResponseList, error) {
-		links, err := svc.LinkService.List(input.ShelfId)
+		links, err := svc.LinkService.List(c, input.ShelfId)
 		if err != nil {
-			return nil, huma.Error400BadRequest("failed to get links", err)
+			return nil, accessError("failed to get links", err)
 		}
 
 		return mapper.MapLinksToLinkResponseList(links), nil
@@ -33,9 +31,9 @@ func GetLinks(svc *domain.Service) func(c context.Context, input *model.LinkRequ
 
 func UpdateLink(svc *domain.Service) func(c context.Context, input *model.LinkFilterFilterAndBody) (*model.LinkResponse, error) {
 	return func(c context.Context, input *model.LinkFilterFilterAndBody) (*model.LinkResponse, error) {
-		link, err := svc.LinkService.Update(input.LinkId, mapper.MapLinkBaseToLinkPointer(input.Body))
+		link, err := svc.LinkService.Update(c, input.LinkId, mapper.MapLinkBaseToLinkPointer(input.Body))
 		if err != nil {
-			return nil, huma.Error400BadRequest("failed to update link", err)
+			return nil, accessError("failed to update link", err)
 		}
 
 		return mapper.MapLinkToLinkResponse(*link), nil
@@ -44,9 +42,9 @@ func UpdateLink(svc *domain.Service) func(c context.Context, input *model.LinkFi
 
 func DeleteLink(svc *domain.Service) func(c context.Context, input *model.LinkRequestFilter) (*struct{}, error) {
 	return func(c context.Context, input *model.LinkRequestFilter) (*struct{}, error) {
-		err := svc.LinkService.Delete(input.LinkId)
+		err := svc.LinkService.Delete(c, input.LinkId)
 		if err != nil {
-			return nil, huma.Error400BadRequest("failed to delete link", err)
+			return nil, accessError("failed to delete link", err)
 		}
 		return nil, nil
 	}
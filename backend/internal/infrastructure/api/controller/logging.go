@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"backend/internal/infrastructure/logging"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength limits the accepted request IDs, so a client can't blow up every log line.
+const maxRequestIDLength = 128
+
+// NewRequestLogger assigns every request an ID, which is taken from the X-Request-ID header of a proxy
+// if present and returned to the client. The request-scoped logger in the request context carries this
+// ID, so the logs of the domain and repository layers can be correlated with the request. After the
+// request is handled, one line with method, route, status, latency and user is logged. Requests to the
+// quiet routes, like the frequent probes of the orchestrator and Prometheus, are only logged at debug level.
+func NewRequestLogger(quietRoutes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		c.Header(requestIDHeader, requestID)
+
+		ctx := logging.WithRequest(c.Request.Context(), requestID, c.ClientIP())
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := c.Writer.Status()
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", route),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("clientIp", c.ClientIP()),
+		}
+		if userID := logging.UserID(ctx); userID != "" {
+			attrs = append(attrs, slog.String("userId", userID))
+		}
+
+		logging.FromContext(ctx).LogAttrs(ctx, requestLogLevel(route, status, quietRoutes), "Request handled", attrs...)
+	}
+}
+
+// NewRecovery turns panics into a 500 response and logs them with the request-scoped logger, instead of
+// writing the stack trace unstructured to stderr like gin.Recovery does.
+func NewRecovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, recovered any) {
+		logging.FromContext(c.Request.Context()).Error("Request panicked",
+			slog.String("error", fmt.Sprint(recovered)),
+			slog.String("stack", string(debug.Stack())),
+		)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
+
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range requestID {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func requestLogLevel(route string, status int, quietRoutes []string) slog.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return slog.LevelError
+	case slices.Contains(quietRoutes, route):
+		return slog.LevelDebug
+	default:
+		return slog.LevelInfo
+	}
+}
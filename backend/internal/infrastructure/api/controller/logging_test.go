@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"backend/internal/infrastructure/logging"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLoggerPropagatesRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	router := gin.New()
+	router.Use(NewRequestLogger())
+	router.GET("/v1/shelf/:shelfId", func(c *gin.Context) {
+		logging.SetUserID(c.Request.Context(), "user-1")
+		logging.FromContext(c.Request.Context()).Info("Inside handler")
+		c.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/shelf/shelf-1", nil)
+	req.Header.Set(requestIDHeader, "proxy-request-1")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, "proxy-request-1", resp.Header().Get(requestIDHeader))
+
+	lines := readLogLines(t, &buf)
+	require.Len(t, lines, 2)
+	require.Equal(t, "Inside handler", lines[0]["msg"])
+	require.Equal(t, "proxy-request-1", lines[0]["requestId"])
+
+	require.Equal(t, "Request handled", lines[1]["msg"])
+	require.Equal(t, "proxy-request-1", lines[1]["requestId"])
+	require.Equal(t, "GET", lines[1]["method"])
+	require.Equal(t, "/v1/shelf/:shelfId", lines[1]["route"])
+	require.EqualValues(t, http.StatusNoContent, lines[1]["status"])
+	require.Equal(t, "user-1", lines[1]["userId"])
+	require.Contains(t, lines[1], "latency")
+
+	// Without a valid header, a new ID is generated.
+	buf.Reset()
+	req = httptest.NewRequest(http.MethodGet, "/v1/shelf/shelf-1", nil)
+	req.Header.Set(requestIDHeader, "contains spaces")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	_, err := uuid.Parse(resp.Header().Get(requestIDHeader))
+	require.NoError(t, err)
+	require.Equal(t, resp.Header().Get(requestIDHeader), readLogLines(t, &buf)[1]["requestId"])
+}
+
+func readLogLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var lines []map[string]any
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var line map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	return lines
+}
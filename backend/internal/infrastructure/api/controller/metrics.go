@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"backend/internal/infrastructure/metrics"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/gin-gonic/gin"
+)
+
+// NewMetricsMiddleware records the count and latency of every huma operation labelled by its OperationID.
+func NewMetricsMiddleware() func(ctx huma.Context, next func(huma.Context)) {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		start := time.Now()
+		next(ctx)
+
+		status := ctx.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		metrics.ObserveRequest(ctx.Operation().OperationID, ctx.Method(), status, time.Since(start))
+	}
+}
+
+// Metrics serves the Prometheus metrics. If a token is configured, scrapers have to send it as bearer token.
+func Metrics(token string) gin.HandlerFunc {
+	handler := metrics.Handler()
+
+	return func(c *gin.Context) {
+		if token != "" {
+			bearer := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			}
+		}
+
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
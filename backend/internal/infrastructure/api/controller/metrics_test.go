@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humagin"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsMiddlewareLabelsRequestsByOperation(t *testing.T) {
+	router := gin.New()
+	api := humagin.New(router, huma.DefaultConfig("Test", "1.0.0"))
+	api.UseMiddleware(NewMetricsMiddleware())
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-metrics-test",
+		Path:        "/v1/metrics-test/{id}",
+	}, func(ctx context.Context, input *struct {
+		Id string `path:"id"`
+	}) (*struct{}, error) {
+		if input.Id == "missing" {
+			return nil, huma.Error404NotFound("not found")
+		}
+		return nil, nil
+	})
+	router.GET("/metrics", Metrics(""))
+
+	for _, id := range []string{"found", "found", "missing"} {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1/metrics-test/"+id, nil))
+	}
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Contains(t, resp.Body.String(), `linkshelf_http_requests_total{method="GET",operation="get-metrics-test",status="204"} 2`)
+	require.Contains(t, resp.Body.String(), `linkshelf_http_requests_total{method="GET",operation="get-metrics-test",status="404"} 1`)
+	require.Contains(t, resp.Body.String(), `linkshelf_http_request_duration_seconds_count{method="GET",operation="get-metrics-test"} 3`)
+	require.NotContains(t, resp.Body.String(), "/v1/metrics-test/found", "paths would make the label cardinality unbounded")
+}
+
+func TestMetricsRequireTheConfiguredBearerToken(t *testing.T) {
+	router := gin.New()
+	router.GET("/metrics", Metrics("scrape-token"))
+
+	scrape := func(authorization string) int {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	require.Equal(t, http.StatusUnauthorized, scrape(""))
+	require.Equal(t, http.StatusUnauthorized, scrape("Bearer wrong-token"))
+	require.Equal(t, http.StatusUnauthorized, scrape("scrape-token-suffix"))
+	require.Equal(t, http.StatusOK, scrape("Bearer scrape-token"))
+}
@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"backend/internal/domain"
+	"backend/internal/infrastructure/api/mapper"
+	"backend/internal/infrastructure/api/model"
+	"context"
+	"errors"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func CreatePersonalAccessToken(svc *domain.Service) func(c context.Context, input *model.PersonalAccessTokenRequestBody) (*model.PersonalAccessTokenCreatedResponse, error) {
+	return func(c context.Context, input *model.PersonalAccessTokenRequestBody) (*model.PersonalAccessTokenCreatedResponse, error) {
+		token, personalAccessToken, err := svc.PersonalAccessTokenService.Create(c, input.UserId, &input.Body)
+		if err != nil {
+			return nil, accessError("failed to create personal access token", err)
+		}
+
+		return &model.PersonalAccessTokenCreatedResponse{
+			Body: model.PersonalAccessTokenCreated{
+				PersonalAccessTokenOutput: mapper.MapPersonalAccessTokenToOutput(*personalAccessToken),
+				Token:                     token,
+			},
+		}, nil
+	}
+}
+
+func GetPersonalAccessTokens(svc *domain.Service) func(c context.Context, input *model.UserRequestFilter) (*model.PersonalAccessTokensResponse, error) {
+	return func(c context.Context, input *model.UserRequestFilter) (*model.PersonalAccessTokensResponse, error) {
+		tokens, err := svc.PersonalAccessTokenService.List(c, input.UserId)
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to get personal access tokens", err)
+		}
+
+		return mapper.MapPersonalAccessTokensToResponse(tokens), nil
+	}
+}
+
+func RevokePersonalAccessToken(svc *domain.Service) func(c context.Context, input *model.PersonalAccessTokenFilter) (*struct{}, error) {
+	return func(c context.Context, input *model.PersonalAccessTokenFilter) (*struct{}, error) {
+		err := svc.PersonalAccessTokenService.Revoke(c, input.UserId, input.TokenId)
+		if errors.Is(err, domain.ErrPersonalAccessTokenNotFound) {
+			return nil, huma.Error404NotFound("failed to revoke personal access token", err)
+		}
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to revoke personal access token", err)
+		}
+
+		return nil, nil
+	}
+}
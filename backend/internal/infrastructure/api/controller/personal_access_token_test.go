@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/repository"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakePersonalAccessTokenRepository struct {
+	repository.PersonalAccessTokenRepository
+	mu     sync.Mutex
+	tokens map[string]model.PersonalAccessToken
+}
+
+func (r *fakePersonalAccessTokenRepository) Create(_ context.Context, t *model.PersonalAccessToken) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t.Id = t.TokenHash[:8]
+	r.tokens[t.Id] = *t
+	return t.Id, nil
+}
+
+func (r *fakePersonalAccessTokenRepository) ListByUserId(_ context.Context, userId string) ([]model.PersonalAccessToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var tokens []model.PersonalAccessToken
+	for _, token := range r.tokens {
+		if token.UserId == userId {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (r *fakePersonalAccessTokenRepository) GetByHash(_ context.Context, tokenHash string) (*model.PersonalAccessToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			return &token, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakePersonalAccessTokenRepository) Touch(_ context.Context, id string, lastUsedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token := r.tokens[id]
+	token.LastUsedAt = &lastUsedAt
+	r.tokens[id] = token
+	return nil
+}
+
+func (r *fakePersonalAccessTokenRepository) Delete(_ context.Context, userId, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok || token.UserId != userId {
+		return false, nil
+	}
+	delete(r.tokens, id)
+	return true, nil
+}
+
+func TestPersonalAccessTokensAreRestrictedToTheirScopes(t *testing.T) {
+	api, _, _ := newTestAPI(t, false)
+	createTestUser(t, api)
+	code, output := login(api, "correct horse 1")
+	require.Equal(t, http.StatusOK, code)
+	session := "Authorization: Bearer " + output.Token
+
+	resp := api.Post("/v1/user/user-1/tokens", session, map[string]any{"name": "backup", "scopes": []string{"shelf:admin"}})
+	require.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+
+	resp = api.Post("/v1/user/user-1/tokens", session, map[string]any{"name": "backup", "scopes": []string{"shelf:read"}, "expires_at": "2000-01-01T00:00:00Z"})
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp = api.Post("/v1/user/user-1/tokens", session, map[string]any{"name": "backup", "scopes": []string{"shelf:read"}})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var created model.PersonalAccessTokenCreated
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+	require.True(t, strings.HasPrefix(created.Token, "lsp_"))
+	token := "Authorization: Bearer " + created.Token
+
+	resp = api.Get("/v1/shelf/shelf-1", token)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	resp = api.Delete("/v1/shelf/shelf-1", token)
+	require.Equal(t, http.StatusForbidden, resp.Code, "the token lacks shelf:write")
+
+	resp = api.Get("/v1/user/user-1", token)
+	require.Equal(t, http.StatusForbidden, resp.Code, "operations without scopes need a session")
+
+	resp = api.Get("/v1/user/user-1/tokens", session)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NotContains(t, resp.Body.String(), created.Token)
+	require.Contains(t, resp.Body.String(), `"last_used_at"`)
+
+	resp = api.Delete("/v1/user/user-1/tokens/"+created.Id, session)
+	require.Equal(t, http.StatusNoContent, resp.Code, resp.Body.String())
+
+	resp = api.Get("/v1/shelf/shelf-1", token)
+	require.Equal(t, http.StatusUnauthorized, resp.Code)
+
+	resp = api.Delete("/v1/user/user-1/tokens/"+created.Id, session)
+	require.Equal(t, http.StatusNotFound, resp.Code)
+}
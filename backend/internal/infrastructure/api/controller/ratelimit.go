@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"backend/internal/infrastructure/logging"
+	"backend/internal/infrastructure/ratelimit"
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humagin"
+)
+
+// NewIPRateLimitMiddleware rejects requests with 429 Too Many Requests once the client IP exhausted the
+// bucket of the operation. It runs before the authorization middleware, so requests with guessed bearer
+// tokens are limited as well. If the store fails, requests are let through, since an unavailable Redis
+// mustn't take the whole API down.
+func NewIPRateLimitMiddleware(api huma.API, limiter *ratelimit.Limiter) func(ctx huma.Context, next func(huma.Context)) {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		// The client IP of gin respects the trusted proxies, so the client and not the proxy is limited.
+		ip := humagin.Unwrap(ctx).ClientIP()
+
+		result, err := limiter.AllowIP(ctx.Context(), ctx.Operation().OperationID, ip)
+		if rejectRateLimited(api, ctx, result, err) {
+			return
+		}
+
+		next(ctx)
+	}
+}
+
+// NewUserRateLimitMiddleware rejects requests with 429 Too Many Requests once the user exhausted the bucket
+// of the operation. It runs after the authorization middleware, the user is the authenticated one or, if
+// the operation has none, the one in the path, so guessing the password of an account is limited even if
+// the attempts come from many IPs. The login names the account in its body, it takes the token of the user
+// bucket itself with limitAccount.
+func NewUserRateLimitMiddleware(api huma.API, limiter *ratelimit.Limiter) func(ctx huma.Context, next func(huma.Context)) {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		userID := logging.UserID(ctx.Context())
+		if userID == "" {
+			userID = ctx.Param("userId")
+		}
+		if userID == "" {
+			next(ctx)
+			return
+		}
+
+		result, err := limiter.AllowUser(ctx.Context(), ctx.Operation().OperationID, userID)
+		if rejectRateLimited(api, ctx, result, err) {
+			return
+		}
+
+		next(ctx)
+	}
+}
+
+// rejectRateLimited writes the 429 response if the bucket was empty and reports whether it did. A failed
+// store only gets logged.
+func rejectRateLimited(api huma.API, ctx huma.Context, result ratelimit.Result, err error) bool {
+	if err != nil {
+		logging.FromContext(ctx.Context()).Error("Rate limit store failed", slog.String("error", err.Error()))
+		return false
+	}
+	if result.Allowed {
+		return false
+	}
+
+	ctx.SetHeader("Retry-After", retryAfter(result.RetryAfter))
+	err = huma.WriteErr(api, ctx, http.StatusTooManyRequests, "Too many requests, retry later")
+	if err != nil {
+		slog.Error("Failed to write too many requests error", slog.String("error", err.Error()))
+	}
+	return true
+}
+
+// limitAccount takes a token from the user bucket of the operation for the account of an email address.
+// Unauthenticated operations like the login name the account in their body, which the middleware can't
+// see, so without it guessing the password or second factor of an account would only be limited per IP.
+func limitAccount(ctx context.Context, limiter *ratelimit.Limiter, operationID, email string) error {
+	if limiter == nil {
+		return nil
+	}
+
+	result, err := limiter.AllowUser(ctx, operationID, "email:"+strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		logging.FromContext(ctx).Error("Rate limit store failed", slog.String("error", err.Error()))
+		return nil
+	}
+	if !result.Allowed {
+		return huma.ErrorWithHeaders(huma.Error429TooManyRequests("Too many requests, retry later"),
+			http.Header{"Retry-After": []string{retryAfter(result.RetryAfter)}})
+	}
+	return nil
+}
+
+func retryAfter(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
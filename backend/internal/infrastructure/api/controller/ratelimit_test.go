@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"backend/internal/config"
+	"backend/internal/infrastructure/ratelimit"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/adapters/humagin"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitMiddlewareRejectsWithRetryAfter(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), ratelimit.Policy{}, map[string]ratelimit.Policy{
+		"patch-user-password": {User: ratelimit.Limit{Requests: 2, Period: time.Hour}},
+	})
+
+	router := gin.New()
+	api := humagin.New(router, huma.DefaultConfig("Test", "1.0.0"))
+	api.UseMiddleware(NewUserRateLimitMiddleware(api, limiter))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodPatch,
+		OperationID:   "patch-user-password",
+		Path:          "/v1/user/{userId}/password",
+		DefaultStatus: http.StatusNoContent,
+	}, func(ctx context.Context, input *struct {
+		UserId string `path:"userId"`
+	}) (*struct{}, error) {
+		return nil, nil
+	})
+
+	patch := func(userId string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest(http.MethodPatch, "/v1/user/"+userId+"/password", nil))
+		return resp
+	}
+
+	require.Equal(t, http.StatusNoContent, patch("user-1").Code)
+	require.Equal(t, http.StatusNoContent, patch("user-1").Code)
+
+	resp := patch("user-1")
+	require.Equal(t, http.StatusTooManyRequests, resp.Code)
+	require.Equal(t, "1800", resp.Header().Get("Retry-After"))
+
+	require.Equal(t, http.StatusNoContent, patch("user-2").Code)
+}
+
+func TestLoginIsLimitedPerAccountAcrossIPs(t *testing.T) {
+	users, svc, _ := newUserTestAPI(t)
+	createTestUser(t, users)
+
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), ratelimit.Policy{}, map[string]ratelimit.Policy{
+		"post-login": {
+			IP:   ratelimit.Limit{Requests: 10, Period: time.Hour},
+			User: ratelimit.Limit{Requests: 2, Period: time.Hour},
+		},
+	})
+
+	router := gin.New()
+	api := humagin.New(router, huma.DefaultConfig("Test", "1.0.0"))
+	api.UseMiddleware(NewIPRateLimitMiddleware(api, limiter))
+	api.UseMiddleware(NewUserRateLimitMiddleware(api, limiter))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-login",
+		Path:        "/v1/auth/login",
+	}, Login(svc, limiter))
+
+	login := func(ip, email string) *httptest.ResponseRecorder {
+		body := strings.NewReader(`{"email": "` + email + `", "password": "wrong password 1"}`)
+		req := httptest.NewRequest(http.MethodPost, "/v1/auth/login", body)
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = ip + ":1234"
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	require.Equal(t, http.StatusUnauthorized, login("192.0.2.1", "jane@example.com").Code)
+	require.Equal(t, http.StatusUnauthorized, login("192.0.2.2", "jane@example.com").Code)
+
+	resp := login("192.0.2.3", " Jane@Example.com")
+	require.Equal(t, http.StatusTooManyRequests, resp.Code, "the account is limited regardless of IP and spelling")
+	require.Equal(t, "1800", resp.Header().Get("Retry-After"))
+
+	require.Equal(t, http.StatusUnauthorized, login("192.0.2.3", "john@example.com").Code)
+}
+
+func TestGuessedBearerTokensAreLimitedPerIP(t *testing.T) {
+	_, svc, _ := newUserTestAPI(t)
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), ratelimit.Policy{
+		IP: ratelimit.Limit{Requests: 2, Period: time.Hour},
+	}, nil)
+
+	router := gin.New()
+	api := humagin.New(router, huma.DefaultConfig("Test", "1.0.0"))
+	api.UseMiddleware(NewIPRateLimitMiddleware(api, limiter))
+	api.UseMiddleware(NewAuthorizationMiddleware(api, &config.Config{}, svc))
+	api.UseMiddleware(NewUserRateLimitMiddleware(api, limiter))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-rate-limit-test",
+		Path:        "/v1/rate-limit-test",
+		Security:    bearerScopes(),
+	}, func(ctx context.Context, input *struct{}) (*struct{}, error) {
+		return nil, nil
+	})
+
+	get := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/v1/rate-limit-test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	require.Equal(t, http.StatusUnauthorized, get("guess-1"))
+	require.Equal(t, http.StatusUnauthorized, get("guess-2"))
+	require.Equal(t, http.StatusTooManyRequests, get("guess-3"), "rejected tokens take from the IP bucket")
+}
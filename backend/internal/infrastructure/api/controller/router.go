@@ -1,49 +1,62 @@
 package controller
 
 import (
+	"backend/internal/config"
 	"backend/internal/domain"
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/health"
+	"backend/internal/infrastructure/logging"
+	"backend/internal/infrastructure/ratelimit"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/danielgtaylor/huma/v2/adapters/humagin"
 	"github.com/gin-gonic/gin"
-	"github.com/spf13/viper"
 )
 
-func Router(svc *domain.Service) (*gin.Engine, error) {
-	if viper.GetString("app.env") == "PROD" || viper.GetString("app.env") == "prd" {
+func Router(cfg *config.Config, svc *domain.Service, checks *health.Registry, limiter *ratelimit.Limiter) (*gin.Engine, error) {
+	environment := strings.ToLower(cfg.App.Environment)
+	if environment == "prod" || environment == "prd" {
 		gin.SetMode(gin.ReleaseMode)
 	}
+	gin.DebugPrintFunc = func(format string, values ...any) {
+		slog.Debug(strings.TrimSpace(fmt.Sprintf(format, values...)))
+	}
+	gin.DebugPrintRouteFunc = func(method, path, handler string, _ int) {
+		slog.Debug("Route registered", slog.String("method", method), slog.String("path", path), slog.String("handler", handler))
+	}
 
-	hostWithScheme := fmt.Sprintf("%s://%s", viper.GetString("server.scheme"), viper.GetString("server.host"))
-	host := viper.GetString("server.host")
-	if viper.GetBool("domain.openapi.usePort") {
-		host = fmt.Sprintf("%s:%d", host, viper.GetInt("server.port"))
-		hostWithScheme = fmt.Sprintf("%s:%d", hostWithScheme, viper.GetInt("server.port"))
+	hostWithScheme := fmt.Sprintf("%s://%s", cfg.Server.Scheme, cfg.Server.Host)
+	host := cfg.Server.Host
+	if cfg.Domain.OpenAPI.UsePort {
+		host = fmt.Sprintf("%s:%s", host, cfg.Server.Port)
+		hostWithScheme = fmt.Sprintf("%s:%s", hostWithScheme, cfg.Server.Port)
 	}
 	slog.Debug(fmt.Sprintf("Host: %s", hostWithScheme))
 
-	humaConfig := huma.DefaultConfig(viper.GetString("app.name"), viper.GetString("app.version"))
+	humaConfig := huma.DefaultConfig(cfg.App.Name, cfg.App.Version)
 	humaConfig.Info = &huma.Info{
-		Title:       viper.GetString("app.name"),
-		Description: viper.GetString("app.description"),
+		Title:       cfg.App.Name,
+		Description: cfg.App.Description,
 		License:     nil,
-		Version:     viper.GetString("app.version"),
+		Version:     cfg.App.Version,
 	}
 	humaConfig.Servers = []*huma.Server{
 		{URL: hostWithScheme},
-		{Description: fmt.Sprintf("This is the default server of %s", viper.GetString("app.name"))},
+		{Description: fmt.Sprintf("This is the default server of %s", cfg.App.Name)},
 	}
 	humaConfig.Components.SecuritySchemes = map[string]*huma.SecurityScheme{
-		viper.GetString("app.name"): {
+		cfg.App.Name: {
 			Type: "oauth2",
 			Flows: &huma.OAuthFlows{
 				AuthorizationCode: &huma.OAuthFlow{
-					AuthorizationURL: fmt.Sprintf("%s/oauth/v2/authorize", viper.GetString("authentication.oidc.issuer")),
-					TokenURL:         fmt.Sprintf("%s/oauth/v2/token", viper.GetString("authentication.oidc.issuer")),
-					RefreshURL:       fmt.Sprintf("%s/oauth/v2/token", viper.GetString("authentication.oidc.issuer")),
+					AuthorizationURL: fmt.Sprintf("%s/oauth/v2/authorize", cfg.Authentication.OIDC.Issuer),
+					TokenURL:         fmt.Sprintf("%s/oauth/v2/token", cfg.Authentication.OIDC.Issuer),
+					RefreshURL:       fmt.Sprintf("%s/oauth/v2/token", cfg.Authentication.OIDC.Issuer),
 					Scopes: map[string]string{
 						"openid":         "To return the openid basic information.",
 						"profile":        "To return the profile attributes like name.",
@@ -53,26 +66,57 @@ func Router(svc *domain.Service) (*gin.Engine, error) {
 				},
 			},
 		},
+		"bearer": {
+			Type:        "http",
+			Scheme:      "bearer",
+			Description: "Token of a session, see `POST /v1/auth/login`, or a personal access token. Personal access tokens can only be used for operations which list scopes and need all of them.",
+		},
 	}
+	humaConfig.Security = []map[string][]string{{"bearer": {}}}
 
-	router := gin.Default()
-	api := humagin.New(router, humaConfig)
-	api.UseMiddleware(NewAuthorizationMiddleware(api))
+	router := gin.New()
+	router.Use(
+		NewRequestLogger("/health/liveness", "/health/readiness", cfg.Metrics.Path),
+		NewRecovery(),
+		NewSecurityHeadersMiddleware(SecurityHeaderOptions{
+			ContentSecurityPolicy: cfg.Security.ContentSecurityPolicy,
+			FrameOptions:          cfg.Security.FrameOptions,
+			ReferrerPolicy:        cfg.Security.ReferrerPolicy,
+			HSTSMaxAge:            cfg.Security.HSTS.MaxAge,
+			HSTSIncludeSubDomains: cfg.Security.HSTS.IncludeSubDomains,
+		}),
+	)
 
-	router.GET("/health/liveness", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "alive"})
+	cors, err := NewCORSMiddleware(CORSOptions{
+		AllowedOrigins:   cfg.CORS.AllowedOrigins,
+		AllowedMethods:   cfg.CORS.AllowedMethods,
+		AllowedHeaders:   cfg.CORS.AllowedHeaders,
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           cfg.CORS.MaxAge,
 	})
-	router.GET("/health/readiness", func(c *gin.Context) {
-		// You can add your readiness checks here (e.g., database connection)
-		c.JSON(http.StatusOK, gin.H{"status": "ready"})
-	})
-
-	trustedProxies, err := getTrustedProxies()
 	if err != nil {
 		return nil, err
 	}
+	router.Use(cors)
 
-	err = router.SetTrustedProxies(trustedProxies)
+	api := humagin.New(router, humaConfig)
+	api.UseMiddleware(NewTracingMiddleware())
+	if cfg.Metrics.Enabled {
+		api.UseMiddleware(NewMetricsMiddleware())
+		router.GET(cfg.Metrics.Path, Metrics(cfg.Metrics.Token))
+	}
+	if limiter != nil {
+		api.UseMiddleware(NewIPRateLimitMiddleware(api, limiter))
+	}
+	api.UseMiddleware(NewAuthorizationMiddleware(api, cfg, svc))
+	if limiter != nil {
+		api.UseMiddleware(NewUserRateLimitMiddleware(api, limiter))
+	}
+
+	router.GET("/health/liveness", Health(checks, health.Liveness, "alive"))
+	router.GET("/health/readiness", Health(checks, health.Readiness, "ready"))
+
+	err = router.SetTrustedProxies(cfg.Server.TrustedProxies)
 	if err != nil {
 		return nil, err
 	}
@@ -88,6 +132,7 @@ func Router(svc *domain.Service) (*gin.Engine, error) {
 		Description: "Create a new user.",
 		Path:        "/v1/user",
 		Tags:        []string{"User"},
+		Security:    publicOperation,
 	}, CreateUser(svc))
 	huma.Register(api, huma.Operation{
 		Method:      http.MethodGet,
@@ -117,19 +162,356 @@ func Router(svc *domain.Service) (*gin.Engine, error) {
 		Method:        http.MethodDelete,
 		OperationID:   "delete-user",
 		Summary:       "Delete user",
-		Description:   "Delete a user by ID.",
+		Description:   "Schedule the deletion of a user. The account is disabled right away and deleted with all its data once the grace period in `purge_at` ended, until then logging in restores it.",
 		Path:          "/v1/user/{userId}",
 		Tags:          []string{"User"},
-		DefaultStatus: http.StatusNoContent,
+		DefaultStatus: http.StatusAccepted,
 	}, DeleteUser(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-user-data-export",
+		Summary:     "Export user data",
+		Description: "Download a ZIP archive with the profile and all shelves, sections and links of a user.",
+		Path:        "/v1/user/{userId}/export",
+		Tags:        []string{"User"},
+		Responses: map[string]*huma.Response{
+			"200": {
+				Description: "ZIP archive with the data of the user",
+				Content: map[string]*huma.MediaType{
+					"application/zip": {Schema: &huma.Schema{Type: huma.TypeString, Format: "binary"}},
+				},
+			},
+		},
+	}, ExportUserData(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodPost,
+		OperationID:   "post-resend-email-verification",
+		Summary:       "Resend email verification",
+		Description:   "Send a new verification mail to the user, which invalidates the previous one.",
+		Path:          "/v1/user/{userId}/email/verification",
+		Tags:          []string{"User"},
+		DefaultStatus: http.StatusAccepted,
+	}, RequestEmailVerification(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-enroll-two-factor",
+		Summary:     "Enroll two-factor authentication",
+		Description: "Create a new TOTP secret after checking the current password. Two-factor authentication is enabled once a code of the authenticator app is confirmed.",
+		Path:        "/v1/user/{userId}/2fa",
+		Tags:        []string{"User"},
+	}, EnrollTwoFactor(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-confirm-two-factor",
+		Summary:     "Confirm two-factor authentication",
+		Description: "Enable two-factor authentication with a code of the authenticator app. The response contains the recovery codes, which are shown only once.",
+		Path:        "/v1/user/{userId}/2fa/confirm",
+		Tags:        []string{"User"},
+	}, ConfirmTwoFactor(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodDelete,
+		OperationID:   "delete-two-factor",
+		Summary:       "Disable two-factor authentication",
+		Description:   "Disable two-factor authentication and drop the recovery codes. Requires a TOTP or recovery code in the `X-OTP-Code` header.",
+		Path:          "/v1/user/{userId}/2fa",
+		Tags:          []string{"User"},
+		DefaultStatus: http.StatusNoContent,
+	}, DisableTwoFactor(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-regenerate-recovery-codes",
+		Summary:     "Regenerate recovery codes",
+		Description: "Replace all recovery codes with new ones. Requires a TOTP or recovery code in the `X-OTP-Code` header.",
+		Path:        "/v1/user/{userId}/2fa/recovery-codes",
+		Tags:        []string{"User"},
+	}, RegenerateRecoveryCodes(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-create-personal-access-token",
+		Summary:     "Create personal access token",
+		Description: "Create a token for scripts, which can be used as bearer token for the operations of its scopes. The token is only returned once.",
+		Path:        "/v1/user/{userId}/tokens",
+		Tags:        []string{"User"},
+	}, CreatePersonalAccessToken(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-personal-access-tokens",
+		Summary:     "Get personal access tokens",
+		Description: "Get the personal access tokens of a user without the tokens themselves.",
+		Path:        "/v1/user/{userId}/tokens",
+		Tags:        []string{"User"},
+	}, GetPersonalAccessTokens(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodDelete,
+		OperationID:   "delete-personal-access-token",
+		Summary:       "Revoke personal access token",
+		Description:   "Revoke a personal access token, it stops working right away.",
+		Path:          "/v1/user/{userId}/tokens/{tokenId}",
+		Tags:          []string{"User"},
+		DefaultStatus: http.StatusNoContent,
+	}, RevokePersonalAccessToken(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-user-shelves",
+		Summary:     "Get shelves of user",
+		Description: "Get the shelves of a user, of the teams of the user and the shelves shared with the user.",
+		Path:        "/v1/user/{userId}/shelves",
+		Tags:        []string{"User"},
+		Security:    bearerScopes(model.ScopeShelfRead),
+	}, GetShelves(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-create-shelf-from-template",
+		Summary:     "Create shelf from template",
+		Description: "Create a shelf of the user with the given title and path and the theme, icon, sections and placeholder links of a template.",
+		Path:        "/v1/user/{userId}/shelves/from-template",
+		Tags:        []string{"User"},
+		Security:    bearerScopes(model.ScopeShelfWrite),
+	}, CreateShelfFromTemplate(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-create-team",
+		Summary:     "Create team",
+		Description: "Create a team with the user as its first owner.",
+		Path:        "/v1/user/{userId}/teams",
+		Tags:        []string{"User"},
+	}, CreateTeam(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-user-teams",
+		Summary:     "Get teams of user",
+		Description: "Get the teams the user is a member of.",
+		Path:        "/v1/user/{userId}/teams",
+		Tags:        []string{"User"},
+	}, GetTeams(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-accept-shelf-invitation",
+		Summary:     "Accept shelf invitation",
+		Description: "Accept an invitation to a shelf with its token, the shelf is shared with the user in the role of the invitation. Invitations mailed to an address can only be accepted by the user with that verified address.",
+		Path:        "/v1/user/{userId}/invitations/accept",
+		Tags:        []string{"User"},
+	}, AcceptShelfInvitation(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-user-trash",
+		Summary:     "Get trash of user",
+		Description: "Get the shelves, sections and links the user deleted and those deleted from the shelves of the user, newest first. They're purged after the retention period.",
+		Path:        "/v1/user/{userId}/trash",
+		Tags:        []string{"User"},
+		Security:    bearerScopes(model.ScopeShelfRead),
+	}, GetTrash(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodPost,
+		OperationID:   "post-restore-trash-item",
+		Summary:       "Restore item from trash",
+		Description:   "Restore a shelf, section or link from the trash together with the children which were deleted with it.",
+		Path:          "/v1/user/{userId}/trash/{itemType}/{itemId}/restore",
+		Tags:          []string{"User"},
+		DefaultStatus: http.StatusNoContent,
+		Security:      bearerScopes(model.ScopeShelfWrite),
+	}, RestoreTrashItem(svc))
+
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-team",
+		Summary:     "Get team",
+		Description: "Get a team, members of every role may see it.",
+		Path:        "/v1/team/{teamId}",
+		Tags:        []string{"Team"},
+	}, GetTeam(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPut,
+		OperationID: "put-update-team",
+		Summary:     "Update team",
+		Description: "Rename a team, only owners may do so.",
+		Path:        "/v1/team/{teamId}",
+		Tags:        []string{"Team"},
+	}, UpdateTeam(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodDelete,
+		OperationID:   "delete-team",
+		Summary:       "Delete team",
+		Description:   "Delete a team, only owners may do so. The shelves of the team have to be deleted or moved first.",
+		Path:          "/v1/team/{teamId}",
+		Tags:          []string{"Team"},
+		DefaultStatus: http.StatusNoContent,
+	}, DeleteTeam(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-team-members",
+		Summary:     "Get team members",
+		Description: "Get the members of a team with their roles.",
+		Path:        "/v1/team/{teamId}/members",
+		Tags:        []string{"Team"},
+	}, GetTeamMembers(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPut,
+		OperationID: "put-team-member",
+		Summary:     "Set team member",
+		Description: "Add a user to a team or change the role of a member. Owners manage the team and its shelves, editors change the content of the team shelves and viewers can only read them.",
+		Path:        "/v1/team/{teamId}/members/{memberId}",
+		Tags:        []string{"Team"},
+	}, SetTeamMember(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodDelete,
+		OperationID:   "delete-team-member",
+		Summary:       "Remove team member",
+		Description:   "Remove a member from a team. Owners may remove every member, everybody may leave a team. The last owner can't be removed.",
+		Path:          "/v1/team/{teamId}/members/{memberId}",
+		Tags:          []string{"Team"},
+		DefaultStatus: http.StatusNoContent,
+	}, RemoveTeamMember(svc))
+
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodPost,
+		OperationID:   "post-verify-email",
+		Summary:       "Verify email",
+		Description:   "Verify the email address of a user with the token of the verification mail.",
+		Path:          "/v1/auth/email/verify",
+		Tags:          []string{"Auth"},
+		DefaultStatus: http.StatusNoContent,
+		Security:      publicOperation,
+	}, VerifyEmail(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodPost,
+		OperationID:   "post-forgot-password",
+		Summary:       "Forgot password",
+		Description:   "Send a password reset mail. The response is the same whether an account with the email address exists or not.",
+		Path:          "/v1/auth/password/forgot",
+		Tags:          []string{"Auth"},
+		DefaultStatus: http.StatusAccepted,
+		Security:      publicOperation,
+	}, ForgotPassword(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodPost,
+		OperationID:   "post-reset-password",
+		Summary:       "Reset password",
+		Description:   "Set a new password with the token of the password reset mail. Each token can only be used once.",
+		Path:          "/v1/auth/password/reset",
+		Tags:          []string{"Auth"},
+		DefaultStatus: http.StatusNoContent,
+		Security:      publicOperation,
+	}, ResetPassword(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-login",
+		Summary:     "Login",
+		Description: "Start a session with the email address and password. Users with two-factor authentication also have to send a TOTP or recovery code in the `X-OTP-Code` header. A login cancels the scheduled deletion of the account.",
+		Path:        "/v1/auth/login",
+		Tags:        []string{"Auth"},
+		Security:    publicOperation,
+	}, Login(svc, limiter))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodPost,
+		OperationID:   "post-logout",
+		Summary:       "Logout",
+		Description:   "End the session of the bearer token.",
+		Path:          "/v1/auth/logout",
+		Tags:          []string{"Auth"},
+		DefaultStatus: http.StatusNoContent,
+	}, Logout(svc))
+
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-admin-users",
+		Summary:     "Search users",
+		Description: "Get a page of the users, optionally filtered by a search term and the role.",
+		Path:        "/v1/admin/users",
+		Tags:        []string{"Admin"},
+		Metadata:    adminOperation,
+	}, SearchUsers(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPut,
+		OperationID: "put-admin-user-role",
+		Summary:     "Set user role",
+		Description: "Make a user an admin or a regular user. Admins can't change their own role.",
+		Path:        "/v1/admin/users/{userId}/role",
+		Tags:        []string{"Admin"},
+		Metadata:    adminOperation,
+	}, SetUserRole(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-admin-disable-user",
+		Summary:     "Disable user",
+		Description: "Disable an account and end all its sessions. A scheduled deletion is cancelled, so the account stays until it's enabled or deleted.",
+		Path:        "/v1/admin/users/{userId}/disable",
+		Tags:        []string{"Admin"},
+		Metadata:    adminOperation,
+	}, DisableUser(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-admin-enable-user",
+		Summary:     "Enable user",
+		Description: "Enable a disabled account, which also cancels a scheduled deletion.",
+		Path:        "/v1/admin/users/{userId}/enable",
+		Tags:        []string{"Admin"},
+		Metadata:    adminOperation,
+	}, EnableUser(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-admin-impersonate-user",
+		Summary:     "Impersonate user",
+		Description: "Start a short session as the user for support. Admins and disabled users can't be impersonated. The session can't change the credentials of the account, i.e. its email address, password, two-factor authentication and personal access tokens, nor delete it.",
+		Path:        "/v1/admin/users/{userId}/impersonate",
+		Tags:        []string{"Admin"},
+		Metadata:    adminOperation,
+	}, ImpersonateUser(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-admin-statistics",
+		Summary:     "Get statistics",
+		Description: "Get the numbers of users, shelves, sections, links and sessions of the instance.",
+		Path:        "/v1/admin/statistics",
+		Tags:        []string{"Admin"},
+		Metadata:    adminOperation,
+	}, GetStatistics(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-admin-unpublish-shelf",
+		Summary:     "Unpublish shelf",
+		Description: "Stop serving a shelf on its domain, e.g. because it violates the terms of the instance.",
+		Path:        "/v1/admin/shelves/{shelfId}/unpublish",
+		Tags:        []string{"Admin"},
+		Metadata:    adminOperation,
+	}, UnpublishShelf(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-admin-publish-shelf",
+		Summary:     "Publish shelf",
+		Description: "Serve an unpublished shelf on its domain again.",
+		Path:        "/v1/admin/shelves/{shelfId}/publish",
+		Tags:        []string{"Admin"},
+		Metadata:    adminOperation,
+	}, PublishShelf(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodDelete,
+		OperationID:   "delete-admin-shelf",
+		Summary:       "Delete shelf",
+		Description:   "Delete a shelf of any user with all its sections and links.",
+		Path:          "/v1/admin/shelves/{shelfId}",
+		Tags:          []string{"Admin"},
+		Metadata:      adminOperation,
+		DefaultStatus: http.StatusNoContent,
+	}, ModerateDeleteShelf(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-admin-audit-events",
+		Summary:     "Search audit events",
+		Description: "Get a page of the audit events of the whole instance, newest first, optionally filtered by shelf, entity, action, actor and time.",
+		Path:        "/v1/admin/audit",
+		Tags:        []string{"Admin"},
+		Metadata:    adminOperation,
+	}, SearchAuditEvents(svc))
 
 	huma.Register(api, huma.Operation{
 		Method:      http.MethodPost,
 		OperationID: "post-create-shelf",
 		Summary:     "Create shelf",
-		Description: "Create a new shelf.",
+		Description: "Create a new shelf for the caller, or for a team the caller edits.",
 		Path:        "/v1/shelf",
 		Tags:        []string{"Shelf"},
+		Security:    bearerScopes(model.ScopeShelfWrite),
 	}, CreateShelf(svc))
 	huma.Register(api, huma.Operation{
 		Method:      http.MethodGet,
@@ -138,6 +520,7 @@ func Router(svc *domain.Service) (*gin.Engine, error) {
 		Description: "Get a shelf by ID.",
 		Path:        "/v1/shelf/{shelfId}",
 		Tags:        []string{"Shelf"},
+		Security:    bearerScopes(model.ScopeShelfRead),
 	}, GetShelfById(svc))
 	huma.Register(api, huma.Operation{
 		Method:      http.MethodPut,
@@ -146,6 +529,7 @@ func Router(svc *domain.Service) (*gin.Engine, error) {
 		Description: "Update an existing shelf.",
 		Path:        "/v1/shelf/{shelfId}",
 		Tags:        []string{"Shelf"},
+		Security:    bearerScopes(model.ScopeShelfWrite),
 	}, UpdateShelf(svc))
 	huma.Register(api, huma.Operation{
 		Method:        http.MethodDelete,
@@ -155,7 +539,155 @@ func Router(svc *domain.Service) (*gin.Engine, error) {
 		Path:          "/v1/shelf/{shelfId}",
 		Tags:          []string{"Shelf"},
 		DefaultStatus: http.StatusNoContent,
+		Security:      bearerScopes(model.ScopeShelfWrite),
 	}, DeleteShelf(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-verify-shelf-domain",
+		Summary:     "Verify shelf domain",
+		Description: "Verify the custom domain of a shelf. The TXT record `_linkshelf.<domain>` has to contain the `domainVerificationToken` of the shelf.",
+		Path:        "/v1/shelf/{shelfId}/domain/verify",
+		Tags:        []string{"Shelf"},
+		Security:    bearerScopes(model.ScopeShelfWrite),
+	}, VerifyShelfDomain(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPut,
+		OperationID: "put-shelf-owner",
+		Summary:     "Move shelf",
+		Description: "Hand a shelf to a team or back to a user. Only owners of the shelf may move it, and only to a team they edit or to themselves.",
+		Path:        "/v1/shelf/{shelfId}/owner",
+		Tags:        []string{"Shelf"},
+		Security:    bearerScopes(model.ScopeShelfWrite),
+	}, MoveShelf(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-clone-shelf",
+		Summary:     "Clone shelf",
+		Description: "Copy a shelf with its sections and links into a new shelf with the given title and path. The copy belongs to the caller unless another owner is given, the custom domain isn't copied.",
+		Path:        "/v1/shelf/{shelfId}/clone",
+		Tags:        []string{"Shelf"},
+		Security:    bearerScopes(model.ScopeShelfWrite),
+	}, CloneShelf(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-publish-shelf-template",
+		Summary:     "Publish shelf as template",
+		Description: "Publish the sections and links of a shelf as a template every user can start a shelf from. Only owners of the shelf may publish it.",
+		Path:        "/v1/shelf/{shelfId}/template",
+		Tags:        []string{"Shelf"},
+		Security:    bearerScopes(model.ScopeShelfWrite),
+	}, PublishShelfTemplate(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-shelf-shares",
+		Summary:     "Get shelf shares",
+		Description: "Get the users a shelf is shared with.",
+		Path:        "/v1/shelf/{shelfId}/shares",
+		Tags:        []string{"Shelf"},
+		Security:    bearerScopes(model.ScopeShelfRead),
+	}, GetShelfShares(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPut,
+		OperationID: "put-shelf-share",
+		Summary:     "Share shelf",
+		Description: "Share a shelf with a user as editor or viewer, or change the role of an existing share. Only owners of the shelf may share it.",
+		Path:        "/v1/shelf/{shelfId}/shares/{memberId}",
+		Tags:        []string{"Shelf"},
+		Security:    bearerScopes(model.ScopeShelfWrite),
+	}, ShareShelf(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodDelete,
+		OperationID:   "delete-shelf-share",
+		Summary:       "Revoke shelf share",
+		Description:   "Stop sharing a shelf with a user. Owners may revoke every share, users may give up their own.",
+		Path:          "/v1/shelf/{shelfId}/shares/{memberId}",
+		Tags:          []string{"Shelf"},
+		DefaultStatus: http.StatusNoContent,
+		Security:      bearerScopes(model.ScopeShelfWrite),
+	}, RevokeShelfShare(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-shelf-audit-events",
+		Summary:     "Get shelf audit events",
+		Description: "Get a page of the changes to a shelf, its sections, links, shares and invitations, newest first. Only owners of the shelf may see them.",
+		Path:        "/v1/shelf/{shelfId}/audit",
+		Tags:        []string{"Shelf"},
+		Security:    bearerScopes(model.ScopeShelfRead),
+	}, GetShelfAuditEvents(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-shelf-revisions",
+		Summary:     "Get shelf revisions",
+		Description: "Get a page of the revisions of a shelf with their authors, newest first. Changes of the same author within a short time are batched into one revision.",
+		Path:        "/v1/shelf/{shelfId}/revisions",
+		Tags:        []string{"Shelf"},
+		Security:    bearerScopes(model.ScopeShelfRead),
+	}, GetShelfRevisions(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-shelf-revision-diff",
+		Summary:     "Diff shelf revisions",
+		Description: "Get the changes of the shelf, its sections and links from one revision to another or to the current state.",
+		Path:        "/v1/shelf/{shelfId}/revisions/diff",
+		Tags:        []string{"Shelf"},
+		Security:    bearerScopes(model.ScopeShelfRead),
+	}, DiffShelfRevisions(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-shelf-revision",
+		Summary:     "Get shelf revision",
+		Description: "Get a revision of a shelf with the snapshot of its sections and links.",
+		Path:        "/v1/shelf/{shelfId}/revisions/{revisionId}",
+		Tags:        []string{"Shelf"},
+		Security:    bearerScopes(model.ScopeShelfRead),
+	}, GetShelfRevision(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-restore-shelf-revision",
+		Summary:     "Restore shelf revision",
+		Description: "Restore the attributes, sections and links of a shelf to a revision in one transaction. The restore is recorded as a new revision.",
+		Path:        "/v1/shelf/{shelfId}/revisions/{revisionId}/restore",
+		Tags:        []string{"Shelf"},
+		Security:    bearerScopes(model.ScopeShelfWrite),
+	}, RestoreShelfRevision(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-create-shelf-invitation",
+		Summary:     "Invite to shelf",
+		Description: "Invite somebody to a shelf as editor or viewer. With an email the invitation is mailed, otherwise a link to share is returned once. Only owners of the shelf may invite.",
+		Path:        "/v1/shelf/{shelfId}/invitations",
+		Tags:        []string{"Shelf"},
+		Security:    bearerScopes(model.ScopeShelfWrite),
+	}, CreateShelfInvitation(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-shelf-invitations",
+		Summary:     "Get shelf invitations",
+		Description: "Get the invitations to a shelf which are neither answered nor expired.",
+		Path:        "/v1/shelf/{shelfId}/invitations",
+		Tags:        []string{"Shelf"},
+		Security:    bearerScopes(model.ScopeShelfRead),
+	}, GetShelfInvitations(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodDelete,
+		OperationID:   "delete-shelf-invitation",
+		Summary:       "Revoke shelf invitation",
+		Description:   "Revoke an invitation to a shelf, its link stops working.",
+		Path:          "/v1/shelf/{shelfId}/invitations/{invitationId}",
+		Tags:          []string{"Shelf"},
+		DefaultStatus: http.StatusNoContent,
+		Security:      bearerScopes(model.ScopeShelfWrite),
+	}, RevokeShelfInvitation(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodPost,
+		OperationID:   "post-decline-shelf-invitation",
+		Summary:       "Decline shelf invitation",
+		Description:   "Decline an invitation to a shelf with the token of the invitation, no account is needed.",
+		Path:          "/v1/invitation/decline",
+		Tags:          []string{"Shelf"},
+		DefaultStatus: http.StatusNoContent,
+		Security:      publicOperation,
+	}, DeclineShelfInvitation(svc))
 
 	huma.Register(api, huma.Operation{
 		Method:      http.MethodPost,
@@ -164,6 +696,7 @@ func Router(svc *domain.Service) (*gin.Engine, error) {
 		Description: "Create a new section.",
 		Path:        "/v1/section",
 		Tags:        []string{"Section"},
+		Security:    bearerScopes(model.ScopeShelfWrite),
 	}, CreateSection(svc))
 	huma.Register(api, huma.Operation{
 		Method:      http.MethodGet,
@@ -172,6 +705,7 @@ func Router(svc *domain.Service) (*gin.Engine, error) {
 		Description: "Get sections by shelf ID.",
 		Path:        "/v1/section/{sectionId}",
 		Tags:        []string{"Section"},
+		Security:    bearerScopes(model.ScopeShelfRead),
 	}, GetSections(svc))
 	huma.Register(api, huma.Operation{
 		Method:      http.MethodPut,
@@ -180,6 +714,7 @@ func Router(svc *domain.Service) (*gin.Engine, error) {
 		Description: "Update an existing section.",
 		Path:        "/v1/section/{sectionId}",
 		Tags:        []string{"Section"},
+		Security:    bearerScopes(model.ScopeShelfWrite),
 	}, UpdateSection(svc))
 	huma.Register(api, huma.Operation{
 		Method:        http.MethodDelete,
@@ -189,7 +724,26 @@ func Router(svc *domain.Service) (*gin.Engine, error) {
 		Path:          "/v1/section/{sectionId}",
 		Tags:          []string{"Section"},
 		DefaultStatus: http.StatusNoContent,
+		Security:      bearerScopes(model.ScopeShelfWrite),
 	}, DeleteSection(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-clone-section",
+		Summary:     "Clone section",
+		Description: "Copy a section with its links into the given shelf, or next to the section if no shelf is given.",
+		Path:        "/v1/section/{sectionId}/clone",
+		Tags:        []string{"Section"},
+		Security:    bearerScopes(model.ScopeShelfWrite),
+	}, CloneSection(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-move-section",
+		Summary:     "Move section",
+		Description: "Move a section with its links to another shelf. The moved section and its links get new IDs.",
+		Path:        "/v1/section/{sectionId}/move",
+		Tags:        []string{"Section"},
+		Security:    bearerScopes(model.ScopeShelfWrite),
+	}, MoveSection(svc))
 
 	huma.Register(api, huma.Operation{
 		Method:      http.MethodPost,
@@ -198,6 +752,7 @@ func Router(svc *domain.Service) (*gin.Engine, error) {
 		Description: "Create a new link.",
 		Path:        "/v1/link",
 		Tags:        []string{"Link"},
+		Security:    bearerScopes(model.ScopeLinkWrite),
 	}, CreateLink(svc))
 	huma.Register(api, huma.Operation{
 		Method:      http.MethodGet,
@@ -206,6 +761,7 @@ func Router(svc *domain.Service) (*gin.Engine, error) {
 		Description: "Get links by shelf ID.",
 		Path:        "/v1/link/{linkId}",
 		Tags:        []string{"Link"},
+		Security:    bearerScopes(model.ScopeShelfRead),
 	}, GetLinks(svc))
 	huma.Register(api, huma.Operation{
 		Method:      http.MethodPut,
@@ -214,6 +770,7 @@ func Router(svc *domain.Service) (*gin.Engine, error) {
 		Description: "Update an existing link.",
 		Path:        "/v1/link/{linkId}",
 		Tags:        []string{"Link"},
+		Security:    bearerScopes(model.ScopeLinkWrite),
 	}, UpdateLink(svc))
 	huma.Register(api, huma.Operation{
 		Method:        http.MethodDelete,
@@ -223,10 +780,52 @@ func Router(svc *domain.Service) (*gin.Engine, error) {
 		Path:          "/v1/link/{linkId}",
 		Tags:          []string{"Link"},
 		DefaultStatus: http.StatusNoContent,
+		Security:      bearerScopes(model.ScopeLinkWrite),
 	}, DeleteLink(svc))
 
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-shelf-templates",
+		Summary:     "Get templates",
+		Description: "Get the built-in templates followed by the templates published by users, newest first.",
+		Path:        "/v1/templates",
+		Tags:        []string{"Template"},
+		Security:    bearerScopes(model.ScopeShelfRead),
+	}, GetShelfTemplates(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-shelf-template",
+		Summary:     "Get template",
+		Description: "Get a template with its sections and placeholder links.",
+		Path:        "/v1/templates/{templateId}",
+		Tags:        []string{"Template"},
+		Security:    bearerScopes(model.ScopeShelfRead),
+	}, GetShelfTemplate(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodDelete,
+		OperationID:   "delete-shelf-template",
+		Summary:       "Delete template",
+		Description:   "Delete a published template. Only its author and admins may delete it, built-in templates can't be deleted.",
+		Path:          "/v1/templates/{templateId}",
+		Tags:          []string{"Template"},
+		DefaultStatus: http.StatusNoContent,
+		Security:      bearerScopes(model.ScopeShelfWrite),
+	}, DeleteShelfTemplate(svc))
+
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-search",
+		Summary:     "Search",
+		Description: "Search the titles and descriptions of shelves and the titles and URLs of links in all shelves the caller can access, the best matches first. The matched words are highlighted. On MySQL, a query with a word shorter than innodb_ft_min_token_size (3 by default) or a stop word returns no results.",
+		Path:        "/v1/search",
+		Tags:        []string{"Search"},
+		Security:    bearerScopes(model.ScopeShelfRead),
+	}, Search(svc))
+
 	router.GET("/swagger", func(c *gin.Context) {
 		c.Header("Content-Type", "text/html")
+		// SwaggerUI is loaded from unpkg and started by an inline script, which the default policy forbids.
+		c.Header("Content-Security-Policy", swaggerContentSecurityPolicy)
 		c.String(http.StatusOK, `<!DOCTYPE html>
 	<html lang="en">
 	<head>
@@ -254,68 +853,84 @@ func Router(svc *domain.Service) (*gin.Engine, error) {
 	return router, nil
 }
 
-func getTrustedProxies() ([]string, error) {
-	var proxies []string
+const swaggerContentSecurityPolicy = "default-src 'self'; script-src 'self' 'unsafe-inline' https://unpkg.com; " +
+	"style-src 'self' https://unpkg.com; img-src 'self' data: https:; frame-ancestors 'none'"
 
-	if err := viper.UnmarshalKey("server.trustedProxies", &proxies); err != nil {
-		return nil, fmt.Errorf("unable to unmarshal trusted proxies; don't forget the scheme: %v", err)
-	}
-	return proxies, nil
+// publicOperation marks operations which don't need a session, like the login itself. The empty security
+// requirement also shows up in the OpenAPI document and overrides the global one.
+var publicOperation = []map[string][]string{}
+
+// requiredRoleMetadata is the key of the operation metadata which restricts an operation to a role.
+const requiredRoleMetadata = "requiredRole"
+
+// adminOperation restricts an operation to admins.
+var adminOperation = map[string]any{requiredRoleMetadata: model.RoleAdmin}
+
+// bearerScopes declares the scopes a personal access token needs for an operation. Sessions aren't
+// restricted by them.
+func bearerScopes(scopes ...string) []map[string][]string {
+	return []map[string][]string{{"bearer": scopes}}
 }
 
-func NewAuthorizationMiddleware(api huma.API) func(ctx huma.Context, next func(huma.Context)) {
+// NewAuthorizationMiddleware authenticates the bearer token of every operation which isn't public and
+// stores the principal in the context. Personal access tokens need the scopes of the operation. Admin
+// operations need the admin role, all other operations on a user, i.e. with a `userId` path parameter, are
+// restricted to that user.
+func NewAuthorizationMiddleware(api huma.API, cfg *config.Config, svc *domain.Service) func(ctx huma.Context, next func(huma.Context)) {
 	return func(ctx huma.Context, next func(huma.Context)) {
+		if cfg.Domain.Authentication.SkipAuthentication {
+			next(ctx)
+			return
+		}
 
-		if viper.GetBool("domain.authentication.skipAuthentication") {
+		security := ctx.Operation().Security
+		if security != nil && len(security) == 0 {
 			next(ctx)
 			return
 		}
 
-		err := huma.WriteErr(api, ctx, http.StatusUnauthorized, "Unauthorized")
+		token, ok := bearerToken(ctx.Header("Authorization"))
+		if !ok {
+			writeErr(api, ctx, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		principal, err := svc.AuthService.Authenticate(ctx.Context(), token)
 		if err != nil {
-			slog.Error("Failed to write unauthorized error", slog.String("error", err.Error()))
+			if !errors.Is(err, domain.ErrUnauthenticated) {
+				logging.FromContext(ctx.Context()).Error("Failed to authenticate", slog.String("error", err.Error()))
+			}
+			writeErr(api, ctx, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		var scopes []string
+		for _, requirement := range security {
+			scopes = append(scopes, requirement["bearer"]...)
+		}
+		if !principal.CanUse(scopes) {
+			writeErr(api, ctx, http.StatusForbidden, "The personal access token lacks the scopes of the operation")
+			return
+		}
+
+		if role, _ := ctx.Operation().Metadata[requiredRoleMetadata].(string); role != "" {
+			if role != principal.Role {
+				writeErr(api, ctx, http.StatusForbidden, "Forbidden")
+				return
+			}
+		} else if userId := ctx.Param("userId"); userId != "" && userId != principal.UserId {
+			writeErr(api, ctx, http.StatusForbidden, "Forbidden")
+			return
 		}
-		return
-		//
-		//if ctx.Operation().Security == nil {
-		//	next(ctx)
-		//	return
-		//}
-		//
-		//bearer := strings.TrimPrefix(ctx.Header("Authorization"), "Bearer ")
-		//if len(bearer) == 0 {
-		//	huma.WriteErr(api, ctx, http.StatusUnauthorized, "Unauthorized")
-		//	return
-		//}
-		//
-		//oidcClientId := viper.GetString("authentication.oidc.clientId")
-		//oidcAuthority := viper.GetString("authentication.oidc.issuer")
-		//runMode := viper.GetString("app.env")
-		//
-		////_, err := validateToken(context.Background(), bearer, oidcClientId, oidcAuthority, runMode)
-		////if err != nil {
-		////	huma.WriteErr(api, ctx, http.StatusUnauthorized, "Unauthorized")
-		////	return
-		////}
-		//
-		//next(ctx)
+
+		logging.SetUserID(ctx.Context(), principal.UserId)
+		next(huma.WithContext(ctx, domain.WithPrincipal(ctx.Context(), principal)))
 	}
 }
 
-// github.com/coreos/go-oidc/v3/oidc
-//func validateToken(ctx context.Context, bearer, oidcClientId, oidcAuthority, runMode string) (*oidc.Provider, error) {
-//	provider, err := oidc.NewProvider(ctx, oidcAuthority)
-//	if err != nil {
-//		return nil, fmt.Errorf("can't create new provider -> %s", err)
-//	}
-//
-//	insecureSkipSignatureCheck := runMode == "DEV"
-//	var verifier = provider.Verifier(&oidc.Config{ClientID: oidcClientId, InsecureSkipSignatureCheck: insecureSkipSignatureCheck})
-//
-//	_, err = verifier.Verify(ctx, bearer)
-//	if err != nil {
-//		return nil, fmt.Errorf("can't verify bearer -> %s", err)
-//	}
-//
-//	return provider, nil
-//}
+func writeErr(api huma.API, ctx huma.Context, status int, message string) {
+	err := huma.WriteErr(api, ctx, status, message)
+	if err != nil {
+		slog.Error("Failed to write error", slog.Int("status", status), slog.String("error", err.Error()))
+	}
+}
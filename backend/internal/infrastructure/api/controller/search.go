@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"backend/internal/domain"
+	"backend/internal/infrastructure/api/model"
+	"context"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func Search(svc *domain.Service) func(c context.Context, input *model.SearchRequest) (*model.SearchPageResponse, error) {
+	return func(c context.Context, input *model.SearchRequest) (*model.SearchPageResponse, error) {
+		results, total, err := svc.SearchService.Search(c, input.Query, input.PaginationQuery)
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to search", err)
+		}
+		if results == nil {
+			results = []model.SearchResult{}
+		}
+
+		return &model.SearchPageResponse{
+			Body: model.SearchPage{
+				Items:      results,
+				Pagination: model.NewPagination(input.PaginationQuery, total),
+			},
+		}, nil
+	}
+}
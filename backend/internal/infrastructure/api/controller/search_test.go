@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/repository"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSearchRepository matches the links of the personal shelves of the user whose title or URL contains
+// all terms, which is close enough to the full-text search for the tests.
+type fakeSearchRepository struct {
+	repository.SearchRepository
+	shelves  *fakeShelfRepository
+	sections *fakeSectionRepository
+	links    *fakeLinkRepository
+}
+
+func (r *fakeSearchRepository) Search(_ context.Context, userId string, terms []string, page model.PaginationQuery) ([]model.SearchResult, int64, error) {
+	var results []model.SearchResult
+	for _, link := range r.links.links {
+		document := strings.ToLower(link.Title + " " + link.Link)
+		if !slices.ContainsFunc(terms, func(term string) bool { return !strings.Contains(document, term) }) {
+			for _, section := range r.sections.sections {
+				shelf := r.shelves.shelves[section.ShelfId]
+				if section.Id == link.SectionId && shelf != nil && shelf.UserId == userId {
+					results = append(results, model.SearchResult{
+						Type:         model.SearchResultLink,
+						Id:           link.Id,
+						ShelfId:      shelf.Id,
+						ShelfTitle:   shelf.Title,
+						SectionId:    section.Id,
+						SectionTitle: section.Title,
+						Title:        link.Title,
+						Link:         link.Link,
+					})
+				}
+			}
+		}
+	}
+
+	offset := min(page.Offset(), len(results))
+	end := min(offset+page.PageSize, len(results))
+	return results[offset:end], int64(len(results)), nil
+}
+
+func TestSearchHighlightsTheMatchesInAccessibleShelves(t *testing.T) {
+	api, _, _, jane, john := newTwoUserTestAPI(t)
+
+	resp := api.Get("/v1/search?q=jane+missing", jane)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var page model.SearchPage
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &page))
+	require.Equal(t, int64(0), page.Total, "every word has to match")
+
+	resp = api.Get("/v1/search?q=JANE+example", jane)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &page))
+	require.Equal(t, int64(1), page.Total)
+	require.Equal(t, "link-1", page.Items[0].Id)
+	require.Equal(t, "Social", page.Items[0].SectionTitle)
+	require.Equal(t, map[string]string{
+		"link": "https://<mark>jane</mark>.<mark>example</mark>.com",
+	}, page.Items[0].Highlights)
+
+	resp = api.Get("/v1/search?q=jane", john)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &page))
+	require.Empty(t, page.Items, "john can't access the shelf of jane")
+
+	resp = api.Get("/v1/search?q=jane&page=2", jane)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &page))
+	require.Equal(t, int64(1), page.Total)
+	require.Empty(t, page.Items)
+
+	resp = api.Get("/v1/search?q=%21%3F", jane)
+	require.Equal(t, http.StatusBadRequest, resp.Code, "the query has no words")
+}
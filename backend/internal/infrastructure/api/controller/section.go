@@ -12,9 +12,9 @@ import (
 
 func CreateSection(svc *domain.Service) func(c context.Context, input *model.SectionRequestBody) (*model.SectionResponse, error) {
 	return func(c context.Context, input *model.SectionRequestBody) (*model.SectionResponse, error) {
-		section, err := svc.SectionService.Create(mapper.MapSectionBaseToSectionPointer(input.Body))
+		section, err := svc.SectionService.Create(c, mapper.MapSectionBaseToSectionPointer(input.Body))
 		if err != nil {
-			return nil, huma.Error400BadRequest("failed to create section", err)
+			return nil, accessError("failed to create section", err)
 		}
 
 		return mapper.MapSectionToSectionResponse(*section), nil
@@ -27,9 +27,9 @@ func GetSections(svc *domain.Service) func(c context.Context, input *model.Secti
 			return nil, huma.Error400BadRequest("shelfId is required", nil)
 		}
 
-		sections, err := svc.SectionService.List(input.ShelfId)
+		sections, err := svc.SectionService.List(c, input.ShelfId)
 		if err != nil {
-			return nil, huma.Error400BadRequest("failed to get section", err)
+			return nil, accessError("failed to get section", err)
 		}
 
 		return mapper.MapSectionsToSectionResponseList(sections), nil
@@ -42,9 +42,9 @@ func UpdateSection(svc *domain.Service) func(c context.Context, input *model.Sec
 			return nil, huma.Error400BadRequest("shelfId is required", nil)
 		}
 
-		section, err := svc.SectionService.Update(input.SectionId, mapper.MapSectionBaseToSectionPointer(input.Body))
+		section, err := svc.SectionService.Update(c, input.SectionId, mapper.MapSectionBaseToSectionPointer(input.Body))
 		if err != nil {
-			return nil, huma.Error400BadRequest("failed to update section", err)
+			return nil, accessError("failed to update section", err)
 		}
 
 		return mapper.MapSectionToSectionResponse(*section), nil
@@ -53,11 +53,33 @@ func UpdateSection(svc *domain.Service) func(c context.Context, input *model.Sec
 
 func DeleteSection(svc *domain.Service) func(c context.Context, input *model.SectionRequestFilter) (*struct{}, error) {
 	return func(c context.Context, input *model.SectionRequestFilter) (*struct{}, error) {
-		err := svc.SectionService.Delete(input.SectionId)
+		err := svc.SectionService.Delete(c, input.SectionId)
 		if err != nil {
-			return nil, huma.Error400BadRequest("failed to delete section", err)
+			return nil, accessError("failed to delete section", err)
 		}
 
 		return nil, nil
 	}
 }
+
+func CloneSection(svc *domain.Service) func(c context.Context, input *model.SectionCloneFilterAndBody) (*model.SectionResponse, error) {
+	return func(c context.Context, input *model.SectionCloneFilterAndBody) (*model.SectionResponse, error) {
+		section, err := svc.SectionService.Clone(c, input.SectionId, &input.Body)
+		if err != nil {
+			return nil, accessError("failed to clone section", err)
+		}
+
+		return mapper.MapSectionToSectionResponse(*section), nil
+	}
+}
+
+func MoveSection(svc *domain.Service) func(c context.Context, input *model.SectionMoveFilterAndBody) (*model.SectionResponse, error) {
+	return func(c context.Context, input *model.SectionMoveFilterAndBody) (*model.SectionResponse, error) {
+		section, err := svc.SectionService.Move(c, input.SectionId, input.Body.ShelfId)
+		if err != nil {
+			return nil, accessError("failed to move section", err)
+		}
+
+		return mapper.MapSectionToSectionResponse(*section), nil
+	}
+}
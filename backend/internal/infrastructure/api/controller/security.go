@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSOptions configures which foreign origins, like the frontend, may call the API from a browser.
+type CORSOptions struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// SecurityHeaderOptions configures the security headers of every response.
+type SecurityHeaderOptions struct {
+	ContentSecurityPolicy string
+	FrameOptions          string
+	ReferrerPolicy        string
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubDomains bool
+}
+
+// exposedHeaders are the response headers the frontend may read besides the CORS-safelisted ones.
+var exposedHeaders = []string{requestIDHeader, "Retry-After"}
+
+// NewCORSMiddleware answers the preflight requests of the allowed origins and adds the CORS headers to
+// their requests. Requests of other origins get no CORS headers, so browsers block their responses.
+func NewCORSMiddleware(options CORSOptions) (gin.HandlerFunc, error) {
+	wildcard := slices.Contains(options.AllowedOrigins, "*")
+	if wildcard && options.AllowCredentials {
+		return nil, errors.New("cors: the wildcard origin can't be combined with credentials")
+	}
+
+	methods := strings.Join(options.AllowedMethods, ", ")
+	headers := strings.Join(options.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(int(options.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		c.Writer.Header().Add("Vary", "Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		if !wildcard && !slices.Contains(options.AllowedOrigins, origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if wildcard {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if options.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			c.Header("Access-Control-Expose-Headers", strings.Join(exposedHeaders, ", "))
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Methods", methods)
+		c.Header("Access-Control-Allow-Headers", headers)
+		c.Header("Access-Control-Max-Age", maxAge)
+		c.AbortWithStatus(http.StatusNoContent)
+	}, nil
+}
+
+// NewSecurityHeadersMiddleware adds the security headers to every response. HSTS is only sent on TLS
+// connections, since browsers ignore it on plain HTTP anyway and a local setup mustn't be pinned to HTTPS.
+func NewSecurityHeadersMiddleware(options SecurityHeaderOptions) gin.HandlerFunc {
+	hsts := fmt.Sprintf("max-age=%d", int(options.HSTSMaxAge.Seconds()))
+	if options.HSTSIncludeSubDomains {
+		hsts += "; includeSubDomains"
+	}
+
+	return func(c *gin.Context) {
+		c.Header("X-Content-Type-Options", "nosniff")
+		if options.ContentSecurityPolicy != "" {
+			c.Header("Content-Security-Policy", options.ContentSecurityPolicy)
+		}
+		if options.FrameOptions != "" {
+			c.Header("X-Frame-Options", options.FrameOptions)
+		}
+		if options.ReferrerPolicy != "" {
+			c.Header("Referrer-Policy", options.ReferrerPolicy)
+		}
+		if c.Request.TLS != nil && options.HSTSMaxAge > 0 {
+			c.Header("Strict-Transport-Security", hsts)
+		}
+
+		c.Next()
+	}
+}
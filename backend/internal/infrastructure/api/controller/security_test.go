@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func newSecurityTestRouter(t *testing.T, options CORSOptions) *gin.Engine {
+	t.Helper()
+
+	cors, err := NewCORSMiddleware(options)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(NewSecurityHeadersMiddleware(SecurityHeaderOptions{
+		ContentSecurityPolicy: "default-src 'self'",
+		FrameOptions:          "DENY",
+		HSTSMaxAge:            time.Hour,
+	}), cors)
+	router.GET("/v1/shelf/:shelfId", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	router := newSecurityTestRouter(t, CORSOptions{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{"GET", "PUT"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+
+	req := httptest.NewRequest(http.MethodOptions, "/v1/shelf/shelf-1", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusNoContent, resp.Code)
+	require.Equal(t, "https://app.example.com", resp.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "true", resp.Header().Get("Access-Control-Allow-Credentials"))
+	require.Equal(t, "GET, PUT", resp.Header().Get("Access-Control-Allow-Methods"))
+	require.Equal(t, "Authorization, Content-Type", resp.Header().Get("Access-Control-Allow-Headers"))
+	require.Equal(t, "600", resp.Header().Get("Access-Control-Max-Age"))
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/shelf/shelf-1", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Equal(t, "https://app.example.com", resp.Header().Get("Access-Control-Allow-Origin"))
+	require.Contains(t, resp.Header().Get("Access-Control-Expose-Headers"), requestIDHeader)
+
+	req = httptest.NewRequest(http.MethodOptions, "/v1/shelf/shelf-1", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, http.StatusForbidden, resp.Code)
+	require.Empty(t, resp.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSMiddlewareRejectsWildcardWithCredentials(t *testing.T) {
+	_, err := NewCORSMiddleware(CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+	require.Error(t, err)
+}
+
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	router := newSecurityTestRouter(t, CORSOptions{})
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1/shelf/shelf-1", nil))
+
+	require.Equal(t, "nosniff", resp.Header().Get("X-Content-Type-Options"))
+	require.Equal(t, "DENY", resp.Header().Get("X-Frame-Options"))
+	require.Equal(t, "default-src 'self'", resp.Header().Get("Content-Security-Policy"))
+	require.Empty(t, resp.Header().Get("Strict-Transport-Security"))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/shelf/shelf-1", nil)
+	req.TLS = &tls.ConnectionState{}
+	resp = httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	require.Equal(t, "max-age=3600", resp.Header().Get("Strict-Transport-Security"))
+}
@@ -11,14 +11,14 @@ import (
 
 func CreateShelf(svc *domain.Service) func(c context.Context, input *model.ShelfRequestBody) (*model.ShelfResponse, error) {
 	return func(c context.Context, input *model.ShelfRequestBody) (*model.ShelfResponse, error) {
-		userId, err := svc.ShelfService.CreateShelf(mapper.MapShelfBaseToShelfPointer(input.Body))
+		userId, err := svc.ShelfService.CreateShelf(c, mapper.MapShelfBaseToShelfPointer(input.Body))
 		if err != nil {
-			return nil, huma.Error400BadRequest("failed to create user", err)
+			return nil, accessError("failed to create user", err)
 		}
 
-		user, err := svc.ShelfService.GetShelfById(userId)
+		user, err := svc.ShelfService.GetShelfById(c, userId)
 		if err != nil {
-			return nil, huma.Error400BadRequest("failed to get user", err)
+			return nil, accessError("failed to get user", err)
 		}
 
 		return mapper.MapShelfToShelfResponse(*user), nil
@@ -27,9 +27,9 @@ func CreateShelf(svc *domain.Service) func(c context.Context, input *model.Shelf
 
 func GetShelfById(svc *domain.Service) func(c context.Context, input *model.ShelfRequestFilter) (*model.ShelfResponse, error) {
 	return func(c context.Context, input *model.ShelfRequestFilter) (*model.ShelfResponse, error) {
-		user, err := svc.ShelfService.GetShelfById(input.ShelfId)
+		user, err := svc.ShelfService.GetShelfById(c, input.ShelfId)
 		if err != nil {
-			return nil, huma.Error400BadRequest("failed to get user", err)
+			return nil, accessError("failed to get user", err)
 		}
 
 		return mapper.MapShelfToShelfResponse(*user), nil
@@ -38,9 +38,9 @@ func GetShelfById(svc *domain.Service) func(c context.Context, input *model.Shel
 
 func UpdateShelf(svc *domain.Service) func(c context.Context, input *model.ShelfFilterFilterAndBody) (*model.ShelfResponse, error) {
 	return func(c context.Context, input *model.ShelfFilterFilterAndBody) (*model.ShelfResponse, error) {
-		shelf, err := svc.ShelfService.UpdateShelf(input.ShelfId, mapper.MapShelfBaseToShelfPointer(input.Body))
+		shelf, err := svc.ShelfService.UpdateShelf(c, input.ShelfId, mapper.MapShelfBaseToShelfPointer(input.Body))
 		if err != nil {
-			return nil, huma.Error400BadRequest("failed to update user", err)
+			return nil, accessError("failed to update user", err)
 		}
 
 		return mapper.MapShelfToShelfResponse(*shelf), nil
@@ -49,14 +49,91 @@ func UpdateShelf(svc *domain.Service) func(c context.Context, input *model.Shelf
 
 func DeleteShelf(svc *domain.Service) func(c context.Context, input *model.ShelfRequestFilter) (*struct{}, error) {
 	return func(c context.Context, input *model.ShelfRequestFilter) (*struct{}, error) {
-		user, err := svc.ShelfService.GetShelfById(input.ShelfId)
+		user, err := svc.ShelfService.GetShelfById(c, input.ShelfId)
 		if err != nil {
-			return nil, huma.Error400BadRequest("failed to get user", err)
+			return nil, accessError("failed to get user", err)
 		}
 
-		err = svc.ShelfService.DeleteShelf(user)
+		err = svc.ShelfService.DeleteShelf(c, user)
 		if err != nil {
-			return nil, huma.Error400BadRequest("failed to delete user", err)
+			return nil, accessError("failed to delete user", err)
+		}
+
+		return nil, nil
+	}
+}
+
+func VerifyShelfDomain(svc *domain.Service) func(c context.Context, input *model.ShelfRequestFilter) (*model.ShelfResponse, error) {
+	return func(c context.Context, input *model.ShelfRequestFilter) (*model.ShelfResponse, error) {
+		shelf, err := svc.ShelfService.VerifyDomain(c, input.ShelfId)
+		if err != nil {
+			return nil, accessError("failed to verify shelf domain", err)
+		}
+
+		return mapper.MapShelfToShelfResponse(*shelf), nil
+	}
+}
+
+func GetShelves(svc *domain.Service) func(c context.Context, input *model.UserRequestFilter) (*model.ShelvesResponse, error) {
+	return func(c context.Context, input *model.UserRequestFilter) (*model.ShelvesResponse, error) {
+		shelves, err := svc.ShelfService.ListShelves(c, input.UserId)
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to get shelves", err)
+		}
+
+		return &model.ShelvesResponse{Body: shelves}, nil
+	}
+}
+
+func MoveShelf(svc *domain.Service) func(c context.Context, input *model.ShelfOwnerFilterAndBody) (*model.ShelfResponse, error) {
+	return func(c context.Context, input *model.ShelfOwnerFilterAndBody) (*model.ShelfResponse, error) {
+		shelf, err := svc.ShelfService.MoveShelf(c, input.ShelfId, &input.Body)
+		if err != nil {
+			return nil, accessError("failed to move shelf", err)
+		}
+
+		return mapper.MapShelfToShelfResponse(*shelf), nil
+	}
+}
+
+func CloneShelf(svc *domain.Service) func(c context.Context, input *model.ShelfCloneFilterAndBody) (*model.ShelfResponse, error) {
+	return func(c context.Context, input *model.ShelfCloneFilterAndBody) (*model.ShelfResponse, error) {
+		shelf, err := svc.ShelfService.CloneShelf(c, input.ShelfId, &input.Body)
+		if err != nil {
+			return nil, accessError("failed to clone shelf", err)
+		}
+
+		return mapper.MapShelfToShelfResponse(*shelf), nil
+	}
+}
+
+func GetShelfShares(svc *domain.Service) func(c context.Context, input *model.ShelfRequestFilter) (*model.ShelfSharesResponse, error) {
+	return func(c context.Context, input *model.ShelfRequestFilter) (*model.ShelfSharesResponse, error) {
+		shares, err := svc.ShelfService.ListShares(c, input.ShelfId)
+		if err != nil {
+			return nil, accessError("failed to get shelf shares", err)
+		}
+
+		return &model.ShelfSharesResponse{Body: shares}, nil
+	}
+}
+
+func ShareShelf(svc *domain.Service) func(c context.Context, input *model.ShelfShareFilterAndBody) (*model.ShelfShareResponse, error) {
+	return func(c context.Context, input *model.ShelfShareFilterAndBody) (*model.ShelfShareResponse, error) {
+		share, err := svc.ShelfService.Share(c, input.ShelfId, input.MemberId, input.Body.Role)
+		if err != nil {
+			return nil, accessError("failed to share shelf", err)
+		}
+
+		return &model.ShelfShareResponse{Body: *share}, nil
+	}
+}
+
+func RevokeShelfShare(svc *domain.Service) func(c context.Context, input *model.ShelfShareFilter) (*struct{}, error) {
+	return func(c context.Context, input *model.ShelfShareFilter) (*struct{}, error) {
+		err := svc.ShelfService.RevokeShare(c, input.ShelfId, input.MemberId)
+		if err != nil {
+			return nil, accessError("failed to revoke shelf share", err)
 		}
 
 		return nil, nil
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"backend/internal/domain"
+	"backend/internal/infrastructure/api/mapper"
+	"backend/internal/infrastructure/api/model"
+	"context"
+	"errors"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func CreateShelfInvitation(svc *domain.Service) func(c context.Context, input *model.ShelfInvitationFilterAndBody) (*model.ShelfInvitationCreatedResponse, error) {
+	return func(c context.Context, input *model.ShelfInvitationFilterAndBody) (*model.ShelfInvitationCreatedResponse, error) {
+		link, invitation, err := svc.ShelfInvitationService.Invite(c, input.ShelfId, &input.Body)
+		if err != nil {
+			return nil, accessError("failed to create shelf invitation", err)
+		}
+
+		return &model.ShelfInvitationCreatedResponse{
+			Body: model.ShelfInvitationCreated{
+				ShelfInvitationOutput: mapper.MapShelfInvitationToOutput(*invitation),
+				Link:                  link,
+			},
+		}, nil
+	}
+}
+
+func GetShelfInvitations(svc *domain.Service) func(c context.Context, input *model.ShelfRequestFilter) (*model.ShelfInvitationsResponse, error) {
+	return func(c context.Context, input *model.ShelfRequestFilter) (*model.ShelfInvitationsResponse, error) {
+		invitations, err := svc.ShelfInvitationService.List(c, input.ShelfId)
+		if err != nil {
+			return nil, accessError("failed to get shelf invitations", err)
+		}
+
+		return mapper.MapShelfInvitationsToResponse(invitations), nil
+	}
+}
+
+func RevokeShelfInvitation(svc *domain.Service) func(c context.Context, input *model.ShelfInvitationFilter) (*struct{}, error) {
+	return func(c context.Context, input *model.ShelfInvitationFilter) (*struct{}, error) {
+		err := svc.ShelfInvitationService.Revoke(c, input.ShelfId, input.InvitationId)
+		if err != nil {
+			return nil, accessError("failed to revoke shelf invitation", err)
+		}
+
+		return nil, nil
+	}
+}
+
+func AcceptShelfInvitation(svc *domain.Service) func(c context.Context, input *model.AcceptShelfInvitationRequestBody) (*model.ShelfShareResponse, error) {
+	return func(c context.Context, input *model.AcceptShelfInvitationRequestBody) (*model.ShelfShareResponse, error) {
+		share, err := svc.ShelfInvitationService.Accept(c, input.UserId, input.Body.Token)
+		if errors.Is(err, domain.ErrInvitationForAnotherEmail) || errors.Is(err, domain.ErrInvitationEmailNotVerified) {
+			return nil, huma.Error403Forbidden("failed to accept shelf invitation", err)
+		}
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to accept shelf invitation", err)
+		}
+
+		return &model.ShelfShareResponse{Body: *share}, nil
+	}
+}
+
+func DeclineShelfInvitation(svc *domain.Service) func(c context.Context, input *model.DeclineShelfInvitationRequestBody) (*struct{}, error) {
+	return func(c context.Context, input *model.DeclineShelfInvitationRequestBody) (*struct{}, error) {
+		err := svc.ShelfInvitationService.Decline(c, input.Body.Token)
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to decline shelf invitation", err)
+		}
+
+		return nil, nil
+	}
+}
@@ -0,0 +1,190 @@
+package controller
+
+import (
+	"backend/internal/domain"
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/repository"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeShelfInvitationRepository struct {
+	repository.ShelfInvitationRepository
+	mu          sync.Mutex
+	invitations map[string]model.ShelfInvitation
+}
+
+func (r *fakeShelfInvitationRepository) Create(_ context.Context, i *model.ShelfInvitation) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i.Id = fmt.Sprintf("invitation-%d", len(r.invitations)+1)
+	r.invitations[i.Id] = *i
+	return i.Id, nil
+}
+
+func (r *fakeShelfInvitationRepository) ListPendingByShelfId(_ context.Context, shelfId string, now time.Time) ([]model.ShelfInvitation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var invitations []model.ShelfInvitation
+	for _, invitation := range r.invitations {
+		if invitation.ShelfId == shelfId && invitation.AcceptedAt == nil && invitation.DeclinedAt == nil && invitation.ExpiresAt.After(now) {
+			invitations = append(invitations, invitation)
+		}
+	}
+	return invitations, nil
+}
+
+func (r *fakeShelfInvitationRepository) GetByHash(_ context.Context, tokenHash string) (*model.ShelfInvitation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, invitation := range r.invitations {
+		if invitation.TokenHash == tokenHash {
+			return &invitation, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeShelfInvitationRepository) Accept(_ context.Context, id, userId string, acceptedAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invitation, ok := r.invitations[id]
+	if !ok || invitation.AcceptedAt != nil || invitation.DeclinedAt != nil {
+		return false, nil
+	}
+	invitation.AcceptedAt = &acceptedAt
+	invitation.AcceptedBy = userId
+	r.invitations[id] = invitation
+	return true, nil
+}
+
+func (r *fakeShelfInvitationRepository) Decline(_ context.Context, id string, declinedAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	invitation, ok := r.invitations[id]
+	if !ok || invitation.AcceptedAt != nil || invitation.DeclinedAt != nil {
+		return false, nil
+	}
+	invitation.DeclinedAt = &declinedAt
+	r.invitations[id] = invitation
+	return true, nil
+}
+
+var invitationTokenPattern = regexp.MustCompile(`token=([A-Za-z0-9_-]+)`)
+
+func invitationToken(t *testing.T, link string) string {
+	match := invitationTokenPattern.FindStringSubmatch(link)
+	require.NotNil(t, match, link)
+	token, err := url.QueryUnescape(match[1])
+	require.NoError(t, err)
+	return token
+}
+
+func TestLinkInvitationsShareTheShelfOnce(t *testing.T) {
+	api, _, _, jane, john := newTwoUserTestAPI(t)
+
+	resp := api.Post("/v1/shelf/shelf-1/invitations", john, map[string]any{"role": "viewer"})
+	require.Equal(t, http.StatusForbidden, resp.Code, "only owners invite")
+
+	resp = api.Post("/v1/shelf/shelf-1/invitations", jane, map[string]any{"role": "viewer"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var created model.ShelfInvitationCreated
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+	require.Equal(t, "user-1", created.InvitedBy)
+	require.Contains(t, created.Link, "http://localhost:3000/invitation?token=")
+
+	resp = api.Get("/v1/shelf/shelf-1/invitations", jane)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.Contains(t, resp.Body.String(), created.Id)
+	require.NotContains(t, resp.Body.String(), "token")
+
+	token := invitationToken(t, created.Link)
+	resp = api.Post("/v1/user/user-2/invitations/accept", john, map[string]any{"token": token})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.Contains(t, resp.Body.String(), `"role":"viewer"`)
+	resp = api.Get("/v1/shelf/shelf-1", john)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	resp = api.Post("/v1/user/user-2/invitations/accept", john, map[string]any{"token": token})
+	require.Equal(t, http.StatusBadRequest, resp.Code, "invitations are only answered once")
+	resp = api.Get("/v1/shelf/shelf-1/invitations", jane)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NotContains(t, resp.Body.String(), created.Id)
+}
+
+func TestMailedInvitationsCanOnlyBeAcceptedByTheInvitee(t *testing.T) {
+	api, svc, mailer, jane, john := newTwoUserTestAPI(t)
+	svc.WaitForBackgroundTasks()
+	sent := len(mailer.Messages())
+
+	resp := api.Post("/v1/shelf/shelf-1/invitations", jane, map[string]any{"role": "editor", "email": "John@example.com"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NotContains(t, resp.Body.String(), "link", "mailed invitations don't return the token")
+
+	svc.WaitForBackgroundTasks()
+	messages := mailer.Messages()
+	require.Len(t, messages, sent+1)
+	message := messages[sent]
+	require.Equal(t, "john@example.com", message.To)
+	require.Equal(t, "Jane Doe invited you to the shelf Jane", message.Subject)
+	token := invitationToken(t, message.Text)
+
+	resp = api.Post("/v1/user/user-1/invitations/accept", jane, map[string]any{"token": token})
+	require.Equal(t, http.StatusForbidden, resp.Code, "the invitation belongs to another address")
+
+	resp = api.Post("/v1/invitation/decline", map[string]any{"token": token})
+	require.Equal(t, http.StatusNoContent, resp.Code, resp.Body.String())
+	resp = api.Post("/v1/user/user-2/invitations/accept", john, map[string]any{"token": token})
+	require.Equal(t, http.StatusBadRequest, resp.Code, "declined invitations can't be accepted")
+	resp = api.Get("/v1/shelf/shelf-1", john)
+	require.Equal(t, http.StatusForbidden, resp.Code)
+}
+
+func TestMailedInvitationsNeedAVerifiedEmail(t *testing.T) {
+	api, svc, mailer, jane, john := newTwoUserTestAPI(t)
+
+	resp := api.Post("/v1/shelf/shelf-1/invitations", jane, map[string]any{"role": "viewer", "email": "john@example.com"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	svc.WaitForBackgroundTasks()
+
+	var verification, invitation string
+	for _, message := range mailer.Messages() {
+		switch {
+		case message.To == "john@example.com" && strings.Contains(message.Text, "/verify-email?token="):
+			verification = invitationToken(t, message.Text)
+		case message.To == "john@example.com" && strings.Contains(message.Text, "/invitation?token="):
+			invitation = invitationToken(t, message.Text)
+		}
+	}
+	require.NotEmpty(t, verification)
+	require.NotEmpty(t, invitation)
+
+	resp = api.Post("/v1/user/user-2/invitations/accept", john, map[string]any{"token": invitation})
+	require.Equal(t, http.StatusForbidden, resp.Code, resp.Body.String())
+	require.Contains(t, resp.Body.String(), domain.ErrInvitationEmailNotVerified.Error())
+
+	require.NoError(t, svc.UserService.VerifyEmail(context.Background(), verification))
+	code, output := loginAs(api, "john@example.com", "correct horse 2")
+	require.Equal(t, http.StatusOK, code)
+	john = "Authorization: Bearer " + output.Token
+
+	resp = api.Post("/v1/user/user-2/invitations/accept", john, map[string]any{"token": invitation})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	resp = api.Get("/v1/shelf/shelf-1", john)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+}
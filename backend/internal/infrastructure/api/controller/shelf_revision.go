@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"backend/internal/domain"
+	"backend/internal/infrastructure/api/model"
+	"context"
+)
+
+func GetShelfRevisions(svc *domain.Service) func(c context.Context, input *model.ShelfRevisionListRequest) (*model.ShelfRevisionPageResponse, error) {
+	return func(c context.Context, input *model.ShelfRevisionListRequest) (*model.ShelfRevisionPageResponse, error) {
+		revisions, total, err := svc.ShelfRevisionService.List(c, input.ShelfId, input.PaginationQuery)
+		if err != nil {
+			return nil, accessError("failed to get shelf revisions", err)
+		}
+		if revisions == nil {
+			revisions = []model.ShelfRevision{}
+		}
+
+		return &model.ShelfRevisionPageResponse{
+			Body: model.ShelfRevisionPage{
+				Items:      revisions,
+				Pagination: model.NewPagination(input.PaginationQuery, total),
+			},
+		}, nil
+	}
+}
+
+func GetShelfRevision(svc *domain.Service) func(c context.Context, input *model.ShelfRevisionFilter) (*model.ShelfRevisionResponse, error) {
+	return func(c context.Context, input *model.ShelfRevisionFilter) (*model.ShelfRevisionResponse, error) {
+		revision, err := svc.ShelfRevisionService.Get(c, input.ShelfId, input.RevisionId)
+		if err != nil {
+			return nil, accessError("failed to get shelf revision", err)
+		}
+
+		return &model.ShelfRevisionResponse{Body: *revision}, nil
+	}
+}
+
+func DiffShelfRevisions(svc *domain.Service) func(c context.Context, input *model.ShelfRevisionDiffRequest) (*model.ShelfRevisionDiffResponse, error) {
+	return func(c context.Context, input *model.ShelfRevisionDiffRequest) (*model.ShelfRevisionDiffResponse, error) {
+		diff, err := svc.ShelfRevisionService.Diff(c, input.ShelfId, input.From, input.To)
+		if err != nil {
+			return nil, accessError("failed to diff shelf revisions", err)
+		}
+
+		return &model.ShelfRevisionDiffResponse{Body: *diff}, nil
+	}
+}
+
+func RestoreShelfRevision(svc *domain.Service) func(c context.Context, input *model.ShelfRevisionFilter) (*model.ShelfResponse, error) {
+	return func(c context.Context, input *model.ShelfRevisionFilter) (*model.ShelfResponse, error) {
+		shelf, err := svc.ShelfRevisionService.Restore(c, input.ShelfId, input.RevisionId)
+		if err != nil {
+			return nil, accessError("failed to restore shelf revision", err)
+		}
+
+		return &model.ShelfResponse{Body: *shelf}, nil
+	}
+}
@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/repository"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeShelfRevisionRepository struct {
+	repository.ShelfRevisionRepository
+	mu        sync.Mutex
+	revisions []model.ShelfRevision
+	created   int
+	shelves   *fakeShelfRepository
+	sections  *fakeSectionRepository
+	links     *fakeLinkRepository
+}
+
+func (r *fakeShelfRevisionRepository) Create(_ context.Context, rev *model.ShelfRevision) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.created++
+	rev.Id = fmt.Sprintf("revision-%d", r.created)
+	r.revisions = append(r.revisions, *rev)
+	return rev.Id, nil
+}
+
+func (r *fakeShelfRevisionRepository) UpdateSnapshot(_ context.Context, id string, snapshot *model.ShelfSnapshot, updatedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.revisions {
+		if r.revisions[i].Id == id {
+			r.revisions[i].Snapshot = snapshot
+			r.revisions[i].UpdatedAt = updatedAt
+		}
+	}
+	return nil
+}
+
+func (r *fakeShelfRevisionRepository) Latest(_ context.Context, shelfId string) (*model.ShelfRevision, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, revision := range slices.Backward(r.revisions) {
+		if revision.ShelfId == shelfId {
+			return &revision, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeShelfRevisionRepository) Get(_ context.Context, shelfId, id string) (*model.ShelfRevision, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, revision := range r.revisions {
+		if revision.ShelfId == shelfId && revision.Id == id {
+			return &revision, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeShelfRevisionRepository) List(_ context.Context, shelfId string, page model.PaginationQuery) ([]model.ShelfRevision, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var revisions []model.ShelfRevision
+	for _, revision := range slices.Backward(r.revisions) {
+		if revision.ShelfId == shelfId {
+			revision.Snapshot = nil
+			revisions = append(revisions, revision)
+		}
+	}
+
+	offset := min(page.Offset(), len(revisions))
+	end := min(offset+page.PageSize, len(revisions))
+	return revisions[offset:end], int64(len(revisions)), nil
+}
+
+func (r *fakeShelfRevisionRepository) Prune(_ context.Context, _ string, _ int) (int64, error) {
+	return 0, nil
+}
+
+// Restore replaces the tree of shelf-1, the only shelf of the fake repositories with sections.
+func (r *fakeShelfRevisionRepository) Restore(_ context.Context, rev *model.ShelfRevision) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	shelf := r.shelves.shelves[rev.ShelfId]
+	shelf.Title = rev.Snapshot.Title
+	shelf.Description = rev.Snapshot.Description
+	shelf.Theme = rev.Snapshot.Theme
+	shelf.Icon = rev.Snapshot.Icon
+
+	r.sections.sections = nil
+	r.links.links = nil
+	for _, section := range rev.Snapshot.Sections {
+		r.sections.sections = append(r.sections.sections, model.Section{
+			Id:          section.Id,
+			SectionBase: model.SectionBase{Title: section.Title, ShelfId: rev.ShelfId},
+		})
+		for _, link := range section.Links {
+			r.links.links = append(r.links.links, model.Link{
+				Id:       link.Id,
+				LinkBase: model.LinkBase{Title: link.Title, Link: link.Link, Icon: link.Icon, Color: link.Color, SectionId: section.Id},
+			})
+		}
+	}
+
+	r.created++
+	rev.Id = fmt.Sprintf("revision-%d", r.created)
+	r.revisions = append(r.revisions, *rev)
+	return nil
+}
+
+func TestShelfRevisionsCanBeComparedAndRestored(t *testing.T) {
+	api, _, _, jane, john := newTwoUserTestAPI(t)
+	link := map[string]any{"title": "Blog", "link": "https://jane.example.com", "icon": "", "color": "#000000", "sectionId": "section-1"}
+
+	resp := api.Put("/v1/shelf/shelf-1/shares/user-2", jane, map[string]any{"role": "viewer"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	link["title"] = "Diary"
+	resp = api.Put("/v1/link/link-1", jane, link)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	link["title"] = "Journal"
+	resp = api.Put("/v1/link/link-1", jane, link)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	resp = api.Get("/v1/shelf/shelf-1/revisions", john)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var page model.ShelfRevisionPage
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &page))
+	require.Equal(t, int64(1), page.Total, "the changes of an editing session are batched")
+	require.Equal(t, "user-1", page.Items[0].AuthorId)
+	janes := page.Items[0].Id
+
+	resp = api.Post("/v1/shelf/shelf-1/revisions/"+janes+"/restore", john)
+	require.Equal(t, http.StatusForbidden, resp.Code, "viewers can't restore")
+
+	resp = api.Put("/v1/shelf/shelf-1/shares/user-2", jane, map[string]any{"role": "editor"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	link["link"] = "https://john.example.com"
+	resp = api.Put("/v1/link/link-1", john, link)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	resp = api.Get("/v1/shelf/shelf-1/revisions/diff?from="+janes, john)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var diff model.ShelfRevisionDiff
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &diff))
+	require.Empty(t, diff.Shelf)
+	require.Empty(t, diff.Sections)
+	require.Equal(t, []model.RevisionEntityDiff{{
+		Id:      "link-1",
+		Change:  model.RevisionChangeChanged,
+		Changes: map[string]model.AuditChange{"link": {Before: "https://jane.example.com", After: "https://john.example.com"}},
+	}}, diff.Links)
+
+	resp = api.Post("/v1/shelf/shelf-1/revisions/"+janes+"/restore", jane)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	resp = api.Get("/v1/shelf/shelf-1/revisions/diff?from="+janes, jane)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &diff))
+	require.Empty(t, diff.Links, "the shelf is back to the revision")
+
+	resp = api.Get("/v1/shelf/shelf-1/revisions", jane)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &page))
+	require.Equal(t, int64(3), page.Total)
+	require.Equal(t, janes, page.Items[0].RestoredFrom)
+	require.Equal(t, "user-2", page.Items[1].AuthorId)
+}
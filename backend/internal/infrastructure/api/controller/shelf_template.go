@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"backend/internal/domain"
+	"backend/internal/infrastructure/api/mapper"
+	"backend/internal/infrastructure/api/model"
+	"context"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func GetShelfTemplates(svc *domain.Service) func(c context.Context, input *struct{}) (*model.ShelfTemplatesResponse, error) {
+	return func(c context.Context, input *struct{}) (*model.ShelfTemplatesResponse, error) {
+		templates, err := svc.ShelfTemplateService.List(c)
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to get templates", err)
+		}
+
+		return &model.ShelfTemplatesResponse{Body: templates}, nil
+	}
+}
+
+func GetShelfTemplate(svc *domain.Service) func(c context.Context, input *model.ShelfTemplateFilter) (*model.ShelfTemplateResponse, error) {
+	return func(c context.Context, input *model.ShelfTemplateFilter) (*model.ShelfTemplateResponse, error) {
+		template, err := svc.ShelfTemplateService.Get(c, input.TemplateId)
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to get template", err)
+		}
+
+		return &model.ShelfTemplateResponse{Body: *template}, nil
+	}
+}
+
+func PublishShelfTemplate(svc *domain.Service) func(c context.Context, input *model.ShelfTemplatePublishFilterAndBody) (*model.ShelfTemplateResponse, error) {
+	return func(c context.Context, input *model.ShelfTemplatePublishFilterAndBody) (*model.ShelfTemplateResponse, error) {
+		template, err := svc.ShelfTemplateService.Publish(c, input.ShelfId, &input.Body)
+		if err != nil {
+			return nil, accessError("failed to publish template", err)
+		}
+
+		return &model.ShelfTemplateResponse{Body: *template}, nil
+	}
+}
+
+func DeleteShelfTemplate(svc *domain.Service) func(c context.Context, input *model.ShelfTemplateFilter) (*struct{}, error) {
+	return func(c context.Context, input *model.ShelfTemplateFilter) (*struct{}, error) {
+		err := svc.ShelfTemplateService.Delete(c, input.TemplateId)
+		if err != nil {
+			return nil, accessError("failed to delete template", err)
+		}
+
+		return nil, nil
+	}
+}
+
+func CreateShelfFromTemplate(svc *domain.Service) func(c context.Context, input *model.ShelfFromTemplateFilterAndBody) (*model.ShelfResponse, error) {
+	return func(c context.Context, input *model.ShelfFromTemplateFilterAndBody) (*model.ShelfResponse, error) {
+		shelf, err := svc.ShelfTemplateService.CreateShelf(c, input.UserId, &input.Body)
+		if err != nil {
+			return nil, accessError("failed to create shelf from template", err)
+		}
+
+		return mapper.MapShelfToShelfResponse(*shelf), nil
+	}
+}
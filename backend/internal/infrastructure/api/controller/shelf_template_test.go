@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/repository"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeShelfTemplateRepository struct {
+	repository.ShelfTemplateRepository
+	mu        sync.Mutex
+	templates map[string]model.ShelfTemplate
+}
+
+func (r *fakeShelfTemplateRepository) Create(_ context.Context, t *model.ShelfTemplate) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t.Id = fmt.Sprintf("template-%d", len(r.templates)+1)
+	r.templates[t.Id] = *t
+	return t.Id, nil
+}
+
+func (r *fakeShelfTemplateRepository) Get(_ context.Context, id string) (*model.ShelfTemplate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	template, ok := r.templates[id]
+	if !ok {
+		return nil, nil
+	}
+	return &template, nil
+}
+
+func (r *fakeShelfTemplateRepository) List(_ context.Context) ([]model.ShelfTemplate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var templates []model.ShelfTemplate
+	for _, template := range r.templates {
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+func (r *fakeShelfTemplateRepository) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.templates, id)
+	return nil
+}
+
+func (r *fakeShelfRepository) Import(ctx context.Context, s *model.Shelf, _ []model.SectionExport) (string, error) {
+	return r.Create(ctx, s)
+}
+
+func TestShelvesCanBeCreatedFromBuiltInAndPublishedTemplates(t *testing.T) {
+	api, _, _, jane, john := newTwoUserTestAPI(t)
+
+	resp := api.Get("/v1/templates", john)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var templates []model.ShelfTemplate
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &templates))
+	require.NotEmpty(t, templates)
+	for _, template := range templates {
+		require.True(t, template.BuiltIn)
+		require.NotEmpty(t, template.Sections, template.Id)
+	}
+
+	resp = api.Post("/v1/user/user-2/shelves/from-template", john, map[string]any{
+		"templateId": "developer", "title": "Project", "path": "john/project",
+	})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var shelf model.Shelf
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &shelf))
+	require.Equal(t, "user-2", shelf.UserId)
+	require.Equal(t, "Project", shelf.Title)
+	require.Equal(t, "dark", shelf.Theme)
+
+	resp = api.Post("/v1/shelf/shelf-1/template", john, map[string]any{"name": "Jane's links"})
+	require.Equal(t, http.StatusForbidden, resp.Code, "only owners publish shelves")
+	resp = api.Post("/v1/shelf/shelf-1/template", jane, map[string]any{"name": "Jane's links"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var published model.ShelfTemplate
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &published))
+	require.False(t, published.BuiltIn)
+	require.Equal(t, "user-1", published.AuthorId)
+
+	resp = api.Get("/v1/templates", john)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &templates))
+	require.True(t, slices.ContainsFunc(templates, func(template model.ShelfTemplate) bool {
+		return template.Id == published.Id
+	}), "published templates are visible to everybody")
+
+	resp = api.Post("/v1/user/user-2/shelves/from-template", john, map[string]any{
+		"templateId": published.Id, "title": "Links", "path": "john/links",
+	})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	resp = api.Delete("/v1/templates/developer", jane)
+	require.Equal(t, http.StatusBadRequest, resp.Code, "built-in templates can't be deleted")
+	resp = api.Delete("/v1/templates/"+published.Id, john)
+	require.Equal(t, http.StatusForbidden, resp.Code, "only the author deletes a template")
+	resp = api.Delete("/v1/templates/"+published.Id, jane)
+	require.Equal(t, http.StatusNoContent, resp.Code, resp.Body.String())
+}
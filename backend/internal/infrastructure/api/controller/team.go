@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"backend/internal/domain"
+	"backend/internal/infrastructure/api/model"
+	"context"
+	"errors"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// accessError answers actions which need a better role on a shelf or team with 403 and everything else
+// with 400.
+func accessError(message string, err error) error {
+	if errors.Is(err, domain.ErrForbidden) {
+		return huma.Error403Forbidden(message, err)
+	}
+	return huma.Error400BadRequest(message, err)
+}
+
+func CreateTeam(svc *domain.Service) func(c context.Context, input *model.TeamCreateFilterAndBody) (*model.TeamResponse, error) {
+	return func(c context.Context, input *model.TeamCreateFilterAndBody) (*model.TeamResponse, error) {
+		team, err := svc.TeamService.Create(c, input.UserId, input.Body.Name)
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to create team", err)
+		}
+
+		return &model.TeamResponse{Body: *team}, nil
+	}
+}
+
+func GetTeams(svc *domain.Service) func(c context.Context, input *model.UserRequestFilter) (*model.TeamsResponse, error) {
+	return func(c context.Context, input *model.UserRequestFilter) (*model.TeamsResponse, error) {
+		teams, err := svc.TeamService.List(c, input.UserId)
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to get teams", err)
+		}
+
+		return &model.TeamsResponse{Body: teams}, nil
+	}
+}
+
+func GetTeam(svc *domain.Service) func(c context.Context, input *model.TeamRequestFilter) (*model.TeamResponse, error) {
+	return func(c context.Context, input *model.TeamRequestFilter) (*model.TeamResponse, error) {
+		team, err := svc.TeamService.Get(c, input.TeamId)
+		if err != nil {
+			return nil, accessError("failed to get team", err)
+		}
+
+		return &model.TeamResponse{Body: *team}, nil
+	}
+}
+
+func UpdateTeam(svc *domain.Service) func(c context.Context, input *model.TeamFilterAndBody) (*model.TeamResponse, error) {
+	return func(c context.Context, input *model.TeamFilterAndBody) (*model.TeamResponse, error) {
+		team, err := svc.TeamService.Rename(c, input.TeamId, input.Body.Name)
+		if err != nil {
+			return nil, accessError("failed to update team", err)
+		}
+
+		return &model.TeamResponse{Body: *team}, nil
+	}
+}
+
+func DeleteTeam(svc *domain.Service) func(c context.Context, input *model.TeamRequestFilter) (*struct{}, error) {
+	return func(c context.Context, input *model.TeamRequestFilter) (*struct{}, error) {
+		err := svc.TeamService.Delete(c, input.TeamId)
+		if errors.Is(err, domain.ErrTeamOwnsShelves) {
+			return nil, huma.Error409Conflict("failed to delete team", err)
+		}
+		if err != nil {
+			return nil, accessError("failed to delete team", err)
+		}
+
+		return nil, nil
+	}
+}
+
+func GetTeamMembers(svc *domain.Service) func(c context.Context, input *model.TeamRequestFilter) (*model.TeamMembersResponse, error) {
+	return func(c context.Context, input *model.TeamRequestFilter) (*model.TeamMembersResponse, error) {
+		members, err := svc.TeamService.ListMembers(c, input.TeamId)
+		if err != nil {
+			return nil, accessError("failed to get team members", err)
+		}
+
+		return &model.TeamMembersResponse{Body: members}, nil
+	}
+}
+
+func SetTeamMember(svc *domain.Service) func(c context.Context, input *model.TeamMemberFilterAndBody) (*model.TeamMemberResponse, error) {
+	return func(c context.Context, input *model.TeamMemberFilterAndBody) (*model.TeamMemberResponse, error) {
+		member, err := svc.TeamService.SetMember(c, input.TeamId, input.MemberId, input.Body.Role)
+		if err != nil {
+			return nil, accessError("failed to set team member", err)
+		}
+
+		return &model.TeamMemberResponse{Body: *member}, nil
+	}
+}
+
+func RemoveTeamMember(svc *domain.Service) func(c context.Context, input *model.TeamMemberFilter) (*struct{}, error) {
+	return func(c context.Context, input *model.TeamMemberFilter) (*struct{}, error) {
+		err := svc.TeamService.RemoveMember(c, input.TeamId, input.MemberId)
+		if err != nil {
+			return nil, accessError("failed to remove team member", err)
+		}
+
+		return nil, nil
+	}
+}
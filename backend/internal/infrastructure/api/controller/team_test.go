@@ -0,0 +1,302 @@
+package controller
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/repository"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTeamRepository struct {
+	repository.TeamRepository
+	mu      sync.Mutex
+	teams   map[string]model.Team
+	members map[string]model.TeamMember
+	shelves *fakeShelfRepository
+}
+
+func (r *fakeTeamRepository) Create(_ context.Context, t *model.Team, ownerId string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t.Id = fmt.Sprintf("team-%d", len(r.teams)+1)
+	r.teams[t.Id] = *t
+	r.members[t.Id+"/"+ownerId] = model.TeamMember{TeamId: t.Id, UserId: ownerId, Role: model.ShelfRoleOwner}
+	return t.Id, nil
+}
+
+func (r *fakeTeamRepository) Get(_ context.Context, id string) (*model.Team, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	team, ok := r.teams[id]
+	if !ok {
+		return nil, nil
+	}
+	return &team, nil
+}
+
+func (r *fakeTeamRepository) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.teams, id)
+	for key, member := range r.members {
+		if member.TeamId == id {
+			delete(r.members, key)
+		}
+	}
+	return nil
+}
+
+func (r *fakeTeamRepository) CountShelves(_ context.Context, id string) (int, error) {
+	count := 0
+	for _, shelf := range r.shelves.shelves {
+		if shelf.TeamId == id {
+			count++
+		}
+	}
+	for _, trashed := range r.shelves.trashed {
+		if trashed.shelf.TeamId == id {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *fakeTeamRepository) ListMembers(_ context.Context, teamId string) ([]model.TeamMember, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var members []model.TeamMember
+	for _, member := range r.members {
+		if member.TeamId == teamId {
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+func (r *fakeTeamRepository) GetMember(_ context.Context, teamId, userId string) (*model.TeamMember, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	member, ok := r.members[teamId+"/"+userId]
+	if !ok {
+		return nil, nil
+	}
+	return &member, nil
+}
+
+func (r *fakeTeamRepository) AddMember(_ context.Context, m *model.TeamMember) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.members[m.TeamId+"/"+m.UserId] = *m
+	return nil
+}
+
+func (r *fakeTeamRepository) SetMemberRole(_ context.Context, teamId, userId, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	member := r.members[teamId+"/"+userId]
+	member.Role = role
+	r.members[teamId+"/"+userId] = member
+	return nil
+}
+
+func (r *fakeTeamRepository) RemoveMember(_ context.Context, teamId, userId string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.members[teamId+"/"+userId]
+	delete(r.members, teamId+"/"+userId)
+	return ok, nil
+}
+
+type fakeShelfShareRepository struct {
+	repository.ShelfShareRepository
+	mu     sync.Mutex
+	shares map[string]model.ShelfShare
+}
+
+func (r *fakeShelfShareRepository) Get(_ context.Context, shelfId, userId string) (*model.ShelfShare, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	share, ok := r.shares[shelfId+"/"+userId]
+	if !ok {
+		return nil, nil
+	}
+	return &share, nil
+}
+
+func (r *fakeShelfShareRepository) Create(_ context.Context, s *model.ShelfShare) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.shares[s.ShelfId+"/"+s.UserId] = *s
+	return nil
+}
+
+func (r *fakeShelfShareRepository) SetRole(_ context.Context, shelfId, userId, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	share := r.shares[shelfId+"/"+userId]
+	share.Role = role
+	r.shares[shelfId+"/"+userId] = share
+	return nil
+}
+
+func (r *fakeShelfShareRepository) Delete(_ context.Context, shelfId, userId string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.shares[shelfId+"/"+userId]
+	delete(r.shares, shelfId+"/"+userId)
+	return ok, nil
+}
+
+func (r *fakeShelfRepository) Create(_ context.Context, s *model.Shelf) (string, error) {
+	s.Id = fmt.Sprintf("shelf-%d", len(r.shelves)+1)
+	r.shelves[s.Id] = s
+	return s.Id, nil
+}
+
+func (r *fakeSectionRepository) Get(_ context.Context, id string) (*model.Section, error) {
+	for _, section := range r.sections {
+		if section.Id == id {
+			return &section, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeLinkRepository) Get(_ context.Context, id string) (*model.Link, error) {
+	for _, link := range r.links {
+		if link.Id == id {
+			return &link, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeLinkRepository) Update(_ context.Context, l *model.Link) error {
+	for i, link := range r.links {
+		if link.Id == l.Id {
+			r.links[i].LinkBase = l.LinkBase
+		}
+	}
+	return nil
+}
+
+func TestTeamShelvesRespectTheRolesOfTheMembers(t *testing.T) {
+	api, _, _, jane, john := newTwoUserTestAPI(t)
+
+	resp := api.Post("/v1/user/user-1/teams", jane, map[string]any{"name": "Docs"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var team model.Team
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &team))
+
+	shelf := map[string]any{"title": "Docs", "path": "docs", "domain": "", "description": "", "theme": "", "icon": "", "teamId": team.Id}
+	resp = api.Post("/v1/shelf", john, shelf)
+	require.Equal(t, http.StatusForbidden, resp.Code, "only editors of the team create team shelves")
+	resp = api.Post("/v1/shelf", jane, shelf)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var created model.Shelf
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+	require.Equal(t, team.Id, created.TeamId)
+	require.Empty(t, created.UserId, "team shelves belong to the team alone")
+
+	resp = api.Get("/v1/shelf/"+created.Id, john)
+	require.Equal(t, http.StatusForbidden, resp.Code)
+
+	resp = api.Put("/v1/team/"+team.Id+"/members/user-2", john, map[string]any{"role": "owner"})
+	require.Equal(t, http.StatusForbidden, resp.Code, "only owners manage the members")
+	resp = api.Put("/v1/team/"+team.Id+"/members/user-2", jane, map[string]any{"role": "viewer"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	resp = api.Get("/v1/shelf/"+created.Id, john)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	resp = api.Get("/v1/team/"+team.Id+"/members", john)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	resp = api.Put("/v1/team/"+team.Id+"/members/user-2", jane, map[string]any{"role": "editor"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	resp = api.Delete("/v1/shelf/"+created.Id, john)
+	require.Equal(t, http.StatusForbidden, resp.Code, "only owners delete shelves")
+
+	resp = api.Delete("/v1/team/"+team.Id+"/members/user-1", jane)
+	require.Equal(t, http.StatusBadRequest, resp.Code, "the last owner can't leave")
+	resp = api.Delete("/v1/team/"+team.Id+"/members/user-2", john)
+	require.Equal(t, http.StatusNoContent, resp.Code, "members may leave")
+	resp = api.Get("/v1/shelf/"+created.Id, john)
+	require.Equal(t, http.StatusForbidden, resp.Code)
+}
+
+func TestTeamsWithShelvesCantBeDeleted(t *testing.T) {
+	api, _, _, jane, _ := newTwoUserTestAPI(t)
+
+	resp := api.Post("/v1/user/user-1/teams", jane, map[string]any{"name": "Docs"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var team model.Team
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &team))
+
+	shelf := map[string]any{"title": "Docs", "path": "docs", "domain": "", "description": "", "theme": "", "icon": "", "teamId": team.Id}
+	resp = api.Post("/v1/shelf", jane, shelf)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var created model.Shelf
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &created))
+
+	resp = api.Delete("/v1/team/"+team.Id, jane)
+	require.Equal(t, http.StatusConflict, resp.Code, resp.Body.String())
+	resp = api.Delete("/v1/shelf/"+created.Id, jane)
+	require.Equal(t, http.StatusNoContent, resp.Code, resp.Body.String())
+	resp = api.Delete("/v1/team/"+team.Id, jane)
+	require.Equal(t, http.StatusConflict, resp.Code, "shelves in the trash would be purged without passing it")
+
+	resp = api.Post("/v1/user/user-1/teams", jane, map[string]any{"name": "Empty"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var empty model.Team
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &empty))
+	resp = api.Delete("/v1/team/"+empty.Id, jane)
+	require.Equal(t, http.StatusNoContent, resp.Code, resp.Body.String())
+	resp = api.Get("/v1/team/"+empty.Id+"/members", jane)
+	require.NotEqual(t, http.StatusOK, resp.Code)
+}
+
+func TestSharedShelvesGrantTheRoleOfTheShare(t *testing.T) {
+	api, _, _, jane, john := newTwoUserTestAPI(t)
+	link := map[string]any{"title": "Blog", "link": "https://john.example.com", "icon": "", "color": "#000000", "sectionId": "section-1"}
+
+	resp := api.Get("/v1/shelf/shelf-1", john)
+	require.Equal(t, http.StatusForbidden, resp.Code)
+	resp = api.Put("/v1/shelf/shelf-1/shares/user-2", john, map[string]any{"role": "editor"})
+	require.Equal(t, http.StatusForbidden, resp.Code, "only owners share shelves")
+
+	resp = api.Put("/v1/shelf/shelf-1/shares/user-2", jane, map[string]any{"role": "viewer"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	resp = api.Get("/v1/shelf/shelf-1", john)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	resp = api.Put("/v1/link/link-1", john, link)
+	require.Equal(t, http.StatusForbidden, resp.Code, "viewers can't change links")
+
+	resp = api.Put("/v1/shelf/shelf-1/shares/user-2", jane, map[string]any{"role": "editor"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	resp = api.Put("/v1/link/link-1", john, link)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.Contains(t, resp.Body.String(), "john.example.com")
+
+	resp = api.Delete("/v1/shelf/shelf-1/shares/user-2", john)
+	require.Equal(t, http.StatusNoContent, resp.Code, "users may give up their shares")
+	resp = api.Get("/v1/shelf/shelf-1", john)
+	require.Equal(t, http.StatusForbidden, resp.Code)
+}
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"backend/internal/infrastructure/logging"
+	"backend/internal/infrastructure/tracing"
+	"log/slog"
+	"net/http"
+
+	"github.com/danielgtaylor/huma/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewTracingMiddleware starts a server span per huma operation which continues the W3C trace context of
+// the incoming request, so the spans of the domain and repository layers become its children. The trace
+// ID is added to the request-scoped logger to jump from a log line to its trace.
+func NewTracingMiddleware() func(ctx huma.Context, next func(huma.Context)) {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		parent := otel.GetTextMapPropagator().Extract(ctx.Context(), headerCarrier{ctx: ctx})
+
+		spanCtx, span := tracing.Start(parent, ctx.Operation().OperationID,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(ctx.Method()),
+				semconv.HTTPRoute(ctx.Operation().Path),
+			),
+		)
+		defer span.End()
+
+		if requestID := logging.RequestID(spanCtx); requestID != "" {
+			span.SetAttributes(attribute.String("http.request.id", requestID))
+		}
+		if span.SpanContext().IsValid() {
+			logger := logging.FromContext(spanCtx).With(slog.String("traceId", span.SpanContext().TraceID().String()))
+			spanCtx = logging.WithLogger(spanCtx, logger)
+		}
+
+		next(huma.WithContext(ctx, spanCtx))
+
+		status := ctx.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
+
+// headerCarrier reads the propagation headers from a huma context.
+type headerCarrier struct {
+	ctx huma.Context
+}
+
+func (c headerCarrier) Get(key string) string {
+	return c.ctx.Header(key)
+}
+
+func (c headerCarrier) Set(string, string) {}
+
+func (c headerCarrier) Keys() []string {
+	var keys []string
+	c.ctx.EachHeader(func(name, _ string) {
+		keys = append(keys, name)
+	})
+	return keys
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"backend/internal/config"
+	"backend/internal/domain"
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/mail"
+	"backend/internal/infrastructure/repository"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type fakeShelfRepository struct {
+	repository.ShelfRepository
+	shelves map[string]*model.Shelf
+	trashed map[string]trashedShelf
+}
+
+func (r *fakeShelfRepository) Get(_ context.Context, id string) (*model.Shelf, error) {
+	return r.shelves[id], nil
+}
+
+func TestTracingMiddlewareContinuesIncomingTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	svc := domain.NewService(&config.Config{}, &repository.Repository{
+		ShelfRepository: &fakeShelfRepository{shelves: map[string]*model.Shelf{
+			"shelf-1": {Id: "shelf-1", ShelfBase: model.ShelfBase{Title: "Shelf"}},
+		}},
+	}, mail.NewMemoryMailer())
+
+	_, api := humatest.New(t)
+	api.UseMiddleware(NewTracingMiddleware())
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-shelf-by-id",
+		Path:        "/v1/shelf/{shelfId}",
+	}, GetShelfById(svc))
+
+	resp := api.Get("/v1/shelf/shelf-1", "traceparent: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+
+	serviceSpan, operationSpan := spans[0], spans[1]
+	require.Equal(t, "ShelfService.GetShelfById", serviceSpan.Name)
+	require.Equal(t, "get-shelf-by-id", operationSpan.Name)
+	require.Equal(t, trace.SpanKindServer, operationSpan.SpanKind)
+
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", operationSpan.SpanContext.TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", operationSpan.Parent.SpanID().String())
+	require.Equal(t, operationSpan.SpanContext.SpanID(), serviceSpan.Parent.SpanID())
+}
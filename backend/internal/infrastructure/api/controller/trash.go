@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"backend/internal/domain"
+	"backend/internal/infrastructure/api/model"
+	"context"
+	"errors"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+func GetTrash(svc *domain.Service) func(c context.Context, input *model.UserRequestFilter) (*model.TrashResponse, error) {
+	return func(c context.Context, input *model.UserRequestFilter) (*model.TrashResponse, error) {
+		items, err := svc.TrashService.List(c, input.UserId)
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to get trash", err)
+		}
+		if items == nil {
+			items = []model.TrashItem{}
+		}
+
+		return &model.TrashResponse{Body: items}, nil
+	}
+}
+
+func RestoreTrashItem(svc *domain.Service) func(c context.Context, input *model.TrashItemFilter) (*struct{}, error) {
+	return func(c context.Context, input *model.TrashItemFilter) (*struct{}, error) {
+		err := svc.TrashService.Restore(c, input.ItemType, input.ItemId)
+		if errors.Is(err, domain.ErrParentInTrash) {
+			return nil, huma.Error409Conflict("failed to restore item", err)
+		}
+		if err != nil {
+			return nil, accessError("failed to restore item", err)
+		}
+
+		return nil, nil
+	}
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/repository"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type trashedShelf struct {
+	shelf     *model.Shelf
+	deletedBy string
+	deletedAt time.Time
+}
+
+func (r *fakeShelfRepository) SoftDelete(_ context.Context, id, deletedBy string, deletedAt time.Time) error {
+	if r.trashed == nil {
+		r.trashed = make(map[string]trashedShelf)
+	}
+	r.trashed[id] = trashedShelf{shelf: r.shelves[id], deletedBy: deletedBy, deletedAt: deletedAt}
+	delete(r.shelves, id)
+	return nil
+}
+
+func (r *fakeShelfRepository) GetDeleted(_ context.Context, id string) (*model.Shelf, error) {
+	if trashed, ok := r.trashed[id]; ok {
+		return trashed.shelf, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeShelfRepository) Restore(_ context.Context, id string) (bool, error) {
+	trashed, ok := r.trashed[id]
+	if !ok {
+		return false, nil
+	}
+	r.shelves[id] = trashed.shelf
+	delete(r.trashed, id)
+	return true, nil
+}
+
+// GetDeleted finds no section, the fake deletes only whole shelves.
+func (r *fakeSectionRepository) GetDeleted(_ context.Context, _ string) (*model.Section, error) {
+	return nil, nil
+}
+
+type fakeTrashRepository struct {
+	repository.TrashRepository
+	shelves *fakeShelfRepository
+}
+
+func (r *fakeTrashRepository) ListByUserId(_ context.Context, userId string) ([]model.TrashItem, error) {
+	var items []model.TrashItem
+	for id, trashed := range r.shelves.trashed {
+		if trashed.deletedBy == userId || trashed.shelf.UserId == userId {
+			items = append(items, model.TrashItem{
+				Type:      model.TrashItemShelf,
+				Id:        id,
+				Title:     trashed.shelf.Title,
+				ShelfId:   id,
+				DeletedAt: trashed.deletedAt,
+				DeletedBy: trashed.deletedBy,
+			})
+		}
+	}
+	return items, nil
+}
+
+func TestDeletedShelvesCanBeRestoredFromTheTrash(t *testing.T) {
+	api, _, _, jane, john := newTwoUserTestAPI(t)
+
+	resp := api.Delete("/v1/shelf/shelf-1", jane)
+	require.Equal(t, http.StatusNoContent, resp.Code, resp.Body.String())
+	resp = api.Get("/v1/shelf/shelf-1", jane)
+	require.Equal(t, http.StatusBadRequest, resp.Code, "deleted shelves are hidden")
+
+	resp = api.Get("/v1/user/user-1/trash", jane)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	var items []model.TrashItem
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &items))
+	require.Len(t, items, 1)
+	require.Equal(t, "shelf-1", items[0].Id)
+	require.Equal(t, items[0].DeletedAt.Add(30*24*time.Hour), items[0].PurgeAt)
+
+	resp = api.Post("/v1/user/user-2/trash/shelf/shelf-1/restore", john)
+	require.Equal(t, http.StatusForbidden, resp.Code, "only owners restore shelves")
+	resp = api.Post("/v1/user/user-1/trash/section/section-1/restore", jane)
+	require.Equal(t, http.StatusBadRequest, resp.Code, "the section wasn't deleted on its own")
+
+	resp = api.Post("/v1/user/user-1/trash/shelf/shelf-1/restore", jane)
+	require.Equal(t, http.StatusNoContent, resp.Code, resp.Body.String())
+	resp = api.Get("/v1/shelf/shelf-1", jane)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+}
@@ -5,13 +5,14 @@ import (
 	"backend/internal/infrastructure/api/mapper"
 	"backend/internal/infrastructure/api/model"
 	"context"
+	"fmt"
 
 	"github.com/danielgtaylor/huma/v2"
 )
 
 func CreateUser(svc *domain.Service) func(c context.Context, input *model.UserRequestBody) (*model.UserResponse, error) {
 	return func(c context.Context, input *model.UserRequestBody) (*model.UserResponse, error) {
-		user, err := svc.UserService.CreateUser(mapper.MapUserBaseToUserPointer(input.Body))
+		user, err := svc.UserService.CreateUser(c, mapper.MapUserCreateToUserPointer(input.Body))
 		if err != nil {
 			return nil, huma.Error400BadRequest("failed to create user", err)
 		}
@@ -22,7 +23,7 @@ func CreateUser(svc *domain.Service) func(c context.Context, input *model.UserRe
 
 func GetUserById(svc *domain.Service) func(c context.Context, input *model.UserRequestFilter) (*model.UserResponse, error) {
 	return func(c context.Context, input *model.UserRequestFilter) (*model.UserResponse, error) {
-		user, err := svc.UserService.GetUserById(input.UserId)
+		user, err := svc.UserService.GetUserById(c, input.UserId)
 		if err != nil {
 			return nil, huma.Error400BadRequest("failed to get user", err)
 		}
@@ -33,9 +34,9 @@ func GetUserById(svc *domain.Service) func(c context.Context, input *model.UserR
 
 func UpdateUser(svc *domain.Service) func(c context.Context, input *model.UserFilterFilterAndBody) (*model.UserResponse, error) {
 	return func(c context.Context, input *model.UserFilterFilterAndBody) (*model.UserResponse, error) {
-		user, err := svc.UserService.UpdateUser(input.UserId, mapper.MapUserBaseToUserPointer(input.Body))
+		user, err := svc.UserService.UpdateUser(c, input.UserId, mapper.MapUserBaseToUserPointer(input.Body))
 		if err != nil {
-			return nil, huma.Error400BadRequest("failed to update user", err)
+			return nil, accessError("failed to update user", err)
 		}
 
 		return mapper.MapUserToUserResponse(*user), nil
@@ -44,25 +45,78 @@ func UpdateUser(svc *domain.Service) func(c context.Context, input *model.UserFi
 
 func PatchUserPassword(svc *domain.Service) func(c context.Context, input *model.UserPatchPasswordFilterAndBody) (*struct{}, error) {
 	return func(c context.Context, input *model.UserPatchPasswordFilterAndBody) (*struct{}, error) {
-		err := svc.UserService.PatchPassword(input.UserId, &input.Body)
+		err := svc.UserService.PatchPassword(c, input.UserId, &input.Body, input.SecondFactor)
 		if err != nil {
-			return nil, huma.Error400BadRequest("failed to patch user password", err)
+			return nil, authError("failed to patch user password", err)
 		}
 
 		return nil, nil
 	}
 }
 
-func DeleteUser(svc *domain.Service) func(c context.Context, input *model.UserRequestFilter) (*struct{}, error) {
+// DeleteUser schedules the deletion of the user, the account is disabled and can be restored until the
+// grace period ends.
+func DeleteUser(svc *domain.Service) func(c context.Context, input *model.UserSecondFactorFilter) (*model.UserResponse, error) {
+	return func(c context.Context, input *model.UserSecondFactorFilter) (*model.UserResponse, error) {
+		user, err := svc.UserService.ScheduleUserDeletion(c, input.UserId, input.SecondFactor)
+		if err != nil {
+			return nil, authError("failed to delete user", err)
+		}
+
+		return mapper.MapUserToUserResponse(*user), nil
+	}
+}
+
+func ExportUserData(svc *domain.Service) func(c context.Context, input *model.UserRequestFilter) (*model.UserExportResponse, error) {
+	return func(c context.Context, input *model.UserRequestFilter) (*model.UserExportResponse, error) {
+		archive, err := svc.UserService.ExportUserData(c, input.UserId)
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to export user data", err)
+		}
+
+		return &model.UserExportResponse{
+			ContentType:        "application/zip",
+			ContentDisposition: fmt.Sprintf(`attachment; filename="linkshelf-export-%s.zip"`, input.UserId),
+			Body:               archive,
+		}, nil
+	}
+}
+
+func RequestEmailVerification(svc *domain.Service) func(c context.Context, input *model.UserRequestFilter) (*struct{}, error) {
 	return func(c context.Context, input *model.UserRequestFilter) (*struct{}, error) {
-		user, err := svc.UserService.GetUserById(input.UserId)
+		err := svc.UserService.RequestEmailVerification(c, input.UserId)
 		if err != nil {
-			return nil, huma.Error400BadRequest("failed to get user", err)
+			return nil, huma.Error400BadRequest("failed to request email verification", err)
+		}
+
+		return nil, nil
+	}
+}
+
+func VerifyEmail(svc *domain.Service) func(c context.Context, input *model.VerifyEmailRequestBody) (*struct{}, error) {
+	return func(c context.Context, input *model.VerifyEmailRequestBody) (*struct{}, error) {
+		err := svc.UserService.VerifyEmail(c, input.Body.Token)
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to verify email", err)
 		}
 
-		err = svc.UserService.DeleteUser(user)
+		return nil, nil
+	}
+}
+
+func ForgotPassword(svc *domain.Service) func(c context.Context, input *model.ForgotPasswordRequestBody) (*struct{}, error) {
+	return func(c context.Context, input *model.ForgotPasswordRequestBody) (*struct{}, error) {
+		svc.UserService.RequestPasswordReset(c, input.Body.Email)
+
+		return nil, nil
+	}
+}
+
+func ResetPassword(svc *domain.Service) func(c context.Context, input *model.ResetPasswordRequestBody) (*struct{}, error) {
+	return func(c context.Context, input *model.ResetPasswordRequestBody) (*struct{}, error) {
+		err := svc.UserService.ResetPasswordWithToken(c, input.Body.Token, input.Body.NewPassword)
 		if err != nil {
-			return nil, huma.Error400BadRequest("failed to delete user", err)
+			return nil, huma.Error400BadRequest("failed to reset password", err)
 		}
 
 		return nil, nil
@@ -0,0 +1,741 @@
+package controller
+
+import (
+	"archive/zip"
+	"backend/internal/config"
+	"backend/internal/domain"
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/mail"
+	"backend/internal/infrastructure/repository"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUserRepository keeps users in memory, their IDs are numbered in the order of creation. Get returns the
+// stored password hash on purpose, so the tests prove that the API drops it even if a repository leaks it.
+type fakeUserRepository struct {
+	repository.UserRepository
+	mu    sync.Mutex
+	users map[string]model.User
+}
+
+func (r *fakeUserRepository) Create(_ context.Context, u *model.User) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u.Id = fmt.Sprintf("user-%d", len(r.users)+1)
+	if u.Role == "" {
+		u.Role = model.RoleUser
+	}
+	r.users[u.Id] = *u
+	return u.Id, nil
+}
+
+func (r *fakeUserRepository) Get(_ context.Context, id string) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+func (r *fakeUserRepository) GetByEmail(_ context.Context, email string) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeUserRepository) GetPassword(_ context.Context, id string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.users[id].Password, nil
+}
+
+func (r *fakeUserRepository) Update(_ context.Context, u *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user := r.users[u.Id]
+	user.UserBase = u.UserBase
+	r.users[u.Id] = user
+	return nil
+}
+
+func (r *fakeUserRepository) SetEmailVerifiedAt(_ context.Context, id string, verifiedAt *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user := r.users[id]
+	user.EmailVerifiedAt = verifiedAt
+	r.users[id] = user
+	return nil
+}
+
+func (r *fakeUserRepository) ScheduleDeletion(_ context.Context, id string, disabledAt, purgeAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user := r.users[id]
+	user.DisabledAt, user.PurgeAt = &disabledAt, &purgeAt
+	r.users[id] = user
+	return nil
+}
+
+func (r *fakeUserRepository) CancelDeletion(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user := r.users[id]
+	user.DisabledAt, user.PurgeAt = nil, nil
+	r.users[id] = user
+	return nil
+}
+
+func (r *fakeShelfRepository) ListByUserId(_ context.Context, userId string) ([]model.Shelf, error) {
+	var shelves []model.Shelf
+	for _, shelf := range r.shelves {
+		if shelf.UserId == userId {
+			shelves = append(shelves, *shelf)
+		}
+	}
+	return shelves, nil
+}
+
+type fakeSectionRepository struct {
+	repository.SectionRepository
+	sections []model.Section
+}
+
+func (r *fakeSectionRepository) ListByShelfId(_ context.Context, _ string) ([]model.Section, error) {
+	return r.sections, nil
+}
+
+type fakeLinkRepository struct {
+	repository.LinkRepository
+	links []model.Link
+}
+
+func (r *fakeLinkRepository) ListByShelfId(_ context.Context, _ string) ([]model.Link, error) {
+	return r.links, nil
+}
+
+type fakeUserTokenRepository struct {
+	repository.UserTokenRepository
+	mu     sync.Mutex
+	tokens map[string]model.UserToken
+}
+
+func (r *fakeUserTokenRepository) Create(_ context.Context, t *model.UserToken) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t.Id = fmt.Sprintf("token-%d", len(r.tokens)+1)
+	r.tokens[t.Id] = *t
+	return t.Id, nil
+}
+
+func (r *fakeUserTokenRepository) GetByHash(_ context.Context, purpose, tokenHash string) (*model.UserToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.Purpose == purpose && token.TokenHash == tokenHash {
+			return &token, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeUserTokenRepository) Use(_ context.Context, id string, usedAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok || token.UsedAt != nil {
+		return false, nil
+	}
+	token.UsedAt = &usedAt
+	r.tokens[id] = token
+	return true, nil
+}
+
+func (r *fakeUserTokenRepository) DeleteByUser(_ context.Context, userId, purpose string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, token := range r.tokens {
+		if token.UserId == userId && token.Purpose == purpose {
+			delete(r.tokens, id)
+		}
+	}
+	return nil
+}
+
+func newUserTestAPI(t *testing.T) (humatest.TestAPI, *domain.Service, *mail.MemoryMailer) {
+	return newTestAPI(t, true)
+}
+
+// newTestAPI registers the user and auth operations. Unless the authentication is skipped, they're
+// protected by the authorization middleware like in the router.
+func newTestAPI(t *testing.T, skipAuthentication bool) (humatest.TestAPI, *domain.Service, *mail.MemoryMailer) {
+	cfg := &config.Config{}
+	cfg.App.Name = "LinkShelf"
+	cfg.Mail.BaseURL = "http://localhost:3000"
+	cfg.Domain.PasswordPolicy = config.PasswordPolicy{MinLength: 12, RequireDigit: true}
+	cfg.Domain.Tokens.EmailVerificationTTL = time.Hour
+	cfg.Domain.Tokens.ShelfInvitationTTL = time.Hour
+	cfg.Domain.AccountDeletion.GracePeriod = 30 * 24 * time.Hour
+	cfg.Domain.Revisions.SessionWindow = 10 * time.Minute
+	cfg.Domain.Revisions.Limit = 100
+	cfg.Domain.Trash.Retention = 30 * 24 * time.Hour
+	cfg.Domain.Sessions.TTL = time.Hour
+	cfg.Domain.Sessions.ImpersonationTTL = time.Hour
+	cfg.Domain.TwoFactor.EncryptionKey = "mJ7pwDHBC7pVQ8yF7mMaQQEt2xkEOpdGdPTDGGmLsPA="
+	cfg.Domain.Authentication.SkipAuthentication = skipAuthentication
+
+	shelves := &fakeShelfRepository{shelves: map[string]*model.Shelf{
+		"shelf-1": {Id: "shelf-1", ShelfBase: model.ShelfBase{Title: "Jane", Path: "jane/links", UserId: "user-1"}},
+	}}
+	sections := &fakeSectionRepository{sections: []model.Section{
+		{Id: "section-1", SectionBase: model.SectionBase{Title: "Social", ShelfId: "shelf-1"}},
+	}}
+	links := &fakeLinkRepository{links: []model.Link{
+		{Id: "link-1", LinkBase: model.LinkBase{Title: "Blog", Link: "https://jane.example.com", SectionId: "section-1"}},
+	}}
+
+	mailer := mail.NewMemoryMailer()
+	svc := domain.NewService(cfg, &repository.Repository{
+		UserRepository:      &fakeUserRepository{users: make(map[string]model.User)},
+		UserTokenRepository: &fakeUserTokenRepository{tokens: make(map[string]model.UserToken)},
+		SessionRepository:   &fakeSessionRepository{sessions: make(map[string]model.UserSession)},
+		TwoFactorRepository: &fakeTwoFactorRepository{recoveryCodes: make(map[string]bool)},
+		PersonalAccessTokenRepository: &fakePersonalAccessTokenRepository{
+			tokens: make(map[string]model.PersonalAccessToken),
+		},
+		StatisticsRepository: &fakeStatisticsRepository{},
+		TeamRepository: &fakeTeamRepository{
+			teams:   make(map[string]model.Team),
+			members: make(map[string]model.TeamMember),
+			shelves: shelves,
+		},
+		ShelfShareRepository: &fakeShelfShareRepository{shares: make(map[string]model.ShelfShare)},
+		ShelfInvitationRepository: &fakeShelfInvitationRepository{
+			invitations: make(map[string]model.ShelfInvitation),
+		},
+		AuditRepository: &fakeAuditRepository{},
+		ShelfRevisionRepository: &fakeShelfRevisionRepository{
+			shelves:  shelves,
+			sections: sections,
+			links:    links,
+		},
+		TrashRepository: &fakeTrashRepository{shelves: shelves},
+		ShelfTemplateRepository: &fakeShelfTemplateRepository{
+			templates: make(map[string]model.ShelfTemplate),
+		},
+		SearchRepository:  &fakeSearchRepository{shelves: shelves, sections: sections, links: links},
+		ShelfRepository:   shelves,
+		SectionRepository: sections,
+		LinkRepository:    links,
+	}, mailer)
+	t.Cleanup(svc.WaitForBackgroundTasks)
+
+	_, api := humatest.New(t)
+	api.UseMiddleware(NewAuthorizationMiddleware(api, cfg, svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-create-user",
+		Path:        "/v1/user",
+		Security:    publicOperation,
+	}, CreateUser(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-user-by-id",
+		Path:        "/v1/user/{userId}",
+	}, GetUserById(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPut,
+		OperationID: "put-update-user",
+		Path:        "/v1/user/{userId}",
+	}, UpdateUser(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPatch,
+		OperationID: "patch-user-password",
+		Path:        "/v1/user/{userId}/password",
+	}, PatchUserPassword(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodDelete,
+		OperationID:   "delete-user",
+		Path:          "/v1/user/{userId}",
+		DefaultStatus: http.StatusAccepted,
+	}, DeleteUser(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-user-data-export",
+		Path:        "/v1/user/{userId}/export",
+	}, ExportUserData(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-login",
+		Path:        "/v1/auth/login",
+		Security:    publicOperation,
+	}, Login(svc, nil))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodPost,
+		OperationID:   "post-logout",
+		Path:          "/v1/auth/logout",
+		DefaultStatus: http.StatusNoContent,
+	}, Logout(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-enroll-two-factor",
+		Path:        "/v1/user/{userId}/2fa",
+	}, EnrollTwoFactor(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-confirm-two-factor",
+		Path:        "/v1/user/{userId}/2fa/confirm",
+	}, ConfirmTwoFactor(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodDelete,
+		OperationID:   "delete-two-factor",
+		Path:          "/v1/user/{userId}/2fa",
+		DefaultStatus: http.StatusNoContent,
+	}, DisableTwoFactor(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-regenerate-recovery-codes",
+		Path:        "/v1/user/{userId}/2fa/recovery-codes",
+	}, RegenerateRecoveryCodes(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-create-personal-access-token",
+		Path:        "/v1/user/{userId}/tokens",
+	}, CreatePersonalAccessToken(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-personal-access-tokens",
+		Path:        "/v1/user/{userId}/tokens",
+	}, GetPersonalAccessTokens(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodDelete,
+		OperationID:   "delete-personal-access-token",
+		Path:          "/v1/user/{userId}/tokens/{tokenId}",
+		DefaultStatus: http.StatusNoContent,
+	}, RevokePersonalAccessToken(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-shelf-by-id",
+		Path:        "/v1/shelf/{shelfId}",
+		Security:    bearerScopes(model.ScopeShelfRead),
+	}, GetShelfById(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodDelete,
+		OperationID:   "delete-shelf",
+		Path:          "/v1/shelf/{shelfId}",
+		Security:      bearerScopes(model.ScopeShelfWrite),
+		DefaultStatus: http.StatusNoContent,
+	}, DeleteShelf(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-create-shelf",
+		Path:        "/v1/shelf",
+		Security:    bearerScopes(model.ScopeShelfWrite),
+	}, CreateShelf(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-clone-shelf",
+		Path:        "/v1/shelf/{shelfId}/clone",
+		Security:    bearerScopes(model.ScopeShelfWrite),
+	}, CloneShelf(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-publish-shelf-template",
+		Path:        "/v1/shelf/{shelfId}/template",
+		Security:    bearerScopes(model.ScopeShelfWrite),
+	}, PublishShelfTemplate(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-shelf-templates",
+		Path:        "/v1/templates",
+		Security:    bearerScopes(model.ScopeShelfRead),
+	}, GetShelfTemplates(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodDelete,
+		OperationID:   "delete-shelf-template",
+		Path:          "/v1/templates/{templateId}",
+		Security:      bearerScopes(model.ScopeShelfWrite),
+		DefaultStatus: http.StatusNoContent,
+	}, DeleteShelfTemplate(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-create-shelf-from-template",
+		Path:        "/v1/user/{userId}/shelves/from-template",
+		Security:    bearerScopes(model.ScopeShelfWrite),
+	}, CreateShelfFromTemplate(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-search",
+		Path:        "/v1/search",
+		Security:    bearerScopes(model.ScopeShelfRead),
+	}, Search(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPut,
+		OperationID: "put-shelf-share",
+		Path:        "/v1/shelf/{shelfId}/shares/{memberId}",
+		Security:    bearerScopes(model.ScopeShelfWrite),
+	}, ShareShelf(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodDelete,
+		OperationID:   "delete-shelf-share",
+		Path:          "/v1/shelf/{shelfId}/shares/{memberId}",
+		Security:      bearerScopes(model.ScopeShelfWrite),
+		DefaultStatus: http.StatusNoContent,
+	}, RevokeShelfShare(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPut,
+		OperationID: "put-update-link",
+		Path:        "/v1/link/{linkId}",
+		Security:    bearerScopes(model.ScopeLinkWrite),
+	}, UpdateLink(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-create-shelf-invitation",
+		Path:        "/v1/shelf/{shelfId}/invitations",
+		Security:    bearerScopes(model.ScopeShelfWrite),
+	}, CreateShelfInvitation(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-shelf-invitations",
+		Path:        "/v1/shelf/{shelfId}/invitations",
+		Security:    bearerScopes(model.ScopeShelfRead),
+	}, GetShelfInvitations(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodPost,
+		OperationID:   "post-decline-shelf-invitation",
+		Path:          "/v1/invitation/decline",
+		DefaultStatus: http.StatusNoContent,
+		Security:      publicOperation,
+	}, DeclineShelfInvitation(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-accept-shelf-invitation",
+		Path:        "/v1/user/{userId}/invitations/accept",
+	}, AcceptShelfInvitation(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-create-team",
+		Path:        "/v1/user/{userId}/teams",
+	}, CreateTeam(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodDelete,
+		OperationID:   "delete-team",
+		Path:          "/v1/team/{teamId}",
+		DefaultStatus: http.StatusNoContent,
+	}, DeleteTeam(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-team-members",
+		Path:        "/v1/team/{teamId}/members",
+	}, GetTeamMembers(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPut,
+		OperationID: "put-team-member",
+		Path:        "/v1/team/{teamId}/members/{memberId}",
+	}, SetTeamMember(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodDelete,
+		OperationID:   "delete-team-member",
+		Path:          "/v1/team/{teamId}/members/{memberId}",
+		DefaultStatus: http.StatusNoContent,
+	}, RemoveTeamMember(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-admin-users",
+		Path:        "/v1/admin/users",
+		Metadata:    adminOperation,
+	}, SearchUsers(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-admin-disable-user",
+		Path:        "/v1/admin/users/{userId}/disable",
+		Metadata:    adminOperation,
+	}, DisableUser(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-admin-enable-user",
+		Path:        "/v1/admin/users/{userId}/enable",
+		Metadata:    adminOperation,
+	}, EnableUser(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-admin-impersonate-user",
+		Path:        "/v1/admin/users/{userId}/impersonate",
+		Metadata:    adminOperation,
+	}, ImpersonateUser(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-admin-audit-events",
+		Path:        "/v1/admin/audit",
+		Metadata:    adminOperation,
+	}, SearchAuditEvents(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-shelf-audit-events",
+		Path:        "/v1/shelf/{shelfId}/audit",
+		Security:    bearerScopes(model.ScopeShelfRead),
+	}, GetShelfAuditEvents(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-shelf-revisions",
+		Path:        "/v1/shelf/{shelfId}/revisions",
+		Security:    bearerScopes(model.ScopeShelfRead),
+	}, GetShelfRevisions(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-shelf-revision-diff",
+		Path:        "/v1/shelf/{shelfId}/revisions/diff",
+		Security:    bearerScopes(model.ScopeShelfRead),
+	}, DiffShelfRevisions(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-restore-shelf-revision",
+		Path:        "/v1/shelf/{shelfId}/revisions/{revisionId}/restore",
+		Security:    bearerScopes(model.ScopeShelfWrite),
+	}, RestoreShelfRevision(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-admin-statistics",
+		Path:        "/v1/admin/statistics",
+		Metadata:    adminOperation,
+	}, GetStatistics(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodPost,
+		OperationID: "post-admin-unpublish-shelf",
+		Path:        "/v1/admin/shelves/{shelfId}/unpublish",
+		Metadata:    adminOperation,
+	}, UnpublishShelf(svc))
+	huma.Register(api, huma.Operation{
+		Method:      http.MethodGet,
+		OperationID: "get-user-trash",
+		Path:        "/v1/user/{userId}/trash",
+		Security:    bearerScopes(model.ScopeShelfRead),
+	}, GetTrash(svc))
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodPost,
+		OperationID:   "post-restore-trash-item",
+		Path:          "/v1/user/{userId}/trash/{itemType}/{itemId}/restore",
+		Security:      bearerScopes(model.ScopeShelfWrite),
+		DefaultStatus: http.StatusNoContent,
+	}, RestoreTrashItem(svc))
+
+	return api, svc, mailer
+}
+
+func requireNoCredentials(t *testing.T, body string) {
+	t.Helper()
+	require.NotContains(t, body, "password")
+	require.NotContains(t, body, "$2a$")
+	require.NotContains(t, body, "correct horse 1")
+}
+
+func TestUserResponsesNeverContainThePasswordHash(t *testing.T) {
+	api, svc, mailer := newUserTestAPI(t)
+
+	resp := api.Post("/v1/user", map[string]any{
+		"email":      "jane@example.com",
+		"first_name": "Jane",
+		"last_name":  "Doe",
+		"password":   "correct horse 1",
+	})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	requireNoCredentials(t, resp.Body.String())
+	require.Contains(t, resp.Body.String(), `"id":"user-1"`)
+
+	resp = api.Get("/v1/user/user-1")
+	require.Equal(t, http.StatusOK, resp.Code)
+	requireNoCredentials(t, resp.Body.String())
+
+	resp = api.Put("/v1/user/user-1", map[string]any{
+		"email":      "jane@example.org",
+		"first_name": "Jane",
+		"last_name":  "Roe",
+	})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	requireNoCredentials(t, resp.Body.String())
+
+	svc.WaitForBackgroundTasks()
+	require.Len(t, mailer.Messages(), 2)
+}
+
+func TestUpdateUserRejectsPasswords(t *testing.T) {
+	api, _, _ := newUserTestAPI(t)
+
+	resp := api.Put("/v1/user/user-1", map[string]any{
+		"email":      "jane@example.com",
+		"first_name": "Jane",
+		"last_name":  "Doe",
+		"password":   "correct horse 1",
+	})
+	require.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+}
+
+func TestCreateUserRequiresAStrongPassword(t *testing.T) {
+	api, _, _ := newUserTestAPI(t)
+
+	resp := api.Post("/v1/user", map[string]any{"email": "jane@example.com"})
+	require.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+
+	resp = api.Post("/v1/user", map[string]any{"email": "jane@example.com", "first_name": "Jane", "last_name": "Doe", "password": "short"})
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.Contains(t, resp.Body.String(), "at least 12 characters")
+	require.Contains(t, resp.Body.String(), "a digit")
+
+	resp = api.Post("/v1/user", map[string]any{"email": "jane@example.com", "first_name": "Jane", "last_name": "Doe", "password": strings.Repeat("a1", 40)})
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.Contains(t, resp.Body.String(), "72 bytes")
+}
+
+func createTestUser(t *testing.T, api humatest.TestAPI) {
+	t.Helper()
+
+	resp := api.Post("/v1/user", map[string]any{
+		"email":      "jane@example.com",
+		"first_name": "Jane",
+		"last_name":  "Doe",
+		"password":   "correct horse 1",
+	})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+}
+
+// newTwoUserTestAPI creates jane as user-1 and john as user-2 and returns the sessions of both.
+func newTwoUserTestAPI(t *testing.T) (api humatest.TestAPI, svc *domain.Service, mailer *mail.MemoryMailer, jane, john string) {
+	api, svc, mailer = newTestAPI(t, false)
+	createTestUser(t, api)
+	resp := api.Post("/v1/user", map[string]any{
+		"email":      "john@example.com",
+		"first_name": "John",
+		"last_name":  "Doe",
+		"password":   "correct horse 2",
+	})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+
+	code, output := login(api, "correct horse 1")
+	require.Equal(t, http.StatusOK, code)
+	jane = "Authorization: Bearer " + output.Token
+	code, output = loginAs(api, "john@example.com", "correct horse 2")
+	require.Equal(t, http.StatusOK, code)
+	john = "Authorization: Bearer " + output.Token
+
+	return api, svc, mailer, jane, john
+}
+
+func TestDeleteUserDisablesTheAccountUntilTheNextLogin(t *testing.T) {
+	api, _, _ := newTestAPI(t, false)
+	createTestUser(t, api)
+	code, output := login(api, "correct horse 1")
+	require.Equal(t, http.StatusOK, code)
+	authorization := "Authorization: Bearer " + output.Token
+
+	resp := api.Delete("/v1/user/user-1", authorization)
+	require.Equal(t, http.StatusAccepted, resp.Code, resp.Body.String())
+	require.Contains(t, resp.Body.String(), `"disabled_at"`)
+	require.Contains(t, resp.Body.String(), `"purge_at"`)
+
+	resp = api.Get("/v1/user/user-1", authorization)
+	require.Equal(t, http.StatusUnauthorized, resp.Code, "the sessions end with the deletion")
+
+	code, _ = login(api, "wrong password 1")
+	require.Equal(t, http.StatusUnauthorized, code)
+
+	code, output = login(api, "correct horse 1")
+	require.Equal(t, http.StatusOK, code)
+	authorization = "Authorization: Bearer " + output.Token
+
+	resp = api.Get("/v1/user/user-1", authorization)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.NotContains(t, resp.Body.String(), `"disabled_at"`)
+	require.NotContains(t, resp.Body.String(), `"purge_at"`)
+
+	resp = api.Put("/v1/user/user-1", map[string]any{"email": "jane@example.com", "first_name": "Jane", "last_name": "Roe"}, authorization)
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+}
+
+func TestExportUserDataReturnsAnArchiveWithoutCredentials(t *testing.T) {
+	api, _, _ := newUserTestAPI(t)
+	createTestUser(t, api)
+
+	resp := api.Get("/v1/user/user-1/export")
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	require.Equal(t, "application/zip", resp.Header().Get("Content-Type"))
+	require.Contains(t, resp.Header().Get("Content-Disposition"), "attachment")
+
+	archive, err := zip.NewReader(bytes.NewReader(resp.Body.Bytes()), int64(resp.Body.Len()))
+	require.NoError(t, err)
+
+	files := make(map[string]string)
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+		files[file.Name] = string(content)
+	}
+
+	require.ElementsMatch(t, []string{"README.txt", "profile.json", "shelves/001-jane_links.json"}, slices.Collect(maps.Keys(files)))
+	require.Contains(t, files["profile.json"], `"email": "jane@example.com"`)
+	requireNoCredentials(t, files["profile.json"])
+	require.Contains(t, files["shelves/001-jane_links.json"], `"link": "https://jane.example.com"`)
+}
+
+func TestChangingTheEmailInvalidatesThePendingTokens(t *testing.T) {
+	api, svc, mailer, _, john := newTwoUserTestAPI(t)
+	ctx := context.Background()
+
+	svc.UserService.RequestPasswordReset(ctx, "john@example.com")
+	svc.WaitForBackgroundTasks()
+	mailedToken := func(to, page string) string {
+		var token string
+		for _, message := range mailer.Messages() {
+			if message.To == to && strings.Contains(message.Text, page+"?token=") {
+				token = invitationToken(t, message.Text)
+			}
+		}
+		require.NotEmpty(t, token, to+page)
+		return token
+	}
+	reset := mailedToken("john@example.com", "/reset-password")
+	verification := mailedToken("john@example.com", "/verify-email")
+
+	resp := api.Put("/v1/user/user-2", john, map[string]any{"email": "victim@example.com", "first_name": "John", "last_name": "Doe"})
+	require.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	svc.WaitForBackgroundTasks()
+
+	require.ErrorIs(t, svc.UserService.ResetPasswordWithToken(ctx, reset, "correct horse 3"), domain.ErrInvalidToken,
+		"the reset mailed to the former address would verify the new one")
+	require.ErrorIs(t, svc.UserService.VerifyEmail(ctx, verification), domain.ErrInvalidToken)
+	require.NoError(t, svc.UserService.VerifyEmail(ctx, mailedToken("victim@example.com", "/verify-email")))
+}
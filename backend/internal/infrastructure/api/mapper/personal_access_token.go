@@ -0,0 +1,25 @@
+package mapper
+
+import (
+	"backend/internal/infrastructure/api/model"
+)
+
+// MapPersonalAccessTokenToOutput drops the token hash.
+func MapPersonalAccessTokenToOutput(token model.PersonalAccessToken) model.PersonalAccessTokenOutput {
+	return model.PersonalAccessTokenOutput{
+		Id:         token.Id,
+		Name:       token.Name,
+		Scopes:     token.Scopes,
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		CreatedAt:  token.CreatedAt,
+	}
+}
+
+func MapPersonalAccessTokensToResponse(tokens []model.PersonalAccessToken) *model.PersonalAccessTokensResponse {
+	outputs := make([]model.PersonalAccessTokenOutput, 0, len(tokens))
+	for _, token := range tokens {
+		outputs = append(outputs, MapPersonalAccessTokenToOutput(token))
+	}
+	return &model.PersonalAccessTokensResponse{Body: outputs}
+}
@@ -0,0 +1,26 @@
+package mapper
+
+import (
+	"backend/internal/infrastructure/api/model"
+)
+
+// MapShelfInvitationToOutput drops the token hash and the answer, only pending invitations are returned.
+func MapShelfInvitationToOutput(invitation model.ShelfInvitation) model.ShelfInvitationOutput {
+	return model.ShelfInvitationOutput{
+		Id:        invitation.Id,
+		ShelfId:   invitation.ShelfId,
+		Role:      invitation.Role,
+		Email:     invitation.Email,
+		InvitedBy: invitation.InvitedBy,
+		ExpiresAt: invitation.ExpiresAt,
+		CreatedAt: invitation.CreatedAt,
+	}
+}
+
+func MapShelfInvitationsToResponse(invitations []model.ShelfInvitation) *model.ShelfInvitationsResponse {
+	outputs := make([]model.ShelfInvitationOutput, 0, len(invitations))
+	for _, invitation := range invitations {
+		outputs = append(outputs, MapShelfInvitationToOutput(invitation))
+	}
+	return &model.ShelfInvitationsResponse{Body: outputs}
+}
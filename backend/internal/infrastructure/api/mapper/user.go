@@ -10,8 +10,30 @@ func MapUserBaseToUserPointer(base model.UserBase) *model.User {
 	}
 }
 
+func MapUserCreateToUserPointer(create model.UserCreate) *model.User {
+	return &model.User{
+		UserBase: create.UserBase,
+		Password: create.Password,
+	}
+}
+
+// MapUserToUserOutput copies the public attributes only, so credentials can't end up in a response.
+func MapUserToUserOutput(user model.User) model.UserOutput {
+	return model.UserOutput{
+		Id:               user.Id,
+		Email:            user.Email,
+		FirstName:        user.FirstName,
+		LastName:         user.LastName,
+		EmailVerifiedAt:  user.EmailVerifiedAt,
+		DisabledAt:       user.DisabledAt,
+		PurgeAt:          user.PurgeAt,
+		TwoFactorEnabled: user.TwoFactorEnabled,
+		Role:             user.Role,
+	}
+}
+
 func MapUserToUserResponse(body model.User) *model.UserResponse {
 	return &model.UserResponse{
-		Body: body,
+		Body: MapUserToUserOutput(body),
 	}
 }
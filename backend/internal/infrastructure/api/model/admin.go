@@ -0,0 +1,44 @@
+package model
+
+// UserSearch filters the users of the admin API.
+type UserSearch struct {
+	Query string `query:"q" maxLength:"255" doc:"Matches the email address, first and last name, ignoring the case."`
+	Role  string `query:"role" enum:"user,admin" doc:"Only return users with this role."`
+	PaginationQuery
+}
+
+type UserPage struct {
+	Items []UserOutput `json:"items" bson:"items"`
+	Pagination
+}
+
+type UserPageResponse struct {
+	Body UserPage `json:"body" bson:"body"`
+}
+
+type UserRoleFilterAndBody struct {
+	UserRequestFilter
+	Body UserRole `json:"body" bson:"body"`
+}
+
+type UserRole struct {
+	Role string `json:"role" bson:"role" enum:"user,admin"`
+}
+
+// Statistics are the instance-wide numbers of the admin dashboard.
+type Statistics struct {
+	Users                int64 `json:"users" bson:"users"`
+	Admins               int64 `json:"admins" bson:"admins"`
+	DisabledUsers        int64 `json:"disabled_users" bson:"disabled_users"`
+	UsersPendingDeletion int64 `json:"users_pending_deletion" bson:"users_pending_deletion"`
+	Shelves              int64 `json:"shelves" bson:"shelves"`
+	UnpublishedShelves   int64 `json:"unpublished_shelves" bson:"unpublished_shelves"`
+	VerifiedDomains      int64 `json:"verified_domains" bson:"verified_domains"`
+	Sections             int64 `json:"sections" bson:"sections"`
+	Links                int64 `json:"links" bson:"links"`
+	ActiveSessions       int64 `json:"active_sessions" bson:"active_sessions"`
+}
+
+type StatisticsResponse struct {
+	Body Statistics `json:"body" bson:"body"`
+}
@@ -0,0 +1,76 @@
+package model
+
+import "time"
+
+// Actions of audit events.
+const (
+	AuditActionCreate = "create"
+	AuditActionUpdate = "update"
+	AuditActionDelete = "delete"
+)
+
+// Entity types of audit events.
+const (
+	AuditEntityUser                = "user"
+	AuditEntityPersonalAccessToken = "personal_access_token"
+	AuditEntityShelf               = "shelf"
+	AuditEntitySection             = "section"
+	AuditEntityLink                = "link"
+	AuditEntityShelfShare          = "shelf_share"
+	AuditEntityShelfInvitation     = "shelf_invitation"
+	AuditEntityTeam                = "team"
+	AuditEntityTeamMember          = "team_member"
+	AuditEntityShelfTemplate       = "shelf_template"
+)
+
+// AuditEvent records a single mutation. Events are only ever appended and never removed, not even together
+// with the user or shelf they refer to. Purging a user only redacts the attribute values of the events about
+// the user and the IPs of the events by or about the user.
+type AuditEvent struct {
+	Id             string                 `json:"id" bson:"id"`
+	ActorId        string                 `json:"actor_id,omitempty" bson:"actor_id,omitempty" doc:"The user who made the change, empty for the CLI, background jobs and anonymous requests."`
+	ImpersonatorId string                 `json:"impersonator_id,omitempty" bson:"impersonator_id,omitempty" doc:"The admin who acted as the actor."`
+	Action         string                 `json:"action" bson:"action" enum:"create,update,delete"`
+	EntityType     string                 `json:"entity_type" bson:"entity_type"`
+	EntityId       string                 `json:"entity_id" bson:"entity_id"`
+	ShelfId        string                 `json:"shelf_id,omitempty" bson:"shelf_id,omitempty" doc:"The shelf the entity belongs to, if any."`
+	Changes        map[string]AuditChange `json:"changes" bson:"changes" doc:"The changed attributes, creations only have after values and deletions only before values."`
+	RequestId      string                 `json:"request_id,omitempty" bson:"request_id,omitempty"`
+	IP             string                 `json:"ip,omitempty" bson:"ip,omitempty"`
+	CreatedAt      time.Time              `json:"created_at" bson:"created_at"`
+}
+
+type AuditChange struct {
+	Before any `json:"before,omitempty" bson:"before,omitempty"`
+	After  any `json:"after,omitempty" bson:"after,omitempty"`
+}
+
+// AuditSearch filters the audit events, which are returned newest first.
+type AuditSearch struct {
+	EntityType string    `query:"entityType" enum:"user,personal_access_token,shelf,section,link,shelf_share,shelf_invitation,team,team_member" doc:"Only return events of this entity type."`
+	EntityId   string    `query:"entityId" maxLength:"255" doc:"Only return events of this entity."`
+	Action     string    `query:"action" enum:"create,update,delete" doc:"Only return events of this action."`
+	ActorId    string    `query:"actorId" maxLength:"36" doc:"Only return changes made by this user."`
+	Since      time.Time `query:"since" doc:"Only return events at or after this time."`
+	Until      time.Time `query:"until" doc:"Only return events before this time."`
+	PaginationQuery
+}
+
+type ShelfAuditSearch struct {
+	ShelfRequestFilter
+	AuditSearch
+}
+
+type AdminAuditSearch struct {
+	ShelfId string `query:"shelfId" maxLength:"36" doc:"Only return events of this shelf."`
+	AuditSearch
+}
+
+type AuditEventPage struct {
+	Items []AuditEvent `json:"items" bson:"items"`
+	Pagination
+}
+
+type AuditEventPageResponse struct {
+	Body AuditEventPage `json:"body" bson:"body"`
+}
@@ -0,0 +1,96 @@
+package model
+
+import "time"
+
+// UserTOTP is the TOTP secret of a user. Two-factor authentication is only enforced once the enrollment is
+// confirmed with a valid code.
+type UserTOTP struct {
+	UserId       string
+	Secret       string
+	ConfirmedAt  *time.Time
+	LastUsedStep *int64
+	CreatedAt    time.Time
+}
+
+// UserSession is a login of a user. Only the hash of the bearer token is stored.
+type UserSession struct {
+	Id     string
+	UserId string
+	// ImpersonatorId is the admin who started the session to act as the user, it's empty for logins.
+	ImpersonatorId string
+	TokenHash      string
+	ExpiresAt      time.Time
+	LastUsedAt     *time.Time
+	CreatedAt      time.Time
+}
+
+// SecondFactorHeader carries the second factor of operations which require it once two-factor
+// authentication is enabled.
+type SecondFactorHeader struct {
+	SecondFactor string `header:"X-OTP-Code" doc:"A TOTP code or an unused recovery code, required if two-factor authentication is enabled."`
+}
+
+type LoginRequestBody struct {
+	SecondFactorHeader
+	Body LoginBody `json:"body" bson:"body"`
+}
+
+type LoginBody struct {
+	Email    string `json:"email" bson:"email" format:"email"`
+	Password string `json:"password" bson:"password" minLength:"1"`
+}
+
+type LoginResponse struct {
+	Body LoginOutput `json:"body" bson:"body"`
+}
+
+type LoginOutput struct {
+	Token     string     `json:"token" bson:"token" doc:"Bearer token for the Authorization header."`
+	ExpiresAt time.Time  `json:"expires_at" bson:"expires_at"`
+	User      UserOutput `json:"user" bson:"user"`
+}
+
+type LogoutRequest struct {
+	Authorization string `header:"Authorization" doc:"The bearer token of the session to end."`
+}
+
+type UserSecondFactorFilter struct {
+	UserRequestFilter
+	SecondFactorHeader
+}
+
+type TwoFactorEnrollFilterAndBody struct {
+	UserRequestFilter
+	Body TwoFactorEnrollBody `json:"body" bson:"body"`
+}
+
+type TwoFactorEnrollBody struct {
+	Password string `json:"password" bson:"password" minLength:"1" doc:"The current password of the user."`
+}
+
+type TwoFactorEnrollmentResponse struct {
+	Body TwoFactorEnrollment `json:"body" bson:"body"`
+}
+
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret" bson:"secret" doc:"The base32 encoded secret for manual entry."`
+	OTPAuthURI string `json:"otpauth_uri" bson:"otpauth_uri" doc:"The otpauth:// URI of the secret."`
+	QRCode     string `json:"qr_code" bson:"qr_code" doc:"The otpauth URI as PNG encoded QR code in a data URI."`
+}
+
+type TwoFactorConfirmFilterAndBody struct {
+	UserRequestFilter
+	Body TwoFactorCode `json:"body" bson:"body"`
+}
+
+type TwoFactorCode struct {
+	Code string `json:"code" bson:"code" minLength:"6" maxLength:"6" pattern:"^[0-9]+$" doc:"The current code of the authenticator app."`
+}
+
+type RecoveryCodesResponse struct {
+	Body RecoveryCodes `json:"body" bson:"body"`
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes" bson:"recovery_codes" doc:"One-time codes which replace a TOTP code, they are only shown once."`
+}
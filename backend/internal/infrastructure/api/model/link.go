@@ -18,7 +18,7 @@ type LinkRequestBody struct {
 }
 
 type LinkRequestFilter struct {
-	ShelfId string `path:"shelfId"`
+	ShelfId string `query:"shelfId" doc:"The shelf to list the links of."`
 	LinkId  string `path:"linkId"`
 }
 
@@ -0,0 +1,23 @@
+package model
+
+// PaginationQuery selects a page of a list. Pages start at 1.
+type PaginationQuery struct {
+	Page     int `query:"page" default:"1" minimum:"1" doc:"The page to return, starting at 1."`
+	PageSize int `query:"pageSize" default:"20" minimum:"1" maximum:"100" doc:"The number of items per page."`
+}
+
+// Offset returns the number of items before the page.
+func (q PaginationQuery) Offset() int {
+	return (q.Page - 1) * q.PageSize
+}
+
+// Pagination describes the returned page of a list.
+type Pagination struct {
+	Page     int   `json:"page" bson:"page"`
+	PageSize int   `json:"page_size" bson:"page_size"`
+	Total    int64 `json:"total" bson:"total" doc:"The number of items on all pages."`
+}
+
+func NewPagination(query PaginationQuery, total int64) Pagination {
+	return Pagination{Page: query.Page, PageSize: query.PageSize, Total: total}
+}
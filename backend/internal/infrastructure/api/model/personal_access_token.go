@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+// Scopes of personal access tokens. A token can only be used for operations which declare scopes and only
+// if it was granted all of them.
+const (
+	ScopeShelfRead  = "shelf:read"
+	ScopeShelfWrite = "shelf:write"
+	ScopeLinkWrite  = "link:write"
+)
+
+// Scopes lists all scopes which can be granted to a personal access token.
+var Scopes = []string{ScopeShelfRead, ScopeShelfWrite, ScopeLinkWrite}
+
+// PersonalAccessToken is a long-lived bearer token for scripts. Only the hash of the token is stored.
+type PersonalAccessToken struct {
+	Id         string
+	UserId     string
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+type PersonalAccessTokenCreate struct {
+	Name      string     `json:"name" bson:"name" minLength:"1" maxLength:"255" doc:"A name to recognize the token by, e.g. the script which uses it."`
+	Scopes    []string   `json:"scopes" bson:"scopes" minItems:"1" uniqueItems:"true" enum:"shelf:read,shelf:write,link:write" doc:"The operations the token may be used for."`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty" doc:"When the token stops working, it never expires if omitted."`
+}
+
+type PersonalAccessTokenRequestBody struct {
+	UserRequestFilter
+	Body PersonalAccessTokenCreate `json:"body" bson:"body"`
+}
+
+type PersonalAccessTokenFilter struct {
+	UserRequestFilter
+	TokenId string `path:"tokenId" doc:"The identifier of the personal access token."`
+}
+
+// PersonalAccessTokenOutput is a token as returned to clients, the token itself is only returned once on
+// creation.
+type PersonalAccessTokenOutput struct {
+	Id         string     `json:"id" bson:"id"`
+	Name       string     `json:"name" bson:"name"`
+	Scopes     []string   `json:"scopes" bson:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" bson:"created_at"`
+}
+
+type PersonalAccessTokenCreated struct {
+	PersonalAccessTokenOutput
+	Token string `json:"token" bson:"token" doc:"Bearer token for the Authorization header, it's only shown once."`
+}
+
+type PersonalAccessTokenCreatedResponse struct {
+	Body PersonalAccessTokenCreated `json:"body" bson:"body"`
+}
+
+type PersonalAccessTokensResponse struct {
+	Body []PersonalAccessTokenOutput `json:"body" bson:"body"`
+}
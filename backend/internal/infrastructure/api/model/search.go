@@ -0,0 +1,36 @@
+package model
+
+// Types of search results.
+const (
+	SearchResultShelf = "shelf"
+	SearchResultLink  = "link"
+)
+
+type SearchRequest struct {
+	Query string `query:"q" required:"true" minLength:"1" maxLength:"200" doc:"The words to search for. Every word has to match, words also match as the start of longer ones. On MySQL, words shorter than innodb_ft_min_token_size (3 by default) and stop words never match, so a query containing one returns no results."`
+	PaginationQuery
+}
+
+// SearchResult is a shelf matching by its title or description, or a link matching by its title or URL.
+type SearchResult struct {
+	Type         string            `json:"type" bson:"type" enum:"shelf,link"`
+	Id           string            `json:"id" bson:"id"`
+	ShelfId      string            `json:"shelfId" bson:"shelfId"`
+	ShelfTitle   string            `json:"shelfTitle" bson:"shelfTitle"`
+	SectionId    string            `json:"sectionId,omitempty" bson:"sectionId,omitempty"`
+	SectionTitle string            `json:"sectionTitle,omitempty" bson:"sectionTitle,omitempty"`
+	Title        string            `json:"title" bson:"title"`
+	Link         string            `json:"link,omitempty" bson:"link,omitempty"`
+	Description  string            `json:"description,omitempty" bson:"description,omitempty"`
+	Highlights   map[string]string `json:"highlights" bson:"highlights" doc:"The matching attributes, HTML-escaped with the matched words wrapped in <mark> elements."`
+	Rank         float64           `json:"-" bson:"-"`
+}
+
+type SearchPage struct {
+	Items []SearchResult `json:"items" bson:"items"`
+	Pagination
+}
+
+type SearchPageResponse struct {
+	Body SearchPage `json:"body" bson:"body"`
+}
@@ -15,7 +15,7 @@ type SectionRequestBody struct {
 }
 
 type SectionRequestFilter struct {
-	ShelfId   string `path:"shelfId"`
+	ShelfId   string `query:"shelfId" doc:"The shelf of the sections, required to list and update them."`
 	SectionId string `path:"sectionId"`
 }
 
@@ -24,6 +24,26 @@ type SectionFilterFilterAndBody struct {
 	Body SectionBase `json:"body" bson:"body"`
 }
 
+// SectionClone places the copy of a section. Empty fields keep the shelf and the title of the section.
+type SectionClone struct {
+	Title   string `json:"title,omitempty" bson:"title,omitempty"`
+	ShelfId string `json:"shelfId,omitempty" bson:"shelfId,omitempty"`
+}
+
+type SectionCloneFilterAndBody struct {
+	SectionId string       `path:"sectionId"`
+	Body      SectionClone `json:"body" bson:"body"`
+}
+
+type SectionMove struct {
+	ShelfId string `json:"shelfId" bson:"shelfId" minLength:"1"`
+}
+
+type SectionMoveFilterAndBody struct {
+	SectionId string      `path:"sectionId"`
+	Body      SectionMove `json:"body" bson:"body"`
+}
+
 type SectionResponse struct {
 	Body Section `json:"body" bson:"body"`
 }
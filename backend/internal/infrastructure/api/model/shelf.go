@@ -1,8 +1,13 @@
 package model
 
+import "time"
+
 type Shelf struct {
 	Id string `json:"id" bson:"id"`
 	ShelfBase
+	DomainVerificationToken string     `json:"domainVerificationToken,omitempty" bson:"domainVerificationToken,omitempty" doc:"Value of the TXT record _linkshelf.<domain> which proves the ownership of the domain."`
+	DomainVerifiedAt        *time.Time `json:"domainVerifiedAt,omitempty" bson:"domainVerifiedAt,omitempty"`
+	UnpublishedAt           *time.Time `json:"unpublishedAt,omitempty" bson:"unpublishedAt,omitempty" doc:"Set if a moderator unpublished the shelf, it's not served on its domain until it's published again."`
 }
 
 type ShelfBase struct {
@@ -12,7 +17,8 @@ type ShelfBase struct {
 	Description string `json:"description" bson:"description"`
 	Theme       string `json:"theme" bson:"theme"`
 	Icon        string `json:"icon" bson:"icon"`
-	UserId      string `json:"userId" bson:"userId"`
+	UserId      string `json:"userId,omitempty" bson:"userId,omitempty" doc:"Owner of a personal shelf, defaults to the caller. It's empty for team shelves."`
+	TeamId      string `json:"teamId,omitempty" bson:"teamId,omitempty" doc:"Team which owns the shelf. It's only set on creation, shelves are moved with their owner operation."`
 }
 
 type ShelfRequestBody struct {
@@ -28,6 +34,58 @@ type ShelfFilterFilterAndBody struct {
 	Body ShelfBase `json:"body" bson:"body"`
 }
 
+// ShelfOwner is either a user or a team. An empty owner stands for the caller.
+type ShelfOwner struct {
+	UserId string `json:"userId,omitempty" bson:"userId,omitempty"`
+	TeamId string `json:"teamId,omitempty" bson:"teamId,omitempty"`
+}
+
+type ShelfOwnerFilterAndBody struct {
+	ShelfRequestFilter
+	Body ShelfOwner `json:"body" bson:"body"`
+}
+
+// ShelfClone names the copy of a shelf. The owner defaults to the caller like on creation.
+type ShelfClone struct {
+	Title  string `json:"title" bson:"title" minLength:"1"`
+	Path   string `json:"path" bson:"path" minLength:"1"`
+	UserId string `json:"userId,omitempty" bson:"userId,omitempty"`
+	TeamId string `json:"teamId,omitempty" bson:"teamId,omitempty"`
+}
+
+type ShelfCloneFilterAndBody struct {
+	ShelfRequestFilter
+	Body ShelfClone `json:"body" bson:"body"`
+}
+
 type ShelfResponse struct {
 	Body Shelf `json:"body" bson:"body"`
 }
+
+type ShelvesResponse struct {
+	Body []Shelf `json:"body" bson:"body"`
+}
+
+// ShelfExport is the portable representation of a shelf with all its sections and links. It contains no
+// identifiers, so it can be imported again for any user and on any instance.
+type ShelfExport struct {
+	Title       string          `json:"title" bson:"title"`
+	Path        string          `json:"path" bson:"path"`
+	Domain      string          `json:"domain" bson:"domain"`
+	Description string          `json:"description" bson:"description"`
+	Theme       string          `json:"theme" bson:"theme"`
+	Icon        string          `json:"icon" bson:"icon"`
+	Sections    []SectionExport `json:"sections" bson:"sections"`
+}
+
+type SectionExport struct {
+	Title string       `json:"title" bson:"title"`
+	Links []LinkExport `json:"links" bson:"links"`
+}
+
+type LinkExport struct {
+	Title string `json:"title" bson:"title"`
+	Link  string `json:"link" bson:"link"`
+	Icon  string `json:"icon" bson:"icon"`
+	Color string `json:"color" bson:"color"`
+}
@@ -0,0 +1,72 @@
+package model
+
+import "time"
+
+// ShelfInvitation offers a share of a shelf. Invitations with an email are mailed and can only be accepted by
+// the user with that address, the others are shareable links. Only the hash of the token is stored.
+type ShelfInvitation struct {
+	Id         string
+	ShelfId    string
+	Role       string
+	Email      string
+	TokenHash  string
+	InvitedBy  string
+	ExpiresAt  time.Time
+	AcceptedAt *time.Time
+	AcceptedBy string
+	DeclinedAt *time.Time
+	CreatedAt  time.Time
+}
+
+type ShelfInvitationCreate struct {
+	Role  string `json:"role" bson:"role" enum:"editor,viewer" doc:"The role of the share the invitee gets."`
+	Email string `json:"email,omitempty" bson:"email,omitempty" format:"email" doc:"Mail the invitation to this address, a shareable link is returned if omitted."`
+}
+
+type ShelfInvitationFilterAndBody struct {
+	ShelfRequestFilter
+	Body ShelfInvitationCreate `json:"body" bson:"body"`
+}
+
+type ShelfInvitationFilter struct {
+	ShelfRequestFilter
+	InvitationId string `path:"invitationId" doc:"The identifier of the invitation."`
+}
+
+// ShelfInvitationOutput is an invitation as returned to clients, the link with the token is only returned
+// once for invitations without an email.
+type ShelfInvitationOutput struct {
+	Id        string    `json:"id" bson:"id"`
+	ShelfId   string    `json:"shelf_id" bson:"shelf_id"`
+	Role      string    `json:"role" bson:"role"`
+	Email     string    `json:"email,omitempty" bson:"email,omitempty"`
+	InvitedBy string    `json:"invited_by,omitempty" bson:"invited_by,omitempty"`
+	ExpiresAt time.Time `json:"expires_at" bson:"expires_at"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+type ShelfInvitationCreated struct {
+	ShelfInvitationOutput
+	Link string `json:"link,omitempty" bson:"link,omitempty" doc:"The link to share with the invitee, it's only shown once."`
+}
+
+type ShelfInvitationCreatedResponse struct {
+	Body ShelfInvitationCreated `json:"body" bson:"body"`
+}
+
+type ShelfInvitationsResponse struct {
+	Body []ShelfInvitationOutput `json:"body" bson:"body"`
+}
+
+type ShelfInvitationToken struct {
+	Token string `json:"token" bson:"token" minLength:"1" doc:"The token of the invitation mail or link."`
+}
+
+type AcceptShelfInvitationRequestBody struct {
+	UserRequestFilter
+	Body ShelfInvitationToken `json:"body" bson:"body"`
+}
+
+type DeclineShelfInvitationRequestBody struct {
+	Body ShelfInvitationToken `json:"body" bson:"body"`
+}
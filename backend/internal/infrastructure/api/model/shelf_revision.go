@@ -0,0 +1,96 @@
+package model
+
+import "time"
+
+// Kinds of changes in a revision diff.
+const (
+	RevisionChangeAdded   = "added"
+	RevisionChangeRemoved = "removed"
+	RevisionChangeChanged = "changed"
+)
+
+// ShelfRevision is a snapshot of a shelf with all its sections and links. The snapshot is only set when a
+// single revision is requested.
+type ShelfRevision struct {
+	Id           string         `json:"id" bson:"id"`
+	ShelfId      string         `json:"shelfId" bson:"shelfId"`
+	AuthorId     string         `json:"authorId,omitempty" bson:"authorId,omitempty" doc:"The user whose changes led to the revision, empty for the CLI and deleted users."`
+	AuthorName   string         `json:"authorName,omitempty" bson:"authorName,omitempty"`
+	RestoredFrom string         `json:"restoredFrom,omitempty" bson:"restoredFrom,omitempty" doc:"The revision the shelf was restored to, if the revision is a restore."`
+	CreatedAt    time.Time      `json:"createdAt" bson:"createdAt"`
+	UpdatedAt    time.Time      `json:"updatedAt" bson:"updatedAt" doc:"Changes of the same author within a short time are batched into a single revision."`
+	Snapshot     *ShelfSnapshot `json:"snapshot,omitempty" bson:"snapshot,omitempty"`
+}
+
+// ShelfSnapshot is the tree of a shelf at the time of a revision. Path and domain are the address of the
+// shelf, they're neither part of it nor restored.
+type ShelfSnapshot struct {
+	Title       string            `json:"title" bson:"title"`
+	Description string            `json:"description" bson:"description"`
+	Theme       string            `json:"theme" bson:"theme"`
+	Icon        string            `json:"icon" bson:"icon"`
+	Sections    []SectionSnapshot `json:"sections" bson:"sections"`
+}
+
+type SectionSnapshot struct {
+	Id    string         `json:"id" bson:"id"`
+	Title string         `json:"title" bson:"title"`
+	Links []LinkSnapshot `json:"links" bson:"links"`
+}
+
+type LinkSnapshot struct {
+	Id    string `json:"id" bson:"id"`
+	Title string `json:"title" bson:"title"`
+	Link  string `json:"link" bson:"link"`
+	Icon  string `json:"icon" bson:"icon"`
+	Color string `json:"color" bson:"color"`
+}
+
+type ShelfRevisionListRequest struct {
+	ShelfRequestFilter
+	PaginationQuery
+}
+
+type ShelfRevisionFilter struct {
+	ShelfRequestFilter
+	RevisionId string `path:"revisionId"`
+}
+
+type ShelfRevisionDiffRequest struct {
+	ShelfRequestFilter
+	From string `query:"from" required:"true" maxLength:"36" doc:"The older revision."`
+	To   string `query:"to" maxLength:"36" doc:"The newer revision, the current state of the shelf if empty."`
+}
+
+// ShelfRevisionDiff lists what changed from one revision to another. Sections and links are matched by
+// their IDs, links which moved to another section show the change of their section ID.
+type ShelfRevisionDiff struct {
+	From     string                 `json:"from" bson:"from"`
+	To       string                 `json:"to,omitempty" bson:"to,omitempty"`
+	Shelf    map[string]AuditChange `json:"shelf" bson:"shelf"`
+	Sections []RevisionEntityDiff   `json:"sections" bson:"sections"`
+	Links    []RevisionEntityDiff   `json:"links" bson:"links"`
+}
+
+type RevisionEntityDiff struct {
+	Id      string                 `json:"id" bson:"id"`
+	Change  string                 `json:"change" bson:"change" enum:"added,removed,changed"`
+	Changes map[string]AuditChange `json:"changes" bson:"changes"`
+}
+
+type ShelfRevisionPage struct {
+	Items []ShelfRevision `json:"items" bson:"items"`
+	Pagination
+}
+
+type ShelfRevisionPageResponse struct {
+	Body ShelfRevisionPage `json:"body" bson:"body"`
+}
+
+type ShelfRevisionResponse struct {
+	Body ShelfRevision `json:"body" bson:"body"`
+}
+
+type ShelfRevisionDiffResponse struct {
+	Body ShelfRevisionDiff `json:"body" bson:"body"`
+}
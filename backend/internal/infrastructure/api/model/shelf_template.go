@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+// ShelfTemplate describes the sections and placeholder links a new shelf starts with. Built-in templates are
+// shipped with the application and have no author, the others were published by users from their shelves.
+type ShelfTemplate struct {
+	Id          string          `json:"id" bson:"id"`
+	Name        string          `json:"name" bson:"name"`
+	Description string          `json:"description" bson:"description"`
+	Theme       string          `json:"theme" bson:"theme"`
+	Icon        string          `json:"icon" bson:"icon"`
+	BuiltIn     bool            `json:"builtIn" bson:"builtIn"`
+	AuthorId    string          `json:"authorId,omitempty" bson:"authorId,omitempty"`
+	AuthorName  string          `json:"authorName,omitempty" bson:"authorName,omitempty"`
+	CreatedAt   *time.Time      `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+	Sections    []SectionExport `json:"sections" bson:"sections"`
+}
+
+// ShelfTemplatePublish names the template published from a shelf.
+type ShelfTemplatePublish struct {
+	Name        string `json:"name" bson:"name" minLength:"1"`
+	Description string `json:"description,omitempty" bson:"description,omitempty"`
+}
+
+type ShelfTemplatePublishFilterAndBody struct {
+	ShelfRequestFilter
+	Body ShelfTemplatePublish `json:"body" bson:"body"`
+}
+
+type ShelfTemplateFilter struct {
+	TemplateId string `path:"templateId"`
+}
+
+// ShelfFromTemplate names the shelf created from a template.
+type ShelfFromTemplate struct {
+	TemplateId string `json:"templateId" bson:"templateId" minLength:"1"`
+	Title      string `json:"title" bson:"title" minLength:"1"`
+	Path       string `json:"path" bson:"path" minLength:"1"`
+}
+
+type ShelfFromTemplateFilterAndBody struct {
+	UserRequestFilter
+	Body ShelfFromTemplate `json:"body" bson:"body"`
+}
+
+type ShelfTemplateResponse struct {
+	Body ShelfTemplate `json:"body" bson:"body"`
+}
+
+type ShelfTemplatesResponse struct {
+	Body []ShelfTemplate `json:"body" bson:"body"`
+}
@@ -0,0 +1,105 @@
+package model
+
+import "time"
+
+// Roles of team members and of users a shelf is shared with. Owners manage the team, its members and the
+// team shelves, editors change the content of shelves and viewers can only read them. Shares grant editor
+// or viewer at most.
+const (
+	ShelfRoleOwner  = "owner"
+	ShelfRoleEditor = "editor"
+	ShelfRoleViewer = "viewer"
+)
+
+type Team struct {
+	Id        string    `json:"id" bson:"id"`
+	Name      string    `json:"name" bson:"name"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+type TeamMember struct {
+	TeamId    string    `json:"team_id" bson:"team_id"`
+	UserId    string    `json:"user_id" bson:"user_id"`
+	Role      string    `json:"role" bson:"role" enum:"owner,editor,viewer"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// ShelfShare grants a single user access to a shelf, independent of who owns the shelf.
+type ShelfShare struct {
+	ShelfId   string    `json:"shelf_id" bson:"shelf_id"`
+	UserId    string    `json:"user_id" bson:"user_id"`
+	Role      string    `json:"role" bson:"role" enum:"editor,viewer"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+type TeamBase struct {
+	Name string `json:"name" bson:"name" minLength:"1" maxLength:"255"`
+}
+
+type TeamCreateFilterAndBody struct {
+	UserRequestFilter
+	Body TeamBase `json:"body" bson:"body"`
+}
+
+type TeamRequestFilter struct {
+	TeamId string `path:"teamId" doc:"The identifier of the team."`
+}
+
+type TeamFilterAndBody struct {
+	TeamRequestFilter
+	Body TeamBase `json:"body" bson:"body"`
+}
+
+type TeamResponse struct {
+	Body Team `json:"body" bson:"body"`
+}
+
+type TeamsResponse struct {
+	Body []Team `json:"body" bson:"body"`
+}
+
+// TeamMemberFilter addresses a member by `memberId`, since a `userId` path parameter restricts an
+// operation to that user.
+type TeamMemberFilter struct {
+	TeamRequestFilter
+	MemberId string `path:"memberId" doc:"The identifier of the user."`
+}
+
+type TeamMemberRole struct {
+	Role string `json:"role" bson:"role" enum:"owner,editor,viewer"`
+}
+
+type TeamMemberFilterAndBody struct {
+	TeamMemberFilter
+	Body TeamMemberRole `json:"body" bson:"body"`
+}
+
+type TeamMemberResponse struct {
+	Body TeamMember `json:"body" bson:"body"`
+}
+
+type TeamMembersResponse struct {
+	Body []TeamMember `json:"body" bson:"body"`
+}
+
+type ShelfShareFilter struct {
+	ShelfRequestFilter
+	MemberId string `path:"memberId" doc:"The identifier of the user the shelf is shared with."`
+}
+
+type ShelfShareRole struct {
+	Role string `json:"role" bson:"role" enum:"editor,viewer"`
+}
+
+type ShelfShareFilterAndBody struct {
+	ShelfShareFilter
+	Body ShelfShareRole `json:"body" bson:"body"`
+}
+
+type ShelfShareResponse struct {
+	Body ShelfShare `json:"body" bson:"body"`
+}
+
+type ShelfSharesResponse struct {
+	Body []ShelfShare `json:"body" bson:"body"`
+}
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// Types of items in the trash.
+const (
+	TrashItemShelf   = "shelf"
+	TrashItemSection = "section"
+	TrashItemLink    = "link"
+)
+
+// TrashItem is a deleted shelf, section or link. Children which were deleted together with their parent
+// aren't listed on their own, they're restored with it.
+type TrashItem struct {
+	Type      string    `json:"type" bson:"type" enum:"shelf,section,link"`
+	Id        string    `json:"id" bson:"id"`
+	Title     string    `json:"title" bson:"title"`
+	ShelfId   string    `json:"shelfId" bson:"shelfId"`
+	SectionId string    `json:"sectionId,omitempty" bson:"sectionId,omitempty"`
+	DeletedAt time.Time `json:"deletedAt" bson:"deletedAt"`
+	DeletedBy string    `json:"deletedBy,omitempty" bson:"deletedBy,omitempty"`
+	PurgeAt   time.Time `json:"purgeAt" bson:"purgeAt" doc:"When the item is removed for good."`
+}
+
+type TrashItemFilter struct {
+	UserRequestFilter
+	ItemType string `path:"itemType" enum:"shelf,section,link"`
+	ItemId   string `path:"itemId"`
+}
+
+type TrashResponse struct {
+	Body []TrashItem `json:"body" bson:"body"`
+}
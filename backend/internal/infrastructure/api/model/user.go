@@ -1,29 +1,59 @@
 package model
 
+import "time"
+
+// User is the stored user. It carries the password hash and must never be returned to clients, responses
+// use UserOutput instead.
 type User struct {
-	Id string `json:"id" bson:"id"`
+	Id               string
+	Role             string
+	EmailVerifiedAt  *time.Time
+	DisabledAt       *time.Time
+	PurgeAt          *time.Time
+	TwoFactorEnabled bool
+	Password         string `json:"-" bson:"-"`
 	UserBase
 }
 
+// UserBase holds the attributes of a user which clients can change.
 type UserBase struct {
-	Email     string `json:"email" bson:"email"`
+	Email     string `json:"email" bson:"email" format:"email"`
 	FirstName string `json:"first_name" bson:"first_name"`
 	LastName  string `json:"last_name" bson:"last_name"`
-	Password  string `json:"password" bson:"password"`
+}
+
+// UserCreate is the input of a new user, the only one which accepts a password besides the password changes.
+type UserCreate struct {
+	UserBase
+	Password string `json:"password" bson:"password" minLength:"1" doc:"The password of the user, it has to satisfy the password policy of the instance."`
+}
+
+// UserOutput is the user as returned to clients, it contains no credentials.
+type UserOutput struct {
+	Id               string     `json:"id" bson:"id"`
+	Email            string     `json:"email" bson:"email"`
+	FirstName        string     `json:"first_name" bson:"first_name"`
+	LastName         string     `json:"last_name" bson:"last_name"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at,omitempty" bson:"email_verified_at,omitempty"`
+	DisabledAt       *time.Time `json:"disabled_at,omitempty" bson:"disabled_at,omitempty" doc:"Set while the account is disabled, e.g. during the grace period of its deletion."`
+	PurgeAt          *time.Time `json:"purge_at,omitempty" bson:"purge_at,omitempty" doc:"When the account and all its data will be deleted for good, it can be restored until then."`
+	TwoFactorEnabled bool       `json:"two_factor_enabled" bson:"two_factor_enabled"`
+	Role             string     `json:"role" bson:"role" enum:"user,admin"`
 }
 
 type UserRequestBody struct {
-	Body UserBase `json:"body" bson:"body"`
+	Body UserCreate `json:"body" bson:"body"`
 }
 
 type UserPatchPasswordFilterAndBody struct {
 	UserRequestFilter
+	SecondFactorHeader
 	Body UserRequestBodyOnlyPassword `json:"body" bson:"body"`
 }
 
 type UserRequestBodyOnlyPassword struct {
-	OldPassword string `json:"old_password" bson:"old_password"`
-	NewPassword string `json:"new_password" bson:"new_password"`
+	OldPassword string `json:"old_password" bson:"old_password" minLength:"1"`
+	NewPassword string `json:"new_password" bson:"new_password" minLength:"1"`
 }
 
 type UserRequestFilter struct {
@@ -36,5 +66,62 @@ type UserFilterFilterAndBody struct {
 }
 
 type UserResponse struct {
-	Body User `json:"body" bson:"body"`
+	Body UserOutput `json:"body" bson:"body"`
+}
+
+// Roles of users. Admins may use the admin API in addition to everything users may do.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Purposes of user tokens, a token is only accepted for the purpose it was issued for.
+const (
+	UserTokenPurposeVerifyEmail   = "verify_email"
+	UserTokenPurposeResetPassword = "reset_password"
+)
+
+// UserToken is a single-use token which is sent to a user by mail. Only the hash of the token is stored, so
+// a leaked database doesn't allow taking over accounts.
+type UserToken struct {
+	Id        string
+	UserId    string
+	Purpose   string
+	Email     string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type VerifyEmailRequestBody struct {
+	Body VerifyEmailBody `json:"body" bson:"body"`
+}
+
+type VerifyEmailBody struct {
+	Token string `json:"token" bson:"token" minLength:"1" doc:"The token of the verification mail."`
+}
+
+type ForgotPasswordRequestBody struct {
+	Body ForgotPasswordBody `json:"body" bson:"body"`
+}
+
+type ForgotPasswordBody struct {
+	Email string `json:"email" bson:"email" format:"email" doc:"The email address of the account."`
+}
+
+type ResetPasswordRequestBody struct {
+	Body ResetPasswordBody `json:"body" bson:"body"`
+}
+
+type ResetPasswordBody struct {
+	Token       string `json:"token" bson:"token" minLength:"1" doc:"The token of the password reset mail."`
+	NewPassword string `json:"new_password" bson:"new_password" minLength:"1"`
+}
+
+// UserExportResponse is a ZIP archive with all data stored about a user.
+type UserExportResponse struct {
+	ContentType        string `header:"Content-Type"`
+	ContentDisposition string `header:"Content-Disposition"`
+	Body               []byte
 }
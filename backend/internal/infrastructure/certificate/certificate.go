@@ -0,0 +1,108 @@
+package certificate
+
+import (
+	"backend/internal/config"
+	"backend/internal/domain"
+	"backend/internal/infrastructure/repository"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+const (
+	ModeNone   = "none"
+	ModeStatic = "static"
+	ModeACME   = "acme"
+)
+
+// DomainVerifier decides whether a custom domain belongs to a shelf and its ownership was verified.
+type DomainVerifier interface {
+	IsVerifiedDomain(ctx context.Context, domain string) (bool, error)
+}
+
+// NewTLSConfig returns the TLS configuration of the server depending on `server.tls.mode`. Without TLS both
+// return values are nil. In the ACME mode the returned manager has to answer the HTTP-01 challenges.
+func NewTLSConfig(cfg *config.Config, svc *domain.Service, repo *repository.Repository) (*tls.Config, *autocert.Manager, error) {
+	switch mode := strings.ToLower(cfg.Server.TLS.Mode); mode {
+	case "", ModeNone:
+		return nil, nil, nil
+	case ModeStatic:
+		cert, err := tls.LoadX509KeyPair(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+
+		slog.Info("TLS enabled with static certificate")
+		return &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{cert},
+		}, nil, nil
+	case ModeACME:
+		cache, err := newCache(cfg, repo)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		manager := NewManager(
+			&acme.Client{DirectoryURL: cfg.Server.TLS.ACME.DirectoryURL},
+			cache,
+			HostPolicy(cfg.Server.Host, svc.ShelfService),
+			cfg.Server.TLS.ACME.Email,
+		)
+
+		slog.Info("TLS enabled with ACME", slog.String("directory", manager.Client.DirectoryURL))
+		return manager.TLSConfig(), manager, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported TLS mode %q, use one of: %s, %s, %s", mode, ModeNone, ModeStatic, ModeACME)
+	}
+}
+
+// NewManager creates the ACME manager which provisions certificates on demand during the TLS handshake.
+func NewManager(client *acme.Client, cache autocert.Cache, policy autocert.HostPolicy, email string) *autocert.Manager {
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      cache,
+		HostPolicy: policy,
+		Client:     client,
+		Email:      email,
+	}
+}
+
+// HostPolicy only allows certificates for the host of the instance itself and for verified shelf domains,
+// otherwise anybody could point a domain to the server and make it request certificates for it.
+func HostPolicy(host string, verifier DomainVerifier) autocert.HostPolicy {
+	host = strings.ToLower(host)
+
+	return func(ctx context.Context, requested string) error {
+		requested = strings.ToLower(requested)
+		if requested == host {
+			return nil
+		}
+
+		verified, err := verifier.IsVerifiedDomain(ctx, requested)
+		if err != nil {
+			return fmt.Errorf("failed to check domain %s: %w", requested, err)
+		}
+		if !verified {
+			return fmt.Errorf("domain %s isn't a verified shelf domain", requested)
+		}
+
+		return nil
+	}
+}
+
+func newCache(cfg *config.Config, repo *repository.Repository) (autocert.Cache, error) {
+	switch cache := strings.ToLower(cfg.Server.TLS.ACME.Cache); cache {
+	case "", "database":
+		return repo.CertificateRepository, nil
+	case "directory":
+		return autocert.DirCache(cfg.Server.TLS.ACME.CacheDir), nil
+	default:
+		return nil, fmt.Errorf("unsupported certificate cache %q, use database or directory", cache)
+	}
+}
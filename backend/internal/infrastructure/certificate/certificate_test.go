@@ -0,0 +1,277 @@
+package certificate
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+type fakeVerifier map[string]bool
+
+func (v fakeVerifier) IsVerifiedDomain(_ context.Context, domain string) (bool, error) {
+	return v[domain], nil
+}
+
+func TestHostPolicy(t *testing.T) {
+	policy := HostPolicy("LinkShelf.example.com", fakeVerifier{"verified.example.org": true})
+
+	require.NoError(t, policy(context.Background(), "linkshelf.example.com"))
+	require.NoError(t, policy(context.Background(), "verified.example.org"))
+	require.Error(t, policy(context.Background(), "unverified.example.org"))
+}
+
+func TestManagerIssuesCertificatesOnlyForVerifiedDomains(t *testing.T) {
+	ca := newFakeACME(t)
+	cacheDir := t.TempDir()
+
+	manager := NewManager(
+		&acme.Client{DirectoryURL: ca.server.URL + "/directory"},
+		autocert.DirCache(cacheDir),
+		HostPolicy("linkshelf.example.com", fakeVerifier{"verified.example.org": true}),
+		"admin@example.com",
+	)
+
+	cert, err := manager.GetCertificate(clientHello("verified.example.org"))
+	require.NoError(t, err)
+	require.NotNil(t, cert.Leaf)
+	require.Equal(t, []string{"verified.example.org"}, cert.Leaf.DNSNames)
+	require.FileExists(t, filepath.Join(cacheDir, "verified.example.org"))
+
+	// The second handshake must be served from the cache without another order.
+	_, err = manager.GetCertificate(clientHello("verified.example.org"))
+	require.NoError(t, err)
+	require.Equal(t, 1, ca.orderCount())
+
+	_, err = manager.GetCertificate(clientHello("unverified.example.org"))
+	require.Error(t, err)
+	require.Equal(t, 1, ca.orderCount())
+
+	entries, err := os.ReadDir(cacheDir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		require.NotContains(t, entry.Name(), "unverified")
+	}
+}
+
+func clientHello(serverName string) *tls.ClientHelloInfo {
+	return &tls.ClientHelloInfo{
+		ServerName:       serverName,
+		SignatureSchemes: []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+		SupportedCurves:  []tls.CurveID{tls.CurveP256},
+		CipherSuites:     []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+	}
+}
+
+// fakeACME is a minimal ACME (RFC 8555) server in the style of Pebble. It skips the JWS validation and
+// creates every order in the "ready" state, so no challenges have to be solved.
+type fakeACME struct {
+	server *httptest.Server
+	caKey  *ecdsa.PrivateKey
+	caCert *x509.Certificate
+
+	mu     sync.Mutex
+	orders map[string][]string
+	certs  map[string][]byte
+}
+
+func newFakeACME(t *testing.T) *fakeACME {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Fake ACME CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	ca := &fakeACME{
+		caKey:  caKey,
+		caCert: caCert,
+		orders: make(map[string][]string),
+		certs:  make(map[string][]byte),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/directory", ca.directory)
+	mux.HandleFunc("/nonce", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("POST /account", ca.newAccount)
+	mux.HandleFunc("POST /order", ca.newOrder)
+	mux.HandleFunc("POST /finalize/{id}", ca.finalize)
+	mux.HandleFunc("POST /cert/{id}", ca.certificate)
+
+	ca.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Replay-Nonce", base64.RawURLEncoding.EncodeToString([]byte(time.Now().String())))
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ca.server.Close)
+
+	return ca
+}
+
+func (ca *fakeACME) orderCount() int {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	return len(ca.orders)
+}
+
+func (ca *fakeACME) directory(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"newNonce":   ca.server.URL + "/nonce",
+		"newAccount": ca.server.URL + "/account",
+		"newOrder":   ca.server.URL + "/order",
+		"revokeCert": ca.server.URL + "/revoke",
+		"keyChange":  ca.server.URL + "/key-change",
+	})
+}
+
+func (ca *fakeACME) newAccount(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Location", ca.server.URL+"/account/1")
+	writeJSON(w, http.StatusCreated, map[string]any{"status": "valid"})
+}
+
+func (ca *fakeACME) newOrder(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Identifiers []struct {
+			Type  string `json:"type"`
+			Value string `json:"value"`
+		} `json:"identifiers"`
+	}
+	if err := decodeJWSPayload(r, &payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var domains []string
+	for _, identifier := range payload.Identifiers {
+		domains = append(domains, identifier.Value)
+	}
+
+	ca.mu.Lock()
+	id := fmt.Sprint(len(ca.orders) + 1)
+	ca.orders[id] = domains
+	ca.mu.Unlock()
+
+	w.Header().Set("Location", ca.server.URL+"/order/"+id)
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"status":         "ready",
+		"identifiers":    payload.Identifiers,
+		"authorizations": []string{},
+		"finalize":       ca.server.URL + "/finalize/" + id,
+	})
+}
+
+func (ca *fakeACME) finalize(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var payload struct {
+		CSR string `json:"csr"`
+	}
+	if err := decodeJWSPayload(r, &payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	csrDER, err := base64.RawURLEncoding.DecodeString(payload.CSR)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	csr, err := x509.ParseCertificateRequest(csrDER)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: csr.DNSNames[0]},
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.caCert, csr.PublicKey, ca.caKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	chain := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	chain = append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.caCert.Raw})...)
+
+	ca.mu.Lock()
+	ca.certs[id] = chain
+	ca.mu.Unlock()
+
+	w.Header().Set("Location", ca.server.URL+"/order/"+id)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":      "valid",
+		"finalize":    ca.server.URL + "/finalize/" + id,
+		"certificate": ca.server.URL + "/cert/" + id,
+	})
+}
+
+func (ca *fakeACME) certificate(w http.ResponseWriter, r *http.Request) {
+	ca.mu.Lock()
+	chain, ok := ca.certs[r.PathValue("id")]
+	ca.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pem-certificate-chain")
+	_, _ = w.Write(chain)
+}
+
+func decodeJWSPayload(r *http.Request, v any) error {
+	var jws struct {
+		Payload string `json:"payload"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&jws); err != nil {
+		return err
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(jws.Payload, "="))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(payload, v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,125 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Kind string
+
+const (
+	// Liveness checks fail if the process is broken and has to be restarted.
+	Liveness Kind = "liveness"
+	// Readiness checks fail if the process can't serve requests at the moment, e.g. the database is down.
+	Readiness Kind = "readiness"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name string
+	kind Kind
+	fn   CheckFunc
+}
+
+// Registry holds the registered checks and runs them with a timeout per check.
+type Registry struct {
+	timeout time.Duration
+	checks  []check
+}
+
+type Report struct {
+	Healthy bool                   `json:"-"`
+	Checks  map[string]CheckResult `json:"checks"`
+}
+
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{
+		timeout: timeout,
+	}
+}
+
+func (r *Registry) Register(kind Kind, name string, fn CheckFunc) {
+	r.checks = append(r.checks, check{
+		name: name,
+		kind: kind,
+		fn:   fn,
+	})
+}
+
+// Run executes all checks of the given kind concurrently. The report is only healthy if every check passed.
+func (r *Registry) Run(ctx context.Context, kind Kind) Report {
+	report := Report{
+		Healthy: true,
+		Checks:  make(map[string]CheckResult),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range r.checks {
+		if c.kind != kind {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := r.run(ctx, c)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.name] = result
+			if result.Status != StatusUp {
+				report.Healthy = false
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, c check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := runWithContext(ctx, c.fn)
+	result := CheckResult{
+		Status:    StatusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// runWithContext returns as soon as the context is done, even if the check itself ignores the context.
+func runWithContext(ctx context.Context, fn CheckFunc) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
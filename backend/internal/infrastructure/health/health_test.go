@@ -0,0 +1,40 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryRunsOnlyChecksOfTheGivenKind(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.Register(Readiness, "database", func(ctx context.Context) error { return nil })
+	registry.Register(Liveness, "workers", func(ctx context.Context) error { return errors.New("stuck") })
+
+	report := registry.Run(context.Background(), Readiness)
+	require.True(t, report.Healthy)
+	require.Len(t, report.Checks, 1)
+	require.Equal(t, StatusUp, report.Checks["database"].Status)
+
+	report = registry.Run(context.Background(), Liveness)
+	require.False(t, report.Healthy)
+	require.Equal(t, StatusDown, report.Checks["workers"].Status)
+	require.Equal(t, "stuck", report.Checks["workers"].Error)
+}
+
+func TestRegistryTimesOutSlowChecks(t *testing.T) {
+	registry := NewRegistry(10 * time.Millisecond)
+	registry.Register(Readiness, "database", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	start := time.Now()
+	report := registry.Run(context.Background(), Readiness)
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+	require.False(t, report.Healthy)
+	require.Equal(t, context.DeadlineExceeded.Error(), report.Checks["database"].Error)
+}
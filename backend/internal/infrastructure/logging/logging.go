@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// New creates a logger which writes either human-readable text or one JSON object per line.
+func New(w io.Writer, format string, level slog.Level) (*slog.Logger, error) {
+	options := &slog.HandlerOptions{Level: level}
+
+	switch strings.ToLower(format) {
+	case "", "json":
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	case "text":
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	default:
+		return nil, fmt.Errorf("unsupported logging format %q, use json or text", format)
+	}
+}
+
+type loggerKey struct{}
+
+type requestKey struct{}
+
+// request holds the request-scoped values. The user is only known after the authentication, which runs
+// further down the handler chain, so it's set on the shared struct instead of a derived context.
+type request struct {
+	id       string
+	clientIP string
+
+	mu     sync.Mutex
+	userID string
+}
+
+// WithRequest starts the logging scope of a request. Every logger returned by FromContext carries the
+// request ID from now on.
+func WithRequest(ctx context.Context, requestID, clientIP string) context.Context {
+	ctx = context.WithValue(ctx, requestKey{}, &request{id: requestID, clientIP: clientIP})
+	return WithLogger(ctx, FromContext(ctx).With(slog.String("requestId", requestID)))
+}
+
+// WithLogger returns a context carrying the given logger, usually one enriched by With.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the request-scoped logger, or the default logger outside a request.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// RequestID returns the ID of the current request, or an empty string outside a request.
+func RequestID(ctx context.Context) string {
+	if r, ok := ctx.Value(requestKey{}).(*request); ok {
+		return r.id
+	}
+	return ""
+}
+
+// ClientIP returns the IP address of the client of the current request, or an empty string outside a
+// request.
+func ClientIP(ctx context.Context) string {
+	if r, ok := ctx.Value(requestKey{}).(*request); ok {
+		return r.clientIP
+	}
+	return ""
+}
+
+// SetUserID records the authenticated user of the current request, so it shows up in the request log.
+func SetUserID(ctx context.Context, userID string) {
+	if r, ok := ctx.Value(requestKey{}).(*request); ok {
+		r.mu.Lock()
+		r.userID = userID
+		r.mu.Unlock()
+	}
+}
+
+// UserID returns the authenticated user of the current request, or an empty string if there's none.
+func UserID(ctx context.Context) string {
+	if r, ok := ctx.Value(requestKey{}).(*request); ok {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return r.userID
+	}
+	return ""
+}
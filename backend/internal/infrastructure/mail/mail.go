@@ -0,0 +1,83 @@
+package mail
+
+import (
+	"backend/internal/config"
+	"backend/internal/infrastructure/logging"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Message is a mail with a plain text and an HTML body, clients show the one they support.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers mails. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// NewMailer creates the mailer selected by `mail.driver`.
+func NewMailer(cfg *config.Config) (Mailer, error) {
+	switch driver := strings.ToLower(cfg.Mail.Driver); driver {
+	case "smtp":
+		slog.Info("Mails are sent via SMTP", slog.String("host", cfg.Mail.SMTP.Host))
+		return NewSMTPMailer(cfg.Mail.SMTP.Host, cfg.Mail.SMTP.Port, cfg.Mail.SMTP.Username, cfg.Mail.SMTP.Password, cfg.Mail.From), nil
+	case "log":
+		slog.Warn("Mails are only logged and never delivered")
+		return LogMailer{}, nil
+	case "memory":
+		slog.Warn("Mails are only kept in memory and never delivered")
+		return NewMemoryMailer(), nil
+	default:
+		return nil, fmt.Errorf("unsupported mail driver %q, use smtp, log or memory", driver)
+	}
+}
+
+// LogMailer only logs the recipient and subject of the mails and drops them. It's meant for local
+// development without a mail server.
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, message Message) error {
+	logging.FromContext(ctx).Info("Mail dropped", slog.String("to", message.To), slog.String("subject", message.Subject))
+	return nil
+}
+
+// maxMemoryMessages bounds the mails a MemoryMailer keeps, older ones are dropped.
+const maxMemoryMessages = 100
+
+// MemoryMailer keeps the latest sent mails in memory instead of delivering them. It's meant for tests.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(ctx context.Context, message Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	logging.FromContext(ctx).Info("Mail kept in memory", slog.String("to", message.To), slog.String("subject", message.Subject))
+	m.messages = append(m.messages, message)
+	if len(m.messages) > maxMemoryMessages {
+		m.messages = append([]Message(nil), m.messages[len(m.messages)-maxMemoryMessages:]...)
+	}
+	return nil
+}
+
+// Messages returns a copy of the kept mails, oldest first.
+func (m *MemoryMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Message(nil), m.messages...)
+}
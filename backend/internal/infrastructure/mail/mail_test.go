@@ -0,0 +1,169 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderEscapesOnlyTheHTMLBody(t *testing.T) {
+	message, err := Render(TemplateResetPassword, map[string]any{
+		"AppName":   "LinkShelf",
+		"Name":      "<b>Jane</b>",
+		"Link":      "https://linkshelf.example.com/reset-password?token=abc",
+		"ExpiresAt": time.Date(2026, 1, 2, 3, 4, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, "Reset your password for LinkShelf", message.Subject)
+	require.Contains(t, message.Text, "Hello <b>Jane</b>,")
+	require.Contains(t, message.Text, "https://linkshelf.example.com/reset-password?token=abc")
+	require.Contains(t, message.Text, "2026-01-02 03:04 UTC")
+	require.Contains(t, message.HTML, "Hello &lt;b&gt;Jane&lt;/b&gt;,")
+	require.Contains(t, message.HTML, `href="https://linkshelf.example.com/reset-password?token=abc"`)
+}
+
+func TestRenderFailsForUnknownTemplates(t *testing.T) {
+	_, err := Render("unknown", nil)
+	require.Error(t, err)
+}
+
+func TestMemoryMailer(t *testing.T) {
+	mailer := NewMemoryMailer()
+	require.NoError(t, mailer.Send(context.Background(), Message{To: "jane@example.com", Subject: "Hello"}))
+
+	messages := mailer.Messages()
+	require.Len(t, messages, 1)
+	require.Equal(t, "jane@example.com", messages[0].To)
+}
+
+func TestMemoryMailerKeepsOnlyTheLatestMessages(t *testing.T) {
+	mailer := NewMemoryMailer()
+	for i := range maxMemoryMessages + 5 {
+		require.NoError(t, mailer.Send(context.Background(), Message{To: fmt.Sprintf("user-%d@example.com", i)}))
+	}
+
+	messages := mailer.Messages()
+	require.Len(t, messages, maxMemoryMessages)
+	require.Equal(t, "user-5@example.com", messages[0].To)
+	require.Equal(t, fmt.Sprintf("user-%d@example.com", maxMemoryMessages+4), messages[len(messages)-1].To)
+}
+
+func TestSMTPMailerSendsMultipartMessage(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	received := make(chan smtpTransaction, 1)
+	go serveSMTP(t, listener, received)
+
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+
+	mailer := NewSMTPMailer(host, port, "", "", "LinkShelf <no-reply@example.com>")
+	err = mailer.Send(context.Background(), Message{
+		To:      "jane@example.com",
+		Subject: "Grüße",
+		Text:    "plain text",
+		HTML:    "<p>html</p>",
+	})
+	require.NoError(t, err)
+
+	transaction := <-received
+	require.Equal(t, "<no-reply@example.com>", transaction.from)
+	require.Equal(t, "<jane@example.com>", transaction.to)
+
+	parsed, err := mail.ReadMessage(strings.NewReader(transaction.data))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	require.Equal(t, "Grüße", subject)
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+		bodies = append(bodies, string(body))
+	}
+	require.Equal(t, []string{"plain text", "<p>html</p>"}, bodies)
+}
+
+type smtpTransaction struct {
+	from, to, data string
+}
+
+// serveSMTP answers a single SMTP session without any extensions, which is just enough for net/smtp.
+func serveSMTP(t *testing.T, listener net.Listener, received chan<- smtpTransaction) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		_, _ = conn.Write([]byte(line + "\r\n"))
+	}
+
+	var transaction smtpTransaction
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			transaction.from = strings.TrimPrefix(command, "MAIL FROM:")
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			transaction.to = strings.TrimPrefix(command, "RCPT TO:")
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			transaction.data = data.String()
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			received <- transaction
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
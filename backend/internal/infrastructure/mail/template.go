@@ -0,0 +1,51 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Templates are stored as <name>.txt.tmpl and <name>.html.tmpl. The text template defines the subject in
+// a "subject" block, the HTML template is escaped contextually.
+//
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+const (
+	TemplateVerifyEmail     = "verify-email"
+	TemplateResetPassword   = "reset-password"
+	TemplateShelfInvitation = "shelf-invitation"
+)
+
+// Render builds a message from the templates with the given name. The recipient has to be set by the caller.
+func Render(name string, data any) (Message, error) {
+	text, err := texttemplate.ParseFS(templates, "templates/"+name+".txt.tmpl")
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to parse the text template %s: %w", name, err)
+	}
+	html, err := htmltemplate.ParseFS(templates, "templates/"+name+".html.tmpl")
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to parse the HTML template %s: %w", name, err)
+	}
+
+	var subject, textBody, htmlBody bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render the subject of %s: %w", name, err)
+	}
+	if err := text.Execute(&textBody, data); err != nil {
+		return Message{}, fmt.Errorf("failed to render the text body of %s: %w", name, err)
+	}
+	if err := html.Execute(&htmlBody, data); err != nil {
+		return Message{}, fmt.Errorf("failed to render the HTML body of %s: %w", name, err)
+	}
+
+	return Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    textBody.String(),
+		HTML:    htmlBody.String(),
+	}, nil
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry contains all metrics of the application. A dedicated registry is used instead of the global
+// default one, so only metrics registered on purpose are exposed.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "linkshelf",
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of handled HTTP requests by operation and status code.",
+	}, []string{"operation", "method", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "linkshelf",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of handled HTTP requests by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "method"})
+
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "linkshelf",
+		Subsystem: "repository",
+		Name:      "query_duration_seconds",
+		Help:      "Duration of the repository methods including all their statements.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"repository", "method"})
+
+	shelvesCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "linkshelf",
+		Subsystem: "domain",
+		Name:      "shelves_created_total",
+		Help:      "Number of created shelves, including imported ones.",
+	})
+
+	linksCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "linkshelf",
+		Subsystem: "domain",
+		Name:      "links_created_total",
+		Help:      "Number of created links, including imported and cloned ones.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpRequestDuration,
+		queryDuration,
+		shelvesCreated,
+		linksCreated,
+	)
+}
+
+// RegisterDatabase exposes the connection pool statistics of sql.DB.Stats().
+func RegisterDatabase(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+func ObserveRequest(operation, method string, status int, duration time.Duration) {
+	httpRequests.WithLabelValues(operation, method, strconv.Itoa(status)).Inc()
+	httpRequestDuration.WithLabelValues(operation, method).Observe(duration.Seconds())
+}
+
+// ObserveQuery measures a repository method, use it as `defer metrics.ObserveQuery("shelf", "Get")()`.
+func ObserveQuery(repository, method string) func() {
+	start := time.Now()
+	return func() {
+		queryDuration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+	}
+}
+
+func ShelfCreated() {
+	shelvesCreated.Inc()
+}
+
+func LinkCreated() {
+	linksCreated.Inc()
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserveQueryRecordsTheDurationPerRepositoryMethod(t *testing.T) {
+	before := testutil.CollectAndCount(queryDuration, "linkshelf_repository_query_duration_seconds")
+
+	ObserveQuery("shelf", "ObserveQueryTest")()
+	ObserveQuery("shelf", "ObserveQueryTest")()
+
+	require.Equal(t, before+1, testutil.CollectAndCount(queryDuration, "linkshelf_repository_query_duration_seconds"))
+
+	families, err := Registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "linkshelf_repository_query_duration_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["repository"] == "shelf" && labels["method"] == "ObserveQueryTest" {
+				require.Equal(t, uint64(2), metric.GetHistogram().GetSampleCount())
+				return
+			}
+		}
+	}
+	t.Fatal("no histogram for shelf ObserveQueryTest")
+}
@@ -0,0 +1,172 @@
+package ratelimit
+
+import (
+	"backend/internal/config"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Limit is a token bucket which holds up to Requests tokens and is refilled with Requests tokens per
+// Period, so short bursts are allowed while the average rate is bounded. A zero Limit is unlimited.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+func (l Limit) unlimited() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+// refill returns the tokens which are added to the bucket per second.
+func (l Limit) refill() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// Policy combines the bucket of the client IP with the bucket of the user the operation acts on.
+type Policy struct {
+	IP   Limit
+	User Limit
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// Store keeps the token buckets. The in-memory store is enough for a single instance, a shared store
+// like Redis is required as soon as multiple instances serve the API.
+type Store interface {
+	// Take removes one token from the bucket with the given key, if there's one left.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+	Close() error
+}
+
+// Limiter applies the configured policies to operations.
+type Limiter struct {
+	store      Store
+	defaults   Policy
+	operations map[string]Policy
+}
+
+func NewLimiter(store Store, defaults Policy, operations map[string]Policy) *Limiter {
+	return &Limiter{
+		store:      store,
+		defaults:   defaults,
+		operations: operations,
+	}
+}
+
+// NewLimiterFromConfig creates the limiter with the store and policies of the configuration, or returns
+// nil if rate limiting is disabled.
+func NewLimiterFromConfig(cfg *config.Config) (*Limiter, error) {
+	if !cfg.RateLimit.Enabled {
+		return nil, nil
+	}
+
+	operations := make(map[string]Policy, len(cfg.RateLimit.Operations))
+	for operation, policy := range cfg.RateLimit.Operations {
+		operations[strings.ToLower(operation)] = newPolicy(policy)
+	}
+
+	var store Store
+	switch backend := strings.ToLower(cfg.RateLimit.Store); backend {
+	case "memory":
+		store = NewMemoryStore()
+	case "redis":
+		store = NewRedisStore(redis.NewClient(&redis.Options{
+			Addr:     cfg.RateLimit.Redis.Address,
+			Password: cfg.RateLimit.Redis.Password,
+			DB:       cfg.RateLimit.Redis.DB,
+		}))
+	default:
+		return nil, fmt.Errorf("unsupported rate limit store %q, use memory or redis", backend)
+	}
+
+	slog.Info("Rate limiting enabled", slog.String("store", cfg.RateLimit.Store))
+	return NewLimiter(store, newPolicy(cfg.RateLimit.Default), operations), nil
+}
+
+func newPolicy(policy config.RateLimitPolicy) Policy {
+	return Policy{
+		IP:   Limit{Requests: policy.IP.Requests, Period: policy.IP.Period},
+		User: Limit{Requests: policy.User.Requests, Period: policy.User.Period},
+	}
+}
+
+// Allow takes a token from the IP bucket and, if a user is known, from the user bucket of the operation.
+// Both buckets are checked, so neither many IPs against one account nor one IP against many accounts
+// get around the limit.
+func (l *Limiter) Allow(ctx context.Context, operationID, ip, userID string) (Result, error) {
+	result, err := l.AllowIP(ctx, operationID, ip)
+	if err != nil || !result.Allowed || userID == "" {
+		return result, err
+	}
+
+	return l.AllowUser(ctx, operationID, userID)
+}
+
+// AllowIP only takes a token from the IP bucket of the operation. It's checked before the authentication,
+// so guessing bearer tokens is limited as well.
+func (l *Limiter) AllowIP(ctx context.Context, operationID, ip string) (Result, error) {
+	return l.take(ctx, "ip:"+operationID+":"+ip, l.policy(operationID).IP)
+}
+
+// AllowUser only takes a token from the user bucket of the operation. It's for callers which learn the
+// user after the IP bucket was already checked, like the authentication or the email address of a login.
+func (l *Limiter) AllowUser(ctx context.Context, operationID, userID string) (Result, error) {
+	return l.take(ctx, "user:"+operationID+":"+userID, l.policy(operationID).User)
+}
+
+func (l *Limiter) Close() error {
+	return l.store.Close()
+}
+
+func (l *Limiter) policy(operationID string) Policy {
+	policy, ok := l.operations[strings.ToLower(operationID)]
+	if !ok {
+		return l.defaults
+	}
+
+	// Operations only have to configure the buckets they tighten.
+	if policy.IP.unlimited() {
+		policy.IP = l.defaults.IP
+	}
+	if policy.User.unlimited() {
+		policy.User = l.defaults.User
+	}
+	return policy
+}
+
+func (l *Limiter) take(ctx context.Context, key string, limit Limit) (Result, error) {
+	if limit.unlimited() {
+		return Result{Allowed: true, Remaining: math.MaxInt}, nil
+	}
+
+	return l.store.Take(ctx, key, limit)
+}
+
+// takeToken applies the token bucket algorithm to a bucket last updated at last, it's shared by the stores
+// which keep the state themselves.
+func takeToken(tokens float64, last, now time.Time, limit Limit) (float64, Result) {
+	elapsed := now.Sub(last).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	tokens = math.Min(float64(limit.Requests), tokens+elapsed*limit.refill())
+
+	if tokens < 1 {
+		retryAfter := time.Duration((1 - tokens) / limit.refill() * float64(time.Second))
+		return tokens, Result{Allowed: false, RetryAfter: retryAfter}
+	}
+
+	tokens--
+	return tokens, Result{Allowed: true, Remaining: int(tokens)}
+}
@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func TestStores(t *testing.T) {
+	tests := []struct {
+		name     string
+		newStore func(t *testing.T, c *clock) Store
+	}{
+		{name: "memory", newStore: func(_ *testing.T, c *clock) Store {
+			store := NewMemoryStore()
+			store.now = c.Now
+			return store
+		}},
+		{name: "redis", newStore: func(t *testing.T, c *clock) Store {
+			server := miniredis.RunT(t)
+			store := NewRedisStore(redis.NewClient(&redis.Options{Addr: server.Addr()}))
+			store.now = c.Now
+			t.Cleanup(func() { _ = store.Close() })
+			return store
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := &clock{now: time.Now()}
+			store := tt.newStore(t, c)
+			limit := Limit{Requests: 3, Period: time.Minute}
+
+			for i := range 3 {
+				result, err := store.Take(ctx, "ip:login:192.0.2.1", limit)
+				require.NoError(t, err)
+				require.True(t, result.Allowed)
+				require.Equal(t, 2-i, result.Remaining)
+			}
+
+			result, err := store.Take(ctx, "ip:login:192.0.2.1", limit)
+			require.NoError(t, err)
+			require.False(t, result.Allowed)
+			require.InDelta(t, 20*time.Second, result.RetryAfter, float64(time.Second))
+
+			// Other keys have their own bucket.
+			result, err = store.Take(ctx, "ip:login:192.0.2.2", limit)
+			require.NoError(t, err)
+			require.True(t, result.Allowed)
+
+			// One token is refilled every 20 seconds.
+			c.now = c.now.Add(20 * time.Second)
+			result, err = store.Take(ctx, "ip:login:192.0.2.1", limit)
+			require.NoError(t, err)
+			require.True(t, result.Allowed)
+			result, err = store.Take(ctx, "ip:login:192.0.2.1", limit)
+			require.NoError(t, err)
+			require.False(t, result.Allowed)
+		})
+	}
+}
+
+func TestLimiterChecksIPAndUser(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewLimiter(NewMemoryStore(),
+		Policy{IP: Limit{Requests: 100, Period: time.Minute}},
+		map[string]Policy{
+			"patch-user-password": {User: Limit{Requests: 2, Period: time.Hour}},
+		},
+	)
+
+	// The user bucket of the password change applies across IPs.
+	for _, ip := range []string{"192.0.2.1", "192.0.2.2"} {
+		result, err := limiter.Allow(ctx, "patch-user-password", ip, "user-1")
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+	result, err := limiter.Allow(ctx, "patch-user-password", "192.0.2.3", "user-1")
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+
+	// The user bucket alone doesn't touch the IP bucket.
+	result, err = limiter.AllowUser(ctx, "patch-user-password", "user-2")
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	result, err = limiter.AllowUser(ctx, "patch-user-password", "user-1")
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+
+	// Operations without a user bucket only limit the IP.
+	for range 10 {
+		result, err = limiter.Allow(ctx, "get-shelf-by-id", "192.0.2.1", "user-1")
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+}
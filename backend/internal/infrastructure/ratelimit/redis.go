@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript runs the token bucket algorithm atomically inside Redis, so concurrent requests on different
+// instances can't take the same token. The bucket expires once it would be full again.
+var takeScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local refill = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(state[1]) or capacity
+local last = tonumber(state[2]) or now
+
+local elapsed = math.max(0, now - last) / 1000
+tokens = math.min(capacity, tokens + elapsed * refill)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "last", now)
+redis.call("PEXPIRE", KEYS[1], ttl)
+
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore keeps the buckets in Redis, so all instances share them.
+type RedisStore struct {
+	client *redis.Client
+	now    func() time.Time
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{
+		client: client,
+		now:    time.Now,
+	}
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	values, err := takeScript.Run(ctx, s.client, []string{"ratelimit:" + key},
+		limit.Requests,
+		limit.refill(),
+		s.now().UnixMilli(),
+		limit.Period.Milliseconds(),
+	).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+
+	allowed, _ := values[0].(int64)
+	remaining, _ := values[1].(string)
+	tokens, err := strconv.ParseFloat(remaining, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected rate limit script result %v: %w", values, err)
+	}
+
+	if allowed == 1 {
+		return Result{Allowed: true, Remaining: int(tokens)}, nil
+	}
+	retryAfter := time.Duration((1 - tokens) / limit.refill() * float64(time.Second))
+	return Result{Allowed: false, RetryAfter: retryAfter}, nil
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
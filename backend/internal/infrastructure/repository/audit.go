@@ -0,0 +1,259 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/metrics"
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// AuditRepository appends and reads events. The only change to the audit log it offers is the redaction of
+// the personal data of purged users.
+type AuditRepository interface {
+	Create(ctx context.Context, e *model.AuditEvent) error
+	Search(ctx context.Context, shelfId string, search model.AuditSearch) ([]model.AuditEvent, int64, error)
+	RedactUser(ctx context.Context, userId, redacted string) error
+}
+
+type auditRepository struct {
+	Engine *tracedDB
+	Table  string
+}
+
+func NewAuditRepository(engine *sql.DB, dialect, table string) (AuditRepository, error) {
+	return &auditRepository{
+		Engine: &tracedDB{DB: engine, engine: dialect},
+		Table:  table,
+	}, nil
+}
+
+func (r *auditRepository) Create(ctx context.Context, e *model.AuditEvent) error {
+	defer metrics.ObserveQuery("audit_event", "Create")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		INSERT INTO audit_event (id, actor_id, impersonator_id, action, entity_type, entity_id, shelf_id, changes, request_id, ip, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+
+	changes, err := json.Marshal(e.Changes)
+	if err != nil {
+		return err
+	}
+
+	e.Id = uuid.New().String()
+
+	_, err = r.Engine.ExecContext(
+		ctx,
+		query,
+		e.Id,
+		nullString(e.ActorId),
+		nullString(e.ImpersonatorId),
+		e.Action,
+		e.EntityType,
+		e.EntityId,
+		nullString(e.ShelfId),
+		string(changes),
+		nullString(e.RequestId),
+		nullString(e.IP),
+		e.CreatedAt,
+	)
+	return err
+}
+
+// Search returns a page of the events matching the search, newest first, and the number of all matching
+// events. An empty shelf ID searches the events of the whole instance.
+func (r *auditRepository) Search(ctx context.Context, shelfId string, search model.AuditSearch) ([]model.AuditEvent, int64, error) {
+	defer metrics.ObserveQuery("audit_event", "Search")()
+
+	where := "1 = 1"
+	var args []any
+	if shelfId != "" {
+		where += " AND shelf_id = ?"
+		args = append(args, shelfId)
+	}
+	if search.EntityType != "" {
+		where += " AND entity_type = ?"
+		args = append(args, search.EntityType)
+	}
+	if search.EntityId != "" {
+		where += " AND entity_id = ?"
+		args = append(args, search.EntityId)
+	}
+	if search.Action != "" {
+		where += " AND action = ?"
+		args = append(args, search.Action)
+	}
+	if search.ActorId != "" {
+		where += " AND actor_id = ?"
+		args = append(args, search.ActorId)
+	}
+	if !search.Since.IsZero() {
+		where += " AND created_at >= ?"
+		args = append(args, search.Since.UTC())
+	}
+	if !search.Until.IsZero() {
+		where += " AND created_at < ?"
+		args = append(args, search.Until.UTC())
+	}
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT COUNT(*)
+		FROM audit_event
+		WHERE ` + where)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	err = r.Engine.QueryRowContext(ctx, query, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query, err = r.Engine.buildSqlStatements(`
+		SELECT id, actor_id, impersonator_id, action, entity_type, entity_id, shelf_id, changes, request_id, ip, created_at
+		FROM audit_event
+		WHERE ` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.Engine.QueryContext(ctx, query, append(args, search.PageSize, search.Offset())...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var events []model.AuditEvent
+	for rows.Next() {
+		var event model.AuditEvent
+		var actorId, impersonatorId, eventShelfId, requestId, ip sql.NullString
+		var changes string
+		err := rows.Scan(
+			&event.Id,
+			&actorId,
+			&impersonatorId,
+			&event.Action,
+			&event.EntityType,
+			&event.EntityId,
+			&eventShelfId,
+			&changes,
+			&requestId,
+			&ip,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		err = json.Unmarshal([]byte(changes), &event.Changes)
+		if err != nil {
+			return nil, 0, err
+		}
+		event.ActorId = actorId.String
+		event.ImpersonatorId = impersonatorId.String
+		event.ShelfId = eventShelfId.String
+		event.RequestId = requestId.String
+		event.IP = ip.String
+		events = append(events, event)
+	}
+
+	return events, total, rows.Err()
+}
+
+// RedactUser removes the personal data of the user from the audit log, in a single transaction. The events
+// stay, but the attribute values of the events about the user are replaced by redacted and the IPs of the
+// events by or about the user are removed.
+func (r *auditRepository) RedactUser(ctx context.Context, userId, redacted string) error {
+	defer metrics.ObserveQuery("audit_event", "RedactUser")()
+
+	selectQuery, err := r.Engine.buildSqlStatements(`
+		SELECT id, changes
+		FROM audit_event
+		WHERE entity_type = ? AND entity_id = ?
+	`)
+	if err != nil {
+		return err
+	}
+	changesQuery, err := r.Engine.buildSqlStatements(`
+		UPDATE audit_event
+		SET changes = ?
+		WHERE id = ?
+	`)
+	if err != nil {
+		return err
+	}
+	ipQuery, err := r.Engine.buildSqlStatements(`
+		UPDATE audit_event
+		SET ip = NULL
+		WHERE actor_id = ? OR impersonator_id = ? OR (entity_type = ? AND entity_id = ?)
+	`)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.Engine.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, selectQuery, model.AuditEntityUser, userId)
+	if err != nil {
+		return err
+	}
+	changesById := make(map[string]string)
+	for rows.Next() {
+		var id, changes string
+		if err := rows.Scan(&id, &changes); err != nil {
+			rows.Close()
+			return err
+		}
+		changesById[id] = changes
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, changes := range changesById {
+		var event map[string]model.AuditChange
+		err := json.Unmarshal([]byte(changes), &event)
+		if err != nil {
+			return err
+		}
+		for attribute, change := range event {
+			if change.Before != nil {
+				change.Before = redacted
+			}
+			if change.After != nil {
+				change.After = redacted
+			}
+			event[attribute] = change
+		}
+		content, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, changesQuery, string(content), id)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, ipQuery, userId, userId, model.AuditEntityUser, userId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactUserRemovesThePersonalDataFromTheAuditLog(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, repo *Repository) {
+		ctx := context.Background()
+		purgedId := uuid.New().String()
+		otherId := uuid.New().String()
+		createdAt := time.Now().UTC().Truncate(time.Second)
+
+		events := []model.AuditEvent{
+			{
+				ActorId: purgedId, Action: model.AuditActionUpdate, EntityType: model.AuditEntityUser, EntityId: purgedId,
+				Changes: map[string]model.AuditChange{"email": {Before: "jane@test.com", After: "jane.doe@test.com"}},
+				IP:      "192.0.2.1", CreatedAt: createdAt,
+			},
+			{
+				ActorId: purgedId, Action: model.AuditActionCreate, EntityType: model.AuditEntityShelf, EntityId: uuid.New().String(),
+				Changes: map[string]model.AuditChange{"title": {After: "Reading"}},
+				IP:      "192.0.2.1", CreatedAt: createdAt,
+			},
+			{
+				ActorId: otherId, Action: model.AuditActionUpdate, EntityType: model.AuditEntityUser, EntityId: otherId,
+				Changes: map[string]model.AuditChange{"email": {Before: "john@test.com", After: "john.doe@test.com"}},
+				IP:      "192.0.2.2", CreatedAt: createdAt,
+			},
+		}
+		for i := range events {
+			require.NoError(t, repo.AuditRepository.Create(ctx, &events[i]))
+		}
+
+		require.NoError(t, repo.AuditRepository.RedactUser(ctx, purgedId, "[redacted]"))
+
+		search := func(actorId string) []model.AuditEvent {
+			found, _, err := repo.AuditRepository.Search(ctx, "", model.AuditSearch{
+				ActorId:         actorId,
+				PaginationQuery: model.PaginationQuery{Page: 1, PageSize: 20},
+			})
+			require.NoError(t, err)
+			return found
+		}
+
+		redacted := search(purgedId)
+		require.Len(t, redacted, 2, "the events stay in the audit log")
+		for _, event := range redacted {
+			require.Empty(t, event.IP)
+			if event.EntityType == model.AuditEntityUser {
+				require.Equal(t, model.AuditChange{Before: "[redacted]", After: "[redacted]"}, event.Changes["email"])
+			} else {
+				require.Equal(t, model.AuditChange{After: "Reading"}, event.Changes["title"])
+			}
+		}
+
+		kept := search(otherId)
+		require.Len(t, kept, 1)
+		require.Equal(t, "192.0.2.2", kept[0].IP)
+		require.Equal(t, model.AuditChange{Before: "john@test.com", After: "john.doe@test.com"}, kept[0].Changes["email"])
+	})
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/metrics"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// CertificateRepository stores the ACME account key and the issued certificates, so all instances behind a
+// load balancer share them. It implements autocert.Cache.
+type CertificateRepository interface {
+	Get(ctx context.Context, name string) ([]byte, error)
+	Put(ctx context.Context, name string, data []byte) error
+	Delete(ctx context.Context, name string) error
+}
+
+type certificateRepository struct {
+	Engine *tracedDB
+	Table  string
+}
+
+func NewCertificateRepository(engine *sql.DB, dialect, table string) (CertificateRepository, error) {
+	return &certificateRepository{
+		Engine: &tracedDB{DB: engine, engine: dialect},
+		Table:  table,
+	}, nil
+}
+
+func (r *certificateRepository) Get(ctx context.Context, name string) ([]byte, error) {
+	defer metrics.ObserveQuery("certificate", "Get")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT data
+		FROM certificate
+		WHERE name = ?
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	err = r.Engine.QueryRowContext(ctx, query, name).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, autocert.ErrCacheMiss
+	}
+
+	return data, err
+}
+
+// Put replaces the entry within a transaction, since upserts aren't portable across the supported engines.
+func (r *certificateRepository) Put(ctx context.Context, name string, data []byte) error {
+	defer metrics.ObserveQuery("certificate", "Put")()
+
+	deleteQuery, err := r.Engine.buildSqlStatements(`
+		DELETE FROM certificate
+		WHERE name = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	insertQuery, err := r.Engine.buildSqlStatements(`
+		INSERT INTO certificate (name, data, updated_at)
+		VALUES (?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.Engine.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, deleteQuery, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, insertQuery, name, data, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *certificateRepository) Delete(ctx context.Context, name string) error {
+	defer metrics.ObserveQuery("certificate", "Delete")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		DELETE FROM certificate
+		WHERE name = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, name)
+	return err
+}
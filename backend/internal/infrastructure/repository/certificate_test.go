@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+func TestCertificateCache(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, repo *Repository) {
+		ctx := context.Background()
+		cache := autocert.Cache(repo.CertificateRepository)
+
+		_, err := cache.Get(ctx, "shelf.example.com")
+		require.ErrorIs(t, err, autocert.ErrCacheMiss)
+
+		require.NoError(t, cache.Put(ctx, "shelf.example.com", []byte("first")))
+		require.NoError(t, cache.Put(ctx, "shelf.example.com", []byte("second")))
+
+		data, err := cache.Get(ctx, "shelf.example.com")
+		require.NoError(t, err)
+		require.Equal(t, []byte("second"), data)
+
+		require.NoError(t, cache.Delete(ctx, "shelf.example.com"))
+		_, err = cache.Get(ctx, "shelf.example.com")
+		require.ErrorIs(t, err, autocert.ErrCacheMiss)
+	})
+}
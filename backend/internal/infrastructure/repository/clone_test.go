@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloneAndMoveCopyTheTreeWithFreshIds(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, repo *Repository) {
+		ctx := context.Background()
+		userId, err := repo.UserRepository.Create(ctx, &model.User{
+			Id: uuid.New().String(),
+			UserBase: model.UserBase{
+				Email:     "clone@test.com",
+				FirstName: "Jane",
+				LastName:  "Doe",
+			},
+			Password: "userpassword",
+		})
+		require.NoError(t, err)
+
+		shelfId, err := repo.ShelfRepository.Create(ctx, &model.Shelf{ShelfBase: model.ShelfBase{Title: "Source", Path: "source", Theme: "dark", UserId: userId}})
+		require.NoError(t, err)
+		sectionId, err := repo.SectionRepository.Create(ctx, &model.Section{SectionBase: model.SectionBase{Title: "Section", ShelfId: shelfId}})
+		require.NoError(t, err)
+		linkId, err := repo.LinkRepository.Create(ctx, &model.Link{LinkBase: model.LinkBase{Title: "Kept", Link: "https://kept.example.com", SectionId: sectionId}})
+		require.NoError(t, err)
+		trashedId, err := repo.LinkRepository.Create(ctx, &model.Link{LinkBase: model.LinkBase{Title: "Trashed", Link: "https://trashed.example.com", SectionId: sectionId}})
+		require.NoError(t, err)
+		require.NoError(t, repo.LinkRepository.SoftDelete(ctx, trashedId, userId, time.Now().UTC().Truncate(time.Second)))
+
+		cloneId, err := repo.ShelfRepository.Clone(ctx, shelfId, &model.Shelf{ShelfBase: model.ShelfBase{Title: "Clone", Path: "clone", Theme: "dark", UserId: userId}})
+		require.NoError(t, err)
+		require.NotEqual(t, shelfId, cloneId)
+
+		sections, err := repo.SectionRepository.ListByShelfId(ctx, cloneId)
+		require.NoError(t, err)
+		require.Len(t, sections, 1)
+		require.NotEqual(t, sectionId, sections[0].Id)
+		require.Equal(t, "Section", sections[0].Title)
+		links, err := repo.LinkRepository.ListByShelfId(ctx, cloneId)
+		require.NoError(t, err)
+		require.Len(t, links, 1, "links in the trash aren't copied")
+		require.NotEqual(t, linkId, links[0].Id)
+		require.Equal(t, "https://kept.example.com", links[0].Link)
+
+		movedId, err := repo.SectionRepository.Move(ctx, sectionId, &model.Section{SectionBase: model.SectionBase{Title: "Section", ShelfId: cloneId}})
+		require.NoError(t, err)
+		sections, err = repo.SectionRepository.ListByShelfId(ctx, shelfId)
+		require.NoError(t, err)
+		require.Empty(t, sections)
+		sections, err = repo.SectionRepository.ListByShelfId(ctx, cloneId)
+		require.NoError(t, err)
+		require.Len(t, sections, 2)
+		moved, err := repo.SectionRepository.Get(ctx, movedId)
+		require.NoError(t, err)
+		require.Equal(t, cloneId, moved.ShelfId)
+		original, err := repo.LinkRepository.Get(ctx, linkId)
+		require.NoError(t, err)
+		require.Nil(t, original, "the original links went with the original section")
+	})
+}
@@ -2,49 +2,56 @@ package repository
 
 import (
 	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/metrics"
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type LinkRepository interface {
-	ListByShelfId(id string) ([]model.Link, error)
-	Get(id string) (*model.Link, error)
-	Create(l *model.Link) (string, error)
-	Update(l *model.Link) error
-	Delete(l *model.Link) error
+	ListByShelfId(ctx context.Context, id string) ([]model.Link, error)
+	Get(ctx context.Context, id string) (*model.Link, error)
+	Create(ctx context.Context, l *model.Link) (string, error)
+	Update(ctx context.Context, l *model.Link) error
+	Delete(ctx context.Context, l *model.Link) error
+	GetDeleted(ctx context.Context, id string) (*model.Link, error)
+	SoftDelete(ctx context.Context, id, deletedBy string, deletedAt time.Time) error
+	Restore(ctx context.Context, id string) (bool, error)
 }
 
 type linkRepository struct {
-	Engine *sql.DB
+	Engine *tracedDB
 	Table  string
 }
 
-func NewLinkRepository(engine *sql.DB, table string) (LinkRepository, error) {
+func NewLinkRepository(engine *sql.DB, dialect, table string) (LinkRepository, error) {
 	return &linkRepository{
-		Engine: engine,
+		Engine: &tracedDB{DB: engine, engine: dialect},
 		Table:  table,
 	}, nil
 }
 
-func (r *linkRepository) ListByShelfId(id string) ([]model.Link, error) {
-	query, err := buildSqlStatements(fmt.Sprintf(`
-		SELECT l.*
+func (r *linkRepository) ListByShelfId(ctx context.Context, id string) ([]model.Link, error) {
+	defer metrics.ObserveQuery("link", "ListByShelfId")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT l.id, l.title, l.link, l.icon, l.color, l.section_id
 		FROM link l
 		JOIN section s ON l.section_id = s.id
-		WHERE s.shelf_id = %s;
-	`, id))
+		WHERE s.shelf_id = ? AND s.deleted_at IS NULL AND l.deleted_at IS NULL
+	`)
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := r.Engine.QueryContext(context.TODO(), query)
+	rows, err := r.Engine.QueryContext(ctx, query, id)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	var links []model.Link
 	for rows.Next() {
@@ -63,25 +70,23 @@ func (r *linkRepository) ListByShelfId(id string) ([]model.Link, error) {
 		links = append(links, link)
 	}
 
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil
-	}
-
-	return links, err
+	return links, rows.Err()
 }
 
-func (r *linkRepository) Get(id string) (*model.Link, error) {
-	query, err := buildSqlStatements(fmt.Sprintf(`
-		SELECT *
+func (r *linkRepository) Get(ctx context.Context, id string) (*model.Link, error) {
+	defer metrics.ObserveQuery("link", "Get")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT id, title, link, icon, color, section_id
 		FROM link
-		WHERE id = %s
+		WHERE id = ? AND deleted_at IS NULL
 		LIMIT 1
-	`, id))
+	`)
 	if err != nil {
 		return nil, err
 	}
 
-	row := r.Engine.QueryRowContext(context.TODO(), query)
+	row := r.Engine.QueryRowContext(ctx, query, id)
 
 	var link model.Link
 	err = row.Scan(
@@ -102,9 +107,11 @@ func (r *linkRepository) Get(id string) (*model.Link, error) {
 	return &link, nil
 }
 
-func (r *linkRepository) Create(l *model.Link) (string, error) {
-	query, err := buildSqlStatements(`
-		INSERT INTO links (id, title, link, icon, color, section_id)
+func (r *linkRepository) Create(ctx context.Context, l *model.Link) (string, error) {
+	defer metrics.ObserveQuery("link", "Create")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		INSERT INTO link (id, title, link, icon, color, section_id)
 		VALUES (?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
@@ -114,7 +121,7 @@ func (r *linkRepository) Create(l *model.Link) (string, error) {
 	l.Id = uuid.New().String()
 
 	_, err = r.Engine.ExecContext(
-		context.TODO(),
+		ctx,
 		query,
 		l.Id,
 		l.Title,
@@ -130,9 +137,11 @@ func (r *linkRepository) Create(l *model.Link) (string, error) {
 	return l.Id, nil
 }
 
-func (r *linkRepository) Update(l *model.Link) error {
-	query, err := buildSqlStatements(`
-		UPDATE links
+func (r *linkRepository) Update(ctx context.Context, l *model.Link) error {
+	defer metrics.ObserveQuery("link", "Update")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE link
 		SET title = ?,
 			link = ?,
 			icon = ?,
@@ -144,7 +153,7 @@ func (r *linkRepository) Update(l *model.Link) error {
 	}
 
 	_, err = r.Engine.ExecContext(
-		context.TODO(),
+		ctx,
 		query,
 		l.Title,
 		l.Link,
@@ -159,9 +168,12 @@ func (r *linkRepository) Update(l *model.Link) error {
 	return nil
 }
 
-func (r *linkRepository) Delete(l *model.Link) error {
-	query, err := buildSqlStatements(`
-		DELETE FROM links
+// Delete removes the link for good, bypassing the trash.
+func (r *linkRepository) Delete(ctx context.Context, l *model.Link) error {
+	defer metrics.ObserveQuery("link", "Delete")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		DELETE FROM link
 		WHERE id = ?
 	`)
 	if err != nil {
@@ -169,7 +181,7 @@ func (r *linkRepository) Delete(l *model.Link) error {
 	}
 
 	_, err = r.Engine.ExecContext(
-		context.TODO(),
+		ctx,
 		query,
 		l.Id,
 	)
@@ -179,3 +191,79 @@ func (r *linkRepository) Delete(l *model.Link) error {
 
 	return nil
 }
+
+// GetDeleted returns the link if it's in the trash, nil otherwise.
+func (r *linkRepository) GetDeleted(ctx context.Context, id string) (*model.Link, error) {
+	defer metrics.ObserveQuery("link", "GetDeleted")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT id, title, link, icon, color, section_id
+		FROM link
+		WHERE id = ? AND deleted_at IS NOT NULL
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var link model.Link
+	err = r.Engine.QueryRowContext(ctx, query, id).Scan(
+		&link.Id,
+		&link.Title,
+		&link.Link,
+		&link.Icon,
+		&link.Color,
+		&link.SectionId,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+func (r *linkRepository) SoftDelete(ctx context.Context, id, deletedBy string, deletedAt time.Time) error {
+	defer metrics.ObserveQuery("link", "SoftDelete")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE link
+		SET deleted_at = ?,
+			deleted_by = ?
+		WHERE id = ? AND deleted_at IS NULL
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, deletedAt, nullString(deletedBy), id)
+	return err
+}
+
+// Restore takes the link out of the trash. It returns false if the link isn't in the trash.
+func (r *linkRepository) Restore(ctx context.Context, id string) (bool, error) {
+	defer metrics.ObserveQuery("link", "Restore")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE link
+		SET deleted_at = NULL,
+			deleted_by = NULL
+		WHERE id = ? AND deleted_at IS NOT NULL
+	`)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := r.Engine.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/mysql"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+func TestMigrationsUpAndDown(t *testing.T) {
+	tests := []struct {
+		engine string
+		start  func(ctx context.Context, t *testing.T) (driver, sqlDSN, migrateDSN string)
+	}{
+		{engine: "postgres", start: startPostgres},
+		{engine: "mysql", start: startMySQL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.engine, func(t *testing.T) {
+			ctx := context.Background()
+			driver, sqlDSN, migrateDSN := tt.start(ctx, t)
+
+			db, err := sql.Open(driver, sqlDSN)
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = db.Close() })
+			require.NoError(t, waitForDatabase(ctx, db, 30*time.Second))
+
+			m, err := newMigrate(tt.engine, migrateDSN)
+			require.NoError(t, err)
+			t.Cleanup(func() { _, _ = m.Close() })
+
+			require.NoError(t, m.Up())
+			version, dirty, err := m.Version()
+			require.NoError(t, err)
+			require.False(t, dirty)
+			require.NotZero(t, version)
+			requireTablesExist(t, db, true)
+
+			require.NoError(t, m.Down())
+			_, _, err = m.Version()
+			require.True(t, errors.Is(err, migrate.ErrNilVersion))
+			requireTablesExist(t, db, false)
+
+			// The down migrations must leave a clean schema behind, so applying them again has to work.
+			require.NoError(t, m.Up())
+			requireTablesExist(t, db, true)
+		})
+	}
+}
+
+func requireTablesExist(t *testing.T, db *sql.DB, exist bool) {
+	t.Helper()
+
+	for _, table := range []string{"shelf", "section", "link"} {
+		_, err := db.Exec("SELECT COUNT(*) FROM " + table)
+		if exist {
+			require.NoError(t, err, "table %s should exist", table)
+		} else {
+			require.Error(t, err, "table %s should not exist", table)
+		}
+	}
+}
+
+func startPostgres(ctx context.Context, t *testing.T) (driver, sqlDSN, migrateDSN string) {
+	t.Helper()
+
+	pg, err := postgres.Run(
+		ctx,
+		"postgres:18",
+		postgres.WithDatabase(testConfig.Database.Name),
+		postgres.WithUsername(testConfig.Database.Username),
+		postgres.WithPassword(testConfig.Database.Password),
+	)
+	testcontainers.CleanupContainer(t, pg)
+	require.NoError(t, err)
+
+	dsn, err := pg.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	return "pgx", dsn, dsn
+}
+
+func startMySQL(ctx context.Context, t *testing.T) (driver, sqlDSN, migrateDSN string) {
+	t.Helper()
+
+	my, err := mysql.Run(
+		ctx,
+		"mysql:9.6.0",
+		mysql.WithDatabase(testConfig.Database.Name),
+		mysql.WithUsername(testConfig.Database.Username),
+		mysql.WithPassword(testConfig.Database.Password),
+	)
+	testcontainers.CleanupContainer(t, my)
+	require.NoError(t, err)
+
+	dsn, err := my.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	return "mysql", dsn, "mysql://" + dsn
+}
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/metrics"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PersonalAccessTokenRepository interface {
+	Create(ctx context.Context, t *model.PersonalAccessToken) (string, error)
+	ListByUserId(ctx context.Context, userId string) ([]model.PersonalAccessToken, error)
+	GetByHash(ctx context.Context, tokenHash string) (*model.PersonalAccessToken, error)
+	Touch(ctx context.Context, id string, lastUsedAt time.Time) error
+	Delete(ctx context.Context, userId, id string) (bool, error)
+}
+
+type personalAccessTokenRepository struct {
+	Engine *tracedDB
+	Table  string
+}
+
+func NewPersonalAccessTokenRepository(engine *sql.DB, dialect, table string) (PersonalAccessTokenRepository, error) {
+	return &personalAccessTokenRepository{
+		Engine: &tracedDB{DB: engine, engine: dialect},
+		Table:  table,
+	}, nil
+}
+
+func (r *personalAccessTokenRepository) Create(ctx context.Context, t *model.PersonalAccessToken) (string, error) {
+	defer metrics.ObserveQuery("personal_access_token", "Create")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		INSERT INTO personal_access_token (id, user_id, name, token_hash, scopes, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return "", err
+	}
+
+	t.Id = uuid.New().String()
+
+	_, err = r.Engine.ExecContext(
+		ctx,
+		query,
+		t.Id,
+		t.UserId,
+		t.Name,
+		t.TokenHash,
+		strings.Join(t.Scopes, " "),
+		t.ExpiresAt,
+		t.CreatedAt,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return t.Id, nil
+}
+
+func (r *personalAccessTokenRepository) ListByUserId(ctx context.Context, userId string) ([]model.PersonalAccessToken, error) {
+	defer metrics.ObserveQuery("personal_access_token", "ListByUserId")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT id, user_id, name, token_hash, scopes, expires_at, last_used_at, created_at
+		FROM personal_access_token
+		WHERE user_id = ?
+		ORDER BY created_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.Engine.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []model.PersonalAccessToken
+	for rows.Next() {
+		token, err := scanPersonalAccessToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+
+	return tokens, rows.Err()
+}
+
+func (r *personalAccessTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*model.PersonalAccessToken, error) {
+	defer metrics.ObserveQuery("personal_access_token", "GetByHash")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT id, user_id, name, token_hash, scopes, expires_at, last_used_at, created_at
+		FROM personal_access_token
+		WHERE token_hash = ?
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := scanPersonalAccessToken(r.Engine.QueryRowContext(ctx, query, tokenHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return token, err
+}
+
+func (r *personalAccessTokenRepository) Touch(ctx context.Context, id string, lastUsedAt time.Time) error {
+	defer metrics.ObserveQuery("personal_access_token", "Touch")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE personal_access_token
+		SET last_used_at = ?
+		WHERE id = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, lastUsedAt, id)
+	return err
+}
+
+// Delete revokes the token. It reports false if the user has no token with the ID.
+func (r *personalAccessTokenRepository) Delete(ctx context.Context, userId, id string) (bool, error) {
+	defer metrics.ObserveQuery("personal_access_token", "Delete")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		DELETE FROM personal_access_token
+		WHERE user_id = ? AND id = ?
+	`)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := r.Engine.ExecContext(ctx, query, userId, id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// scanPersonalAccessToken reads a token, the scopes are stored separated by spaces.
+func scanPersonalAccessToken(row interface{ Scan(dest ...any) error }) (*model.PersonalAccessToken, error) {
+	var token model.PersonalAccessToken
+	var scopes string
+	var expiresAt, lastUsedAt sql.NullTime
+	err := row.Scan(
+		&token.Id,
+		&token.UserId,
+		&token.Name,
+		&token.TokenHash,
+		&scopes,
+		&expiresAt,
+		&lastUsedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	token.Scopes = strings.Fields(scopes)
+	if expiresAt.Valid {
+		token.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		token.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return &token, nil
+}
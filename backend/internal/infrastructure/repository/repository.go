@@ -1,12 +1,15 @@
 package repository
 
 import (
+	"backend/internal/config"
+	"backend/internal/infrastructure/metrics"
 	"backend/migrations"
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
-	"os"
 	"strings"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -17,66 +20,220 @@ import (
 	_ "github.com/golang-migrate/migrate/v4/database/mysql"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
-
-	"github.com/spf13/viper"
 )
 
 type Repository struct {
-	UserRepository    UserRepository
-	ShelfRepository   ShelfRepository
-	SectionRepository SectionRepository
-	LinkRepository    LinkRepository
+	UserRepository                UserRepository
+	ShelfRepository               ShelfRepository
+	SectionRepository             SectionRepository
+	LinkRepository                LinkRepository
+	CertificateRepository         CertificateRepository
+	UserTokenRepository           UserTokenRepository
+	TwoFactorRepository           TwoFactorRepository
+	SessionRepository             SessionRepository
+	PersonalAccessTokenRepository PersonalAccessTokenRepository
+	StatisticsRepository          StatisticsRepository
+	TeamRepository                TeamRepository
+	ShelfShareRepository          ShelfShareRepository
+	ShelfInvitationRepository     ShelfInvitationRepository
+	AuditRepository               AuditRepository
+	ShelfRevisionRepository       ShelfRevisionRepository
+	TrashRepository               TrashRepository
+	ShelfTemplateRepository       ShelfTemplateRepository
+	SearchRepository              SearchRepository
+
+	db              *sql.DB
+	databaseName    string
+	latestMigration uint
 }
 
-func NewRepository() (*Repository, error) {
-	sqlDSN, driver, migrateDSN, err := getConnectionInformation()
+func NewRepository(cfg *config.Config) (*Repository, error) {
+	engine := getEngine(cfg)
+	sqlDSN, driver, migrateDSN, err := getConnectionInformation(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := connectToDatabase(cfg, sqlDSN, driver)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := runMigrations(engine, migrateDSN); err != nil {
+		return nil, err
+	}
+
+	userRepo, err := NewUserRepository(db, engine, "users")
+	if err != nil {
+		return nil, err
+	}
+
+	shelfRepo, err := NewShelfRepository(db, engine, "shelf")
+	if err != nil {
+		return nil, err
+	}
+
+	sectionRepo, err := NewSectionRepository(db, engine, "section")
+	if err != nil {
+		return nil, err
+	}
+
+	linkRepo, err := NewLinkRepository(db, engine, "link")
+	if err != nil {
+		return nil, err
+	}
+
+	certificateRepo, err := NewCertificateRepository(db, engine, "certificate")
+	if err != nil {
+		return nil, err
+	}
+
+	userTokenRepo, err := NewUserTokenRepository(db, engine, "user_token")
 	if err != nil {
 		return nil, err
 	}
 
-	db, err := connectToDatabase(sqlDSN, driver)
+	twoFactorRepo, err := NewTwoFactorRepository(db, engine, "user_totp")
 	if err != nil {
 		return nil, err
 	}
 
-	if err := runMigrations(migrateDSN); err != nil {
+	sessionRepo, err := NewSessionRepository(db, engine, "user_session")
+	if err != nil {
+		return nil, err
+	}
+
+	personalAccessTokenRepo, err := NewPersonalAccessTokenRepository(db, engine, "personal_access_token")
+	if err != nil {
+		return nil, err
+	}
+
+	statisticsRepo, err := NewStatisticsRepository(db, engine)
+	if err != nil {
+		return nil, err
+	}
+
+	teamRepo, err := NewTeamRepository(db, engine, "team")
+	if err != nil {
+		return nil, err
+	}
+
+	shelfShareRepo, err := NewShelfShareRepository(db, engine, "shelf_share")
+	if err != nil {
+		return nil, err
+	}
+
+	shelfInvitationRepo, err := NewShelfInvitationRepository(db, engine, "shelf_invitation")
+	if err != nil {
+		return nil, err
+	}
+
+	auditRepo, err := NewAuditRepository(db, engine, "audit_event")
+	if err != nil {
+		return nil, err
+	}
+
+	shelfRevisionRepo, err := NewShelfRevisionRepository(db, engine, "shelf_revision")
+	if err != nil {
 		return nil, err
 	}
 
-	userRepo, err := NewUserRepository(db, "users")
+	trashRepo, err := NewTrashRepository(db, engine)
 	if err != nil {
 		return nil, err
 	}
 
-	shelfRepo, err := NewShelfRepository(db, "shelf")
+	shelfTemplateRepo, err := NewShelfTemplateRepository(db, engine, "shelf_template")
 	if err != nil {
 		return nil, err
 	}
 
-	sectionRepo, err := NewSectionRepository(db, "section")
+	searchRepo, err := NewSearchRepository(db, engine)
 	if err != nil {
 		return nil, err
 	}
 
-	linkRepo, err := NewLinkRepository(db, "link")
+	latestMigration, err := latestMigrationVersion(engine)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Repository{
-		UserRepository:    userRepo,
-		ShelfRepository:   shelfRepo,
-		SectionRepository: sectionRepo,
-		LinkRepository:    linkRepo,
+		UserRepository:                userRepo,
+		ShelfRepository:               shelfRepo,
+		SectionRepository:             sectionRepo,
+		LinkRepository:                linkRepo,
+		CertificateRepository:         certificateRepo,
+		UserTokenRepository:           userTokenRepo,
+		TwoFactorRepository:           twoFactorRepo,
+		SessionRepository:             sessionRepo,
+		PersonalAccessTokenRepository: personalAccessTokenRepo,
+		StatisticsRepository:          statisticsRepo,
+		TeamRepository:                teamRepo,
+		ShelfShareRepository:          shelfShareRepo,
+		ShelfInvitationRepository:     shelfInvitationRepo,
+		AuditRepository:               auditRepo,
+		ShelfRevisionRepository:       shelfRevisionRepo,
+		TrashRepository:               trashRepo,
+		ShelfTemplateRepository:       shelfTemplateRepo,
+		SearchRepository:              searchRepo,
+		db:                            db,
+		databaseName:                  cfg.Database.Name,
+		latestMigration:               latestMigration,
 	}, nil
 }
 
-func connectToDatabase(dsn, driver string) (*sql.DB, error) {
+// Close closes the connection pool, it has to be called once no more queries are executed.
+func (r *Repository) Close() error {
+	if r.db == nil {
+		return nil
+	}
+
+	slog.Info("Closing database connections")
+	return r.db.Close()
+}
+
+// RegisterMetrics exposes the statistics of the connection pool.
+func (r *Repository) RegisterMetrics() error {
+	return metrics.RegisterDatabase(r.db, r.databaseName)
+}
+
+// Ping checks whether the database is reachable.
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// CheckMigrations fails if the database schema isn't on the latest embedded migration, e.g. because a
+// migration failed halfway and left the schema dirty.
+func (r *Repository) CheckMigrations(ctx context.Context) error {
+	var version uint
+	var dirty bool
+	err := r.db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err != nil {
+		return fmt.Errorf("failed to read migration version: %w", err)
+	}
+
+	if dirty {
+		return fmt.Errorf("migration %d is dirty", version)
+	}
+	if version != r.latestMigration {
+		return fmt.Errorf("migration version is %d, expected %d", version, r.latestMigration)
+	}
+
+	return nil
+}
+
+func connectToDatabase(cfg *config.Config, dsn, driver string) (*sql.DB, error) {
 	db, err := sql.Open(driver, dsn)
 	if err != nil {
 		return nil, err
 	}
 
+	db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.Database.ConnMaxIdleTime)
+
 	// Validate connection
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to ping DB: %w", err)
@@ -86,12 +243,45 @@ func connectToDatabase(dsn, driver string) (*sql.DB, error) {
 	return db, nil
 }
 
-func runMigrations(migrateDSN string) error {
-	slog.Info("Applying DB migrations...")
+func runMigrations(engine, migrateDSN string) error {
+	slog.Info("Applying DB migrations...", slog.String("engine", engine))
+
+	m, err := newMigrate(engine, migrateDSN)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	err = m.Up()
+	if errors.Is(err, migrate.ErrNoChange) {
+		slog.Info("No new migrations")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
+	slog.Info("Migrations applied successfully")
+	return nil
+}
+
+// NewMigrate creates a migrate instance for the configured database, so the migrations can be managed
+// manually (e.g. rolled back or forced to a version) instead of being applied on startup.
+func NewMigrate(cfg *config.Config) (*migrate.Migrate, error) {
+	_, _, migrateDSN, err := getConnectionInformation(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newMigrate(getEngine(cfg), migrateDSN)
+}
 
-	source, err := iofs.New(migrations.FS, ".")
+// newMigrate creates a migrate instance which uses the embedded migrations of the given engine,
+// since every engine has its own SQL dialect and therefore its own migrations directory.
+func newMigrate(engine, migrateDSN string) (*migrate.Migrate, error) {
+	source, err := iofs.New(migrations.FS, engine)
 	if err != nil {
-		return fmt.Errorf("migration source failed: %w", err)
+		return nil, fmt.Errorf("migration source failed: %w", err)
 	}
 
 	m, err := migrate.NewWithSourceInstance(
@@ -100,31 +290,49 @@ func runMigrations(migrateDSN string) error {
 		migrateDSN,
 	)
 	if err != nil {
-		return fmt.Errorf("migration setup failed: %w", err)
+		return nil, fmt.Errorf("migration setup failed: %w", err)
 	}
 
-	err = m.Up()
-	if errors.Is(err, migrate.ErrNoChange) {
-		slog.Info("No new migrations")
-		return nil
+	return m, nil
+}
+
+func latestMigrationVersion(engine string) (uint, error) {
+	source, err := iofs.New(migrations.FS, engine)
+	if err != nil {
+		return 0, fmt.Errorf("migration source failed: %w", err)
 	}
+	defer source.Close()
+
+	version, err := source.First()
 	if err != nil {
-		return fmt.Errorf("migration failed: %w", err)
+		return 0, err
 	}
 
-	slog.Info("Migrations applied successfully")
-	return nil
+	for {
+		next, err := source.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		version = next
+	}
 }
 
-func getConnectionInformation() (sqlDSN, driver, migrateDSN string, err error) {
-	engine := strings.ToLower(viper.GetString("database.engine"))
+func getEngine(cfg *config.Config) string {
+	return strings.ToLower(cfg.Database.Engine)
+}
+
+func getConnectionInformation(cfg *config.Config) (sqlDSN, driver, migrateDSN string, err error) {
+	engine := getEngine(cfg)
 
-	host := viper.GetString("database.host")
-	port := viper.GetString("database.port")
-	dbname := viper.GetString("database.name")
-	username := viper.GetString("database.username")
-	password := viper.GetString("database.password")
-	params := viper.GetString("database.params")
+	host := cfg.Database.Host
+	port := cfg.Database.Port
+	dbname := cfg.Database.Name
+	username := cfg.Database.Username
+	password := cfg.Database.Password
+	params := cfg.Database.Params
 
 	safePassword := "***"
 
@@ -147,14 +355,13 @@ func getConnectionInformation() (sqlDSN, driver, migrateDSN string, err error) {
 		driver = "mysql"
 
 		// database/sql DSN (NO scheme)
-		sqlDSN = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s",
-			username, password, host, port, dbname)
+		sqlDSN = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?%s",
+			username, password, host, port, dbname, params)
 
 		migrateDSN = fmt.Sprintf("mysql://%s:%s@tcp(%s:%s)/%s?%s",
 			username, password, host, port, dbname, params)
 	default:
-		slog.Error("Unsupported DB engine", slog.String("engine", engine))
-		os.Exit(1)
+		return "", "", "", fmt.Errorf("unsupported database engine %q, use postgres or mysql", engine)
 	}
 
 	sqlDSN = strings.TrimSuffix(sqlDSN, "?")
@@ -167,26 +374,45 @@ func getConnectionInformation() (sqlDSN, driver, migrateDSN string, err error) {
 	return sqlDSN, driver, migrateDSN, nil
 }
 
-func buildSqlStatements(query string) (string, error) {
-	_, driver, _, err := getConnectionInformation()
-	if err != nil {
-		return "", err
-	}
-
-	if driver != "pgx" {
+// buildSqlStatements converts the ? placeholders of a query to the $N placeholders of PostgreSQL. For
+// MySQL it converts the "quoted" identifiers, e.g. of the user table, to backticks instead, because MySQL
+// reads double quotes as string literals unless ANSI_QUOTES is set. The 'string literals' of the query are
+// left as they are.
+func (db *tracedDB) buildSqlStatements(query string) (string, error) {
+	if db.engine != "postgres" && db.engine != "mysql" {
 		return query, nil
 	}
 
 	next := 1
+	literal := false
 	res := make([]rune, 0, len(query))
 
 	for _, r := range query {
-		if r == '?' {
+		switch {
+		case r == '\'':
+			literal = !literal
+			res = append(res, r)
+		case literal:
+			res = append(res, r)
+		case r == '?' && db.engine == "postgres":
 			res = append(res, []rune(fmt.Sprintf("$%d", next))...)
 			next++
-		} else {
+		case r == '"' && db.engine == "mysql":
+			res = append(res, '`')
+		default:
 			res = append(res, r)
 		}
 	}
 	return string(res), nil
 }
+
+// escapeLike escapes the wildcards of a LIKE pattern, so user input only matches literally. Backslash is
+// the default escape character of both PostgreSQL and MySQL.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// nullString stores empty strings as NULL, e.g. for optional foreign keys.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
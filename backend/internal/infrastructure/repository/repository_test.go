@@ -10,18 +10,30 @@ import (
 	"testing"
 	"time"
 
-	"github.com/spf13/viper"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/mysql"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 )
 
 var (
-	testRepo *Repository
+	testConfig  *config.Config
+	testEngines []testEngine
 )
 
+// testEngine is a repository on a database of one of the supported engines.
+type testEngine struct {
+	name string
+	repo *Repository
+}
+
 func TestMain(m *testing.M) {
 	ctx := context.Background()
 
-	if err := config.LoadConfig(); err != nil {
+	var err error
+	testConfig, err = config.LoadConfig()
+	if err != nil {
 		slog.Error(err.Error())
 		panic(err)
 	}
@@ -29,59 +41,102 @@ func TestMain(m *testing.M) {
 	pg, err := postgres.Run(
 		ctx,
 		"postgres:18",
-		postgres.WithDatabase(viper.GetString("database.name")),
-		postgres.WithUsername(viper.GetString("database.username")),
-		postgres.WithPassword(viper.GetString("database.password")),
+		postgres.WithDatabase(testConfig.Database.Name),
+		postgres.WithUsername(testConfig.Database.Username),
+		postgres.WithPassword(testConfig.Database.Password),
 	)
 	if err != nil {
 		slog.Error(err.Error())
 		panic(err)
 	}
 
-	port, err := pg.MappedPort(ctx, "5432/tcp")
+	my, err := mysql.Run(
+		ctx,
+		"mysql:9.6.0",
+		mysql.WithDatabase(testConfig.Database.Name),
+		mysql.WithUsername(testConfig.Database.Username),
+		mysql.WithPassword(testConfig.Database.Password),
+	)
 	if err != nil {
 		slog.Error(err.Error())
 		panic(err)
 	}
 
-	viper.Set("database.host", "localhost")
-	viper.Set("database.port", port.Port())
+	for _, engine := range []struct {
+		name      string
+		container testcontainers.Container
+		port      nat.Port
+		driver    string
+		params    string
+	}{
+		{name: "postgres", container: pg, port: "5432/tcp", driver: "pgx", params: "sslmode=disable"},
+		{name: "mysql", container: my, port: "3306/tcp", driver: "mysql", params: "charset=utf8mb4&parseTime=true"},
+	} {
+		port, err := engine.container.MappedPort(ctx, engine.port)
+		if err != nil {
+			slog.Error(err.Error())
+			panic(err)
+		}
 
-	dsn, err := pg.ConnectionString(ctx)
-	if err != nil {
-		slog.Error(err.Error())
-		panic(err)
-	}
+		cfg := *testConfig
+		cfg.Database.Engine = engine.name
+		cfg.Database.Host = "localhost"
+		cfg.Database.Port = port.Port()
+		cfg.Database.Params = engine.params
 
-	db, err := sql.Open("pgx", dsn)
-	if err != nil {
-		slog.Error(err.Error())
-		panic(err)
-	}
+		dsn, _, _, err := getConnectionInformation(&cfg)
+		if err != nil {
+			slog.Error(err.Error())
+			panic(err)
+		}
 
-	err = waitForDatabase(ctx, db, 30*time.Second)
-	if err != nil {
-		slog.Error(err.Error())
-		panic(err)
-	}
+		db, err := sql.Open(engine.driver, dsn)
+		if err != nil {
+			slog.Error(err.Error())
+			panic(err)
+		}
 
-	testRepo, err = NewRepository()
-	if err != nil {
-		slog.Error(err.Error())
-		panic(err)
+		err = waitForDatabase(ctx, db, 30*time.Second)
+		_ = db.Close()
+		if err != nil {
+			slog.Error(err.Error())
+			panic(err)
+		}
+
+		repo, err := NewRepository(&cfg)
+		if err != nil {
+			slog.Error(err.Error())
+			panic(err)
+		}
+		testEngines = append(testEngines, testEngine{name: engine.name, repo: repo})
 	}
 
 	code := m.Run()
 
-	err = pg.Terminate(ctx)
-	if err != nil {
-		slog.Error(err.Error())
-		panic(err)
+	for _, container := range []testcontainers.Container{pg, my} {
+		err = container.Terminate(ctx)
+		if err != nil {
+			slog.Error(err.Error())
+			panic(err)
+		}
 	}
 
 	os.Exit(code)
 }
 
+// forEachEngine runs the test against the repository of every supported database engine.
+func forEachEngine(t *testing.T, test func(t *testing.T, repo *Repository)) {
+	if len(testEngines) == 0 {
+		t.Fatal("repository not initialized")
+	}
+
+	for _, engine := range testEngines {
+		t.Run(engine.name, func(t *testing.T) {
+			test(t, engine.repo)
+		})
+	}
+}
+
 func waitForDatabase(ctx context.Context, db *sql.DB, timeout time.Duration) error {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
@@ -103,3 +158,15 @@ func waitForDatabase(ctx context.Context, db *sql.DB, timeout time.Duration) err
 		}
 	}
 }
+
+func TestSqlStatementsAreBuiltForTheDialect(t *testing.T) {
+	query := `SELECT id FROM "user" WHERE email = ? AND link <> '?"' AND role = ?`
+
+	postgresQuery, err := (&tracedDB{engine: "postgres"}).buildSqlStatements(query)
+	require.NoError(t, err)
+	require.Equal(t, `SELECT id FROM "user" WHERE email = $1 AND link <> '?"' AND role = $2`, postgresQuery)
+
+	mysqlQuery, err := (&tracedDB{engine: "mysql"}).buildSqlStatements(query)
+	require.NoError(t, err)
+	require.Equal(t, "SELECT id FROM `user` WHERE email = ? AND link <> '?\"' AND role = ?", mysqlQuery)
+}
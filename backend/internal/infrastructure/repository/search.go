@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// SearchRepository finds the shelves and links a user can access with the full-text search of the
+// database. The terms have to consist of letters and digits only, every term has to match, as a whole
+// word or as the start of one.
+type SearchRepository interface {
+	Search(ctx context.Context, userId string, terms []string, page model.PaginationQuery) ([]model.SearchResult, int64, error)
+}
+
+// NewSearchRepository returns the search of the dialect, the full-text search differs between the
+// databases in both syntax and indexes.
+func NewSearchRepository(engine *sql.DB, dialect string) (SearchRepository, error) {
+	db := &tracedDB{DB: engine, engine: dialect}
+	switch dialect {
+	case "postgres":
+		return &postgresSearchRepository{Engine: db}, nil
+	case "mysql":
+		return &mysqlSearchRepository{Engine: db}, nil
+	default:
+		return nil, fmt.Errorf("unsupported database engine %q for the search", dialect)
+	}
+}
+
+// accessibleShelf restricts the shelves s to those the user can access like ShelfRepository.ListAccessible,
+// it takes the ID of the user three times.
+const accessibleShelf = `s.deleted_at IS NULL
+			AND (s.user_id = ?
+				OR s.team_id IN (SELECT team_id FROM team_member WHERE user_id = ?)
+				OR s.id IN (SELECT shelf_id FROM shelf_share WHERE user_id = ?))`
+
+// searchResults pages through the union of the matching shelves and links, which selects the columns of a
+// result followed by its score. The results with the highest score come first.
+func searchResults(ctx context.Context, db *tracedDB, union string, args []any, page model.PaginationQuery) ([]model.SearchResult, int64, error) {
+	query, err := db.buildSqlStatements(`
+		SELECT COUNT(*)
+		FROM (` + union + `) results
+	`)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	err = db.QueryRowContext(ctx, query, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query, err = db.buildSqlStatements(`
+		SELECT type, id, shelf_id, shelf_title, section_id, section_title, title, link, description, score
+		FROM (` + union + `) results
+		ORDER BY score DESC, title, id
+		LIMIT ? OFFSET ?
+	`)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := db.QueryContext(ctx, query, append(args, page.PageSize, page.Offset())...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var results []model.SearchResult
+	for rows.Next() {
+		var result model.SearchResult
+		var sectionId, sectionTitle, link, description sql.NullString
+		err := rows.Scan(
+			&result.Type,
+			&result.Id,
+			&result.ShelfId,
+			&result.ShelfTitle,
+			&sectionId,
+			&sectionTitle,
+			&result.Title,
+			&link,
+			&description,
+			&result.Rank,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		result.SectionId = sectionId.String
+		result.SectionTitle = sectionTitle.String
+		result.Link = link.String
+		result.Description = description.String
+		results = append(results, result)
+	}
+
+	return results, total, rows.Err()
+}
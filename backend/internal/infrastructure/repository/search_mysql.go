@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/metrics"
+	"context"
+	"strings"
+)
+
+// mysqlSearchRepository searches with the FULLTEXT indexes ft_shelf_search and ft_link_search of MySQL in
+// boolean mode. Words shorter than innodb_ft_min_token_size (3 by default) and stop words like "the" aren't
+// indexed, so they never match. Every term is required with +, so a single such term in the query returns
+// no results at all, even if the other terms match. PostgreSQL has no such limits.
+type mysqlSearchRepository struct {
+	Engine *tracedDB
+}
+
+func (r *mysqlSearchRepository) Search(ctx context.Context, userId string, terms []string, page model.PaginationQuery) ([]model.SearchResult, int64, error) {
+	defer metrics.ObserveQuery("search", "Search")()
+
+	union := `
+		SELECT 'shelf' AS type, s.id, s.id AS shelf_id, s.title AS shelf_title, NULL AS section_id, NULL AS section_title,
+			s.title, NULL AS link, s.description,
+			MATCH(s.title, s.description) AGAINST (? IN BOOLEAN MODE) AS score
+		FROM shelf s
+		WHERE ` + accessibleShelf + `
+			AND MATCH(s.title, s.description) AGAINST (? IN BOOLEAN MODE)
+		UNION ALL
+		SELECT 'link', l.id, s.id, s.title, sec.id, sec.title, l.title, l.link, NULL,
+			MATCH(l.title, l.link) AGAINST (? IN BOOLEAN MODE)
+		FROM link l
+		JOIN section sec ON sec.id = l.section_id
+		JOIN shelf s ON s.id = sec.shelf_id
+		WHERE ` + accessibleShelf + `
+			AND sec.deleted_at IS NULL AND l.deleted_at IS NULL
+			AND MATCH(l.title, l.link) AGAINST (? IN BOOLEAN MODE)
+	`
+
+	required := make([]string, len(terms))
+	for i, term := range terms {
+		required[i] = "+" + term + "*"
+	}
+	query := strings.Join(required, " ")
+
+	args := []any{query, userId, userId, userId, query, query, userId, userId, userId, query}
+	return searchResults(ctx, r.Engine, union, args, page)
+}
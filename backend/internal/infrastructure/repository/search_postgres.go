@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/metrics"
+	"context"
+	"strings"
+)
+
+// The documents of the full-text search, they have to be the same as the expressions of the indexes
+// idx_shelf_search and idx_link_search. URLs are split into words at everything but letters and digits.
+const (
+	postgresShelfDocument = `to_tsvector('simple', s.title || ' ' || COALESCE(s.description, ''))`
+	postgresLinkDocument  = `to_tsvector('simple', l.title || ' ' || regexp_replace(l.link, '[^[:alnum:]]+', ' ', 'g'))`
+)
+
+// postgresSearchRepository searches with the text search of PostgreSQL. The simple configuration neither
+// stems nor drops stop words, since titles and URLs are in any language.
+type postgresSearchRepository struct {
+	Engine *tracedDB
+}
+
+func (r *postgresSearchRepository) Search(ctx context.Context, userId string, terms []string, page model.PaginationQuery) ([]model.SearchResult, int64, error) {
+	defer metrics.ObserveQuery("search", "Search")()
+
+	union := `
+		SELECT 'shelf' AS type, s.id, s.id AS shelf_id, s.title AS shelf_title, CAST(NULL AS CHAR(36)) AS section_id,
+			CAST(NULL AS VARCHAR(255)) AS section_title, s.title, CAST(NULL AS VARCHAR(255)) AS link, s.description,
+			ts_rank(` + postgresShelfDocument + `, to_tsquery('simple', ?)) AS score
+		FROM shelf s
+		WHERE ` + accessibleShelf + `
+			AND ` + postgresShelfDocument + ` @@ to_tsquery('simple', ?)
+		UNION ALL
+		SELECT 'link', l.id, s.id, s.title, sec.id, sec.title, l.title, l.link, NULL,
+			ts_rank(` + postgresLinkDocument + `, to_tsquery('simple', ?))
+		FROM link l
+		JOIN section sec ON sec.id = l.section_id
+		JOIN shelf s ON s.id = sec.shelf_id
+		WHERE ` + accessibleShelf + `
+			AND sec.deleted_at IS NULL AND l.deleted_at IS NULL
+			AND ` + postgresLinkDocument + ` @@ to_tsquery('simple', ?)
+	`
+
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = term + ":*"
+	}
+	query := strings.Join(prefixes, " & ")
+
+	args := []any{query, userId, userId, userId, query, query, userId, userId, userId, query}
+	return searchResults(ctx, r.Engine, union, args, page)
+}
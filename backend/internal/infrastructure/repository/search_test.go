@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchFindsShelvesAndLinksTheUserCanAccess(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, repo *Repository) {
+		ctx := context.Background()
+		createUser := func(email string) string {
+			userId, err := repo.UserRepository.Create(ctx, &model.User{
+				Id:       uuid.New().String(),
+				UserBase: model.UserBase{Email: email, FirstName: "Jane", LastName: "Doe"},
+				Password: "userpassword",
+			})
+			require.NoError(t, err)
+			return userId
+		}
+		ownerId := createUser("search-owner@test.com")
+		otherId := createUser("search-other@test.com")
+
+		shelfId, err := repo.ShelfRepository.Create(ctx, &model.Shelf{ShelfBase: model.ShelfBase{
+			Title: "Kubernetes", Path: "search", Description: "Operating clusters", UserId: ownerId,
+		}})
+		require.NoError(t, err)
+		sectionId, err := repo.SectionRepository.Create(ctx, &model.Section{SectionBase: model.SectionBase{Title: "Docs", ShelfId: shelfId}})
+		require.NoError(t, err)
+		linkId, err := repo.LinkRepository.Create(ctx, &model.Link{LinkBase: model.LinkBase{
+			Title: "Reference", Link: "https://kubernetes.example.com/docs/reference", SectionId: sectionId,
+		}})
+		require.NoError(t, err)
+		trashedId, err := repo.LinkRepository.Create(ctx, &model.Link{LinkBase: model.LinkBase{
+			Title: "Kubernetes blog", Link: "https://blog.example.com", SectionId: sectionId,
+		}})
+		require.NoError(t, err)
+		require.NoError(t, repo.LinkRepository.SoftDelete(ctx, trashedId, ownerId, time.Now().UTC().Truncate(time.Second)))
+
+		page := model.PaginationQuery{Page: 1, PageSize: 20}
+		results, total, err := repo.SearchRepository.Search(ctx, ownerId, []string{"kube"}, page)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), total, "the shelf by its title and the link by its URL, not the trashed link")
+		ids := []string{results[0].Id, results[1].Id}
+		require.ElementsMatch(t, []string{shelfId, linkId}, ids)
+
+		results, _, err = repo.SearchRepository.Search(ctx, ownerId, []string{"reference", "docs"}, page)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		require.Equal(t, model.SearchResultLink, results[0].Type)
+		require.Equal(t, "Docs", results[0].SectionTitle)
+
+		results, total, err = repo.SearchRepository.Search(ctx, ownerId, []string{"kube"}, model.PaginationQuery{Page: 2, PageSize: 1})
+		require.NoError(t, err)
+		require.Equal(t, int64(2), total)
+		require.Len(t, results, 1)
+
+		results, _, err = repo.SearchRepository.Search(ctx, otherId, []string{"kube"}, page)
+		require.NoError(t, err)
+		require.Empty(t, results, "the shelf isn't shared with the other user")
+	})
+}
@@ -2,48 +2,57 @@ package repository
 
 import (
 	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/metrics"
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type SectionRepository interface {
-	ListByShelfId(id string) ([]model.Section, error)
-	Get(id string) (*model.Section, error)
-	Create(s *model.Section) (string, error)
-	Update(s *model.Section) error
-	Delete(s *model.Section) error
+	ListByShelfId(ctx context.Context, id string) ([]model.Section, error)
+	Get(ctx context.Context, id string) (*model.Section, error)
+	Create(ctx context.Context, s *model.Section) (string, error)
+	Update(ctx context.Context, s *model.Section) error
+	Delete(ctx context.Context, s *model.Section) error
+	GetDeleted(ctx context.Context, id string) (*model.Section, error)
+	SoftDelete(ctx context.Context, id, deletedBy string, deletedAt time.Time) error
+	Restore(ctx context.Context, id string) (bool, error)
+	Clone(ctx context.Context, id string, s *model.Section) (string, error)
+	Move(ctx context.Context, id string, s *model.Section) (string, error)
 }
 
 type sectionRepository struct {
-	Engine *sql.DB
+	Engine *tracedDB
 	Table  string
 }
 
-func NewSectionRepository(engine *sql.DB, table string) (SectionRepository, error) {
+func NewSectionRepository(engine *sql.DB, dialect, table string) (SectionRepository, error) {
 	return &sectionRepository{
-		Engine: engine,
+		Engine: &tracedDB{DB: engine, engine: dialect},
 		Table:  table,
 	}, nil
 }
 
-func (r *sectionRepository) ListByShelfId(id string) ([]model.Section, error) {
-	query, err := buildSqlStatements(fmt.Sprintf(`
-		SELECT *
+func (r *sectionRepository) ListByShelfId(ctx context.Context, id string) ([]model.Section, error) {
+	defer metrics.ObserveQuery("section", "ListByShelfId")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT id, title, shelf_id
 		FROM section
-		WHERE shelf_id = %s
-	`, id))
+		WHERE shelf_id = ? AND deleted_at IS NULL
+	`)
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := r.Engine.QueryContext(context.TODO(), query)
+	rows, err := r.Engine.QueryContext(ctx, query, id)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	var sections []model.Section
 	for rows.Next() {
@@ -59,25 +68,23 @@ func (r *sectionRepository) ListByShelfId(id string) ([]model.Section, error) {
 		sections = append(sections, section)
 	}
 
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil
-	}
-
-	return sections, err
+	return sections, rows.Err()
 }
 
-func (r *sectionRepository) Get(id string) (*model.Section, error) {
-	query, err := buildSqlStatements(fmt.Sprintf(`
-		SELECT *
+func (r *sectionRepository) Get(ctx context.Context, id string) (*model.Section, error) {
+	defer metrics.ObserveQuery("section", "Get")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT id, title, shelf_id
 		FROM section
-		WHERE id = %s
+		WHERE id = ? AND deleted_at IS NULL
 		LIMIT 1
-	`, id))
+	`)
 	if err != nil {
 		return nil, err
 	}
 
-	row := r.Engine.QueryRowContext(context.TODO(), query)
+	row := r.Engine.QueryRowContext(ctx, query, id)
 
 	var section model.Section
 	err = row.Scan(
@@ -85,6 +92,9 @@ func (r *sectionRepository) Get(id string) (*model.Section, error) {
 		&section.Title,
 		&section.ShelfId,
 	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -92,9 +102,11 @@ func (r *sectionRepository) Get(id string) (*model.Section, error) {
 	return &section, nil
 }
 
-func (r *sectionRepository) Create(s *model.Section) (string, error) {
-	query, err := buildSqlStatements(`
-		INSERT INTO sections (id, title, shelf_id)
+func (r *sectionRepository) Create(ctx context.Context, s *model.Section) (string, error) {
+	defer metrics.ObserveQuery("section", "Create")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		INSERT INTO section (id, title, shelf_id)
 		VALUES (?, ?, ?)
 	`)
 	if err != nil {
@@ -104,7 +116,7 @@ func (r *sectionRepository) Create(s *model.Section) (string, error) {
 	s.Id = uuid.New().String()
 
 	_, err = r.Engine.ExecContext(
-		context.TODO(),
+		ctx,
 		query,
 		s.Id,
 		s.Title,
@@ -117,9 +129,11 @@ func (r *sectionRepository) Create(s *model.Section) (string, error) {
 	return s.Id, nil
 }
 
-func (r *sectionRepository) Update(s *model.Section) error {
-	query, err := buildSqlStatements(`
-		UPDATE sections
+func (r *sectionRepository) Update(ctx context.Context, s *model.Section) error {
+	defer metrics.ObserveQuery("section", "Update")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE section
 		SET title = ?
 		WHERE id = ?
 	`)
@@ -128,7 +142,7 @@ func (r *sectionRepository) Update(s *model.Section) error {
 	}
 
 	_, err = r.Engine.ExecContext(
-		context.TODO(),
+		ctx,
 		query,
 		s.Title,
 		s.Id,
@@ -140,9 +154,12 @@ func (r *sectionRepository) Update(s *model.Section) error {
 	return nil
 }
 
-func (r *sectionRepository) Delete(s *model.Section) error {
-	query, err := buildSqlStatements(`
-		DELETE FROM sections
+// Delete removes the section with all its links for good, bypassing the trash.
+func (r *sectionRepository) Delete(ctx context.Context, s *model.Section) error {
+	defer metrics.ObserveQuery("section", "Delete")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		DELETE FROM section
 		WHERE id = ?
 	`)
 	if err != nil {
@@ -150,7 +167,7 @@ func (r *sectionRepository) Delete(s *model.Section) error {
 	}
 
 	_, err = r.Engine.ExecContext(
-		context.TODO(),
+		ctx,
 		query,
 		s.Id,
 	)
@@ -160,3 +177,239 @@ func (r *sectionRepository) Delete(s *model.Section) error {
 
 	return nil
 }
+
+// GetDeleted returns the section if it's in the trash, nil otherwise.
+func (r *sectionRepository) GetDeleted(ctx context.Context, id string) (*model.Section, error) {
+	defer metrics.ObserveQuery("section", "GetDeleted")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT id, title, shelf_id
+		FROM section
+		WHERE id = ? AND deleted_at IS NOT NULL
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var section model.Section
+	err = r.Engine.QueryRowContext(ctx, query, id).Scan(&section.Id, &section.Title, &section.ShelfId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &section, nil
+}
+
+// SoftDelete moves the section to the trash together with its links which aren't in the trash yet.
+func (r *sectionRepository) SoftDelete(ctx context.Context, id, deletedBy string, deletedAt time.Time) error {
+	defer metrics.ObserveQuery("section", "SoftDelete")()
+
+	linkQuery, err := r.Engine.buildSqlStatements(`
+		UPDATE link
+		SET deleted_at = ?,
+			deleted_by = ?
+		WHERE section_id = ? AND deleted_at IS NULL
+	`)
+	if err != nil {
+		return err
+	}
+	sectionQuery, err := r.Engine.buildSqlStatements(`
+		UPDATE section
+		SET deleted_at = ?,
+			deleted_by = ?
+		WHERE id = ? AND deleted_at IS NULL
+	`)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.Engine.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{linkQuery, sectionQuery} {
+		_, err = tx.ExecContext(ctx, query, deletedAt, nullString(deletedBy), id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Restore takes the section out of the trash together with the links which were deleted with it. It
+// returns false if the section isn't in the trash.
+func (r *sectionRepository) Restore(ctx context.Context, id string) (bool, error) {
+	defer metrics.ObserveQuery("section", "Restore")()
+
+	selectQuery, err := r.Engine.buildSqlStatements(`
+		SELECT deleted_at
+		FROM section
+		WHERE id = ? AND deleted_at IS NOT NULL
+	`)
+	if err != nil {
+		return false, err
+	}
+	linkQuery, err := r.Engine.buildSqlStatements(`
+		UPDATE link
+		SET deleted_at = NULL,
+			deleted_by = NULL
+		WHERE section_id = ? AND deleted_at = ?
+	`)
+	if err != nil {
+		return false, err
+	}
+	sectionQuery, err := r.Engine.buildSqlStatements(`
+		UPDATE section
+		SET deleted_at = NULL,
+			deleted_by = NULL
+		WHERE id = ?
+	`)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := r.Engine.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+	err = tx.QueryRowContext(ctx, selectQuery, id).Scan(&deletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.ExecContext(ctx, linkQuery, id, deletedAt)
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.ExecContext(ctx, sectionQuery, id)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// Clone copies the section with its links which aren't in the trash as the new section s, in a single
+// transaction. The copy and its links get fresh IDs.
+func (r *sectionRepository) Clone(ctx context.Context, id string, s *model.Section) (string, error) {
+	defer metrics.ObserveQuery("section", "Clone")()
+
+	tx, err := r.Engine.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	err = copySection(ctx, r.Engine, tx, id, s)
+	if err != nil {
+		return "", err
+	}
+
+	return s.Id, tx.Commit()
+}
+
+// Move copies the section like Clone and removes the original in the same transaction. The fresh IDs keep
+// the revisions of the former shelf restorable, they still contain the original IDs.
+func (r *sectionRepository) Move(ctx context.Context, id string, s *model.Section) (string, error) {
+	defer metrics.ObserveQuery("section", "Move")()
+
+	deleteQuery, err := r.Engine.buildSqlStatements(`
+		DELETE FROM section
+		WHERE id = ?
+	`)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := r.Engine.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	err = copySection(ctx, r.Engine, tx, id, s)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.ExecContext(ctx, deleteQuery, id)
+	if err != nil {
+		return "", err
+	}
+
+	return s.Id, tx.Commit()
+}
+
+// copySection inserts s with a fresh ID and copies the links of the section id which aren't in the trash
+// into it, each with a fresh ID as well.
+func copySection(ctx context.Context, db *tracedDB, tx *tracedTx, id string, s *model.Section) error {
+	selectQuery, err := db.buildSqlStatements(`
+		SELECT title, link, icon, color
+		FROM link
+		WHERE section_id = ? AND deleted_at IS NULL
+	`)
+	if err != nil {
+		return err
+	}
+	sectionQuery, err := db.buildSqlStatements(`
+		INSERT INTO section (id, title, shelf_id)
+		VALUES (?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	linkQuery, err := db.buildSqlStatements(`
+		INSERT INTO link (id, title, link, icon, color, section_id)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, selectQuery, id)
+	if err != nil {
+		return err
+	}
+	var links []model.Link
+	for rows.Next() {
+		var link model.Link
+		err := rows.Scan(&link.Title, &link.Link, &link.Icon, &link.Color)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		links = append(links, link)
+	}
+	// The rows are read completely before the inserts, MySQL can't run them on a connection with open rows.
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	s.Id = uuid.New().String()
+	_, err = tx.ExecContext(ctx, sectionQuery, s.Id, s.Title, s.ShelfId)
+	if err != nil {
+		return err
+	}
+
+	for _, link := range links {
+		_, err = tx.ExecContext(ctx, linkQuery, uuid.New().String(), link.Title, link.Link, link.Icon, link.Color, s.Id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/metrics"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, s *model.UserSession) (string, error)
+	GetByHash(ctx context.Context, tokenHash string) (*model.UserSession, error)
+	Touch(ctx context.Context, id string, lastUsedAt time.Time) error
+	Delete(ctx context.Context, id string) error
+	DeleteByUser(ctx context.Context, userId, exceptId string) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type sessionRepository struct {
+	Engine *tracedDB
+	Table  string
+}
+
+func NewSessionRepository(engine *sql.DB, dialect, table string) (SessionRepository, error) {
+	return &sessionRepository{
+		Engine: &tracedDB{DB: engine, engine: dialect},
+		Table:  table,
+	}, nil
+}
+
+func (r *sessionRepository) Create(ctx context.Context, s *model.UserSession) (string, error) {
+	defer metrics.ObserveQuery("user_session", "Create")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		INSERT INTO user_session (id, user_id, impersonator_id, token_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return "", err
+	}
+
+	s.Id = uuid.New().String()
+
+	_, err = r.Engine.ExecContext(
+		ctx,
+		query,
+		s.Id,
+		s.UserId,
+		sql.NullString{String: s.ImpersonatorId, Valid: s.ImpersonatorId != ""},
+		s.TokenHash,
+		s.ExpiresAt,
+		s.CreatedAt,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return s.Id, nil
+}
+
+func (r *sessionRepository) GetByHash(ctx context.Context, tokenHash string) (*model.UserSession, error) {
+	defer metrics.ObserveQuery("user_session", "GetByHash")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT id, user_id, impersonator_id, token_hash, expires_at, last_used_at, created_at
+		FROM user_session
+		WHERE token_hash = ?
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var session model.UserSession
+	var impersonatorId sql.NullString
+	var lastUsedAt sql.NullTime
+	err = r.Engine.QueryRowContext(ctx, query, tokenHash).Scan(
+		&session.Id,
+		&session.UserId,
+		&impersonatorId,
+		&session.TokenHash,
+		&session.ExpiresAt,
+		&lastUsedAt,
+		&session.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	session.ImpersonatorId = impersonatorId.String
+	if lastUsedAt.Valid {
+		session.LastUsedAt = &lastUsedAt.Time
+	}
+
+	return &session, nil
+}
+
+func (r *sessionRepository) Touch(ctx context.Context, id string, lastUsedAt time.Time) error {
+	defer metrics.ObserveQuery("user_session", "Touch")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE user_session
+		SET last_used_at = ?
+		WHERE id = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, lastUsedAt, id)
+	return err
+}
+
+func (r *sessionRepository) Delete(ctx context.Context, id string) error {
+	defer metrics.ObserveQuery("user_session", "Delete")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		DELETE FROM user_session
+		WHERE id = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, id)
+	return err
+}
+
+// DeleteByUser ends all sessions of the user except the one with exceptId, e.g. after the password changed.
+// An empty exceptId ends all sessions.
+func (r *sessionRepository) DeleteByUser(ctx context.Context, userId, exceptId string) error {
+	defer metrics.ObserveQuery("user_session", "DeleteByUser")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		DELETE FROM user_session
+		WHERE user_id = ? AND id <> ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, userId, exceptId)
+	return err
+}
+
+func (r *sessionRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	defer metrics.ObserveQuery("user_session", "DeleteExpired")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		DELETE FROM user_session
+		WHERE expires_at < ?
+	`)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := r.Engine.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
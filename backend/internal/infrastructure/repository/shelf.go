@@ -2,35 +2,50 @@ package repository
 
 import (
 	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/metrics"
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type ShelfRepository interface {
-	List() (*model.Shelf, error)
-	Get(id string) (*model.Shelf, error)
-	Create(s *model.Shelf) (string, error)
-	Update(s *model.Shelf) error
-	Delete(s *model.Shelf) error
+	List(ctx context.Context) (*model.Shelf, error)
+	Get(ctx context.Context, id string) (*model.Shelf, error)
+	ListByUserId(ctx context.Context, userId string) ([]model.Shelf, error)
+	ListAccessible(ctx context.Context, userId string) ([]model.Shelf, error)
+	GetVerifiedByDomain(ctx context.Context, domain string) (*model.Shelf, error)
+	Create(ctx context.Context, s *model.Shelf) (string, error)
+	Update(ctx context.Context, s *model.Shelf) error
+	UpdateDomainVerification(ctx context.Context, s *model.Shelf) error
+	SetUnpublishedAt(ctx context.Context, id string, unpublishedAt *time.Time) error
+	SetOwner(ctx context.Context, id, userId, teamId string) error
+	Delete(ctx context.Context, s *model.Shelf) error
+	GetDeleted(ctx context.Context, id string) (*model.Shelf, error)
+	SoftDelete(ctx context.Context, id, deletedBy string, deletedAt time.Time) error
+	Restore(ctx context.Context, id string) (bool, error)
+	Clone(ctx context.Context, id string, s *model.Shelf) (string, error)
+	Import(ctx context.Context, s *model.Shelf, sections []model.SectionExport) (string, error)
 }
 
 type shelfRepository struct {
-	Engine *sql.DB
+	Engine *tracedDB
 	Table  string
 }
 
-func NewShelfRepository(engine *sql.DB, table string) (ShelfRepository, error) {
+func NewShelfRepository(engine *sql.DB, dialect, table string) (ShelfRepository, error) {
 	return &shelfRepository{
-		Engine: engine,
+		Engine: &tracedDB{DB: engine, engine: dialect},
 		Table:  table,
 	}, nil
 }
 
-func (r *shelfRepository) List() (*model.Shelf, error) {
-	query, err := buildSqlStatements(`
+func (r *shelfRepository) List(ctx context.Context) (*model.Shelf, error) {
+	defer metrics.ObserveQuery("shelf", "List")()
+
+	query, err := r.Engine.buildSqlStatements(`
 		SELECT *
 		FROM shelf
 		WHERE id = ?
@@ -40,7 +55,7 @@ func (r *shelfRepository) List() (*model.Shelf, error) {
 	}
 
 	var shelf model.Shelf
-	err = r.Engine.QueryRowContext(context.TODO(), query).Scan(
+	err = r.Engine.QueryRowContext(ctx, query).Scan(
 		&shelf.Id,
 		&shelf.Title,
 		&shelf.Description,
@@ -56,39 +71,166 @@ func (r *shelfRepository) List() (*model.Shelf, error) {
 	return &shelf, err
 }
 
-func (r *shelfRepository) Get(id string) (*model.Shelf, error) {
-	query, err := buildSqlStatements(`
-		SELECT *
+func (r *shelfRepository) Get(ctx context.Context, id string) (*model.Shelf, error) {
+	defer metrics.ObserveQuery("shelf", "Get")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT id, title, path, domain, description, theme, icon, user_id, team_id, domain_verification_token,
+			domain_verified_at, unpublished_at
 		FROM shelf
-		WHERE id = ?
+		WHERE id = ? AND deleted_at IS NULL
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	shelf, err := scanShelf(r.Engine.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return shelf, err
+}
+
+func (r *shelfRepository) ListByUserId(ctx context.Context, userId string) ([]model.Shelf, error) {
+	defer metrics.ObserveQuery("shelf", "ListByUserId")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT id, title, path, domain, description, theme, icon, user_id, team_id, domain_verification_token,
+			domain_verified_at, unpublished_at
+		FROM shelf
+		WHERE user_id = ? AND deleted_at IS NULL
+		ORDER BY title
 	`)
 	if err != nil {
 		return nil, err
 	}
 
+	rows, err := r.Engine.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shelves []model.Shelf
+	for rows.Next() {
+		shelf, err := scanShelf(rows)
+		if err != nil {
+			return nil, err
+		}
+		shelves = append(shelves, *shelf)
+	}
+
+	return shelves, rows.Err()
+}
+
+// ListAccessible returns the shelves of the user, of the teams the user is a member of and the shelves
+// shared with the user.
+func (r *shelfRepository) ListAccessible(ctx context.Context, userId string) ([]model.Shelf, error) {
+	defer metrics.ObserveQuery("shelf", "ListAccessible")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT id, title, path, domain, description, theme, icon, user_id, team_id, domain_verification_token,
+			domain_verified_at, unpublished_at
+		FROM shelf
+		WHERE deleted_at IS NULL
+			AND (user_id = ?
+				OR team_id IN (SELECT team_id FROM team_member WHERE user_id = ?)
+				OR id IN (SELECT shelf_id FROM shelf_share WHERE user_id = ?))
+		ORDER BY title
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.Engine.QueryContext(ctx, query, userId, userId, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shelves []model.Shelf
+	for rows.Next() {
+		shelf, err := scanShelf(rows)
+		if err != nil {
+			return nil, err
+		}
+		shelves = append(shelves, *shelf)
+	}
+
+	return shelves, rows.Err()
+}
+
+func (r *shelfRepository) GetVerifiedByDomain(ctx context.Context, domain string) (*model.Shelf, error) {
+	defer metrics.ObserveQuery("shelf", "GetVerifiedByDomain")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT id, title, path, domain, description, theme, icon, user_id, team_id, domain_verification_token,
+			domain_verified_at, unpublished_at
+		FROM shelf
+		WHERE domain = ? AND domain_verified_at IS NOT NULL AND unpublished_at IS NULL AND deleted_at IS NULL
+			AND (user_id IS NULL OR user_id NOT IN (SELECT id FROM "user" WHERE disabled_at IS NOT NULL))
+		LIMIT 1
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	shelf, err := scanShelf(r.Engine.QueryRowContext(ctx, query, domain))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return shelf, err
+}
+
+// scanShelf reads a shelf from a *sql.Row or the current row of *sql.Rows.
+func scanShelf(row interface{ Scan(dest ...any) error }) (*model.Shelf, error) {
 	var shelf model.Shelf
-	err = r.Engine.QueryRowContext(context.TODO(), query, id).Scan(
+	var userId, teamId, domain, description, theme, icon, verificationToken sql.NullString
+	var verifiedAt, unpublishedAt sql.NullTime
+	err := row.Scan(
 		&shelf.Id,
 		&shelf.Title,
 		&shelf.Path,
-		&shelf.Domain,
-		&shelf.Description,
-		&shelf.Theme,
-		&shelf.Icon,
-		&shelf.UserId,
+		&domain,
+		&description,
+		&theme,
+		&icon,
+		&userId,
+		&teamId,
+		&verificationToken,
+		&verifiedAt,
+		&unpublishedAt,
 	)
+	if err != nil {
+		return nil, err
+	}
 
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil
+	shelf.UserId = userId.String
+	shelf.TeamId = teamId.String
+	shelf.Domain = domain.String
+	shelf.Description = description.String
+	shelf.Theme = theme.String
+	shelf.Icon = icon.String
+	shelf.DomainVerificationToken = verificationToken.String
+	if verifiedAt.Valid {
+		shelf.DomainVerifiedAt = &verifiedAt.Time
+	}
+	if unpublishedAt.Valid {
+		shelf.UnpublishedAt = &unpublishedAt.Time
 	}
 
-	return &shelf, err
+	return &shelf, nil
 }
 
-func (r *shelfRepository) Create(s *model.Shelf) (string, error) {
-	query, err := buildSqlStatements(`
-		INSERT INTO shelf (id, title, path, domain, description, theme, icon, user_id)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+func (r *shelfRepository) Create(ctx context.Context, s *model.Shelf) (string, error) {
+	defer metrics.ObserveQuery("shelf", "Create")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		INSERT INTO shelf (id, title, path, domain, description, theme, icon, user_id, team_id, domain_verification_token,
+			domain_verified_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return "", err
@@ -97,7 +239,7 @@ func (r *shelfRepository) Create(s *model.Shelf) (string, error) {
 	s.Id = uuid.New().String()
 
 	_, err = r.Engine.ExecContext(
-		context.TODO(),
+		ctx,
 		query,
 		s.Id,
 		s.Title,
@@ -106,7 +248,10 @@ func (r *shelfRepository) Create(s *model.Shelf) (string, error) {
 		s.Description,
 		s.Theme,
 		s.Icon,
-		s.UserId,
+		nullString(s.UserId),
+		nullString(s.TeamId),
+		s.DomainVerificationToken,
+		s.DomainVerifiedAt,
 	)
 	if err != nil {
 		return "", err
@@ -115,15 +260,19 @@ func (r *shelfRepository) Create(s *model.Shelf) (string, error) {
 	return s.Id, nil
 }
 
-func (r *shelfRepository) Update(s *model.Shelf) error {
-	query, err := buildSqlStatements(`
+func (r *shelfRepository) Update(ctx context.Context, s *model.Shelf) error {
+	defer metrics.ObserveQuery("shelf", "Update")()
+
+	query, err := r.Engine.buildSqlStatements(`
 		UPDATE shelf
 		SET title = ?,
 			path = ?,
 			domain = ?,
 			description = ?,
 			theme = ?,
-			icon = ?
+			icon = ?,
+			domain_verification_token = ?,
+			domain_verified_at = ?
 		WHERE id = ?
 	`)
 	if err != nil {
@@ -131,7 +280,7 @@ func (r *shelfRepository) Update(s *model.Shelf) error {
 	}
 
 	_, err = r.Engine.ExecContext(
-		context.TODO(),
+		ctx,
 		query,
 		s.Title,
 		s.Path,
@@ -139,6 +288,8 @@ func (r *shelfRepository) Update(s *model.Shelf) error {
 		s.Description,
 		s.Theme,
 		s.Icon,
+		s.DomainVerificationToken,
+		s.DomainVerifiedAt,
 		s.Id,
 	)
 	if err != nil {
@@ -148,8 +299,73 @@ func (r *shelfRepository) Update(s *model.Shelf) error {
 	return nil
 }
 
-func (r *shelfRepository) Delete(s *model.Shelf) error {
-	query, err := buildSqlStatements(`
+func (r *shelfRepository) UpdateDomainVerification(ctx context.Context, s *model.Shelf) error {
+	defer metrics.ObserveQuery("shelf", "UpdateDomainVerification")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE shelf
+		SET domain_verification_token = ?,
+			domain_verified_at = ?
+		WHERE id = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(
+		ctx,
+		query,
+		s.DomainVerificationToken,
+		s.DomainVerifiedAt,
+		s.Id,
+	)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetUnpublishedAt hides the shelf from its domain, or publishes it again if unpublishedAt is nil.
+func (r *shelfRepository) SetUnpublishedAt(ctx context.Context, id string, unpublishedAt *time.Time) error {
+	defer metrics.ObserveQuery("shelf", "SetUnpublishedAt")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE shelf
+		SET unpublished_at = ?
+		WHERE id = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, unpublishedAt, id)
+	return err
+}
+
+// SetOwner hands the shelf to a user or a team, exactly one of both has to be set.
+func (r *shelfRepository) SetOwner(ctx context.Context, id, userId, teamId string) error {
+	defer metrics.ObserveQuery("shelf", "SetOwner")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE shelf
+		SET user_id = ?,
+			team_id = ?
+		WHERE id = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, nullString(userId), nullString(teamId), id)
+	return err
+}
+
+// Delete removes the shelf with all its sections and links for good, bypassing the trash.
+func (r *shelfRepository) Delete(ctx context.Context, s *model.Shelf) error {
+	defer metrics.ObserveQuery("shelf", "Delete")()
+
+	query, err := r.Engine.buildSqlStatements(`
 		DELETE FROM shelf
 		WHERE id = ?
 	`)
@@ -158,7 +374,7 @@ func (r *shelfRepository) Delete(s *model.Shelf) error {
 	}
 
 	_, err = r.Engine.ExecContext(
-		context.TODO(),
+		ctx,
 		query,
 		s.Id,
 	)
@@ -168,3 +384,271 @@ func (r *shelfRepository) Delete(s *model.Shelf) error {
 
 	return nil
 }
+
+// GetDeleted returns the shelf if it's in the trash, nil otherwise.
+func (r *shelfRepository) GetDeleted(ctx context.Context, id string) (*model.Shelf, error) {
+	defer metrics.ObserveQuery("shelf", "GetDeleted")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT id, title, path, domain, description, theme, icon, user_id, team_id, domain_verification_token,
+			domain_verified_at, unpublished_at
+		FROM shelf
+		WHERE id = ? AND deleted_at IS NOT NULL
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	shelf, err := scanShelf(r.Engine.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return shelf, err
+}
+
+// SoftDelete moves the shelf to the trash together with its sections and links which aren't in the trash
+// yet, all of them get the same deleted_at.
+func (r *shelfRepository) SoftDelete(ctx context.Context, id, deletedBy string, deletedAt time.Time) error {
+	defer metrics.ObserveQuery("shelf", "SoftDelete")()
+
+	linkQuery, err := r.Engine.buildSqlStatements(`
+		UPDATE link
+		SET deleted_at = ?,
+			deleted_by = ?
+		WHERE deleted_at IS NULL
+			AND section_id IN (SELECT id FROM section WHERE shelf_id = ? AND deleted_at IS NULL)
+	`)
+	if err != nil {
+		return err
+	}
+	sectionQuery, err := r.Engine.buildSqlStatements(`
+		UPDATE section
+		SET deleted_at = ?,
+			deleted_by = ?
+		WHERE shelf_id = ? AND deleted_at IS NULL
+	`)
+	if err != nil {
+		return err
+	}
+	shelfQuery, err := r.Engine.buildSqlStatements(`
+		UPDATE shelf
+		SET deleted_at = ?,
+			deleted_by = ?
+		WHERE id = ? AND deleted_at IS NULL
+	`)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.Engine.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{linkQuery, sectionQuery, shelfQuery} {
+		_, err = tx.ExecContext(ctx, query, deletedAt, nullString(deletedBy), id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Restore takes the shelf out of the trash together with the sections and links which were deleted with
+// it. Children which were deleted before stay in the trash. It returns false if the shelf isn't in the trash.
+func (r *shelfRepository) Restore(ctx context.Context, id string) (bool, error) {
+	defer metrics.ObserveQuery("shelf", "Restore")()
+
+	selectQuery, err := r.Engine.buildSqlStatements(`
+		SELECT deleted_at
+		FROM shelf
+		WHERE id = ? AND deleted_at IS NOT NULL
+	`)
+	if err != nil {
+		return false, err
+	}
+	linkQuery, err := r.Engine.buildSqlStatements(`
+		UPDATE link
+		SET deleted_at = NULL,
+			deleted_by = NULL
+		WHERE deleted_at = ?
+			AND section_id IN (SELECT id FROM section WHERE shelf_id = ? AND deleted_at = ?)
+	`)
+	if err != nil {
+		return false, err
+	}
+	sectionQuery, err := r.Engine.buildSqlStatements(`
+		UPDATE section
+		SET deleted_at = NULL,
+			deleted_by = NULL
+		WHERE shelf_id = ? AND deleted_at = ?
+	`)
+	if err != nil {
+		return false, err
+	}
+	shelfQuery, err := r.Engine.buildSqlStatements(`
+		UPDATE shelf
+		SET deleted_at = NULL,
+			deleted_by = NULL
+		WHERE id = ?
+	`)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := r.Engine.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+	err = tx.QueryRowContext(ctx, selectQuery, id).Scan(&deletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// The links go first, they're found through the sections which are still in the trash.
+	_, err = tx.ExecContext(ctx, linkQuery, deletedAt, id, deletedAt)
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.ExecContext(ctx, sectionQuery, id, deletedAt)
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.ExecContext(ctx, shelfQuery, id)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// Clone creates the new shelf s with copies of the sections and links of the shelf id which aren't in the
+// trash, in a single transaction. Every copy gets a fresh ID.
+func (r *shelfRepository) Clone(ctx context.Context, id string, s *model.Shelf) (string, error) {
+	defer metrics.ObserveQuery("shelf", "Clone")()
+
+	shelfQuery, err := r.Engine.buildSqlStatements(`
+		INSERT INTO shelf (id, title, path, domain, description, theme, icon, user_id, team_id, domain_verification_token,
+			domain_verified_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return "", err
+	}
+	sectionQuery, err := r.Engine.buildSqlStatements(`
+		SELECT id, title
+		FROM section
+		WHERE shelf_id = ? AND deleted_at IS NULL
+	`)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := r.Engine.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	s.Id = uuid.New().String()
+	_, err = tx.ExecContext(ctx, shelfQuery, s.Id, s.Title, s.Path, s.Domain, s.Description, s.Theme, s.Icon,
+		nullString(s.UserId), nullString(s.TeamId), s.DomainVerificationToken, s.DomainVerifiedAt)
+	if err != nil {
+		return "", err
+	}
+
+	rows, err := tx.QueryContext(ctx, sectionQuery, id)
+	if err != nil {
+		return "", err
+	}
+	var sections []model.Section
+	for rows.Next() {
+		var section model.Section
+		err := rows.Scan(&section.Id, &section.Title)
+		if err != nil {
+			rows.Close()
+			return "", err
+		}
+		sections = append(sections, section)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	for _, section := range sections {
+		err = copySection(ctx, r.Engine, tx, section.Id, &model.Section{SectionBase: model.SectionBase{Title: section.Title, ShelfId: s.Id}})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return s.Id, tx.Commit()
+}
+
+// Import creates the new shelf s with the sections and links of an export, in a single transaction, so a
+// failed import leaves nothing behind. Every section and link gets a fresh ID.
+func (r *shelfRepository) Import(ctx context.Context, s *model.Shelf, sections []model.SectionExport) (string, error) {
+	defer metrics.ObserveQuery("shelf", "Import")()
+
+	shelfQuery, err := r.Engine.buildSqlStatements(`
+		INSERT INTO shelf (id, title, path, domain, description, theme, icon, user_id, team_id, domain_verification_token,
+			domain_verified_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return "", err
+	}
+	sectionQuery, err := r.Engine.buildSqlStatements(`
+		INSERT INTO section (id, title, shelf_id)
+		VALUES (?, ?, ?)
+	`)
+	if err != nil {
+		return "", err
+	}
+	linkQuery, err := r.Engine.buildSqlStatements(`
+		INSERT INTO link (id, title, link, icon, color, section_id)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := r.Engine.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	s.Id = uuid.New().String()
+	_, err = tx.ExecContext(ctx, shelfQuery, s.Id, s.Title, s.Path, s.Domain, s.Description, s.Theme, s.Icon,
+		nullString(s.UserId), nullString(s.TeamId), s.DomainVerificationToken, s.DomainVerifiedAt)
+	if err != nil {
+		return "", err
+	}
+
+	for _, section := range sections {
+		sectionId := uuid.New().String()
+		_, err = tx.ExecContext(ctx, sectionQuery, sectionId, section.Title, s.Id)
+		if err != nil {
+			return "", err
+		}
+		for _, link := range section.Links {
+			_, err = tx.ExecContext(ctx, linkQuery, uuid.New().String(), link.Title, link.Link, link.Icon, link.Color, sectionId)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return s.Id, tx.Commit()
+}
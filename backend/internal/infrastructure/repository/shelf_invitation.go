@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/metrics"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ShelfInvitationRepository interface {
+	Create(ctx context.Context, i *model.ShelfInvitation) (string, error)
+	ListPendingByShelfId(ctx context.Context, shelfId string, now time.Time) ([]model.ShelfInvitation, error)
+	GetByHash(ctx context.Context, tokenHash string) (*model.ShelfInvitation, error)
+	Accept(ctx context.Context, id, userId string, acceptedAt time.Time) (bool, error)
+	Decline(ctx context.Context, id string, declinedAt time.Time) (bool, error)
+	Delete(ctx context.Context, shelfId, id string) (bool, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type shelfInvitationRepository struct {
+	Engine *tracedDB
+	Table  string
+}
+
+func NewShelfInvitationRepository(engine *sql.DB, dialect, table string) (ShelfInvitationRepository, error) {
+	return &shelfInvitationRepository{
+		Engine: &tracedDB{DB: engine, engine: dialect},
+		Table:  table,
+	}, nil
+}
+
+func (r *shelfInvitationRepository) Create(ctx context.Context, i *model.ShelfInvitation) (string, error) {
+	defer metrics.ObserveQuery("shelf_invitation", "Create")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		INSERT INTO shelf_invitation (id, shelf_id, role, email, token_hash, invited_by, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return "", err
+	}
+
+	i.Id = uuid.New().String()
+
+	_, err = r.Engine.ExecContext(
+		ctx,
+		query,
+		i.Id,
+		i.ShelfId,
+		i.Role,
+		nullString(i.Email),
+		i.TokenHash,
+		nullString(i.InvitedBy),
+		i.ExpiresAt,
+		i.CreatedAt,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return i.Id, nil
+}
+
+// ListPendingByShelfId returns the invitations of the shelf which were neither answered nor expired.
+func (r *shelfInvitationRepository) ListPendingByShelfId(ctx context.Context, shelfId string, now time.Time) ([]model.ShelfInvitation, error) {
+	defer metrics.ObserveQuery("shelf_invitation", "ListPendingByShelfId")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT id, shelf_id, role, email, token_hash, invited_by, expires_at, accepted_at, accepted_by, declined_at, created_at
+		FROM shelf_invitation
+		WHERE shelf_id = ? AND accepted_at IS NULL AND declined_at IS NULL AND expires_at > ?
+		ORDER BY created_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.Engine.QueryContext(ctx, query, shelfId, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invitations []model.ShelfInvitation
+	for rows.Next() {
+		invitation, err := scanShelfInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, *invitation)
+	}
+
+	return invitations, rows.Err()
+}
+
+func (r *shelfInvitationRepository) GetByHash(ctx context.Context, tokenHash string) (*model.ShelfInvitation, error) {
+	defer metrics.ObserveQuery("shelf_invitation", "GetByHash")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT id, shelf_id, role, email, token_hash, invited_by, expires_at, accepted_at, accepted_by, declined_at, created_at
+		FROM shelf_invitation
+		WHERE token_hash = ?
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := scanShelfInvitation(r.Engine.QueryRowContext(ctx, query, tokenHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// Accept marks the invitation as accepted by the user. Like Decline, it reports false if the invitation was
+// already answered or expired, the check is part of the update, so an invitation is only answered once.
+func (r *shelfInvitationRepository) Accept(ctx context.Context, id, userId string, acceptedAt time.Time) (bool, error) {
+	defer metrics.ObserveQuery("shelf_invitation", "Accept")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE shelf_invitation
+		SET accepted_at = ?, accepted_by = ?
+		WHERE id = ? AND accepted_at IS NULL AND declined_at IS NULL AND expires_at > ?
+	`)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := r.Engine.ExecContext(ctx, query, acceptedAt, userId, id, acceptedAt)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (r *shelfInvitationRepository) Decline(ctx context.Context, id string, declinedAt time.Time) (bool, error) {
+	defer metrics.ObserveQuery("shelf_invitation", "Decline")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE shelf_invitation
+		SET declined_at = ?
+		WHERE id = ? AND accepted_at IS NULL AND declined_at IS NULL AND expires_at > ?
+	`)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := r.Engine.ExecContext(ctx, query, declinedAt, id, declinedAt)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// Delete revokes the invitation. It reports false if the shelf has no such invitation.
+func (r *shelfInvitationRepository) Delete(ctx context.Context, shelfId, id string) (bool, error) {
+	defer metrics.ObserveQuery("shelf_invitation", "Delete")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		DELETE FROM shelf_invitation
+		WHERE shelf_id = ? AND id = ?
+	`)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := r.Engine.ExecContext(ctx, query, shelfId, id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (r *shelfInvitationRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	defer metrics.ObserveQuery("shelf_invitation", "DeleteExpired")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		DELETE FROM shelf_invitation
+		WHERE expires_at < ?
+	`)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := r.Engine.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func scanShelfInvitation(row interface{ Scan(dest ...any) error }) (*model.ShelfInvitation, error) {
+	var invitation model.ShelfInvitation
+	var email, invitedBy, acceptedBy sql.NullString
+	var acceptedAt, declinedAt sql.NullTime
+	err := row.Scan(
+		&invitation.Id,
+		&invitation.ShelfId,
+		&invitation.Role,
+		&email,
+		&invitation.TokenHash,
+		&invitedBy,
+		&invitation.ExpiresAt,
+		&acceptedAt,
+		&acceptedBy,
+		&declinedAt,
+		&invitation.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation.Email = email.String
+	invitation.InvitedBy = invitedBy.String
+	invitation.AcceptedBy = acceptedBy.String
+	if acceptedAt.Valid {
+		invitation.AcceptedAt = &acceptedAt.Time
+	}
+	if declinedAt.Valid {
+		invitation.DeclinedAt = &declinedAt.Time
+	}
+
+	return &invitation, nil
+}
@@ -0,0 +1,424 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/metrics"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ShelfRevisionRepository interface {
+	Create(ctx context.Context, r *model.ShelfRevision) (string, error)
+	UpdateSnapshot(ctx context.Context, id string, snapshot *model.ShelfSnapshot, updatedAt time.Time) error
+	Latest(ctx context.Context, shelfId string) (*model.ShelfRevision, error)
+	Get(ctx context.Context, shelfId, id string) (*model.ShelfRevision, error)
+	List(ctx context.Context, shelfId string, page model.PaginationQuery) ([]model.ShelfRevision, int64, error)
+	Prune(ctx context.Context, shelfId string, keep int) (int64, error)
+	Restore(ctx context.Context, r *model.ShelfRevision) error
+}
+
+type shelfRevisionRepository struct {
+	Engine *tracedDB
+	Table  string
+}
+
+func NewShelfRevisionRepository(engine *sql.DB, dialect, table string) (ShelfRevisionRepository, error) {
+	return &shelfRevisionRepository{
+		Engine: &tracedDB{DB: engine, engine: dialect},
+		Table:  table,
+	}, nil
+}
+
+func (r *shelfRevisionRepository) Create(ctx context.Context, rev *model.ShelfRevision) (string, error) {
+	defer metrics.ObserveQuery("shelf_revision", "Create")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		INSERT INTO shelf_revision (id, shelf_id, author_id, restored_from, snapshot, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return "", err
+	}
+
+	snapshot, err := json.Marshal(rev.Snapshot)
+	if err != nil {
+		return "", err
+	}
+
+	rev.Id = uuid.New().String()
+
+	_, err = r.Engine.ExecContext(
+		ctx,
+		query,
+		rev.Id,
+		rev.ShelfId,
+		nullString(rev.AuthorId),
+		nullString(rev.RestoredFrom),
+		string(snapshot),
+		rev.CreatedAt,
+		rev.UpdatedAt,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return rev.Id, nil
+}
+
+// UpdateSnapshot replaces the snapshot of a revision, which batches the changes of an editing session.
+func (r *shelfRevisionRepository) UpdateSnapshot(ctx context.Context, id string, snapshot *model.ShelfSnapshot, updatedAt time.Time) error {
+	defer metrics.ObserveQuery("shelf_revision", "UpdateSnapshot")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE shelf_revision
+		SET snapshot = ?,
+			updated_at = ?
+		WHERE id = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, string(content), updatedAt, id)
+	return err
+}
+
+// Latest returns the newest revision of the shelf with its snapshot, nil if it has none.
+func (r *shelfRevisionRepository) Latest(ctx context.Context, shelfId string) (*model.ShelfRevision, error) {
+	defer metrics.ObserveQuery("shelf_revision", "Latest")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT r.id, r.shelf_id, r.author_id, u.first_name, u.last_name, r.restored_from, r.created_at, r.updated_at, r.snapshot
+		FROM shelf_revision r
+		LEFT JOIN "user" u ON u.id = r.author_id
+		WHERE r.shelf_id = ?
+		ORDER BY r.created_at DESC, r.id DESC
+		LIMIT 1
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := scanShelfRevision(r.Engine.QueryRowContext(ctx, query, shelfId), true)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return revision, err
+}
+
+// Get returns the revision of the shelf with its snapshot, nil if the shelf has no such revision.
+func (r *shelfRevisionRepository) Get(ctx context.Context, shelfId, id string) (*model.ShelfRevision, error) {
+	defer metrics.ObserveQuery("shelf_revision", "Get")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT r.id, r.shelf_id, r.author_id, u.first_name, u.last_name, r.restored_from, r.created_at, r.updated_at, r.snapshot
+		FROM shelf_revision r
+		LEFT JOIN "user" u ON u.id = r.author_id
+		WHERE r.shelf_id = ? AND r.id = ?
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := scanShelfRevision(r.Engine.QueryRowContext(ctx, query, shelfId, id), true)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return revision, err
+}
+
+// List returns a page of the revisions of the shelf without their snapshots, newest first, and the number
+// of all revisions.
+func (r *shelfRevisionRepository) List(ctx context.Context, shelfId string, page model.PaginationQuery) ([]model.ShelfRevision, int64, error) {
+	defer metrics.ObserveQuery("shelf_revision", "List")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT COUNT(*)
+		FROM shelf_revision
+		WHERE shelf_id = ?
+	`)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	err = r.Engine.QueryRowContext(ctx, query, shelfId).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query, err = r.Engine.buildSqlStatements(`
+		SELECT r.id, r.shelf_id, r.author_id, u.first_name, u.last_name, r.restored_from, r.created_at, r.updated_at
+		FROM shelf_revision r
+		LEFT JOIN "user" u ON u.id = r.author_id
+		WHERE r.shelf_id = ?
+		ORDER BY r.created_at DESC, r.id DESC
+		LIMIT ? OFFSET ?
+	`)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.Engine.QueryContext(ctx, query, shelfId, page.PageSize, page.Offset())
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var revisions []model.ShelfRevision
+	for rows.Next() {
+		revision, err := scanShelfRevision(rows, false)
+		if err != nil {
+			return nil, 0, err
+		}
+		revisions = append(revisions, *revision)
+	}
+
+	return revisions, total, rows.Err()
+}
+
+// Prune removes all but the newest revisions of the shelf.
+func (r *shelfRevisionRepository) Prune(ctx context.Context, shelfId string, keep int) (int64, error) {
+	defer metrics.ObserveQuery("shelf_revision", "Prune")()
+
+	// MySQL doesn't allow LIMIT in IN subqueries, the derived table works around it.
+	query, err := r.Engine.buildSqlStatements(`
+		DELETE FROM shelf_revision
+		WHERE shelf_id = ?
+			AND id NOT IN (
+				SELECT id FROM (
+					SELECT id
+					FROM shelf_revision
+					WHERE shelf_id = ?
+					ORDER BY created_at DESC, id DESC
+					LIMIT ?
+				) newest
+			)
+	`)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := r.Engine.ExecContext(ctx, query, shelfId, shelfId, keep)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// Restore replaces the attributes, sections and links of the shelf with the snapshot of the revision and
+// adds the revision, all in one transaction. Sections and links keep the IDs of the snapshot, so the live
+// ones are replaced and those in the trash are only replaced if they're part of the snapshot. The rest of
+// the trash stays, except for the trashed links of live sections which aren't in the snapshot.
+func (r *shelfRevisionRepository) Restore(ctx context.Context, rev *model.ShelfRevision) error {
+	defer metrics.ObserveQuery("shelf_revision", "Restore")()
+
+	shelfQuery, err := r.Engine.buildSqlStatements(`
+		UPDATE shelf
+		SET title = ?,
+			description = ?,
+			theme = ?,
+			icon = ?
+		WHERE id = ?
+	`)
+	if err != nil {
+		return err
+	}
+	liveLinksQuery, err := r.Engine.buildSqlStatements(`
+		DELETE FROM link
+		WHERE deleted_at IS NULL
+			AND section_id IN (SELECT id FROM section WHERE shelf_id = ?)
+	`)
+	if err != nil {
+		return err
+	}
+	sectionsQuery, err := r.Engine.buildSqlStatements(`
+		SELECT id, deleted_at
+		FROM section
+		WHERE shelf_id = ?
+	`)
+	if err != nil {
+		return err
+	}
+	deleteSectionQuery, err := r.Engine.buildSqlStatements(`
+		DELETE FROM section
+		WHERE id = ?
+	`)
+	if err != nil {
+		return err
+	}
+	updateSectionQuery, err := r.Engine.buildSqlStatements(`
+		UPDATE section
+		SET title = ?,
+			deleted_at = NULL,
+			deleted_by = NULL
+		WHERE id = ?
+	`)
+	if err != nil {
+		return err
+	}
+	sectionQuery, err := r.Engine.buildSqlStatements(`
+		INSERT INTO section (id, title, shelf_id)
+		VALUES (?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	deleteLinkQuery, err := r.Engine.buildSqlStatements(`
+		DELETE FROM link
+		WHERE id = ?
+	`)
+	if err != nil {
+		return err
+	}
+	linkQuery, err := r.Engine.buildSqlStatements(`
+		INSERT INTO link (id, title, link, icon, color, section_id)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	revisionQuery, err := r.Engine.buildSqlStatements(`
+		INSERT INTO shelf_revision (id, shelf_id, author_id, restored_from, snapshot, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := json.Marshal(rev.Snapshot)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.Engine.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, shelfQuery, rev.Snapshot.Title, rev.Snapshot.Description, rev.Snapshot.Theme, rev.Snapshot.Icon, rev.ShelfId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, liveLinksQuery, rev.ShelfId)
+	if err != nil {
+		return err
+	}
+	for _, section := range rev.Snapshot.Sections {
+		for _, link := range section.Links {
+			_, err = tx.ExecContext(ctx, deleteLinkQuery, link.Id)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// The sections of the snapshot are updated in place, deleting them would take their trashed links along.
+	rows, err := tx.QueryContext(ctx, sectionsQuery, rev.ShelfId)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		var deletedAt sql.NullTime
+		if err := rows.Scan(&id, &deletedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[id] = !deletedAt.Valid
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	restored := make(map[string]bool)
+	for _, section := range rev.Snapshot.Sections {
+		restored[section.Id] = true
+	}
+	for id, live := range existing {
+		if live && !restored[id] {
+			_, err = tx.ExecContext(ctx, deleteSectionQuery, id)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, section := range rev.Snapshot.Sections {
+		if _, ok := existing[section.Id]; ok {
+			_, err = tx.ExecContext(ctx, updateSectionQuery, section.Title, section.Id)
+		} else {
+			_, err = tx.ExecContext(ctx, sectionQuery, section.Id, section.Title, rev.ShelfId)
+		}
+		if err != nil {
+			return err
+		}
+		for _, link := range section.Links {
+			_, err = tx.ExecContext(ctx, linkQuery, link.Id, link.Title, link.Link, link.Icon, link.Color, section.Id)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	rev.Id = uuid.New().String()
+	_, err = tx.ExecContext(ctx, revisionQuery, rev.Id, rev.ShelfId, nullString(rev.AuthorId), nullString(rev.RestoredFrom),
+		string(snapshot), rev.CreatedAt, rev.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func scanShelfRevision(row interface{ Scan(dest ...any) error }, withSnapshot bool) (*model.ShelfRevision, error) {
+	var revision model.ShelfRevision
+	var authorId, firstName, lastName, restoredFrom sql.NullString
+	var snapshot string
+	dest := []any{
+		&revision.Id,
+		&revision.ShelfId,
+		&authorId,
+		&firstName,
+		&lastName,
+		&restoredFrom,
+		&revision.CreatedAt,
+		&revision.UpdatedAt,
+	}
+	if withSnapshot {
+		dest = append(dest, &snapshot)
+	}
+
+	err := row.Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+
+	revision.AuthorId = authorId.String
+	revision.RestoredFrom = restoredFrom.String
+	revision.AuthorName = strings.TrimSpace(firstName.String + " " + lastName.String)
+	if withSnapshot {
+		err = json.Unmarshal([]byte(snapshot), &revision.Snapshot)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &revision, nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevisionRestoreKeepsTheTrashOutsideTheSnapshot(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, repo *Repository) {
+		ctx := context.Background()
+		userId, err := repo.UserRepository.Create(ctx, &model.User{
+			Id:       uuid.New().String(),
+			UserBase: model.UserBase{Email: "revision@test.com", FirstName: "Jane", LastName: "Doe"},
+			Password: "userpassword",
+		})
+		require.NoError(t, err)
+
+		shelfId, err := repo.ShelfRepository.Create(ctx, &model.Shelf{ShelfBase: model.ShelfBase{Title: "Revisions", Path: "revisions", UserId: userId}})
+		require.NoError(t, err)
+		createSection := func(title string) string {
+			id, err := repo.SectionRepository.Create(ctx, &model.Section{SectionBase: model.SectionBase{Title: title, ShelfId: shelfId}})
+			require.NoError(t, err)
+			return id
+		}
+		createLink := func(title, sectionId string) string {
+			id, err := repo.LinkRepository.Create(ctx, &model.Link{LinkBase: model.LinkBase{Title: title, Link: "https://example.com", SectionId: sectionId}})
+			require.NoError(t, err)
+			return id
+		}
+		keptId := createSection("Kept")
+		keptLinkId := createLink("Kept link", keptId)
+		trashedLinkId := createLink("Trashed link", keptId)
+		trashedId := createSection("Trashed")
+		createLink("Trashed with its section", trashedId)
+		restoredId := createSection("Restored")
+		restoredLinkId := createLink("Restored link", restoredId)
+		removedId := createSection("Removed")
+
+		deletedAt := time.Now().UTC().Truncate(time.Second)
+		require.NoError(t, repo.LinkRepository.SoftDelete(ctx, trashedLinkId, userId, deletedAt))
+		require.NoError(t, repo.SectionRepository.SoftDelete(ctx, trashedId, userId, deletedAt))
+		require.NoError(t, repo.SectionRepository.SoftDelete(ctx, restoredId, userId, deletedAt))
+
+		now := time.Now().UTC().Truncate(time.Second)
+		require.NoError(t, repo.ShelfRevisionRepository.Restore(ctx, &model.ShelfRevision{
+			ShelfId:  shelfId,
+			AuthorId: userId,
+			Snapshot: &model.ShelfSnapshot{
+				Title: "Restored revisions",
+				Sections: []model.SectionSnapshot{
+					{Id: keptId, Title: "Renamed", Links: []model.LinkSnapshot{{Id: keptLinkId, Title: "Kept link", Link: "https://example.com"}}},
+					{Id: restoredId, Title: "Restored", Links: []model.LinkSnapshot{{Id: restoredLinkId, Title: "Restored link", Link: "https://example.com"}}},
+				},
+			},
+			CreatedAt: now,
+			UpdatedAt: now,
+		}))
+
+		sections, err := repo.SectionRepository.ListByShelfId(ctx, shelfId)
+		require.NoError(t, err)
+		titles := make(map[string]string)
+		for _, section := range sections {
+			titles[section.Id] = section.Title
+		}
+		require.Equal(t, map[string]string{keptId: "Renamed", restoredId: "Restored"}, titles)
+		links, err := repo.LinkRepository.ListByShelfId(ctx, shelfId)
+		require.NoError(t, err)
+		require.Len(t, links, 2)
+		require.ElementsMatch(t, []string{keptLinkId, restoredLinkId}, []string{links[0].Id, links[1].Id})
+
+		section, err := repo.SectionRepository.GetDeleted(ctx, trashedId)
+		require.NoError(t, err)
+		require.NotNil(t, section, "the trashed section isn't part of the snapshot")
+		link, err := repo.LinkRepository.GetDeleted(ctx, trashedLinkId)
+		require.NoError(t, err)
+		require.NotNil(t, link, "the trashed link of a restored section stays in the trash")
+		section, err = repo.SectionRepository.Get(ctx, removedId)
+		require.NoError(t, err)
+		require.Nil(t, section)
+	})
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/metrics"
+	"context"
+	"database/sql"
+	"errors"
+)
+
+type ShelfShareRepository interface {
+	ListByShelfId(ctx context.Context, shelfId string) ([]model.ShelfShare, error)
+	Get(ctx context.Context, shelfId, userId string) (*model.ShelfShare, error)
+	Create(ctx context.Context, s *model.ShelfShare) error
+	SetRole(ctx context.Context, shelfId, userId, role string) error
+	Delete(ctx context.Context, shelfId, userId string) (bool, error)
+}
+
+type shelfShareRepository struct {
+	Engine *tracedDB
+	Table  string
+}
+
+func NewShelfShareRepository(engine *sql.DB, dialect, table string) (ShelfShareRepository, error) {
+	return &shelfShareRepository{
+		Engine: &tracedDB{DB: engine, engine: dialect},
+		Table:  table,
+	}, nil
+}
+
+func (r *shelfShareRepository) ListByShelfId(ctx context.Context, shelfId string) ([]model.ShelfShare, error) {
+	defer metrics.ObserveQuery("shelf_share", "ListByShelfId")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT shelf_id, user_id, role, created_at
+		FROM shelf_share
+		WHERE shelf_id = ?
+		ORDER BY created_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.Engine.QueryContext(ctx, query, shelfId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shares []model.ShelfShare
+	for rows.Next() {
+		var share model.ShelfShare
+		err := rows.Scan(&share.ShelfId, &share.UserId, &share.Role, &share.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+
+	return shares, rows.Err()
+}
+
+func (r *shelfShareRepository) Get(ctx context.Context, shelfId, userId string) (*model.ShelfShare, error) {
+	defer metrics.ObserveQuery("shelf_share", "Get")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT shelf_id, user_id, role, created_at
+		FROM shelf_share
+		WHERE shelf_id = ? AND user_id = ?
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var share model.ShelfShare
+	err = r.Engine.QueryRowContext(ctx, query, shelfId, userId).Scan(&share.ShelfId, &share.UserId, &share.Role, &share.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &share, nil
+}
+
+func (r *shelfShareRepository) Create(ctx context.Context, s *model.ShelfShare) error {
+	defer metrics.ObserveQuery("shelf_share", "Create")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		INSERT INTO shelf_share (shelf_id, user_id, role, created_at)
+		VALUES (?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, s.ShelfId, s.UserId, s.Role, s.CreatedAt)
+	return err
+}
+
+func (r *shelfShareRepository) SetRole(ctx context.Context, shelfId, userId, role string) error {
+	defer metrics.ObserveQuery("shelf_share", "SetRole")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE shelf_share
+		SET role = ?
+		WHERE shelf_id = ? AND user_id = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, role, shelfId, userId)
+	return err
+}
+
+// Delete revokes the share. It reports false if the shelf isn't shared with the user.
+func (r *shelfShareRepository) Delete(ctx context.Context, shelfId, userId string) (bool, error) {
+	defer metrics.ObserveQuery("shelf_share", "Delete")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		DELETE FROM shelf_share
+		WHERE shelf_id = ? AND user_id = ?
+	`)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := r.Engine.ExecContext(ctx, query, shelfId, userId)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/metrics"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ShelfTemplateRepository stores the templates published by users, the built-in templates aren't stored.
+type ShelfTemplateRepository interface {
+	Create(ctx context.Context, t *model.ShelfTemplate) (string, error)
+	Get(ctx context.Context, id string) (*model.ShelfTemplate, error)
+	List(ctx context.Context) ([]model.ShelfTemplate, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type shelfTemplateRepository struct {
+	Engine *tracedDB
+	Table  string
+}
+
+func NewShelfTemplateRepository(engine *sql.DB, dialect, table string) (ShelfTemplateRepository, error) {
+	return &shelfTemplateRepository{
+		Engine: &tracedDB{DB: engine, engine: dialect},
+		Table:  table,
+	}, nil
+}
+
+func (r *shelfTemplateRepository) Create(ctx context.Context, t *model.ShelfTemplate) (string, error) {
+	defer metrics.ObserveQuery("shelf_template", "Create")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		INSERT INTO shelf_template (id, user_id, name, description, theme, icon, sections, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return "", err
+	}
+
+	sections, err := json.Marshal(t.Sections)
+	if err != nil {
+		return "", err
+	}
+
+	t.Id = uuid.New().String()
+	if t.CreatedAt == nil {
+		createdAt := time.Now().UTC()
+		t.CreatedAt = &createdAt
+	}
+
+	_, err = r.Engine.ExecContext(
+		ctx,
+		query,
+		t.Id,
+		t.AuthorId,
+		t.Name,
+		t.Description,
+		t.Theme,
+		t.Icon,
+		string(sections),
+		*t.CreatedAt,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return t.Id, nil
+}
+
+// Get returns the published template, nil if there is none with the ID.
+func (r *shelfTemplateRepository) Get(ctx context.Context, id string) (*model.ShelfTemplate, error) {
+	defer metrics.ObserveQuery("shelf_template", "Get")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT t.id, t.user_id, u.first_name, u.last_name, t.name, t.description, t.theme, t.icon, t.sections, t.created_at
+		FROM shelf_template t
+		JOIN "user" u ON u.id = t.user_id
+		WHERE t.id = ?
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := scanShelfTemplate(r.Engine.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return template, err
+}
+
+// List returns the published templates, newest first.
+func (r *shelfTemplateRepository) List(ctx context.Context) ([]model.ShelfTemplate, error) {
+	defer metrics.ObserveQuery("shelf_template", "List")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT t.id, t.user_id, u.first_name, u.last_name, t.name, t.description, t.theme, t.icon, t.sections, t.created_at
+		FROM shelf_template t
+		JOIN "user" u ON u.id = t.user_id
+		ORDER BY t.created_at DESC, t.id DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.Engine.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []model.ShelfTemplate
+	for rows.Next() {
+		template, err := scanShelfTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, *template)
+	}
+
+	return templates, rows.Err()
+}
+
+func (r *shelfTemplateRepository) Delete(ctx context.Context, id string) error {
+	defer metrics.ObserveQuery("shelf_template", "Delete")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		DELETE FROM shelf_template
+		WHERE id = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, id)
+	return err
+}
+
+func scanShelfTemplate(row interface{ Scan(dest ...any) error }) (*model.ShelfTemplate, error) {
+	var template model.ShelfTemplate
+	var firstName, lastName sql.NullString
+	var sections string
+	var createdAt time.Time
+	err := row.Scan(
+		&template.Id,
+		&template.AuthorId,
+		&firstName,
+		&lastName,
+		&template.Name,
+		&template.Description,
+		&template.Theme,
+		&template.Icon,
+		&sections,
+		&createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	template.AuthorName = strings.TrimSpace(firstName.String + " " + lastName.String)
+	template.CreatedAt = &createdAt
+	err = json.Unmarshal([]byte(sections), &template.Sections)
+	if err != nil {
+		return nil, err
+	}
+
+	return &template, nil
+}
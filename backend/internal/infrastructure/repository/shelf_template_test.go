@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShelfTemplatesKeepTheirSections(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, repo *Repository) {
+		ctx := context.Background()
+		userId, err := repo.UserRepository.Create(ctx, &model.User{
+			Id: uuid.New().String(),
+			UserBase: model.UserBase{
+				Email:     "template@test.com",
+				FirstName: "Jane",
+				LastName:  "Doe",
+			},
+			Password: "userpassword",
+		})
+		require.NoError(t, err)
+
+		template := &model.ShelfTemplate{
+			Name:     "Reading",
+			Theme:    "light",
+			Icon:     "book",
+			AuthorId: userId,
+			Sections: []model.SectionExport{{
+				Title: "Blogs",
+				Links: []model.LinkExport{{Title: "Blog", Link: "https://blog.example.com", Color: "#000000"}},
+			}},
+		}
+		templateId, err := repo.ShelfTemplateRepository.Create(ctx, template)
+		require.NoError(t, err)
+
+		stored, err := repo.ShelfTemplateRepository.Get(ctx, templateId)
+		require.NoError(t, err)
+		require.Equal(t, "Jane Doe", stored.AuthorName)
+		require.Equal(t, template.Sections, stored.Sections)
+
+		templates, err := repo.ShelfTemplateRepository.List(ctx)
+		require.NoError(t, err)
+		require.NotEmpty(t, templates)
+		require.Equal(t, templateId, templates[0].Id)
+
+		require.NoError(t, repo.ShelfTemplateRepository.Delete(ctx, templateId))
+		stored, err = repo.ShelfTemplateRepository.Get(ctx, templateId)
+		require.NoError(t, err)
+		require.Nil(t, stored)
+	})
+}
+
+func TestImportCreatesTheTreeOrNothing(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, repo *Repository) {
+		ctx := context.Background()
+		userId, err := repo.UserRepository.Create(ctx, &model.User{
+			Id: uuid.New().String(),
+			UserBase: model.UserBase{
+				Email:     "import@test.com",
+				FirstName: "Jane",
+				LastName:  "Doe",
+			},
+			Password: "userpassword",
+		})
+		require.NoError(t, err)
+
+		sections := []model.SectionExport{{
+			Title: "Blogs",
+			Links: []model.LinkExport{
+				{Title: "Blog", Link: "https://blog.example.com", Color: "#000000"},
+				{Title: "News", Link: "https://news.example.com", Color: "#ffffff"},
+			},
+		}}
+		shelfId, err := repo.ShelfRepository.Import(ctx, &model.Shelf{ShelfBase: model.ShelfBase{Title: "Reading", Path: "reading", UserId: userId}}, sections)
+		require.NoError(t, err)
+		imported, err := repo.SectionRepository.ListByShelfId(ctx, shelfId)
+		require.NoError(t, err)
+		require.Len(t, imported, 1)
+		require.Equal(t, "Blogs", imported[0].Title)
+		links, err := repo.LinkRepository.ListByShelfId(ctx, shelfId)
+		require.NoError(t, err)
+		require.Len(t, links, 2)
+
+		sections[0].Links[1].Color = "not a color"
+		_, err = repo.ShelfRepository.Import(ctx, &model.Shelf{ShelfBase: model.ShelfBase{Title: "Broken", Path: "broken", UserId: userId}}, sections)
+		require.Error(t, err, "the color doesn't fit the column")
+		shelves, err := repo.ShelfRepository.ListByUserId(ctx, userId)
+		require.NoError(t, err)
+		require.Len(t, shelves, 1, "the failed import leaves no shelf behind")
+		require.Equal(t, shelfId, shelves[0].Id)
+	})
+}
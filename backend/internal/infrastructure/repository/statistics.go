@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/metrics"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type StatisticsRepository interface {
+	Get(ctx context.Context, now time.Time) (*model.Statistics, error)
+}
+
+type statisticsRepository struct {
+	Engine *tracedDB
+}
+
+func NewStatisticsRepository(engine *sql.DB, dialect string) (StatisticsRepository, error) {
+	return &statisticsRepository{
+		Engine: &tracedDB{DB: engine, engine: dialect},
+	}, nil
+}
+
+// Get counts everything in a single statement, so the numbers are consistent with each other.
+func (r *statisticsRepository) Get(ctx context.Context, now time.Time) (*model.Statistics, error) {
+	defer metrics.ObserveQuery("statistics", "Get")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT
+			(SELECT COUNT(*) FROM "user"),
+			(SELECT COUNT(*) FROM "user" WHERE role = 'admin'),
+			(SELECT COUNT(*) FROM "user" WHERE disabled_at IS NOT NULL AND purge_at IS NULL),
+			(SELECT COUNT(*) FROM "user" WHERE purge_at IS NOT NULL),
+			(SELECT COUNT(*) FROM shelf WHERE deleted_at IS NULL),
+			(SELECT COUNT(*) FROM shelf WHERE unpublished_at IS NOT NULL AND deleted_at IS NULL),
+			(SELECT COUNT(*) FROM shelf WHERE domain_verified_at IS NOT NULL AND deleted_at IS NULL),
+			(SELECT COUNT(*) FROM section WHERE deleted_at IS NULL),
+			(SELECT COUNT(*) FROM link WHERE deleted_at IS NULL),
+			(SELECT COUNT(*) FROM user_session WHERE expires_at > ?)
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var statistics model.Statistics
+	err = r.Engine.QueryRowContext(ctx, query, now).Scan(
+		&statistics.Users,
+		&statistics.Admins,
+		&statistics.DisabledUsers,
+		&statistics.UsersPendingDeletion,
+		&statistics.Shelves,
+		&statistics.UnpublishedShelves,
+		&statistics.VerifiedDomains,
+		&statistics.Sections,
+		&statistics.Links,
+		&statistics.ActiveSessions,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &statistics, nil
+}
@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/metrics"
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+type TeamRepository interface {
+	Create(ctx context.Context, t *model.Team, ownerId string) (string, error)
+	Get(ctx context.Context, id string) (*model.Team, error)
+	ListByUserId(ctx context.Context, userId string) ([]model.Team, error)
+	Update(ctx context.Context, t *model.Team) error
+	Delete(ctx context.Context, id string) error
+	CountShelves(ctx context.Context, id string) (int, error)
+	ListMembers(ctx context.Context, teamId string) ([]model.TeamMember, error)
+	GetMember(ctx context.Context, teamId, userId string) (*model.TeamMember, error)
+	AddMember(ctx context.Context, m *model.TeamMember) error
+	SetMemberRole(ctx context.Context, teamId, userId, role string) error
+	RemoveMember(ctx context.Context, teamId, userId string) (bool, error)
+}
+
+type teamRepository struct {
+	Engine *tracedDB
+	Table  string
+}
+
+func NewTeamRepository(engine *sql.DB, dialect, table string) (TeamRepository, error) {
+	return &teamRepository{
+		Engine: &tracedDB{DB: engine, engine: dialect},
+		Table:  table,
+	}, nil
+}
+
+// Create stores the team together with its first owner, so a team never exists without one.
+func (r *teamRepository) Create(ctx context.Context, t *model.Team, ownerId string) (string, error) {
+	defer metrics.ObserveQuery("team", "Create")()
+
+	teamQuery, err := r.Engine.buildSqlStatements(`
+		INSERT INTO team (id, name, created_at)
+		VALUES (?, ?, ?)
+	`)
+	if err != nil {
+		return "", err
+	}
+	memberQuery, err := r.Engine.buildSqlStatements(`
+		INSERT INTO team_member (team_id, user_id, role, created_at)
+		VALUES (?, ?, ?, ?)
+	`)
+	if err != nil {
+		return "", err
+	}
+
+	t.Id = uuid.New().String()
+
+	tx, err := r.Engine.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, teamQuery, t.Id, t.Name, t.CreatedAt)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.ExecContext(ctx, memberQuery, t.Id, ownerId, model.ShelfRoleOwner, t.CreatedAt)
+	if err != nil {
+		return "", err
+	}
+
+	return t.Id, tx.Commit()
+}
+
+func (r *teamRepository) Get(ctx context.Context, id string) (*model.Team, error) {
+	defer metrics.ObserveQuery("team", "Get")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT id, name, created_at
+		FROM team
+		WHERE id = ?
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var team model.Team
+	err = r.Engine.QueryRowContext(ctx, query, id).Scan(&team.Id, &team.Name, &team.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &team, nil
+}
+
+// ListByUserId returns the teams the user is a member of.
+func (r *teamRepository) ListByUserId(ctx context.Context, userId string) ([]model.Team, error) {
+	defer metrics.ObserveQuery("team", "ListByUserId")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT t.id, t.name, t.created_at
+		FROM team t
+		JOIN team_member m ON m.team_id = t.id
+		WHERE m.user_id = ?
+		ORDER BY t.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.Engine.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var teams []model.Team
+	for rows.Next() {
+		var team model.Team
+		err := rows.Scan(&team.Id, &team.Name, &team.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		teams = append(teams, team)
+	}
+
+	return teams, rows.Err()
+}
+
+func (r *teamRepository) Update(ctx context.Context, t *model.Team) error {
+	defer metrics.ObserveQuery("team", "Update")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE team
+		SET name = ?
+		WHERE id = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, t.Name, t.Id)
+	return err
+}
+
+// Delete removes the team, which cascades to its members and shelves. Shelves would bypass the trash that
+// way, so only teams without shelves are deleted.
+func (r *teamRepository) Delete(ctx context.Context, id string) error {
+	defer metrics.ObserveQuery("team", "Delete")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		DELETE FROM team
+		WHERE id = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, id)
+	return err
+}
+
+// CountShelves counts the shelves of the team, including the ones in the trash.
+func (r *teamRepository) CountShelves(ctx context.Context, id string) (int, error) {
+	defer metrics.ObserveQuery("shelf", "CountShelves")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT COUNT(*)
+		FROM shelf
+		WHERE team_id = ?
+	`)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	err = r.Engine.QueryRowContext(ctx, query, id).Scan(&count)
+	return count, err
+}
+
+func (r *teamRepository) ListMembers(ctx context.Context, teamId string) ([]model.TeamMember, error) {
+	defer metrics.ObserveQuery("team_member", "ListMembers")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT team_id, user_id, role, created_at
+		FROM team_member
+		WHERE team_id = ?
+		ORDER BY created_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.Engine.QueryContext(ctx, query, teamId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []model.TeamMember
+	for rows.Next() {
+		var member model.TeamMember
+		err := rows.Scan(&member.TeamId, &member.UserId, &member.Role, &member.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+func (r *teamRepository) GetMember(ctx context.Context, teamId, userId string) (*model.TeamMember, error) {
+	defer metrics.ObserveQuery("team_member", "GetMember")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT team_id, user_id, role, created_at
+		FROM team_member
+		WHERE team_id = ? AND user_id = ?
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var member model.TeamMember
+	err = r.Engine.QueryRowContext(ctx, query, teamId, userId).Scan(&member.TeamId, &member.UserId, &member.Role, &member.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+func (r *teamRepository) AddMember(ctx context.Context, m *model.TeamMember) error {
+	defer metrics.ObserveQuery("team_member", "AddMember")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		INSERT INTO team_member (team_id, user_id, role, created_at)
+		VALUES (?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, m.TeamId, m.UserId, m.Role, m.CreatedAt)
+	return err
+}
+
+func (r *teamRepository) SetMemberRole(ctx context.Context, teamId, userId, role string) error {
+	defer metrics.ObserveQuery("team_member", "SetMemberRole")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE team_member
+		SET role = ?
+		WHERE team_id = ? AND user_id = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, role, teamId, userId)
+	return err
+}
+
+// RemoveMember reports false if the user isn't a member of the team.
+func (r *teamRepository) RemoveMember(ctx context.Context, teamId, userId string) (bool, error) {
+	defer metrics.ObserveQuery("team_member", "RemoveMember")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		DELETE FROM team_member
+		WHERE team_id = ? AND user_id = ?
+	`)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := r.Engine.ExecContext(ctx, query, teamId, userId)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
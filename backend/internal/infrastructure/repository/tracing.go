@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/logging"
+	"backend/internal/infrastructure/tracing"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedDB records a span and a debug log line with the request-scoped logger for every SQL statement.
+// Only the statement text is recorded and never its parameters, since they contain credentials and
+// personal data.
+type tracedDB struct {
+	*sql.DB
+	engine string
+}
+
+type tracedTx struct {
+	*sql.Tx
+	engine string
+}
+
+func (db *tracedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, stmt := startStatement(ctx, db.engine, query)
+	result, err := db.DB.ExecContext(ctx, query, args...)
+	stmt.end(ctx, err)
+	return result, err
+}
+
+func (db *tracedDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, stmt := startStatement(ctx, db.engine, query)
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	stmt.end(ctx, err)
+	return rows, err
+}
+
+func (db *tracedDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, stmt := startStatement(ctx, db.engine, query)
+	row := db.DB.QueryRowContext(ctx, query, args...)
+	stmt.end(ctx, ignoreNoRows(row.Err()))
+	return row
+}
+
+func (db *tracedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*tracedTx, error) {
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tracedTx{Tx: tx, engine: db.engine}, nil
+}
+
+func (tx *tracedTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, stmt := startStatement(ctx, tx.engine, query)
+	result, err := tx.Tx.ExecContext(ctx, query, args...)
+	stmt.end(ctx, err)
+	return result, err
+}
+
+func (tx *tracedTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, stmt := startStatement(ctx, tx.engine, query)
+	rows, err := tx.Tx.QueryContext(ctx, query, args...)
+	stmt.end(ctx, err)
+	return rows, err
+}
+
+func (tx *tracedTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, stmt := startStatement(ctx, tx.engine, query)
+	row := tx.Tx.QueryRowContext(ctx, query, args...)
+	stmt.end(ctx, ignoreNoRows(row.Err()))
+	return row
+}
+
+// statement is a running SQL statement.
+type statement struct {
+	span  trace.Span
+	query string
+	start time.Time
+}
+
+func startStatement(ctx context.Context, engine, query string) (context.Context, *statement) {
+	query = strings.Join(strings.Fields(query), " ")
+
+	operation, _, _ := strings.Cut(query, " ")
+	operation = strings.ToUpper(operation)
+
+	ctx, span := tracing.Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			dbSystem(engine),
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(query),
+		),
+	)
+
+	return ctx, &statement{span: span, query: query, start: time.Now()}
+}
+
+func (s *statement) end(ctx context.Context, err error) {
+	defer s.span.End()
+	tracing.RecordError(s.span, err)
+
+	attrs := []slog.Attr{
+		slog.String("query", s.query),
+		slog.Duration("duration", time.Since(s.start)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	logging.FromContext(ctx).LogAttrs(ctx, slog.LevelDebug, "SQL statement executed", attrs...)
+}
+
+// ignoreNoRows drops sql.ErrNoRows, since a missing row is an expected result and not a failure.
+func ignoreNoRows(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
+
+func dbSystem(engine string) attribute.KeyValue {
+	if engine == "mysql" {
+		return semconv.DBSystemMySQL
+	}
+	return semconv.DBSystemPostgreSQL
+}
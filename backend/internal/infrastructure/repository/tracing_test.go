@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestStatementsAreTracedWithoutParameters(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, repo *Repository) {
+		exporter := tracetest.NewInMemoryExporter()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+		_, err := repo.UserRepository.Get(context.Background(), "secret-user-id")
+		require.NoError(t, err)
+
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		require.Equal(t, "SELECT", spans[0].Name)
+
+		for _, attribute := range spans[0].Attributes {
+			require.NotContains(t, attribute.Value.Emit(), "secret-user-id")
+			if attribute.Key == semconv.DBQueryTextKey {
+				require.True(t, strings.HasPrefix(attribute.Value.AsString(), "SELECT"))
+			}
+		}
+	})
+}
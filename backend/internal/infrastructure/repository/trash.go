@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/metrics"
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+)
+
+// TrashRepository reads and empties the trash of shelves, sections and links, which are moved to and out
+// of it by their own repositories.
+type TrashRepository interface {
+	ListByUserId(ctx context.Context, userId string) ([]model.TrashItem, error)
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+type trashRepository struct {
+	Engine *tracedDB
+}
+
+func NewTrashRepository(engine *sql.DB, dialect string) (TrashRepository, error) {
+	return &trashRepository{
+		Engine: &tracedDB{DB: engine, engine: dialect},
+	}, nil
+}
+
+// ListByUserId returns the items the user deleted and those of the personal shelves of the user, newest
+// first. Sections and links which were deleted together with their parent are left out.
+func (r *trashRepository) ListByUserId(ctx context.Context, userId string) ([]model.TrashItem, error) {
+	defer metrics.ObserveQuery("trash", "ListByUserId")()
+
+	queries := map[string]string{
+		model.TrashItemShelf: `
+			SELECT id, title, id, NULL, deleted_at, deleted_by
+			FROM shelf
+			WHERE deleted_at IS NOT NULL AND (deleted_by = ? OR user_id = ?)
+		`,
+		model.TrashItemSection: `
+			SELECT s.id, s.title, s.shelf_id, NULL, s.deleted_at, s.deleted_by
+			FROM section s
+			JOIN shelf sh ON sh.id = s.shelf_id
+			WHERE s.deleted_at IS NOT NULL
+				AND (sh.deleted_at IS NULL OR sh.deleted_at <> s.deleted_at)
+				AND (s.deleted_by = ? OR sh.user_id = ?)
+		`,
+		model.TrashItemLink: `
+			SELECT l.id, l.title, s.shelf_id, l.section_id, l.deleted_at, l.deleted_by
+			FROM link l
+			JOIN section s ON s.id = l.section_id
+			JOIN shelf sh ON sh.id = s.shelf_id
+			WHERE l.deleted_at IS NOT NULL
+				AND (s.deleted_at IS NULL OR s.deleted_at <> l.deleted_at)
+				AND (l.deleted_by = ? OR sh.user_id = ?)
+		`,
+	}
+
+	var items []model.TrashItem
+	for _, itemType := range []string{model.TrashItemShelf, model.TrashItemSection, model.TrashItemLink} {
+		query, err := r.Engine.buildSqlStatements(queries[itemType])
+		if err != nil {
+			return nil, err
+		}
+
+		typeItems, err := r.list(ctx, itemType, query, userId)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, typeItems...)
+	}
+
+	slices.SortStableFunc(items, func(a, b model.TrashItem) int {
+		return b.DeletedAt.Compare(a.DeletedAt)
+	})
+	return items, nil
+}
+
+func (r *trashRepository) list(ctx context.Context, itemType, query, userId string) ([]model.TrashItem, error) {
+	rows, err := r.Engine.QueryContext(ctx, query, userId, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []model.TrashItem
+	for rows.Next() {
+		item := model.TrashItem{Type: itemType}
+		var sectionId, deletedBy sql.NullString
+		err := rows.Scan(&item.Id, &item.Title, &item.ShelfId, &sectionId, &item.DeletedAt, &deletedBy)
+		if err != nil {
+			return nil, err
+		}
+		item.SectionId = sectionId.String
+		item.DeletedBy = deletedBy.String
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// Purge removes the items which were deleted before the given time for good. Shelves and sections take
+// their children with them.
+func (r *trashRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	defer metrics.ObserveQuery("trash", "Purge")()
+
+	var purged int64
+	for _, table := range []string{"shelf", "section", "link"} {
+		query, err := r.Engine.buildSqlStatements(`
+			DELETE FROM ` + table + `
+			WHERE deleted_at < ?
+		`)
+		if err != nil {
+			return purged, err
+		}
+
+		result, err := r.Engine.ExecContext(ctx, query, before)
+		if err != nil {
+			return purged, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return purged, err
+		}
+		purged += affected
+	}
+
+	return purged, nil
+}
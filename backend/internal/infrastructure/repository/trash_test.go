@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrashRestoresChildrenDeletedWithTheirParent(t *testing.T) {
+	forEachEngine(t, func(t *testing.T, repo *Repository) {
+		ctx := context.Background()
+		userId, err := repo.UserRepository.Create(ctx, &model.User{
+			Id: uuid.New().String(),
+			UserBase: model.UserBase{
+				Email:     "trash@test.com",
+				FirstName: "Jane",
+				LastName:  "Doe",
+			},
+			Password: "userpassword",
+		})
+		require.NoError(t, err)
+
+		shelfId, err := repo.ShelfRepository.Create(ctx, &model.Shelf{ShelfBase: model.ShelfBase{Title: "Trash", Path: "trash", UserId: userId}})
+		require.NoError(t, err)
+		sectionId, err := repo.SectionRepository.Create(ctx, &model.Section{SectionBase: model.SectionBase{Title: "Section", ShelfId: shelfId}})
+		require.NoError(t, err)
+		firstId, err := repo.LinkRepository.Create(ctx, &model.Link{LinkBase: model.LinkBase{Title: "First", Link: "https://first.example.com", SectionId: sectionId}})
+		require.NoError(t, err)
+		secondId, err := repo.LinkRepository.Create(ctx, &model.Link{LinkBase: model.LinkBase{Title: "Second", Link: "https://second.example.com", SectionId: sectionId}})
+		require.NoError(t, err)
+
+		deletedAt := time.Now().UTC().Truncate(time.Second)
+		require.NoError(t, repo.LinkRepository.SoftDelete(ctx, firstId, userId, deletedAt.Add(-time.Minute)))
+		require.NoError(t, repo.ShelfRepository.SoftDelete(ctx, shelfId, userId, deletedAt))
+
+		shelf, err := repo.ShelfRepository.Get(ctx, shelfId)
+		require.NoError(t, err)
+		require.Nil(t, shelf)
+		links, err := repo.LinkRepository.ListByShelfId(ctx, shelfId)
+		require.NoError(t, err)
+		require.Empty(t, links)
+
+		items, err := repo.TrashRepository.ListByUserId(ctx, userId)
+		require.NoError(t, err)
+		require.Len(t, items, 2, "the section and the second link were deleted with the shelf")
+		require.Equal(t, model.TrashItemShelf, items[0].Type)
+		require.Equal(t, firstId, items[1].Id)
+
+		restored, err := repo.ShelfRepository.Restore(ctx, shelfId)
+		require.NoError(t, err)
+		require.True(t, restored)
+		links, err = repo.LinkRepository.ListByShelfId(ctx, shelfId)
+		require.NoError(t, err)
+		require.Len(t, links, 1)
+		require.Equal(t, secondId, links[0].Id, "the link deleted before stays in the trash")
+
+		purged, err := repo.TrashRepository.Purge(ctx, deletedAt)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), purged)
+		restored, err = repo.LinkRepository.Restore(ctx, firstId)
+		require.NoError(t, err)
+		require.False(t, restored)
+	})
+}
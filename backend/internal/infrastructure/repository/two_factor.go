@@ -0,0 +1,305 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/metrics"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TwoFactorRepository interface {
+	GetTOTP(ctx context.Context, userId string) (*model.UserTOTP, error)
+	SaveTOTP(ctx context.Context, t *model.UserTOTP) error
+	UpdateTOTPSecret(ctx context.Context, userId, previous, secret string) error
+	ConfirmTOTP(ctx context.Context, userId string, confirmedAt time.Time, step int64, recoveryCodeHashes []string) error
+	UseTOTPStep(ctx context.Context, userId string, step int64) (bool, error)
+	DeleteTOTP(ctx context.Context, userId string) error
+	ReplaceRecoveryCodes(ctx context.Context, userId string, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userId, codeHash string, usedAt time.Time) (bool, error)
+}
+
+type twoFactorRepository struct {
+	Engine *tracedDB
+	Table  string
+}
+
+func NewTwoFactorRepository(engine *sql.DB, dialect, table string) (TwoFactorRepository, error) {
+	return &twoFactorRepository{
+		Engine: &tracedDB{DB: engine, engine: dialect},
+		Table:  table,
+	}, nil
+}
+
+func (r *twoFactorRepository) GetTOTP(ctx context.Context, userId string) (*model.UserTOTP, error) {
+	defer metrics.ObserveQuery("user_totp", "GetTOTP")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT user_id, secret, confirmed_at, last_used_step, created_at
+		FROM user_totp
+		WHERE user_id = ?
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var totp model.UserTOTP
+	var confirmedAt sql.NullTime
+	var lastUsedStep sql.NullInt64
+	err = r.Engine.QueryRowContext(ctx, query, userId).Scan(
+		&totp.UserId,
+		&totp.Secret,
+		&confirmedAt,
+		&lastUsedStep,
+		&totp.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if confirmedAt.Valid {
+		totp.ConfirmedAt = &confirmedAt.Time
+	}
+	if lastUsedStep.Valid {
+		totp.LastUsedStep = &lastUsedStep.Int64
+	}
+
+	return &totp, nil
+}
+
+// SaveTOTP replaces the TOTP secret of the user with an unconfirmed one.
+func (r *twoFactorRepository) SaveTOTP(ctx context.Context, t *model.UserTOTP) error {
+	defer metrics.ObserveQuery("user_totp", "SaveTOTP")()
+
+	deleteQuery, err := r.Engine.buildSqlStatements(`
+		DELETE FROM user_totp
+		WHERE user_id = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	insertQuery, err := r.Engine.buildSqlStatements(`
+		INSERT INTO user_totp (user_id, secret, created_at)
+		VALUES (?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.Engine.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, deleteQuery, t.UserId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, insertQuery, t.UserId, t.Secret, t.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateTOTPSecret replaces the stored form of the secret, unless it was replaced by a new enrollment
+// meanwhile.
+func (r *twoFactorRepository) UpdateTOTPSecret(ctx context.Context, userId, previous, secret string) error {
+	defer metrics.ObserveQuery("user_totp", "UpdateTOTPSecret")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE user_totp
+		SET secret = ?
+		WHERE user_id = ? AND secret = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, secret, userId, previous)
+	return err
+}
+
+// ConfirmTOTP enables two-factor authentication together with the first set of recovery codes.
+func (r *twoFactorRepository) ConfirmTOTP(ctx context.Context, userId string, confirmedAt time.Time, step int64, recoveryCodeHashes []string) error {
+	defer metrics.ObserveQuery("user_totp", "ConfirmTOTP")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE user_totp
+		SET confirmed_at = ?,
+			last_used_step = ?
+		WHERE user_id = ? AND confirmed_at IS NULL
+	`)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.Engine.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, confirmedAt, step, userId)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected != 1 {
+		return errors.New("no unconfirmed TOTP enrollment found")
+	}
+
+	err = r.replaceRecoveryCodes(ctx, tx, userId, recoveryCodeHashes)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UseTOTPStep records the time step of a used code. It reports false if the step or a later one was already
+// used, so every code is only accepted once.
+func (r *twoFactorRepository) UseTOTPStep(ctx context.Context, userId string, step int64) (bool, error) {
+	defer metrics.ObserveQuery("user_totp", "UseTOTPStep")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE user_totp
+		SET last_used_step = ?
+		WHERE user_id = ? AND (last_used_step IS NULL OR last_used_step < ?)
+	`)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := r.Engine.ExecContext(ctx, query, step, userId, step)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// DeleteTOTP disables two-factor authentication and removes the recovery codes.
+func (r *twoFactorRepository) DeleteTOTP(ctx context.Context, userId string) error {
+	defer metrics.ObserveQuery("user_totp", "DeleteTOTP")()
+
+	totpQuery, err := r.Engine.buildSqlStatements(`
+		DELETE FROM user_totp
+		WHERE user_id = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	codesQuery, err := r.Engine.buildSqlStatements(`
+		DELETE FROM user_recovery_code
+		WHERE user_id = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.Engine.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, totpQuery, userId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, codesQuery, userId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *twoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userId string, codeHashes []string) error {
+	defer metrics.ObserveQuery("user_recovery_code", "ReplaceRecoveryCodes")()
+
+	tx, err := r.Engine.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = r.replaceRecoveryCodes(ctx, tx, userId, codeHashes)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *twoFactorRepository) replaceRecoveryCodes(ctx context.Context, tx *tracedTx, userId string, codeHashes []string) error {
+	deleteQuery, err := r.Engine.buildSqlStatements(`
+		DELETE FROM user_recovery_code
+		WHERE user_id = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	insertQuery, err := r.Engine.buildSqlStatements(`
+		INSERT INTO user_recovery_code (id, user_id, code_hash, created_at)
+		VALUES (?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, deleteQuery, userId)
+	if err != nil {
+		return err
+	}
+
+	createdAt := time.Now().UTC()
+	for _, codeHash := range codeHashes {
+		_, err = tx.ExecContext(ctx, insertQuery, uuid.New().String(), userId, codeHash, createdAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used. It reports false for unknown and used codes.
+func (r *twoFactorRepository) UseRecoveryCode(ctx context.Context, userId, codeHash string, usedAt time.Time) (bool, error) {
+	defer metrics.ObserveQuery("user_recovery_code", "UseRecoveryCode")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE user_recovery_code
+		SET used_at = ?
+		WHERE user_id = ? AND code_hash = ? AND used_at IS NULL
+	`)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := r.Engine.ExecContext(ctx, query, usedAt, userId, codeHash)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
@@ -2,46 +2,62 @@ package repository
 
 import (
 	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/metrics"
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type UserRepository interface {
-	List() ([]model.User, error)
-	Get(id string) (*model.User, error)
-	GetPassword(id string) (string, error)
-	Create(u *model.User) (string, error)
-	Update(u *model.User) error
-	PatchPassword(u *model.User) error
-	Delete(u *model.User) error
+	List(ctx context.Context) ([]model.User, error)
+	Search(ctx context.Context, search model.UserSearch) ([]model.User, int64, error)
+	Get(ctx context.Context, id string) (*model.User, error)
+	GetByEmail(ctx context.Context, email string) (*model.User, error)
+	GetPassword(ctx context.Context, id string) (string, error)
+	Create(ctx context.Context, u *model.User) (string, error)
+	Update(ctx context.Context, u *model.User) error
+	PatchPassword(ctx context.Context, u *model.User) error
+	SetEmailVerifiedAt(ctx context.Context, id string, verifiedAt *time.Time) error
+	ScheduleDeletion(ctx context.Context, id string, disabledAt, purgeAt time.Time) error
+	CancelDeletion(ctx context.Context, id string) error
+	SetRole(ctx context.Context, id, role string) error
+	Disable(ctx context.Context, id string, disabledAt time.Time) error
+	Enable(ctx context.Context, id string) error
+	ListPurgeable(ctx context.Context, before time.Time) ([]string, error)
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+	Delete(ctx context.Context, u *model.User) error
 }
 
 type userRepository struct {
-	Engine *sql.DB
+	Engine *tracedDB
 	Table  string
 }
 
-func NewUserRepository(engine *sql.DB, table string) (UserRepository, error) {
+func NewUserRepository(engine *sql.DB, dialect, table string) (UserRepository, error) {
 
 	return &userRepository{
-		Engine: engine,
+		Engine: &tracedDB{DB: engine, engine: dialect},
 		Table:  table,
 	}, nil
 }
 
-func (r *userRepository) List() ([]model.User, error) {
-	query, err := buildSqlStatements(`
-		SELECT *
+func (r *userRepository) List(ctx context.Context) ([]model.User, error) {
+	defer metrics.ObserveQuery("user", "List")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT id, email, first_name, last_name, role
 		FROM "user"
+		ORDER BY email
 	`)
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := r.Engine.QueryContext(context.TODO(), query)
+	rows, err := r.Engine.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -55,6 +71,7 @@ func (r *userRepository) List() ([]model.User, error) {
 			&user.Email,
 			&user.FirstName,
 			&user.LastName,
+			&user.Role,
 		)
 		if err != nil {
 			return nil, err
@@ -62,37 +79,147 @@ func (r *userRepository) List() ([]model.User, error) {
 		users = append(users, user)
 	}
 
-	return users, nil
+	return users, rows.Err()
 }
 
-func (r *userRepository) Get(id string) (*model.User, error) {
-	query, err := buildSqlStatements(`
-		SELECT *
-		FROM "user"
+// Search returns a page of the users which match the search and the number of all matching users.
+func (r *userRepository) Search(ctx context.Context, search model.UserSearch) ([]model.User, int64, error) {
+	defer metrics.ObserveQuery("user", "Search")()
+
+	where := "1 = 1"
+	var args []any
+	if search.Query != "" {
+		pattern := "%" + escapeLike(strings.ToLower(search.Query)) + "%"
+		where += " AND (LOWER(email) LIKE ? OR LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ?)"
+		args = append(args, pattern, pattern, pattern)
+	}
+	if search.Role != "" {
+		where += " AND role = ?"
+		args = append(args, search.Role)
+	}
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT COUNT(*)
+		FROM "user" u
+		WHERE ` + where)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	err = r.Engine.QueryRowContext(ctx, query, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query, err = r.Engine.buildSqlStatements(`
+		SELECT id, email, first_name, last_name, email_verified_at, disabled_at, purge_at, role,
+			EXISTS (SELECT 1 FROM user_totp t WHERE t.user_id = u.id AND t.confirmed_at IS NOT NULL)
+		FROM "user" u
+		WHERE ` + where + `
+		ORDER BY email
+		LIMIT ? OFFSET ?
+	`)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.Engine.QueryContext(ctx, query, append(args, search.PageSize, search.Offset())...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var users []model.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, *user)
+	}
+
+	return users, total, rows.Err()
+}
+
+func (r *userRepository) Get(ctx context.Context, id string) (*model.User, error) {
+	defer metrics.ObserveQuery("user", "Get")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT id, email, first_name, last_name, email_verified_at, disabled_at, purge_at, role,
+			EXISTS (SELECT 1 FROM user_totp t WHERE t.user_id = u.id AND t.confirmed_at IS NOT NULL)
+		FROM "user" u
 		WHERE id = ?
 	`)
 	if err != nil {
 		return nil, err
 	}
 
+	user, err := scanUser(r.Engine.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return user, err
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	defer metrics.ObserveQuery("user", "GetByEmail")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT id, email, first_name, last_name, email_verified_at, disabled_at, purge_at, role,
+			EXISTS (SELECT 1 FROM user_totp t WHERE t.user_id = u.id AND t.confirmed_at IS NOT NULL)
+		FROM "user" u
+		WHERE email = ?
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := scanUser(r.Engine.QueryRowContext(ctx, query, email))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	return user, err
+}
+
+// scanUser reads a user from a *sql.Row or the current row of *sql.Rows.
+func scanUser(row interface{ Scan(dest ...any) error }) (*model.User, error) {
 	var user model.User
-	err = r.Engine.QueryRowContext(context.TODO(), query, id).Scan(
+	var emailVerifiedAt, disabledAt, purgeAt sql.NullTime
+	err := row.Scan(
 		&user.Id,
 		&user.Email,
 		&user.FirstName,
 		&user.LastName,
-		&user.Password,
+		&emailVerifiedAt,
+		&disabledAt,
+		&purgeAt,
+		&user.Role,
+		&user.TwoFactorEnabled,
 	)
+	if err != nil {
+		return nil, err
+	}
 
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil
+	if emailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &emailVerifiedAt.Time
+	}
+	if disabledAt.Valid {
+		user.DisabledAt = &disabledAt.Time
+	}
+	if purgeAt.Valid {
+		user.PurgeAt = &purgeAt.Time
 	}
 
-	return &user, err
+	return &user, nil
 }
 
-func (r *userRepository) GetPassword(id string) (string, error) {
-	query, err := buildSqlStatements(`
+func (r *userRepository) GetPassword(ctx context.Context, id string) (string, error) {
+	defer metrics.ObserveQuery("user", "GetPassword")()
+
+	query, err := r.Engine.buildSqlStatements(`
 		SELECT password
 		FROM "user"
 		WHERE id = ?
@@ -102,7 +229,7 @@ func (r *userRepository) GetPassword(id string) (string, error) {
 	}
 
 	var password string
-	err = r.Engine.QueryRowContext(context.TODO(), query, id).Scan(
+	err = r.Engine.QueryRowContext(ctx, query, id).Scan(
 		&password,
 	)
 
@@ -113,8 +240,10 @@ func (r *userRepository) GetPassword(id string) (string, error) {
 	return password, err
 }
 
-func (r *userRepository) Create(u *model.User) (string, error) {
-	query, err := buildSqlStatements(`
+func (r *userRepository) Create(ctx context.Context, u *model.User) (string, error) {
+	defer metrics.ObserveQuery("user", "Create")()
+
+	query, err := r.Engine.buildSqlStatements(`
 		INSERT INTO "user" (id, email, first_name, last_name, password)
 		VALUES (?, ?, ?, ?, ?)
 	`)
@@ -125,7 +254,7 @@ func (r *userRepository) Create(u *model.User) (string, error) {
 	u.Id = uuid.New().String()
 
 	_, err = r.Engine.ExecContext(
-		context.TODO(),
+		ctx,
 		query,
 		u.Id,
 		u.Email,
@@ -140,8 +269,10 @@ func (r *userRepository) Create(u *model.User) (string, error) {
 	return u.Id, nil
 }
 
-func (r *userRepository) Update(u *model.User) error {
-	query, err := buildSqlStatements(`
+func (r *userRepository) Update(ctx context.Context, u *model.User) error {
+	defer metrics.ObserveQuery("user", "Update")()
+
+	query, err := r.Engine.buildSqlStatements(`
 		UPDATE "user"
 		SET email = ?, 
 		 	first_name = ?, 
@@ -153,7 +284,7 @@ func (r *userRepository) Update(u *model.User) error {
 	}
 
 	_, err = r.Engine.ExecContext(
-		context.TODO(),
+		ctx,
 		query,
 		u.Email,
 		u.FirstName,
@@ -167,8 +298,10 @@ func (r *userRepository) Update(u *model.User) error {
 	return nil
 }
 
-func (r *userRepository) PatchPassword(u *model.User) error {
-	query, err := buildSqlStatements(`
+func (r *userRepository) PatchPassword(ctx context.Context, u *model.User) error {
+	defer metrics.ObserveQuery("user", "PatchPassword")()
+
+	query, err := r.Engine.buildSqlStatements(`
 		UPDATE "user"
 		SET password = ?
 		WHERE id = ?
@@ -178,7 +311,7 @@ func (r *userRepository) PatchPassword(u *model.User) error {
 	}
 
 	_, err = r.Engine.ExecContext(
-		context.TODO(),
+		ctx,
 		query,
 		u.Password,
 		u.Id,
@@ -190,8 +323,168 @@ func (r *userRepository) PatchPassword(u *model.User) error {
 	return nil
 }
 
-func (r *userRepository) Delete(u *model.User) error {
-	query, err := buildSqlStatements(`
+// SetEmailVerifiedAt marks the email address of a user as verified, or as unverified if verifiedAt is nil.
+func (r *userRepository) SetEmailVerifiedAt(ctx context.Context, id string, verifiedAt *time.Time) error {
+	defer metrics.ObserveQuery("user", "SetEmailVerifiedAt")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE "user"
+		SET email_verified_at = ?
+		WHERE id = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, verifiedAt, id)
+	return err
+}
+
+// ScheduleDeletion disables the user until the account is purged at purgeAt.
+func (r *userRepository) ScheduleDeletion(ctx context.Context, id string, disabledAt, purgeAt time.Time) error {
+	defer metrics.ObserveQuery("user", "ScheduleDeletion")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE "user"
+		SET disabled_at = ?,
+			purge_at = ?
+		WHERE id = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, disabledAt, purgeAt, id)
+	return err
+}
+
+// CancelDeletion enables a user again whose deletion is scheduled.
+func (r *userRepository) CancelDeletion(ctx context.Context, id string) error {
+	defer metrics.ObserveQuery("user", "CancelDeletion")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE "user"
+		SET disabled_at = NULL,
+			purge_at = NULL
+		WHERE id = ? AND purge_at IS NOT NULL
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *userRepository) SetRole(ctx context.Context, id, role string) error {
+	defer metrics.ObserveQuery("user", "SetRole")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE "user"
+		SET role = ?
+		WHERE id = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, role, id)
+	return err
+}
+
+// Disable disables the user until it's enabled again. It also cancels a scheduled deletion, so the account
+// stays disabled instead of being restored by the next login.
+func (r *userRepository) Disable(ctx context.Context, id string, disabledAt time.Time) error {
+	defer metrics.ObserveQuery("user", "Disable")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE "user"
+		SET disabled_at = ?,
+			purge_at = NULL
+		WHERE id = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, disabledAt, id)
+	return err
+}
+
+// Enable enables a disabled user, which also cancels a scheduled deletion.
+func (r *userRepository) Enable(ctx context.Context, id string) error {
+	defer metrics.ObserveQuery("user", "Enable")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE "user"
+		SET disabled_at = NULL,
+			purge_at = NULL
+		WHERE id = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, id)
+	return err
+}
+
+// ListPurgeable returns the IDs of the users PurgeDeleted deletes with the same time.
+func (r *userRepository) ListPurgeable(ctx context.Context, before time.Time) ([]string, error) {
+	defer metrics.ObserveQuery("user", "ListPurgeable")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT id
+		FROM "user"
+		WHERE purge_at IS NOT NULL AND purge_at <= ?
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.Engine.QueryContext(ctx, query, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// PurgeDeleted deletes all users whose grace period ended before the given time, which cascades to all
+// their data.
+func (r *userRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	defer metrics.ObserveQuery("user", "PurgeDeleted")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		DELETE FROM "user"
+		WHERE purge_at IS NOT NULL AND purge_at <= ?
+	`)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := r.Engine.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+func (r *userRepository) Delete(ctx context.Context, u *model.User) error {
+	defer metrics.ObserveQuery("user", "Delete")()
+
+	query, err := r.Engine.buildSqlStatements(`
 		DELETE FROM "user"
 		WHERE id = ?
 	`)
@@ -200,7 +493,7 @@ func (r *userRepository) Delete(u *model.User) error {
 	}
 
 	_, err = r.Engine.ExecContext(
-		context.TODO(),
+		ctx,
 		query,
 		u.Id,
 	)
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"backend/internal/infrastructure/api/model"
+	"context"
 	"testing"
 
 	"github.com/google/uuid"
@@ -9,20 +10,17 @@ import (
 )
 
 func TestCreateUser(t *testing.T) {
-	if testRepo == nil {
-		t.Fatal("repository not initialized")
-	}
-
-	userId, err := testRepo.UserRepository.Create(&model.User{
-		Id: uuid.New().String(),
-		UserBase: model.UserBase{
-			Email:     "user@test.com",
-			FirstName: "John",
-			LastName:  "Doe",
-			Password:  "userpassword",
-		},
+	forEachEngine(t, func(t *testing.T, repo *Repository) {
+		userId, err := repo.UserRepository.Create(context.Background(), &model.User{
+			Id: uuid.New().String(),
+			UserBase: model.UserBase{
+				Email:     "user@test.com",
+				FirstName: "John",
+				LastName:  "Doe",
+			},
+			Password: "userpassword",
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, userId)
 	})
-	require.NoError(t, err)
-	require.NotEmpty(t, userId)
-
 }
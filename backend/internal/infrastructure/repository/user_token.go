@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"backend/internal/infrastructure/api/model"
+	"backend/internal/infrastructure/metrics"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserTokenRepository interface {
+	Create(ctx context.Context, t *model.UserToken) (string, error)
+	GetByHash(ctx context.Context, purpose, tokenHash string) (*model.UserToken, error)
+	Use(ctx context.Context, id string, usedAt time.Time) (bool, error)
+	DeleteByUser(ctx context.Context, userId, purpose string) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type userTokenRepository struct {
+	Engine *tracedDB
+	Table  string
+}
+
+func NewUserTokenRepository(engine *sql.DB, dialect, table string) (UserTokenRepository, error) {
+	return &userTokenRepository{
+		Engine: &tracedDB{DB: engine, engine: dialect},
+		Table:  table,
+	}, nil
+}
+
+func (r *userTokenRepository) Create(ctx context.Context, t *model.UserToken) (string, error) {
+	defer metrics.ObserveQuery("user_token", "Create")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		INSERT INTO user_token (id, user_id, purpose, email, token_hash, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return "", err
+	}
+
+	t.Id = uuid.New().String()
+
+	_, err = r.Engine.ExecContext(
+		ctx,
+		query,
+		t.Id,
+		t.UserId,
+		t.Purpose,
+		nullString(t.Email),
+		t.TokenHash,
+		t.ExpiresAt,
+		t.CreatedAt,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	return t.Id, nil
+}
+
+func (r *userTokenRepository) GetByHash(ctx context.Context, purpose, tokenHash string) (*model.UserToken, error) {
+	defer metrics.ObserveQuery("user_token", "GetByHash")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		SELECT id, user_id, purpose, email, token_hash, expires_at, used_at, created_at
+		FROM user_token
+		WHERE purpose = ? AND token_hash = ?
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var token model.UserToken
+	var email sql.NullString
+	var usedAt sql.NullTime
+	err = r.Engine.QueryRowContext(ctx, query, purpose, tokenHash).Scan(
+		&token.Id,
+		&token.UserId,
+		&token.Purpose,
+		&email,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&usedAt,
+		&token.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	token.Email = email.String
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+
+	return &token, nil
+}
+
+// Use marks the token as used. It reports false if the token was already used, the check is part of the
+// update, so two concurrent requests can't both use the same token.
+func (r *userTokenRepository) Use(ctx context.Context, id string, usedAt time.Time) (bool, error) {
+	defer metrics.ObserveQuery("user_token", "Use")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		UPDATE user_token
+		SET used_at = ?
+		WHERE id = ? AND used_at IS NULL
+	`)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := r.Engine.ExecContext(ctx, query, usedAt, id)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+// DeleteByUser removes all tokens of the user for the purpose, so only the latest mail stays valid.
+func (r *userTokenRepository) DeleteByUser(ctx context.Context, userId, purpose string) error {
+	defer metrics.ObserveQuery("user_token", "DeleteByUser")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		DELETE FROM user_token
+		WHERE user_id = ? AND purpose = ?
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.Engine.ExecContext(ctx, query, userId, purpose)
+	return err
+}
+
+func (r *userTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	defer metrics.ObserveQuery("user_token", "DeleteExpired")()
+
+	query, err := r.Engine.buildSqlStatements(`
+		DELETE FROM user_token
+		WHERE expires_at < ?
+	`)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := r.Engine.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -2,7 +2,9 @@ package migrations
 
 import "embed"
 
-// FS Embed the migrations directory in the binary file
+// FS Embed the migrations directory in the binary file. The migrations are split per database engine
+// since the SQL dialects differ (e.g. identifier quoting and index creation), the subdirectory is
+// selected by `database.engine`.
 //
-//go:embed *.sql
+//go:embed postgres/*.sql mysql/*.sql
 var FS embed.FS
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS `link`;
+
+DROP TABLE IF EXISTS `section`;
+
+DROP TABLE IF EXISTS `shelf`;
+
+DROP TABLE IF EXISTS `user`;
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS `certificate`;
+
+ALTER TABLE `shelf`
+    DROP INDEX idx_shelf_domain,
+    DROP COLUMN domain_verified_at,
+    DROP COLUMN domain_verification_token;
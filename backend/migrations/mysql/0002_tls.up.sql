@@ -0,0 +1,11 @@
+ALTER TABLE `shelf`
+    ADD COLUMN domain_verification_token VARCHAR(64),
+    ADD COLUMN domain_verified_at TIMESTAMP NULL,
+    ADD INDEX idx_shelf_domain (domain);
+
+CREATE TABLE IF NOT EXISTS `certificate` (
+    name VARCHAR(255) NOT NULL,
+    data MEDIUMBLOB NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_certificate PRIMARY KEY (name)
+);
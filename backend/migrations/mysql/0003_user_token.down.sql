@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS `user_token`;
+
+ALTER TABLE `user`
+    DROP COLUMN email_verified_at;
@@ -0,0 +1,20 @@
+ALTER TABLE `user`
+    ADD COLUMN email_verified_at TIMESTAMP NULL;
+
+CREATE TABLE IF NOT EXISTS `user_token` (
+    id CHAR(36) NOT NULL,
+    user_id CHAR(36) NOT NULL,
+    purpose VARCHAR(32) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_user_token PRIMARY KEY (id),
+    CONSTRAINT uq_user_token_hash UNIQUE (token_hash),
+    INDEX idx_user_token_user_id (user_id),
+    INDEX idx_user_token_expires_at (expires_at),
+    CONSTRAINT fk_user_token_user
+        FOREIGN KEY (user_id)
+        REFERENCES `user`(id)
+        ON DELETE CASCADE
+);
@@ -0,0 +1,4 @@
+ALTER TABLE `user`
+    DROP INDEX idx_user_purge_at,
+    DROP COLUMN purge_at,
+    DROP COLUMN disabled_at;
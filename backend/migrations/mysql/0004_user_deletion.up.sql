@@ -0,0 +1,4 @@
+ALTER TABLE `user`
+    ADD COLUMN disabled_at TIMESTAMP NULL,
+    ADD COLUMN purge_at TIMESTAMP NULL,
+    ADD INDEX idx_user_purge_at (purge_at);
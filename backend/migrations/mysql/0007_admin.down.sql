@@ -0,0 +1,9 @@
+ALTER TABLE `user_session`
+    DROP FOREIGN KEY fk_user_session_impersonator,
+    DROP COLUMN impersonator_id;
+
+ALTER TABLE `shelf`
+    DROP COLUMN unpublished_at;
+
+ALTER TABLE `user`
+    DROP COLUMN role;
@@ -0,0 +1,12 @@
+ALTER TABLE `user`
+    ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'user';
+
+ALTER TABLE `shelf`
+    ADD COLUMN unpublished_at TIMESTAMP NULL;
+
+ALTER TABLE `user_session`
+    ADD COLUMN impersonator_id CHAR(36) NULL,
+    ADD CONSTRAINT fk_user_session_impersonator
+        FOREIGN KEY (impersonator_id)
+        REFERENCES `user`(id)
+        ON DELETE CASCADE;
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS `shelf_share`;
+
+-- Shelves without a user can't be kept once the ownership by teams is gone.
+DELETE FROM `shelf` WHERE user_id IS NULL;
+ALTER TABLE `shelf`
+    DROP FOREIGN KEY fk_shelf_team;
+ALTER TABLE `shelf`
+    DROP COLUMN team_id,
+    MODIFY COLUMN user_id CHAR(36) NOT NULL;
+
+DROP TABLE IF EXISTS `team_member`;
+DROP TABLE IF EXISTS `team`;
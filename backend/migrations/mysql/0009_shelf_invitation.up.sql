@@ -0,0 +1,30 @@
+-- Invitations without an email are shareable links, whoever opens the link first may accept them.
+CREATE TABLE IF NOT EXISTS `shelf_invitation` (
+    id CHAR(36) NOT NULL,
+    shelf_id CHAR(36) NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    email VARCHAR(255) NULL,
+    token_hash CHAR(64) NOT NULL,
+    invited_by CHAR(36) NULL,
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP NULL,
+    accepted_by CHAR(36) NULL,
+    declined_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_shelf_invitation PRIMARY KEY (id),
+    CONSTRAINT uq_shelf_invitation_hash UNIQUE (token_hash),
+    INDEX idx_shelf_invitation_shelf_id (shelf_id),
+    INDEX idx_shelf_invitation_expires_at (expires_at),
+    CONSTRAINT fk_shelf_invitation_shelf
+        FOREIGN KEY (shelf_id)
+        REFERENCES `shelf`(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_shelf_invitation_invited_by
+        FOREIGN KEY (invited_by)
+        REFERENCES `user`(id)
+        ON DELETE SET NULL,
+    CONSTRAINT fk_shelf_invitation_accepted_by
+        FOREIGN KEY (accepted_by)
+        REFERENCES `user`(id)
+        ON DELETE SET NULL
+);
@@ -0,0 +1,20 @@
+-- Audit events have no foreign keys, so they outlive the users and shelves they refer to. The repository
+-- offers no way to remove them, it only redacts the personal data of purged users.
+CREATE TABLE IF NOT EXISTS `audit_event` (
+    id CHAR(36) NOT NULL,
+    actor_id CHAR(36) NULL,
+    impersonator_id CHAR(36) NULL,
+    action VARCHAR(16) NOT NULL,
+    entity_type VARCHAR(32) NOT NULL,
+    entity_id VARCHAR(255) NOT NULL,
+    shelf_id CHAR(36) NULL,
+    changes TEXT NOT NULL,
+    request_id VARCHAR(128) NULL,
+    ip VARCHAR(45) NULL,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_audit_event PRIMARY KEY (id),
+    INDEX idx_audit_event_created_at (created_at),
+    INDEX idx_audit_event_shelf_id (shelf_id, created_at),
+    INDEX idx_audit_event_entity (entity_type, entity_id),
+    INDEX idx_audit_event_actor_id (actor_id)
+);
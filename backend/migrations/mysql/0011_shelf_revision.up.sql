@@ -0,0 +1,21 @@
+-- Revisions are snapshots of the whole tree of a shelf as JSON. Changes of the same author within the
+-- session window update the latest revision instead of adding one.
+CREATE TABLE IF NOT EXISTS `shelf_revision` (
+    id CHAR(36) NOT NULL,
+    shelf_id CHAR(36) NOT NULL,
+    author_id CHAR(36) NULL,
+    restored_from CHAR(36) NULL,
+    snapshot MEDIUMTEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_shelf_revision PRIMARY KEY (id),
+    INDEX idx_shelf_revision_shelf_id (shelf_id, created_at),
+    CONSTRAINT fk_shelf_revision_shelf
+        FOREIGN KEY (shelf_id)
+        REFERENCES `shelf`(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_shelf_revision_author
+        FOREIGN KEY (author_id)
+        REFERENCES `user`(id)
+        ON DELETE SET NULL
+);
@@ -0,0 +1,25 @@
+-- Without the columns the trash would come back to life, so it's emptied first.
+DELETE FROM `link` WHERE deleted_at IS NOT NULL;
+DELETE FROM `section` WHERE deleted_at IS NOT NULL;
+DELETE FROM `shelf` WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE `link`
+    DROP FOREIGN KEY fk_link_deleted_by;
+ALTER TABLE `link`
+    DROP INDEX idx_link_deleted_at,
+    DROP COLUMN deleted_by,
+    DROP COLUMN deleted_at;
+
+ALTER TABLE `section`
+    DROP FOREIGN KEY fk_section_deleted_by;
+ALTER TABLE `section`
+    DROP INDEX idx_section_deleted_at,
+    DROP COLUMN deleted_by,
+    DROP COLUMN deleted_at;
+
+ALTER TABLE `shelf`
+    DROP FOREIGN KEY fk_shelf_deleted_by;
+ALTER TABLE `shelf`
+    DROP INDEX idx_shelf_deleted_at,
+    DROP COLUMN deleted_by,
+    DROP COLUMN deleted_at;
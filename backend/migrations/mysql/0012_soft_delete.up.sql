@@ -0,0 +1,28 @@
+-- Deleted shelves, sections and links stay in the trash until they're restored or purged. The children
+-- of a deleted item get the same deleted_at, which tells them apart from children deleted before.
+ALTER TABLE `shelf`
+    ADD COLUMN deleted_at TIMESTAMP NULL,
+    ADD COLUMN deleted_by CHAR(36) NULL,
+    ADD INDEX idx_shelf_deleted_at (deleted_at),
+    ADD CONSTRAINT fk_shelf_deleted_by
+        FOREIGN KEY (deleted_by)
+        REFERENCES `user`(id)
+        ON DELETE SET NULL;
+
+ALTER TABLE `section`
+    ADD COLUMN deleted_at TIMESTAMP NULL,
+    ADD COLUMN deleted_by CHAR(36) NULL,
+    ADD INDEX idx_section_deleted_at (deleted_at),
+    ADD CONSTRAINT fk_section_deleted_by
+        FOREIGN KEY (deleted_by)
+        REFERENCES `user`(id)
+        ON DELETE SET NULL;
+
+ALTER TABLE `link`
+    ADD COLUMN deleted_at TIMESTAMP NULL,
+    ADD COLUMN deleted_by CHAR(36) NULL,
+    ADD INDEX idx_link_deleted_at (deleted_at),
+    ADD CONSTRAINT fk_link_deleted_by
+        FOREIGN KEY (deleted_by)
+        REFERENCES `user`(id)
+        ON DELETE SET NULL;
@@ -0,0 +1,18 @@
+-- Templates published by users. The sections with their placeholder links are stored as JSON, the
+-- built-in templates are embedded in the binary and not stored at all.
+CREATE TABLE IF NOT EXISTS `shelf_template` (
+    id CHAR(36) NOT NULL,
+    user_id CHAR(36) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL,
+    theme VARCHAR(32) NOT NULL,
+    icon VARCHAR(255) NOT NULL,
+    sections MEDIUMTEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_shelf_template PRIMARY KEY (id),
+    INDEX idx_shelf_template_created_at (created_at),
+    CONSTRAINT fk_shelf_template_user
+        FOREIGN KEY (user_id)
+        REFERENCES `user`(id)
+        ON DELETE CASCADE
+);
@@ -0,0 +1,5 @@
+ALTER TABLE `link`
+    DROP INDEX ft_link_search;
+
+ALTER TABLE `shelf`
+    DROP INDEX ft_shelf_search;
@@ -0,0 +1,7 @@
+-- Full-text indexes of the searched attributes. InnoDB splits URLs at their punctuation, so their host and
+-- path segments are found as words.
+ALTER TABLE `shelf`
+    ADD FULLTEXT INDEX ft_shelf_search (title, description);
+
+ALTER TABLE `link`
+    ADD FULLTEXT INDEX ft_link_search (title, link);
@@ -0,0 +1,2 @@
+ALTER TABLE `user_token`
+    DROP COLUMN email;
@@ -0,0 +1,4 @@
+-- Tokens are bound to the email address they were mailed to, a changed address doesn't get verified by
+-- redeeming a token of the former one. Tokens issued before have no address and never verify one.
+ALTER TABLE `user_token`
+    ADD COLUMN email VARCHAR(255) NULL;
@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_link_section_id;
+
+DROP INDEX IF EXISTS idx_section_shelf_id;
+
+DROP INDEX IF EXISTS idx_shelf_user_id;
+
+DROP TABLE IF EXISTS "link";
+
+DROP TABLE IF EXISTS "section";
+
+DROP TABLE IF EXISTS "shelf";
+
+DROP TABLE IF EXISTS "user";
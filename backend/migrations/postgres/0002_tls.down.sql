@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "certificate";
+
+DROP INDEX IF EXISTS idx_shelf_domain;
+
+ALTER TABLE "shelf" DROP COLUMN IF EXISTS domain_verified_at;
+
+ALTER TABLE "shelf" DROP COLUMN IF EXISTS domain_verification_token;
@@ -0,0 +1,13 @@
+ALTER TABLE "shelf" ADD COLUMN IF NOT EXISTS domain_verification_token VARCHAR(64);
+
+ALTER TABLE "shelf" ADD COLUMN IF NOT EXISTS domain_verified_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_shelf_domain
+    ON "shelf"(domain);
+
+CREATE TABLE IF NOT EXISTS "certificate" (
+    name VARCHAR(255) NOT NULL,
+    data BYTEA NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_certificate PRIMARY KEY (name)
+);
@@ -0,0 +1,23 @@
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS "user_token" (
+    id CHAR(36) NOT NULL,
+    user_id CHAR(36) NOT NULL,
+    purpose VARCHAR(32) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_user_token PRIMARY KEY (id),
+    CONSTRAINT uq_user_token_hash UNIQUE (token_hash),
+    CONSTRAINT fk_user_token_user
+        FOREIGN KEY (user_id)
+        REFERENCES "user"(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_token_user_id
+    ON "user_token"(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_user_token_expires_at
+    ON "user_token"(expires_at);
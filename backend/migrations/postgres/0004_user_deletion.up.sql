@@ -0,0 +1,5 @@
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP;
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS purge_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_user_purge_at
+    ON "user"(purge_at);
@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS "user_totp" (
+    user_id CHAR(36) NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    confirmed_at TIMESTAMP,
+    last_used_step BIGINT,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_user_totp PRIMARY KEY (user_id),
+    CONSTRAINT fk_user_totp_user
+        FOREIGN KEY (user_id)
+        REFERENCES "user"(id)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS "user_recovery_code" (
+    id CHAR(36) NOT NULL,
+    user_id CHAR(36) NOT NULL,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_user_recovery_code PRIMARY KEY (id),
+    CONSTRAINT uq_user_recovery_code UNIQUE (user_id, code_hash),
+    CONSTRAINT fk_user_recovery_code_user
+        FOREIGN KEY (user_id)
+        REFERENCES "user"(id)
+        ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS "user_session" (
+    id CHAR(36) NOT NULL,
+    user_id CHAR(36) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_user_session PRIMARY KEY (id),
+    CONSTRAINT uq_user_session_hash UNIQUE (token_hash),
+    CONSTRAINT fk_user_session_user
+        FOREIGN KEY (user_id)
+        REFERENCES "user"(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_session_user_id
+    ON "user_session"(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_user_session_expires_at
+    ON "user_session"(expires_at);
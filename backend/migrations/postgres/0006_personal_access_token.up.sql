@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS "personal_access_token" (
+    id CHAR(36) NOT NULL,
+    user_id CHAR(36) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    scopes VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_personal_access_token PRIMARY KEY (id),
+    CONSTRAINT uq_personal_access_token_hash UNIQUE (token_hash),
+    CONSTRAINT fk_personal_access_token_user
+        FOREIGN KEY (user_id)
+        REFERENCES "user"(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_personal_access_token_user_id
+    ON "personal_access_token"(user_id);
@@ -0,0 +1,6 @@
+ALTER TABLE "user_session" DROP CONSTRAINT IF EXISTS fk_user_session_impersonator;
+ALTER TABLE "user_session" DROP COLUMN IF EXISTS impersonator_id;
+
+ALTER TABLE "shelf" DROP COLUMN IF EXISTS unpublished_at;
+
+ALTER TABLE "user" DROP COLUMN IF EXISTS role;
@@ -0,0 +1,9 @@
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'user';
+
+ALTER TABLE "shelf" ADD COLUMN IF NOT EXISTS unpublished_at TIMESTAMP;
+
+ALTER TABLE "user_session" ADD COLUMN IF NOT EXISTS impersonator_id CHAR(36);
+ALTER TABLE "user_session" ADD CONSTRAINT fk_user_session_impersonator
+    FOREIGN KEY (impersonator_id)
+    REFERENCES "user"(id)
+    ON DELETE CASCADE;
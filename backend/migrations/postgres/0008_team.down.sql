@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS "shelf_share";
+
+-- Shelves without a user can't be kept once the ownership by teams is gone.
+DELETE FROM "shelf" WHERE user_id IS NULL;
+ALTER TABLE "shelf" DROP CONSTRAINT IF EXISTS fk_shelf_team;
+ALTER TABLE "shelf" DROP COLUMN IF EXISTS team_id;
+ALTER TABLE "shelf" ALTER COLUMN user_id SET NOT NULL;
+
+DROP TABLE IF EXISTS "team_member";
+DROP TABLE IF EXISTS "team";
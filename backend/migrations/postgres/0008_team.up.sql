@@ -0,0 +1,55 @@
+CREATE TABLE IF NOT EXISTS "team" (
+    id CHAR(36) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_team PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS "team_member" (
+    team_id CHAR(36) NOT NULL,
+    user_id CHAR(36) NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_team_member PRIMARY KEY (team_id, user_id),
+    CONSTRAINT fk_team_member_team
+        FOREIGN KEY (team_id)
+        REFERENCES "team"(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_team_member_user
+        FOREIGN KEY (user_id)
+        REFERENCES "user"(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_member_user_id
+    ON "team_member"(user_id);
+
+-- Team shelves belong to the team alone, so they outlive the accounts of their members.
+ALTER TABLE "shelf" ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE "shelf" ADD COLUMN IF NOT EXISTS team_id CHAR(36);
+ALTER TABLE "shelf" ADD CONSTRAINT fk_shelf_team
+    FOREIGN KEY (team_id)
+    REFERENCES "team"(id)
+    ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_shelf_team_id
+    ON "shelf"(team_id);
+
+CREATE TABLE IF NOT EXISTS "shelf_share" (
+    shelf_id CHAR(36) NOT NULL,
+    user_id CHAR(36) NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_shelf_share PRIMARY KEY (shelf_id, user_id),
+    CONSTRAINT fk_shelf_share_shelf
+        FOREIGN KEY (shelf_id)
+        REFERENCES "shelf"(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_shelf_share_user
+        FOREIGN KEY (user_id)
+        REFERENCES "user"(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_shelf_share_user_id
+    ON "shelf_share"(user_id);
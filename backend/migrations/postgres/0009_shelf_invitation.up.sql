@@ -0,0 +1,34 @@
+-- Invitations without an email are shareable links, whoever opens the link first may accept them.
+CREATE TABLE IF NOT EXISTS "shelf_invitation" (
+    id CHAR(36) NOT NULL,
+    shelf_id CHAR(36) NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    email VARCHAR(255),
+    token_hash CHAR(64) NOT NULL,
+    invited_by CHAR(36),
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP,
+    accepted_by CHAR(36),
+    declined_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_shelf_invitation PRIMARY KEY (id),
+    CONSTRAINT uq_shelf_invitation_hash UNIQUE (token_hash),
+    CONSTRAINT fk_shelf_invitation_shelf
+        FOREIGN KEY (shelf_id)
+        REFERENCES "shelf"(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_shelf_invitation_invited_by
+        FOREIGN KEY (invited_by)
+        REFERENCES "user"(id)
+        ON DELETE SET NULL,
+    CONSTRAINT fk_shelf_invitation_accepted_by
+        FOREIGN KEY (accepted_by)
+        REFERENCES "user"(id)
+        ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_shelf_invitation_shelf_id
+    ON "shelf_invitation"(shelf_id);
+
+CREATE INDEX IF NOT EXISTS idx_shelf_invitation_expires_at
+    ON "shelf_invitation"(expires_at);
@@ -0,0 +1,28 @@
+-- Audit events have no foreign keys, so they outlive the users and shelves they refer to. The repository
+-- offers no way to remove them, it only redacts the personal data of purged users.
+CREATE TABLE IF NOT EXISTS "audit_event" (
+    id CHAR(36) NOT NULL,
+    actor_id CHAR(36),
+    impersonator_id CHAR(36),
+    action VARCHAR(16) NOT NULL,
+    entity_type VARCHAR(32) NOT NULL,
+    entity_id VARCHAR(255) NOT NULL,
+    shelf_id CHAR(36),
+    changes TEXT NOT NULL,
+    request_id VARCHAR(128),
+    ip VARCHAR(45),
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT pk_audit_event PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_event_created_at
+    ON "audit_event"(created_at);
+
+CREATE INDEX IF NOT EXISTS idx_audit_event_shelf_id
+    ON "audit_event"(shelf_id, created_at);
+
+CREATE INDEX IF NOT EXISTS idx_audit_event_entity
+    ON "audit_event"(entity_type, entity_id);
+
+CREATE INDEX IF NOT EXISTS idx_audit_event_actor_id
+    ON "audit_event"(actor_id);
@@ -0,0 +1,20 @@
+-- Without the columns the trash would come back to life, so it's emptied first.
+DELETE FROM "link" WHERE deleted_at IS NOT NULL;
+DELETE FROM "section" WHERE deleted_at IS NOT NULL;
+DELETE FROM "shelf" WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_link_deleted_at;
+DROP INDEX IF EXISTS idx_section_deleted_at;
+DROP INDEX IF EXISTS idx_shelf_deleted_at;
+
+ALTER TABLE "link" DROP CONSTRAINT IF EXISTS fk_link_deleted_by;
+ALTER TABLE "link" DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE "link" DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE "section" DROP CONSTRAINT IF EXISTS fk_section_deleted_by;
+ALTER TABLE "section" DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE "section" DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE "shelf" DROP CONSTRAINT IF EXISTS fk_shelf_deleted_by;
+ALTER TABLE "shelf" DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE "shelf" DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,31 @@
+-- Deleted shelves, sections and links stay in the trash until they're restored or purged. The children
+-- of a deleted item get the same deleted_at, which tells them apart from children deleted before.
+ALTER TABLE "shelf" ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE "shelf" ADD COLUMN IF NOT EXISTS deleted_by CHAR(36);
+ALTER TABLE "shelf" ADD CONSTRAINT fk_shelf_deleted_by
+    FOREIGN KEY (deleted_by)
+    REFERENCES "user"(id)
+    ON DELETE SET NULL;
+
+ALTER TABLE "section" ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE "section" ADD COLUMN IF NOT EXISTS deleted_by CHAR(36);
+ALTER TABLE "section" ADD CONSTRAINT fk_section_deleted_by
+    FOREIGN KEY (deleted_by)
+    REFERENCES "user"(id)
+    ON DELETE SET NULL;
+
+ALTER TABLE "link" ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE "link" ADD COLUMN IF NOT EXISTS deleted_by CHAR(36);
+ALTER TABLE "link" ADD CONSTRAINT fk_link_deleted_by
+    FOREIGN KEY (deleted_by)
+    REFERENCES "user"(id)
+    ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_shelf_deleted_at
+    ON "shelf"(deleted_at);
+
+CREATE INDEX IF NOT EXISTS idx_section_deleted_at
+    ON "section"(deleted_at);
+
+CREATE INDEX IF NOT EXISTS idx_link_deleted_at
+    ON "link"(deleted_at);
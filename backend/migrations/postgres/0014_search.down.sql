@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_link_search;
+DROP INDEX IF EXISTS idx_shelf_search;
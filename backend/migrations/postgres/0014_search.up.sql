@@ -0,0 +1,7 @@
+-- Full-text indexes of the searched attributes. URLs are split at their punctuation, so their host and path
+-- segments are found as words. The expressions have to be the same as the ones of the search queries.
+CREATE INDEX IF NOT EXISTS idx_shelf_search
+    ON "shelf" USING GIN (to_tsvector('simple', title || ' ' || COALESCE(description, '')));
+
+CREATE INDEX IF NOT EXISTS idx_link_search
+    ON "link" USING GIN (to_tsvector('simple', title || ' ' || regexp_replace(link, '[^[:alnum:]]+', ' ', 'g')));
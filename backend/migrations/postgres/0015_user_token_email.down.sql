@@ -0,0 +1 @@
+ALTER TABLE "user_token" DROP COLUMN IF EXISTS email;
@@ -0,0 +1,3 @@
+-- Tokens are bound to the email address they were mailed to, a changed address doesn't get verified by
+-- redeeming a token of the former one. Tokens issued before have no address and never verify one.
+ALTER TABLE "user_token" ADD COLUMN IF NOT EXISTS email VARCHAR(255);